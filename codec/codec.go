@@ -0,0 +1,398 @@
+// Package codec implements the registry of key and value codecs, and the
+// tagged encoding built on top of it, used by the binary serialization of the
+// fmap.Map and set.Set collections. It also holds the helpers, and the
+// registry of JSON key decoders, used by the JSON encoding of every
+// collection.
+//
+// A codec turns values of one Go type into bytes and back. Every codec is
+// registered with Register under a unique name, and that name, not the Go
+// type, is what gets written to the encoded data; so a codec name MUST NOT
+// change once data has been written with it. Codecs for key.Str, key.Int,
+// key.ByteSlice, string, int, int64, uint64, float64, bool and []byte are
+// registered by this package. User types (custom key.Hash implementations or
+// values) MUST be registered, normally from an init() function, before a
+// collection holding them is marshaled or unmarshaled.
+//
+// The encoded data is a sequence of unsigned varints (as written by
+// encoding/binary.PutUvarint) and byte strings, which are written as a uvarint
+// length followed by that many bytes:
+//
+//	data    = magic version body
+//	magic   = 4 bytes identifying the collection, eg. "FMAP" or "FSET"
+//	version = uvarint version of the collection's format
+//	body    = defined by the collection's format, from uvarints and values
+//	value   = tag [name] bytes
+//	tag     = uvarint; 0 for a nil value, otherwise the codec number
+//	name    = byte string; only present when a codec is first used
+//	bytes   = byte string as returned by the codec's EncodeFunc
+//
+// Codecs are numbered from 1 in the order they are first used in the data. A
+// tag one greater than the number of codecs seen so far introduces the next
+// codec, and is followed by its registered name. So each codec name is written
+// only once, and the data is self describing as long as the reader has the
+// same codecs registered.
+//
+// The JSON encoding of the collections is:
+//
+//	Map with only key.Str keys:  {"key": value, ...}
+//	any other Map:               [[key, value], ...]
+//	Set:                         [key, ...]
+//
+// Keys and values are encoded with encoding/json, so key.Str and string values
+// become JSON strings and key.Int becomes a JSON number. A key.ByteSlice key
+// becomes {"b64": "<base64>"}, as a bare base64 string would be read back as a
+// key.Str. The sorted collections write their keys in order.
+//
+// Values are decoded as the generic encoding/json types (float64, string,
+// []interface{}, map[string]interface{}, ...). Keys are decoded by the
+// functions registered with RegisterJSONKey, most recently registered first,
+// and then by the defaults: a JSON string becomes a key.Str, a JSON integer
+// becomes a key.Int, and {"b64": "<base64>"} becomes a key.ByteSlice. The keys of a JSON object are decoded the same way, as
+// JSON strings.
+package codec
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sync"
+
+	"github.com/lleo/go-functional-collections/key"
+)
+
+// EncodeFunc converts a value to its binary form.
+type EncodeFunc func(v interface{}) ([]byte, error)
+
+// DecodeFunc converts the binary form, made by the matching EncodeFunc, back
+// to a value.
+type DecodeFunc func(data []byte) (interface{}, error)
+
+var (
+	// ErrBadMagic is returned when the data does not start with the expected
+	// magic bytes.
+	ErrBadMagic = errors.New("codec: bad magic bytes")
+
+	// ErrCorrupt is returned when the data is truncated or malformed.
+	ErrCorrupt = errors.New("codec: corrupt data")
+)
+
+type codec struct {
+	name string
+	typ  reflect.Type
+	enc  EncodeFunc
+	dec  DecodeFunc
+}
+
+var (
+	registryMu sync.RWMutex
+	byName     = make(map[string]*codec)
+	byType     = make(map[reflect.Type]*codec)
+)
+
+// Register makes a codec available, under the given name, for values of the
+// same type as sample. Register panics if the name is empty, if sample is nil,
+// or if a codec is already registered with that name or for that type.
+func Register(name string, sample interface{}, enc EncodeFunc, dec DecodeFunc) {
+	if name == "" {
+		panic("codec: Register with an empty name")
+	}
+	if sample == nil {
+		panic("codec: Register with a nil sample")
+	}
+	if enc == nil || dec == nil {
+		panic("codec: Register with a nil EncodeFunc or DecodeFunc")
+	}
+
+	var typ = reflect.TypeOf(sample)
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, exists := byName[name]; exists {
+		panic(fmt.Sprintf("codec: name %q registered twice", name))
+	}
+	if c, exists := byType[typ]; exists {
+		panic(fmt.Sprintf("codec: type %s already registered as %q",
+			typ, c.name))
+	}
+
+	var c = &codec{name: name, typ: typ, enc: enc, dec: dec}
+	byName[name] = c
+	byType[typ] = c
+}
+
+// lookupType() returns the codec registered for the type of v, or nil.
+func lookupType(v interface{}) *codec {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return byType[reflect.TypeOf(v)]
+}
+
+// lookupName() returns the codec registered with the given name, or nil.
+func lookupName(name string) *codec {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return byName[name]
+}
+
+// An Encoder builds the encoded data in memory.
+type Encoder struct {
+	buf  []byte
+	tags map[*codec]uint64
+}
+
+// NewEncoder returns an Encoder with the given magic bytes and format version
+// already written.
+func NewEncoder(magic string, version uint64) *Encoder {
+	var e = &Encoder{
+		buf:  append([]byte(nil), magic...),
+		tags: make(map[*codec]uint64),
+	}
+	e.WriteUvarint(version)
+	return e
+}
+
+// WriteUvarint appends x as an unsigned varint.
+func (e *Encoder) WriteUvarint(x uint64) {
+	var tmp [binary.MaxVarintLen64]byte
+	var n = binary.PutUvarint(tmp[:], x)
+	e.buf = append(e.buf, tmp[:n]...)
+}
+
+// WriteBytes appends b as a byte string; its uvarint length followed by its
+// bytes.
+func (e *Encoder) WriteBytes(b []byte) {
+	e.WriteUvarint(uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+// Encode appends v, tagged with the codec registered for its type. It returns
+// an error if no codec is registered for the type of v, or if the codec fails.
+func (e *Encoder) Encode(v interface{}) error {
+	if v == nil {
+		e.WriteUvarint(0)
+		return nil
+	}
+
+	var c = lookupType(v)
+	if c == nil {
+		return fmt.Errorf("codec: no codec registered for type %T", v)
+	}
+
+	var b, err = c.enc(v)
+	if err != nil {
+		return err
+	}
+
+	var tag, seen = e.tags[c]
+	if seen {
+		e.WriteUvarint(tag)
+	} else {
+		tag = uint64(len(e.tags)) + 1
+		e.tags[c] = tag
+		e.WriteUvarint(tag)
+		e.WriteBytes([]byte(c.name))
+	}
+	e.WriteBytes(b)
+
+	return nil
+}
+
+// Bytes returns the encoded data.
+func (e *Encoder) Bytes() []byte {
+	return e.buf
+}
+
+// A Decoder reads data written by an Encoder.
+type Decoder struct {
+	data   []byte
+	codecs []*codec
+}
+
+// NewDecoder checks that data starts with the given magic bytes and returns a
+// Decoder positioned after them and the version that follows.
+func NewDecoder(data []byte, magic string) (*Decoder, uint64, error) {
+	if len(data) < len(magic) || string(data[:len(magic)]) != magic {
+		return nil, 0, ErrBadMagic
+	}
+	var d = &Decoder{data: data[len(magic):]}
+	var version, err = d.ReadUvarint()
+	if err != nil {
+		return nil, 0, err
+	}
+	return d, version, nil
+}
+
+// ReadUvarint reads an unsigned varint.
+func (d *Decoder) ReadUvarint() (uint64, error) {
+	var x, n = binary.Uvarint(d.data)
+	if n <= 0 {
+		return 0, ErrCorrupt
+	}
+	d.data = d.data[n:]
+	return x, nil
+}
+
+// ReadBytes reads a byte string. The returned slice refers to the data given
+// to NewDecoder.
+func (d *Decoder) ReadBytes() ([]byte, error) {
+	var l, err = d.ReadUvarint()
+	if err != nil {
+		return nil, err
+	}
+	if l > uint64(len(d.data)) {
+		return nil, ErrCorrupt
+	}
+	var b = d.data[:l:l]
+	d.data = d.data[l:]
+	return b, nil
+}
+
+// Decode reads the next value. It returns an error if the data names a codec
+// that is not registered, or if the codec fails.
+func (d *Decoder) Decode() (interface{}, error) {
+	var tag, err = d.ReadUvarint()
+	if err != nil {
+		return nil, err
+	}
+	if tag == 0 {
+		return nil, nil
+	}
+
+	switch {
+	case tag <= uint64(len(d.codecs)):
+	case tag == uint64(len(d.codecs))+1:
+		var name, err = d.ReadBytes()
+		if err != nil {
+			return nil, err
+		}
+		var c = lookupName(string(name))
+		if c == nil {
+			return nil, fmt.Errorf("codec: no codec registered as %q", name)
+		}
+		d.codecs = append(d.codecs, c)
+	default:
+		return nil, ErrCorrupt
+	}
+
+	b, err := d.ReadBytes()
+	if err != nil {
+		return nil, err
+	}
+	return d.codecs[tag-1].dec(b)
+}
+
+// Len returns the number of bytes not yet read.
+func (d *Decoder) Len() int {
+	return len(d.data)
+}
+
+func encodeInt64(i int64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	var n = binary.PutVarint(tmp[:], i)
+	return append([]byte(nil), tmp[:n]...)
+}
+
+func decodeInt64(data []byte) (int64, error) {
+	var i, n = binary.Varint(data)
+	if n <= 0 || n != len(data) {
+		return 0, ErrCorrupt
+	}
+	return i, nil
+}
+
+func init() {
+	Register("key.Str", key.Str(""),
+		func(v interface{}) ([]byte, error) {
+			return []byte(v.(key.Str)), nil
+		},
+		func(data []byte) (interface{}, error) {
+			return key.Str(data), nil
+		})
+	Register("key.Int", key.Int(0),
+		func(v interface{}) ([]byte, error) {
+			return encodeInt64(int64(v.(key.Int))), nil
+		},
+		func(data []byte) (interface{}, error) {
+			var i, err = decodeInt64(data)
+			return key.Int(i), err
+		})
+	Register("key.ByteSlice", key.ByteSlice(nil),
+		func(v interface{}) ([]byte, error) {
+			return v.(key.ByteSlice), nil
+		},
+		func(data []byte) (interface{}, error) {
+			return key.ByteSlice(append([]byte(nil), data...)), nil
+		})
+
+	Register("string", "",
+		func(v interface{}) ([]byte, error) {
+			return []byte(v.(string)), nil
+		},
+		func(data []byte) (interface{}, error) {
+			return string(data), nil
+		})
+	Register("int", int(0),
+		func(v interface{}) ([]byte, error) {
+			return encodeInt64(int64(v.(int))), nil
+		},
+		func(data []byte) (interface{}, error) {
+			var i, err = decodeInt64(data)
+			return int(i), err
+		})
+	Register("int64", int64(0),
+		func(v interface{}) ([]byte, error) {
+			return encodeInt64(v.(int64)), nil
+		},
+		func(data []byte) (interface{}, error) {
+			var i, err = decodeInt64(data)
+			return i, err
+		})
+	Register("uint64", uint64(0),
+		func(v interface{}) ([]byte, error) {
+			var tmp [binary.MaxVarintLen64]byte
+			var n = binary.PutUvarint(tmp[:], v.(uint64))
+			return append([]byte(nil), tmp[:n]...), nil
+		},
+		func(data []byte) (interface{}, error) {
+			var u, n = binary.Uvarint(data)
+			if n <= 0 || n != len(data) {
+				return uint64(0), ErrCorrupt
+			}
+			return u, nil
+		})
+	Register("float64", float64(0),
+		func(v interface{}) ([]byte, error) {
+			var b = make([]byte, 8)
+			binary.LittleEndian.PutUint64(b, math.Float64bits(v.(float64)))
+			return b, nil
+		},
+		func(data []byte) (interface{}, error) {
+			if len(data) != 8 {
+				return float64(0), ErrCorrupt
+			}
+			return math.Float64frombits(binary.LittleEndian.Uint64(data)), nil
+		})
+	Register("bool", false,
+		func(v interface{}) ([]byte, error) {
+			if v.(bool) {
+				return []byte{1}, nil
+			}
+			return []byte{0}, nil
+		},
+		func(data []byte) (interface{}, error) {
+			if len(data) != 1 || data[0] > 1 {
+				return false, ErrCorrupt
+			}
+			return data[0] == 1, nil
+		})
+	Register("[]byte", []byte(nil),
+		func(v interface{}) ([]byte, error) {
+			return v.([]byte), nil
+		},
+		func(data []byte) (interface{}, error) {
+			return append([]byte(nil), data...), nil
+		})
+}
@@ -0,0 +1,222 @@
+package codec_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/lleo/go-functional-collections/codec"
+	"github.com/lleo/go-functional-collections/key"
+	"github.com/lleo/go-functional-collections/key/hash"
+)
+
+// point is a user defined key.Hash type.
+type point struct{ x, y int }
+
+func (p point) Hash() hash.Val {
+	return hash.Calculate(append(key.Int2ByteSlice(p.x), key.Int2ByteSlice(p.y)...))
+}
+
+func (p point) Equals(okey key.Hash) bool {
+	var op, ok = okey.(point)
+	return ok && p == op
+}
+
+func (p point) String() string {
+	return key.Int(p.x).String() + "," + key.Int(p.y).String()
+}
+
+func init() {
+	codec.Register("codec_test.point", point{},
+		func(v interface{}) ([]byte, error) {
+			var p = v.(point)
+			return append(key.Int2ByteSlice(p.x), key.Int2ByteSlice(p.y)...), nil
+		},
+		func(data []byte) (interface{}, error) {
+			var n = len(data) / 2
+			var p point
+			for i := n - 1; i >= 0; i-- {
+				p.x = p.x<<8 | int(data[i])
+				p.y = p.y<<8 | int(data[n+i])
+			}
+			return p, nil
+		})
+}
+
+func TestRoundTrip(t *testing.T) {
+	var vals = []interface{}{
+		key.Str("a"), key.Int(-7), key.ByteSlice("bs"),
+		"str", 1 << 40, int64(-1), uint64(1 << 63), 3.25, true, false,
+		nil, point{3, 4}, key.Str("b"), point{5, 6},
+	}
+
+	var enc = codec.NewEncoder("TEST", 3)
+	enc.WriteUvarint(uint64(len(vals)))
+	for _, v := range vals {
+		if err := enc.Encode(v); err != nil {
+			t.Fatalf("enc.Encode(%#v) failed: %s", v, err)
+		}
+	}
+	if err := enc.Encode([]byte("raw")); err != nil {
+		t.Fatalf("enc.Encode([]byte) failed: %s", err)
+	}
+
+	var dec, version, err = codec.NewDecoder(enc.Bytes(), "TEST")
+	if err != nil {
+		t.Fatalf("codec.NewDecoder() failed: %s", err)
+	}
+	if version != 3 {
+		t.Fatalf("version,%d != 3", version)
+	}
+	if n, err := dec.ReadUvarint(); err != nil || n != uint64(len(vals)) {
+		t.Fatalf("dec.ReadUvarint(),%d,%v != %d,nil", n, err, len(vals))
+	}
+	for _, v := range vals {
+		var dv, err = dec.Decode()
+		if err != nil {
+			t.Fatalf("dec.Decode() of %#v failed: %s", v, err)
+		}
+		if bs, ok := v.(key.ByteSlice); ok {
+			if !bs.Equals(dv.(key.Hash)) {
+				t.Fatalf("dec.Decode(),%#v != %#v", dv, v)
+			}
+			continue
+		}
+		if dv != v {
+			t.Fatalf("dec.Decode(),%#v != %#v", dv, v)
+		}
+	}
+	if dv, err := dec.Decode(); err != nil ||
+		!bytes.Equal(dv.([]byte), []byte("raw")) {
+		t.Fatalf("dec.Decode(),%#v,%v != []byte(\"raw\"),nil", dv, err)
+	}
+	if dec.Len() != 0 {
+		t.Fatalf("dec.Len(),%d != 0", dec.Len())
+	}
+}
+
+func TestErrors(t *testing.T) {
+	var enc = codec.NewEncoder("TEST", 1)
+	if err := enc.Encode(struct{}{}); err == nil {
+		t.Fatal("enc.Encode() of an unregistered type did not fail")
+	}
+
+	if _, _, err := codec.NewDecoder([]byte("NOPE\x01"), "TEST"); err !=
+		codec.ErrBadMagic {
+		t.Fatalf("codec.NewDecoder() of bad magic returned %v", err)
+	}
+
+	enc.Encode(key.Str("abc"))
+	var data = enc.Bytes()
+	var dec, _, _ = codec.NewDecoder(data[:len(data)-1], "TEST")
+	if _, err := dec.Decode(); err != codec.ErrCorrupt {
+		t.Fatalf("dec.Decode() of truncated data returned %v", err)
+	}
+
+	dec, _, _ = codec.NewDecoder([]byte("TEST\x01\x01\x03bad\x00"), "TEST")
+	if _, err := dec.Decode(); err == nil {
+		t.Fatal("dec.Decode() of an unknown codec name did not fail")
+	}
+
+	dec, _, _ = codec.NewDecoder([]byte("TEST\x01\x02\x00"), "TEST")
+	if _, err := dec.Decode(); err != codec.ErrCorrupt {
+		t.Fatalf("dec.Decode() of an undefined tag returned %v", err)
+	}
+}
+
+func TestRegisterTwice(t *testing.T) {
+	var mustPanic = func(name string, sample interface{}) {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("codec.Register(%q, %T) did not panic", name, sample)
+			}
+		}()
+		codec.Register(name, sample,
+			func(interface{}) ([]byte, error) { return nil, nil },
+			func([]byte) (interface{}, error) { return nil, nil })
+	}
+	mustPanic("key.Str", struct{ a int }{})
+	mustPanic("other name", key.Str(""))
+	mustPanic("", struct{ b int }{})
+}
+
+func TestJSONKeys(t *testing.T) {
+	if k, err := codec.DecodeJSONKey(json.RawMessage(`"a"`)); err != nil ||
+		k != key.Str("a") {
+		t.Fatalf("codec.DecodeJSONKey(\"a\"),%#v,%v != key.Str(\"a\"),nil", k, err)
+	}
+	if k, err := codec.DecodeJSONKey(json.RawMessage(`-3`)); err != nil ||
+		k != key.Int(-3) {
+		t.Fatalf("codec.DecodeJSONKey(-3),%#v,%v != key.Int(-3),nil", k, err)
+	}
+	if _, err := codec.DecodeJSONKey(json.RawMessage(`{}`)); err == nil {
+		t.Fatal("codec.DecodeJSONKey({}) did not fail")
+	}
+
+	codec.RegisterJSONKey(func(data json.RawMessage) (interface{}, bool) {
+		var xy []int
+		if err := json.Unmarshal(data, &xy); err != nil || len(xy) != 2 {
+			return nil, false
+		}
+		return point{xy[0], xy[1]}, true
+	})
+
+	var n int
+	var err = codec.DecodeJSONPairs([]byte(`[[[1,2],"a"],["b",3]]`),
+		func(k, v interface{}) error {
+			switch n {
+			case 0:
+				if k != (point{1, 2}) || v != "a" {
+					t.Fatalf("pair 0 is %#v,%#v", k, v)
+				}
+			case 1:
+				if k != key.Str("b") || v != 3.0 {
+					t.Fatalf("pair 1 is %#v,%#v", k, v)
+				}
+			}
+			n++
+			return nil
+		})
+	if err != nil || n != 2 {
+		t.Fatalf("codec.DecodeJSONPairs() decoded %d pairs, err=%v", n, err)
+	}
+
+	data, err := codec.EncodeJSONPairs(
+		[]interface{}{key.Str("a"), key.Str("b")}, []interface{}{1, nil})
+	if err != nil || string(data) != `{"a":1,"b":null}` {
+		t.Fatalf("codec.EncodeJSONPairs(),%s,%v != {\"a\":1,\"b\":null},nil",
+			data, err)
+	}
+
+	for _, k := range []interface{}{key.ByteSlice("abc"), key.ByteSlice{},
+		key.Str("YWJj"), key.Int(7)} {
+		var data, err = codec.EncodeJSONKey(k)
+		if err != nil {
+			t.Fatalf("codec.EncodeJSONKey(%#v) failed: %s", k, err)
+		}
+		var dk, derr = codec.DecodeJSONKey(data)
+		if derr != nil || !k.(key.Hash).Equals(dk.(key.Hash)) {
+			t.Fatalf("codec.DecodeJSONKey(%s),%#v,%v != %#v,nil", data, dk,
+				derr, k)
+		}
+		if _, isByteSlice := k.(key.ByteSlice); isByteSlice !=
+			bytes.HasPrefix(data, []byte(`{"b64":`)) {
+			t.Fatalf("codec.EncodeJSONKey(%#v),%s", k, data)
+		}
+	}
+
+	data, err = codec.EncodeJSONPairs(
+		[]interface{}{key.ByteSlice("a"), key.Str("a")}, []interface{}{1, 2})
+	if err != nil || string(data) != `[[{"b64":"YQ=="},1],["a",2]]` {
+		t.Fatalf("codec.EncodeJSONPairs() of key.ByteSlice,%s,%v", data, err)
+	}
+	var keys []interface{}
+	err = codec.DecodeJSONPairs(data, func(k, v interface{}) error {
+		keys = append(keys, k)
+		return nil
+	})
+	if err != nil || len(keys) != 2 ||
+		!key.ByteSlice("a").Equals(keys[0].(key.Hash)) || keys[1] != key.Str("a") {
+		t.Fatalf("codec.DecodeJSONPairs(%s) keys,%#v,%v", data, keys, err)
+	}
+}
@@ -0,0 +1,207 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/lleo/go-functional-collections/key"
+)
+
+// JSONKeyFunc converts the JSON encoding of a key back to the key. It returns
+// false if it does not recognize the JSON as one of its keys.
+type JSONKeyFunc func(data json.RawMessage) (interface{}, bool)
+
+var jsonKeyFuncs []JSONKeyFunc
+
+// RegisterJSONKey adds a function for decoding JSON keys. It is tried before
+// every previously registered function.
+func RegisterJSONKey(fn JSONKeyFunc) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	jsonKeyFuncs = append(jsonKeyFuncs, fn)
+}
+
+// DecodeJSONKey converts the JSON encoding of a key back to the key, using the
+// functions registered with RegisterJSONKey and then the defaults.
+func DecodeJSONKey(data json.RawMessage) (interface{}, error) {
+	registryMu.RLock()
+	var fns = jsonKeyFuncs
+	registryMu.RUnlock()
+
+	for i := len(fns) - 1; i >= 0; i-- {
+		if k, ok := fns[i](data); ok {
+			return k, nil
+		}
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		return key.Str(s), nil
+	}
+	var i int
+	if err := json.Unmarshal(data, &i); err == nil {
+		return key.Int(i), nil
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err == nil && len(obj) == 1 {
+		if b64, found := obj["b64"]; found {
+			var bs []byte
+			if err = json.Unmarshal(b64, &bs); err == nil && bs != nil {
+				return key.ByteSlice(bs), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("codec: no JSON key decoder for %s", data)
+}
+
+// byteSliceJSONKey is the JSON encoding of a key.ByteSlice key.
+type byteSliceJSONKey struct {
+	B64 []byte `json:"b64"`
+}
+
+// EncodeJSONKey returns the JSON encoding of a key, which DecodeJSONKey
+// converts back to the key. A key.ByteSlice is encoded as {"b64": "<base64>"}
+// and every other key by encoding/json.
+func EncodeJSONKey(k interface{}) ([]byte, error) {
+	if bs, isByteSlice := k.(key.ByteSlice); isByteSlice {
+		if bs == nil {
+			bs = key.ByteSlice{}
+		}
+		return json.Marshal(byteSliceJSONKey{bs})
+	}
+	return json.Marshal(k)
+}
+
+// EncodeJSONKeys returns the JSON encoding of a Set with the given keys, in
+// the given order.
+func EncodeJSONKeys(keys []interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, k := range keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		var kb, err = EncodeJSONKey(k)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(kb)
+	}
+	buf.WriteByte(']')
+	return buf.Bytes(), nil
+}
+
+// EncodeJSONPairs returns the JSON encoding of a Map with the given keys and
+// values, in the given order.
+func EncodeJSONPairs(keys, vals []interface{}) ([]byte, error) {
+	var isObject = true
+	for _, k := range keys {
+		if _, isStr := k.(key.Str); !isStr {
+			isObject = false
+			break
+		}
+	}
+
+	var buf bytes.Buffer
+	if isObject {
+		buf.WriteByte('{')
+	} else {
+		buf.WriteByte('[')
+	}
+	for i, k := range keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		var kb, err = EncodeJSONKey(k)
+		if err != nil {
+			return nil, err
+		}
+		vb, err := json.Marshal(vals[i])
+		if err != nil {
+			return nil, err
+		}
+		if isObject {
+			buf.Write(kb)
+			buf.WriteByte(':')
+			buf.Write(vb)
+		} else {
+			buf.WriteByte('[')
+			buf.Write(kb)
+			buf.WriteByte(',')
+			buf.Write(vb)
+			buf.WriteByte(']')
+		}
+	}
+	if isObject {
+		buf.WriteByte('}')
+	} else {
+		buf.WriteByte(']')
+	}
+
+	return buf.Bytes(), nil
+}
+
+// DecodeJSONPairs decodes the JSON encoding of a Map, either form, calling fn
+// for every key/value pair. It stops at the first error returned by fn.
+func DecodeJSONPairs(data []byte, fn func(k, v interface{}) error) error {
+	var trimmed = bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		var obj map[string]interface{}
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return err
+		}
+		for s, v := range obj {
+			var kb, _ = json.Marshal(s)
+			var k, err = DecodeJSONKey(kb)
+			if err != nil {
+				return err
+			}
+			if err = fn(k, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var pairs [][]json.RawMessage
+	if err := json.Unmarshal(data, &pairs); err != nil {
+		return err
+	}
+	for _, pair := range pairs {
+		if len(pair) != 2 {
+			return fmt.Errorf("codec: JSON pair has %d elements", len(pair))
+		}
+		var k, err = DecodeJSONKey(pair[0])
+		if err != nil {
+			return err
+		}
+		var v interface{}
+		if err = json.Unmarshal(pair[1], &v); err != nil {
+			return err
+		}
+		if err = fn(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DecodeJSONKeys decodes the JSON encoding of a Set, calling fn for every key.
+// It stops at the first error returned by fn.
+func DecodeJSONKeys(data []byte, fn func(k interface{}) error) error {
+	var raws []json.RawMessage
+	if err := json.Unmarshal(data, &raws); err != nil {
+		return err
+	}
+	for _, raw := range raws {
+		var k, err = DecodeJSONKey(raw)
+		if err != nil {
+			return err
+		}
+		if err = fn(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,508 @@
+package fmap
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/lleo/go-functional-collections/codec"
+	"github.com/lleo/go-functional-collections/key/hash"
+	"github.com/lleo/go-functional-collections/merkle"
+)
+
+// The anti-entropy protocol synchronizes a replica of a Map with its source,
+// walking the hash paths of the HAMT level by level. Every round the replica
+// sends a request naming the hash path prefixes it found different, and the
+// source answers each prefix with either the digests of the prefixes one level
+// down, when it holds a table at that prefix, or with every key/value pair
+// under the prefix, when it holds a leaf or nothing. The replica compares the
+// digests to its own and asks about the different ones in the next round.
+//
+// The digest of a prefix is the sum of the merkle.Entry digests of the
+// key/value pairs under it, so it does not depend on the shape of either HAMT;
+// a prefix holding a leaf in one Map and a table in the other compares equal
+// if they hold the same key/value pairs. Equal digests are not a proof of
+// equal key/value pairs (see the merkle package), so the key/value pairs of
+// both Maps MUST be trusted: whoever chooses them can make a replica skip a
+// prefix that differs.
+//
+// Every message is a uvarint length followed by a blob encoded with the codec
+// package. Version 3 of the three kinds of blob is:
+//
+//	"FMSQ" 3 algorithm size seed layout numPrefixes (depth hashPath)*numPrefixes
+//	"FMSR" 3 (0 bitmap digest*popcount(bitmap) | 1 numKeyVals (key val)*numKeyVals)*numPrefixes
+//	"FMSE" 3 reason
+//
+// where algorithm, size, seed, layout, numPrefixes, depth, hashPath, bitmap
+// and numKeyVals are uvarints, every digest and the reason are byte strings,
+// and every key and val is a value encoded with the codec package. algorithm,
+// size and seed are the hash.Config and hash.SeedDigest of the replica, and
+// layout the hash.Layout of its Map. The source answers a request it cannot
+// serve, because any of them differ from its own, with an "FMSE" blob giving
+// the reason, and the replica fails with it. A request with no prefixes ends
+// the synchronization.
+const (
+	syncRequestMagic  = "FMSQ"
+	syncResponseMagic = "FMSR"
+	syncErrorMagic    = "FMSE"
+	syncVersion       = 3
+
+	syncDigests  = 0
+	syncKeyVals  = 1
+	syncMaxBytes = 1 << 28
+)
+
+// syncConfig is what both ends of a synchronization must agree on, for their
+// hash path prefixes to match.
+type syncConfig struct {
+	algorithm hash.Algorithm
+	size      uint
+	seed      uint64
+	layout    hash.Layout
+}
+
+func newSyncConfig(l hash.Layout) syncConfig {
+	var alg, size = hash.Config()
+	return syncConfig{alg, size, hash.SeedDigest(), l}
+}
+
+// check() returns why a source of configuration c cannot serve a replica of
+// configuration r, or "".
+func (c syncConfig) check(r syncConfig) string {
+	switch {
+	case c.algorithm != r.algorithm || c.size != r.size:
+		return fmt.Sprintf("cannot sync a Map hashed with %s/%d "+
+			"with a replica hashed with %s/%d",
+			c.algorithm, c.size, r.algorithm, r.size)
+	case c.seed != r.seed:
+		return fmt.Sprintf("cannot sync a Map hashed with %s "+
+			"with a replica using a different seed", c.algorithm)
+	case c.layout != r.layout:
+		return fmt.Sprintf("cannot sync a Map of TableWidth %d "+
+			"with a replica of TableWidth %d",
+			c.layout.IndexLimit(), r.layout.IndexLimit())
+	}
+	return ""
+}
+
+// syncPrefix is a hash path prefix; the first depth indexes of hashPath, in
+// the given hash.Layout.
+type syncPrefix struct {
+	depth    uint
+	hashPath hash.Val
+	layout   hash.Layout
+}
+
+func (p syncPrefix) child(idx uint) syncPrefix {
+	return syncPrefix{
+		depth:    p.depth + 1,
+		hashPath: p.hashPath | hash.Val(idx)<<(p.depth*uint(p.layout)),
+		layout:   p.layout,
+	}
+}
+
+func (p syncPrefix) matches(hv hash.Val) bool {
+	return (hv^p.hashPath)&p.mask() == 0
+}
+
+// mask() returns the bits of a hash.Val in the prefix. Unlike
+// hash.Layout.HashPath, it allows a depth of the Layout's DepthLimit().
+func (p syncPrefix) mask() hash.Val {
+	return hash.Val(uint64(1)<<(p.depth*uint(p.layout)) - 1)
+}
+
+// ServeSync answers the requests of a replica calling SyncFrom on the other
+// end of rw, until the replica ends the synchronization. The Map is the
+// source; it is never modified.
+//
+// Every key and value MUST have a codec registered with the codec package.
+// Both ends MUST use the same hash.Configure settings and seed, and both Maps
+// MUST have the same Options; if they do not, ServeSync tells the replica why
+// before failing.
+func (m *Map) ServeSync(rw io.ReadWriter) error {
+	var r = bufio.NewReader(rw)
+	var c = newSyncConfig(m.layout())
+	for {
+		var rc, prefixes, err = readSyncRequest(r)
+		if err != nil {
+			return err
+		}
+		if reason := c.check(rc); reason != "" {
+			var enc = codec.NewEncoder(syncErrorMagic, syncVersion)
+			enc.WriteBytes([]byte(reason))
+			if err = writeSyncMessage(rw, enc.Bytes()); err != nil {
+				return err
+			}
+			return fmt.Errorf("fmap: %s", reason)
+		}
+		if len(prefixes) == 0 {
+			return nil
+		}
+		var l = c.layout
+
+		var enc = codec.NewEncoder(syncResponseMagic, syncVersion)
+		for _, p := range prefixes {
+			var n = m.prefixNode(p)
+			if t, isTable := n.(tableI); isTable {
+				var bitmap uint64
+				var digests []merkle.Digest
+				for idx := uint(0); idx < l.IndexLimit(); idx++ {
+					if cn := t.get(idx); cn != nil {
+						bitmap |= 1 << idx
+						digests = append(digests, nodeDigest(cn))
+					}
+				}
+				enc.WriteUvarint(syncDigests)
+				enc.WriteUvarint(bitmap)
+				for _, d := range digests {
+					enc.WriteBytes(d[:])
+				}
+				continue
+			}
+
+			var kvs = prefixKeyVals(n, p)
+			enc.WriteUvarint(syncKeyVals)
+			enc.WriteUvarint(uint64(len(kvs)))
+			for _, kv := range kvs {
+				if err = enc.Encode(kv.Key); err != nil {
+					return err
+				}
+				if err = enc.Encode(kv.Val); err != nil {
+					return err
+				}
+			}
+		}
+		if err = writeSyncMessage(rw, enc.Bytes()); err != nil {
+			return err
+		}
+	}
+}
+
+// SyncFrom synchronizes the Map with the source Map calling ServeSync on the
+// other end of rw, and returns a new Map holding the same key/value pairs as
+// the source. Only the key/value pairs under the hash path prefixes that
+// differ are transferred, so synchronizing two nearly equal Maps costs a few
+// digests per level of the HAMT, plus the leaves that differ.
+//
+// Every key and value MUST have a codec registered with the codec package.
+// Both ends MUST use the same hash.Configure settings and seed, and both Maps
+// MUST have the same Options; if they do not, SyncFrom returns the error the
+// source sent.
+func (m *Map) SyncFrom(rw io.ReadWriter) (*Map, error) {
+	var r = bufio.NewReader(rw)
+	var t = m.Transient()
+
+	// The prefixes of each round are disjoint from those of the rounds
+	// before, so the changes made to t never affect the digests of m still to
+	// be compared.
+	var c = newSyncConfig(m.layout())
+	var prefixes = []syncPrefix{{layout: c.layout}}
+	for len(prefixes) > 0 {
+		if err := writeSyncRequest(rw, c, prefixes); err != nil {
+			return nil, err
+		}
+		var dec, err = readSyncResponse(r)
+		if err != nil {
+			return nil, err
+		}
+
+		var next []syncPrefix
+		for _, p := range prefixes {
+			var kind, err = dec.ReadUvarint()
+			if err != nil {
+				return nil, err
+			}
+			switch kind {
+			case syncDigests:
+				next, err = syncDigestsFrom(dec, m, t, p, next)
+			case syncKeyVals:
+				err = syncKeyValsFrom(dec, m, t, p)
+			default:
+				err = codec.ErrCorrupt
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		if dec.Len() != 0 {
+			return nil, codec.ErrCorrupt
+		}
+		prefixes = next
+	}
+
+	if err := writeSyncRequest(rw, c, nil); err != nil {
+		return nil, err
+	}
+	return t.Persistent(), nil
+}
+
+// syncDigestsFrom() compares the source's digests of the prefixes one level
+// below p to those of m. The prefixes missing from the source are removed from
+// t; those that differ are appended to next.
+func syncDigestsFrom(
+	dec *codec.Decoder,
+	m *Map,
+	t *Transient,
+	p syncPrefix,
+	next []syncPrefix,
+) ([]syncPrefix, error) {
+	var bitmap, err = dec.ReadUvarint()
+	if err != nil {
+		return nil, err
+	}
+	if p.depth >= p.layout.DepthLimit() || bitmap>>p.layout.IndexLimit() != 0 {
+		return nil, codec.ErrCorrupt
+	}
+
+	for idx := uint(0); idx < p.layout.IndexLimit(); idx++ {
+		var cp = p.child(idx)
+		var n = m.prefixNode(cp)
+		var d, nonEmpty = prefixDigest(n, cp)
+		if bitmap&(1<<idx) == 0 {
+			if nonEmpty {
+				for _, kv := range prefixKeyVals(n, cp) {
+					t.Del(kv.Key)
+				}
+			}
+			continue
+		}
+
+		var b, err = dec.ReadBytes()
+		if err != nil {
+			return nil, err
+		}
+		if len(b) != len(d) {
+			return nil, codec.ErrCorrupt
+		}
+		if !nonEmpty || string(b) != string(d[:]) {
+			next = append(next, cp)
+		}
+	}
+	return next, nil
+}
+
+// syncKeyValsFrom() replaces the key/value pairs of t under p with those sent
+// by the source.
+func syncKeyValsFrom(
+	dec *codec.Decoder,
+	m *Map,
+	t *Transient,
+	p syncPrefix,
+) error {
+	var numKeyVals, err = dec.ReadUvarint()
+	if err != nil {
+		return err
+	}
+	if numKeyVals > uint64(dec.Len()/2) {
+		return codec.ErrCorrupt
+	}
+
+	var kvs = make([]KeyVal, numKeyVals)
+	for i := range kvs {
+		if kvs[i], err = decodeKeyVal(dec); err != nil {
+			return err
+		}
+		if !p.matches(kvs[i].Key.Hash()) {
+			return codec.ErrCorrupt
+		}
+	}
+
+	var src = NewFromList(kvs)
+	for _, kv := range prefixKeyVals(m.prefixNode(p), p) {
+		if _, found := src.Load(kv.Key); !found {
+			t.Del(kv.Key)
+		}
+	}
+	for _, kv := range kvs {
+		t.Put(kv.Key, kv.Val)
+	}
+	return nil
+}
+
+// prefixNode() returns the node at the hash path prefix: a table at that
+// depth, a leaf at that depth or above, which may hold keys outside the
+// prefix, or nil.
+func (m *Map) prefixNode(p syncPrefix) nodeI {
+	var n nodeI = m.root
+	for depth := uint(0); depth < p.depth; depth++ {
+		var t, isTable = n.(tableI)
+		if !isTable {
+			return n
+		}
+		if n = t.get(p.layout.Index(p.hashPath, depth)); n == nil {
+			return nil
+		}
+	}
+	return n
+}
+
+// prefixDigest() returns the digest of the key/value pairs of the node, as
+// returned by prefixNode(), under the prefix, and false if there are none.
+func prefixDigest(n nodeI, p syncPrefix) (merkle.Digest, bool) {
+	switch x := n.(type) {
+	case nil:
+		return merkle.Digest{}, false
+	case tableI:
+		return nodeDigest(x), true
+	}
+
+	var d merkle.Digest
+	var kvs = prefixKeyVals(n, p)
+	for _, kv := range kvs {
+		d = d.Add(merkle.Entry(kv.Key, kv.Val))
+	}
+	return d, len(kvs) > 0
+}
+
+// prefixKeyVals() returns the key/value pairs of the node, as returned by
+// prefixNode(), under the prefix.
+func prefixKeyVals(n nodeI, p syncPrefix) []KeyVal {
+	var kvs []KeyVal
+	switch x := n.(type) {
+	case nil:
+	case tableI:
+		var next = x.iter()
+		for cn := next(); cn != nil; cn = next() {
+			kvs = append(kvs, prefixKeyVals(cn, p)...)
+		}
+	case leafI:
+		for _, kv := range x.keyVals() {
+			if p.matches(kv.Key.Hash()) {
+				kvs = append(kvs, kv)
+			}
+		}
+	}
+	return kvs
+}
+
+func writeSyncRequest(w io.Writer, c syncConfig, prefixes []syncPrefix) error {
+	var enc = codec.NewEncoder(syncRequestMagic, syncVersion)
+	enc.WriteUvarint(uint64(c.algorithm))
+	enc.WriteUvarint(uint64(c.size))
+	enc.WriteUvarint(c.seed)
+	enc.WriteUvarint(uint64(c.layout))
+	enc.WriteUvarint(uint64(len(prefixes)))
+	for _, p := range prefixes {
+		enc.WriteUvarint(uint64(p.depth))
+		enc.WriteUvarint(uint64(p.hashPath))
+	}
+	return writeSyncMessage(w, enc.Bytes())
+}
+
+// readSyncRequest() returns the configuration of the replica and the prefixes
+// it asks about. The prefixes are only valid if the configuration matches that
+// of the source.
+func readSyncRequest(r *bufio.Reader) (syncConfig, []syncPrefix, error) {
+	var c syncConfig
+	var data, err = readSyncMessage(r)
+	if err != nil {
+		return c, nil, err
+	}
+	dec, err := newSyncDecoder(data, syncRequestMagic)
+	if err != nil {
+		return c, nil, err
+	}
+	var fields [4]uint64
+	for i := range fields {
+		if fields[i], err = dec.ReadUvarint(); err != nil {
+			return c, nil, err
+		}
+	}
+	if fields[0] > math.MaxInt32 || fields[1] > 64 ||
+		fields[3] < uint64(hash.MinLayout) || fields[3] > uint64(hash.MaxLayout) {
+		return c, nil, codec.ErrCorrupt
+	}
+	c = syncConfig{
+		algorithm: hash.Algorithm(fields[0]),
+		size:      uint(fields[1]),
+		seed:      fields[2],
+		layout:    hash.Layout(fields[3]),
+	}
+	var l = c.layout
+	numPrefixes, err := dec.ReadUvarint()
+	if err != nil {
+		return c, nil, err
+	}
+	if numPrefixes > uint64(dec.Len()/2) {
+		return c, nil, codec.ErrCorrupt
+	}
+
+	var prefixes = make([]syncPrefix, numPrefixes)
+	for i := range prefixes {
+		var depth, err = dec.ReadUvarint()
+		if err != nil {
+			return c, nil, err
+		}
+		hashPath, err := dec.ReadUvarint()
+		if err != nil {
+			return c, nil, err
+		}
+		var p = syncPrefix{uint(depth), hash.Val(hashPath), l}
+		if depth > uint64(l.DepthLimit()) || p.hashPath&^p.mask() != 0 {
+			return c, nil, codec.ErrCorrupt
+		}
+		prefixes[i] = p
+	}
+	if dec.Len() != 0 {
+		return c, nil, codec.ErrCorrupt
+	}
+	return c, prefixes, nil
+}
+
+// readSyncResponse() returns a decoder of the next response of the source, or
+// the error it sent instead.
+func readSyncResponse(r *bufio.Reader) (*codec.Decoder, error) {
+	var data, err = readSyncMessage(r)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(data, []byte(syncErrorMagic)) {
+		return newSyncDecoder(data, syncResponseMagic)
+	}
+
+	dec, err := newSyncDecoder(data, syncErrorMagic)
+	if err != nil {
+		return nil, err
+	}
+	reason, err := dec.ReadBytes()
+	if err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("fmap: the source refused to sync: %s", reason)
+}
+
+func writeSyncMessage(w io.Writer, data []byte) error {
+	var tmp [binary.MaxVarintLen64]byte
+	var msg = append(tmp[:binary.PutUvarint(tmp[:], uint64(len(data)))], data...)
+	var _, err = w.Write(msg)
+	return err
+}
+
+func readSyncMessage(r *bufio.Reader) ([]byte, error) {
+	var l, err = binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if l > syncMaxBytes {
+		return nil, codec.ErrCorrupt
+	}
+	var data = make([]byte, l)
+	if _, err = io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func newSyncDecoder(data []byte, magic string) (*codec.Decoder, error) {
+	var dec, version, err = codec.NewDecoder(data, magic)
+	if err != nil {
+		return nil, err
+	}
+	if version != syncVersion {
+		return nil, fmt.Errorf("fmap: unsupported sync version %d", version)
+	}
+	return dec, nil
+}
@@ -0,0 +1,51 @@
+package fmap
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/lleo/go-functional-collections/key/hash"
+)
+
+func TestSyncConfigMismatch(t *testing.T) {
+	var m = New()
+	var c = newSyncConfig(m.layout())
+	var other = c.algorithm + 1
+	if other == hash.SipHash+1 {
+		other = hash.FNV1
+	}
+
+	var tests = []struct {
+		name   string
+		c      syncConfig
+		reason string
+	}{
+		{"algorithm", syncConfig{other, c.size, c.seed, c.layout}, "hashed with"},
+		{"size", syncConfig{c.algorithm, 96 - c.size, c.seed, c.layout}, "hashed with"},
+		{"seed", syncConfig{c.algorithm, c.size, c.seed + 1, c.layout}, "seed"},
+		{"layout", syncConfig{c.algorithm, c.size, c.seed, c.layout + 1}, "TableWidth"},
+	}
+	for _, test := range tests {
+		var srcConn, dstConn = net.Pipe()
+		var errc = make(chan error, 1)
+		go func() {
+			errc <- m.ServeSync(srcConn)
+		}()
+
+		var root = []syncPrefix{{layout: test.c.layout}}
+		if err := writeSyncRequest(dstConn, test.c, root); err != nil {
+			t.Fatalf("%s: writeSyncRequest() failed: %s", test.name, err)
+		}
+		var _, err = readSyncResponse(bufio.NewReader(dstConn))
+		if err == nil || !strings.Contains(err.Error(), test.reason) {
+			t.Fatalf("%s: readSyncResponse() failed with %v", test.name, err)
+		}
+		if err = <-errc; err == nil {
+			t.Fatalf("%s: ServeSync() did not fail", test.name)
+		}
+		srcConn.Close()
+		dstConn.Close()
+	}
+}
@@ -0,0 +1,112 @@
+package fmap
+
+import (
+	"encoding"
+	"fmt"
+
+	"github.com/lleo/go-functional-collections/codec"
+	"github.com/lleo/go-functional-collections/key"
+)
+
+// binaryMagic and binaryVersion identify the binary format of a Map.
+//
+// Version 1 of the format is:
+//
+//	"FMAP" 1 numEnts (key val)*numEnts
+//
+// where numEnts is a uvarint, and every key and val is a value encoded with
+// the codec package, in the order they are found in the HAMT.
+const (
+	binaryMagic   = "FMAP"
+	binaryVersion = 1
+)
+
+var (
+	_ encoding.BinaryMarshaler   = (*Map)(nil)
+	_ encoding.BinaryUnmarshaler = (*Map)(nil)
+)
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface. Every key
+// and value in the Map MUST have a codec registered with the codec package, or
+// an error is returned.
+func (m *Map) MarshalBinary() ([]byte, error) {
+	var enc = codec.NewEncoder(binaryMagic, binaryVersion)
+	enc.WriteUvarint(uint64(m.numEnts))
+
+	var err error
+	m.root.walkPreOrder(func(n nodeI, depth uint) bool {
+		if leaf, isLeaf := n.(leafI); isLeaf {
+			for _, kv := range leaf.keyVals() {
+				if err = enc.Encode(kv.Key); err != nil {
+					return false
+				}
+				if err = enc.Encode(kv.Val); err != nil {
+					return false
+				}
+			}
+		}
+		return true
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return enc.Bytes(), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface. It
+// replaces the contents of the receiver, so it SHOULD only be called on a Map
+// that no one else holds, like one just returned by New.
+//
+// The HAMT is rebuilt in bulk, like NewFromList, rather than by repeated calls
+// to Store. The Map keeps the Options of the receiver.
+func (m *Map) UnmarshalBinary(data []byte) error {
+	var dec, version, err = codec.NewDecoder(data, binaryMagic)
+	if err != nil {
+		return err
+	}
+	if version != binaryVersion {
+		return fmt.Errorf("fmap: unsupported binary format version %d",
+			version)
+	}
+
+	numEnts, err := dec.ReadUvarint()
+	if err != nil {
+		return err
+	}
+	// every key/value pair takes at least two bytes
+	if numEnts > uint64(dec.Len()/2) {
+		return codec.ErrCorrupt
+	}
+
+	var kvs = make([]KeyVal, numEnts)
+	for i := range kvs {
+		if kvs[i], err = decodeKeyVal(dec); err != nil {
+			return err
+		}
+	}
+	if dec.Len() != 0 {
+		return codec.ErrCorrupt
+	}
+
+	*m = *newFromList(kvs, m.layout())
+	return nil
+}
+
+// decodeKeyVal() decodes a key, which must be a key.Hash, and its value.
+func decodeKeyVal(dec *codec.Decoder) (KeyVal, error) {
+	var k, err = dec.Decode()
+	if err != nil {
+		return KeyVal{}, err
+	}
+	var hk, isHash = k.(key.Hash)
+	if !isHash {
+		return KeyVal{}, fmt.Errorf(
+			"fmap: decoded key of type %T is not a key.Hash", k)
+	}
+	v, err := dec.Decode()
+	if err != nil {
+		return KeyVal{}, err
+	}
+	return KeyVal{hk, v}, nil
+}
@@ -10,8 +10,9 @@ import (
 // bitmapShift is 3 because we are using uint8 as the base bitmap type.
 const bitmapShift uint = 3
 
-// bitmapSize is the number of uint8 needed to cover hash.IndexLimit bits.
-const bitmapSize uint = (hash.IndexLimit + (1 << bitmapShift) - 1) /
+// bitmapSize is the number of uint8 needed to cover hash.MaxIndexLimit bits,
+// so a bitmap can cover the tables of any hash.Layout.
+const bitmapSize uint = (hash.MaxIndexLimit + (1 << bitmapShift) - 1) /
 	(1 << bitmapShift)
 
 type bitmap [bitmapSize]uint8
@@ -26,7 +27,7 @@ const bitsFmtStr = "%08b" //for bitmap being array of uint8 aka bitmapShift=3
 const byteMask = (1 << bitmapShift) - 1
 
 func (bm *bitmap) String() string {
-	// Show all bits in bitmap because hash.IndexLimit is a multiple of the
+	// Show all bits in bitmap because hash.MaxIndexLimit is a multiple of the
 	// bitmap base type.
 	var strs = make([]string, bitmapSize)
 	//var fmtStr = fmt.Sprintf("%%0%db", 1<<bitmapShift)
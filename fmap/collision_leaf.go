@@ -2,7 +2,9 @@ package fmap
 
 import (
 	"fmt"
+	"sort"
 	"strings"
+	"unsafe"
 
 	"github.com/lleo/go-functional-collections/key"
 	"github.com/lleo/go-functional-collections/key/hash"
@@ -10,41 +12,133 @@ import (
 
 // implements nodeI
 // implements leafI
-type collisionLeaf []KeyVal
+//
+// The key/value pairs of a collisionLeaf are ordered by the hash.Secondary of
+// their key's String(), and found by binary search. So even if an attacker
+// makes many keys collide, finding one costs O(log(n)). Past
+// collisionTrieThreshold pairs they are kept in a collisionTrie instead of
+// slices, so putting or deleting one also costs O(log(n)), rather than a copy
+// of every pair. Keys with the same String() that are not Equal share a
+// secondary hash, so finding one among them is a linear scan; key types
+// SHOULD give unequal keys unequal Strings.
+//
+// The secondary hash is seeded once per process, not per Map: every version of
+// a Map shares its leaves with the others, and Merge mixes the leaves of two
+// Maps, so they must all order a leaf the same way. Nor is a fixed seed
+// needed to save a Map deterministically; the SnapshotStore orders the pairs
+// of a leaf by the encoding of their keys.
+type collisionLeaf struct {
+	kvs    []KeyVal
+	hashes []uint64 // hashes[i] == secondaryHash(kvs[i].Key)
+
+	// trie holds the pairs instead of kvs and hashes, past
+	// collisionTrieThreshold of them.
+	trie *collisionTrie
+}
+
+func secondaryHash(k key.Hash) uint64 {
+	return hash.Secondary(k.String())
+}
 
 func newCollisionLeaf(kvs []KeyVal) *collisionLeaf {
-	var lKvs collisionLeaf = make([]KeyVal, len(kvs))
-	copy(lKvs, kvs)
-	return &lKvs
+	if len(kvs) > collisionTrieThreshold {
+		var t *collisionTrie
+		for _, kv := range kvs {
+			t, _ = t.put(kv.Key, kv.Val, nil, secondaryHash(kv.Key), 0)
+		}
+		return &collisionLeaf{trie: t}
+	}
+
+	var l = &collisionLeaf{
+		kvs:    make([]KeyVal, len(kvs)),
+		hashes: make([]uint64, len(kvs)),
+	}
+	copy(l.kvs, kvs)
+	for i, kv := range l.kvs {
+		l.hashes[i] = secondaryHash(kv.Key)
+	}
+	sort.Stable(l)
+	return l
+}
+
+// Len, Less and Swap implement sort.Interface for newCollisionLeaf().
+func (l *collisionLeaf) Len() int           { return len(l.kvs) }
+func (l *collisionLeaf) Less(i, j int) bool { return l.hashes[i] < l.hashes[j] }
+func (l *collisionLeaf) Swap(i, j int) {
+	l.kvs[i], l.kvs[j] = l.kvs[j], l.kvs[i]
+	l.hashes[i], l.hashes[j] = l.hashes[j], l.hashes[i]
 }
 
 func (l *collisionLeaf) copy() leafI {
-	//return newCollisionLeaf([]KeyVal(*l))
-	return newCollisionLeaf(*l)
+	return &collisionLeaf{
+		kvs:    append([]KeyVal(nil), l.kvs...),
+		hashes: append([]uint64(nil), l.hashes...),
+		trie:   l.trie,
+	}
 }
 
 func (l *collisionLeaf) hash() hash.Val {
-	return (*l)[0].Key.Hash()
+	return l.at(0).Key.Hash()
+}
+
+// at() returns the i'th key/value pair of the collisionLeaf, in the order of
+// the secondary hashes.
+func (l *collisionLeaf) at(i int) KeyVal {
+	if l.trie != nil {
+		return l.trie.at(i)
+	}
+	return l.kvs[i]
 }
 
 func (l *collisionLeaf) String() string {
-	var kvstrs = make([]string, len(*l))
-	for i := 0; i < len(*l); i++ {
-		kvstrs[i] = (*l)[i].String()
+	var kvs = l.keyVals()
+	var kvstrs = make([]string, len(kvs))
+	for i := 0; i < len(kvs); i++ {
+		kvstrs[i] = kvs[i].String()
 	}
 	var jkvstr = strings.Join(kvstrs, ",")
 
 	return fmt.Sprintf("collisionLeaf{hash:%s, kvs:[]KeyVal{%s}}",
-		(*l)[0].Key.Hash(), jkvstr)
+		l.hash(), jkvstr)
 }
 
-func (l *collisionLeaf) get(key key.Hash) (interface{}, bool) {
-	for _, kv := range *l {
-		if kv.Key.Equals(key) {
-			return kv.Val, true
+// findSecondary() returns the index of the key in kvs, ordered by their
+// secondary hashes, and true, or, if the key is not there, the index where it
+// should be inserted, and false. sh is the secondaryHash of the key.
+func findSecondary(
+	kvs []KeyVal,
+	hashes []uint64,
+	key key.Hash,
+	sh uint64,
+) (int, bool) {
+	var i = sort.Search(len(hashes), func(i int) bool {
+		return hashes[i] >= sh
+	})
+	for j := i; j < len(hashes) && hashes[j] == sh; j++ {
+		if kvs[j].Key.Equals(key) {
+			return j, true
 		}
 	}
-	return nil, false
+	return i, false
+}
+
+// find() returns the index of the key, and true, or, if the key is not in the
+// collisionLeaf, the index where it should be inserted, and false. sh is the
+// secondaryHash of the key. The collisionLeaf MUST NOT hold a collisionTrie.
+func (l *collisionLeaf) find(key key.Hash, sh uint64) (int, bool) {
+	return findSecondary(l.kvs, l.hashes, key, sh)
+}
+
+func (l *collisionLeaf) get(key key.Hash) (interface{}, bool) {
+	var sh = secondaryHash(key)
+	if l.trie != nil {
+		return l.trie.get(key, sh)
+	}
+	var i, found = l.find(key, sh)
+	if !found {
+		return nil, false
+	}
+	return l.kvs[i].Val, true
 }
 
 func (l *collisionLeaf) putResolve(
@@ -52,53 +146,104 @@ func (l *collisionLeaf) putResolve(
 	val interface{},
 	resolve ResolveConflictFunc,
 ) (leafI, bool) {
-	for i, kv := range *l {
-		if kv.Key.Equals(key) {
-			var nl = l.copy().(*collisionLeaf)
-			var newVal = resolve(kv.Key, kv.Val, val)
-			(*nl)[i].Val = newVal
-			return nl, false // replaced
-		}
+	var sh = secondaryHash(key)
+	if l.trie != nil {
+		var t, added = l.trie.put(key, val, resolve, sh, 0)
+		return &collisionLeaf{trie: t}, added
+	}
+	var i, found = l.find(key, sh)
+	if found {
+		var nl = l.copy().(*collisionLeaf)
+		nl.kvs[i].Val = resolve(l.kvs[i].Key, l.kvs[i].Val, val)
+		return nl, false // replaced
 	}
-	var nl collisionLeaf = make([]KeyVal, len(*l)+1)
-	nl[len(*l)] = KeyVal{Key: key, Val: val}
-	return &nl, true
+	return l.insert(i, key, val, sh), true
 }
 
 func (l *collisionLeaf) put(key key.Hash, val interface{}) (leafI, bool) {
-	for i, kv := range *l {
-		if kv.Key.Equals(key) {
-			var nl = l.copy().(*collisionLeaf)
-			(*nl)[i].Val = val
-			return nl, false // replaced
+	var sh = secondaryHash(key)
+	if l.trie != nil {
+		var t, added = l.trie.put(key, val, nil, sh, 0)
+		return &collisionLeaf{trie: t}, added
+	}
+	var i, found = l.find(key, sh)
+	if found {
+		var nl = l.copy().(*collisionLeaf)
+		nl.kvs[i].Val = val
+		return nl, false // replaced
+	}
+	return l.insert(i, key, val, sh), true
+}
+
+// insert() returns a new collisionLeaf with the key/value pair inserted at
+// index i; holding them in a collisionTrie if there are too many.
+func (l *collisionLeaf) insert(
+	i int,
+	key key.Hash,
+	val interface{},
+	sh uint64,
+) *collisionLeaf {
+	if len(l.kvs) == collisionTrieThreshold {
+		var t *collisionTrie
+		for j, kv := range l.kvs {
+			t, _ = t.put(kv.Key, kv.Val, nil, l.hashes[j], 0)
 		}
+		t, _ = t.put(key, val, nil, sh, 0)
+		return &collisionLeaf{trie: t}
 	}
-	var nl collisionLeaf = make([]KeyVal, len(*l)+1)
-	nl[len(*l)] = KeyVal{Key: key, Val: val}
-	return &nl, true
+
+	var nl = &collisionLeaf{
+		kvs:    make([]KeyVal, len(l.kvs)+1),
+		hashes: make([]uint64, len(l.hashes)+1),
+	}
+	copy(nl.kvs, l.kvs[:i])
+	copy(nl.hashes, l.hashes[:i])
+	nl.kvs[i] = KeyVal{Key: key, Val: val}
+	nl.hashes[i] = sh
+	copy(nl.kvs[i+1:], l.kvs[i:])
+	copy(nl.hashes[i+1:], l.hashes[i:])
+	return nl
 }
 
 func (l *collisionLeaf) del(key key.Hash) (leafI, interface{}, bool) {
-	for i, kv := range *l {
-		if kv.Key.Equals(key) {
-			var nl leafI
-			if len(*l) == 2 {
-				// think about the index... it works, really :)
-				nl = newFlatLeaf((*l)[1-i].Key, (*l)[1-i].Val)
-			} else {
-				var cl = l.copy().(*collisionLeaf)
-				*cl = append((*cl)[:i], (*cl)[i+1:]...)
-				nl = cl
-			}
-			return nl, kv.Val, true
+	var sh = secondaryHash(key)
+	if l.trie != nil {
+		var t, val, found = l.trie.del(key, sh, 0)
+		if !found {
+			return l, nil, false
+		}
+		if t.size > collisionTrieThreshold/2 {
+			return &collisionLeaf{trie: t}, val, true
 		}
+		var kvs, hashes = t.appendTo(nil, nil)
+		return &collisionLeaf{kvs: kvs, hashes: hashes}, val, true
 	}
-	return l, nil, false
+
+	var i, found = l.find(key, sh)
+	if !found {
+		return l, nil, false
+	}
+
+	var nl leafI
+	if len(l.kvs) == 2 {
+		// think about the index... it works, really :)
+		nl = newFlatLeaf(l.kvs[1-i].Key, l.kvs[1-i].Val)
+	} else {
+		var cl = l.copy().(*collisionLeaf)
+		cl.kvs = append(cl.kvs[:i], cl.kvs[i+1:]...)
+		cl.hashes = append(cl.hashes[:i], cl.hashes[i+1:]...)
+		nl = cl
+	}
+	return nl, l.kvs[i].Val, true
 }
 
 func (l *collisionLeaf) keyVals() []KeyVal {
-	var r = make([]KeyVal, 0, len(*l))
-	r = append(r, *l...)
+	if l.trie != nil {
+		var kvs, _ = l.trie.appendTo(make([]KeyVal, 0, l.trie.size), nil)
+		return kvs
+	}
+	var r = make([]KeyVal, 0, len(l.kvs))
+	r = append(r, l.kvs...)
 	return r
 }
 
@@ -112,38 +257,34 @@ func (l *collisionLeaf) equiv(other nodeI) bool {
 	if !ok {
 		return false
 	}
-	if len(*l) != len(*ol) {
+	if l.count() != ol.count() {
 		return false
 	}
 
-	for _, kv := range *l {
-		var keyFound = false
-		for _, okv := range *ol {
-			if kv.Key.Equals(okv.Key) {
-				keyFound = true
-				if kv.Val != okv.Val {
-					return false
-				}
-			}
-		}
-		if !keyFound {
+	for _, kv := range l.keyVals() {
+		var v, found = ol.get(kv.Key)
+		if !found || !valsEqual(kv.Val, v) {
 			return false
 		}
 	}
 
-	//for i, kv := range *l {
-	//// This assumes the kvs are in the same order.
-	//	if !kv.Key.Equals((*ol)[i].Key) {
-	//		return false
-	//	}
-	//	if kv.Val != (*ol)[i].Val {
-	//		return false
-	//	}
-	//}
-
 	return true
 }
 
 func (l *collisionLeaf) count() int {
-	return len(*l)
+	if l.trie != nil {
+		return l.trie.size
+	}
+	return len(l.kvs)
+}
+
+// numBytes() returns the memory used by the collisionLeaf.
+func (l *collisionLeaf) numBytes() uint {
+	var n = uint(unsafe.Sizeof(*l)) +
+		uint(cap(l.kvs))*uint(unsafe.Sizeof(KeyVal{})) +
+		uint(cap(l.hashes))*uint(unsafe.Sizeof(uint64(0)))
+	if l.trie != nil {
+		n += l.trie.numBytes()
+	}
+	return n
 }
@@ -0,0 +1,226 @@
+package fmap
+
+import (
+	"unsafe"
+
+	"github.com/lleo/go-functional-collections/key"
+)
+
+// collisionTrieThreshold is the number of key/value pairs past which a
+// collisionLeaf keeps them in a collisionTrie rather than in sorted slices,
+// which every put or del copies whole. It goes back to slices once it holds
+// half as many.
+const collisionTrieThreshold = 32
+
+const (
+	trieBits       = 4
+	trieWidth      = 1 << trieBits
+	trieMaxDepth   = 64 / trieBits
+	trieBucketSize = 8
+)
+
+// collisionTrie is a persistent trie of the key/value pairs of a large
+// collisionLeaf, keyed by their secondary hashes, trieBits bits per level
+// starting from the highest. So a walk of the trie visits the pairs in the
+// order of their secondary hashes, like the slices of a small collisionLeaf,
+// and as the secondary hashes are seeded at random, the trie stays
+// O(log(n)) deep however many keys collide; put and del copy a bucket and
+// one branch per level.
+//
+// A collisionTrie is either a branch, with children, or a bucket of at most
+// trieBucketSize pairs ordered by their secondary hashes. Only a bucket at
+// trieMaxDepth, whose pairs all share one secondary hash, holds more.
+type collisionTrie struct {
+	size     int // number of key/value pairs under this node
+	children *[trieWidth]*collisionTrie
+	kvs      []KeyVal
+	hashes   []uint64 // hashes[i] == secondaryHash(kvs[i].Key)
+}
+
+func trieIndex(sh uint64, depth uint) uint {
+	return uint(sh>>(64-trieBits*(depth+1))) & (trieWidth - 1)
+}
+
+func newTrieBucket(kvs []KeyVal, hashes []uint64) *collisionTrie {
+	return &collisionTrie{size: len(kvs), kvs: kvs, hashes: hashes}
+}
+
+// withChild() returns a copy of the branch t with the child c at idx, holding
+// size key/value pairs.
+func (t *collisionTrie) withChild(
+	idx uint,
+	c *collisionTrie,
+	size int,
+) *collisionTrie {
+	var nt = &collisionTrie{size: size}
+	nt.children = new([trieWidth]*collisionTrie)
+	*nt.children = *t.children
+	nt.children[idx] = c
+	return nt
+}
+
+// find() returns the index of the key in the bucket t, and true, or the index
+// where it should be inserted, and false.
+func (t *collisionTrie) find(k key.Hash, sh uint64) (int, bool) {
+	return findSecondary(t.kvs, t.hashes, k, sh)
+}
+
+func (t *collisionTrie) get(k key.Hash, sh uint64) (interface{}, bool) {
+	for depth := uint(0); t.children != nil; depth++ {
+		if t = t.children[trieIndex(sh, depth)]; t == nil {
+			return nil, false
+		}
+	}
+	var i, found = t.find(k, sh)
+	if !found {
+		return nil, false
+	}
+	return t.kvs[i].Val, true
+}
+
+// put() returns a new collisionTrie with the key mapped to val, or to the
+// result of resolve if the key is already there, and true if the key was
+// added. t may be nil.
+func (t *collisionTrie) put(
+	k key.Hash,
+	val interface{},
+	resolve ResolveConflictFunc,
+	sh uint64,
+	depth uint,
+) (*collisionTrie, bool) {
+	if t == nil {
+		return newTrieBucket([]KeyVal{{k, val}}, []uint64{sh}), true
+	}
+
+	if t.children != nil {
+		var idx = trieIndex(sh, depth)
+		var c, added = t.children[idx].put(k, val, resolve, sh, depth+1)
+		var size = t.size
+		if added {
+			size++
+		}
+		return t.withChild(idx, c, size), added
+	}
+
+	var i, found = t.find(k, sh)
+	if found {
+		var kvs = append([]KeyVal(nil), t.kvs...)
+		if resolve != nil {
+			val = resolve(kvs[i].Key, kvs[i].Val, val)
+		}
+		kvs[i].Val = val
+		return newTrieBucket(kvs, t.hashes), false
+	}
+
+	var kvs = make([]KeyVal, len(t.kvs)+1)
+	var hashes = make([]uint64, len(t.hashes)+1)
+	copy(kvs, t.kvs[:i])
+	copy(hashes, t.hashes[:i])
+	kvs[i] = KeyVal{k, val}
+	hashes[i] = sh
+	copy(kvs[i+1:], t.kvs[i:])
+	copy(hashes[i+1:], t.hashes[i:])
+	if len(kvs) <= trieBucketSize || depth == trieMaxDepth {
+		return newTrieBucket(kvs, hashes), true
+	}
+
+	// split the bucket into a branch
+	var nt = &collisionTrie{children: new([trieWidth]*collisionTrie)}
+	for j, kv := range kvs {
+		var idx = trieIndex(hashes[j], depth)
+		nt.children[idx], _ = nt.children[idx].put(kv.Key, kv.Val, nil,
+			hashes[j], depth+1)
+	}
+	nt.size = len(kvs)
+	return nt, true
+}
+
+// del() returns a new collisionTrie without the key, which is nil if it is
+// empty, the value of the key, and true if the key was there.
+func (t *collisionTrie) del(
+	k key.Hash,
+	sh uint64,
+	depth uint,
+) (*collisionTrie, interface{}, bool) {
+	if t.children != nil {
+		var idx = trieIndex(sh, depth)
+		if t.children[idx] == nil {
+			return t, nil, false
+		}
+		var c, val, found = t.children[idx].del(k, sh, depth+1)
+		if !found {
+			return t, nil, false
+		}
+		var nt = t.withChild(idx, c, t.size-1)
+		if nt.size <= trieBucketSize {
+			// merge the branch back into a bucket
+			var kvs, hashes = nt.appendTo(nil, nil)
+			return newTrieBucket(kvs, hashes), val, true
+		}
+		return nt, val, true
+	}
+
+	var i, found = t.find(k, sh)
+	if !found {
+		return t, nil, false
+	}
+	if len(t.kvs) == 1 {
+		return nil, t.kvs[0].Val, true
+	}
+	var kvs = make([]KeyVal, 0, len(t.kvs)-1)
+	var hashes = make([]uint64, 0, len(t.hashes)-1)
+	kvs = append(append(kvs, t.kvs[:i]...), t.kvs[i+1:]...)
+	hashes = append(append(hashes, t.hashes[:i]...), t.hashes[i+1:]...)
+	return newTrieBucket(kvs, hashes), t.kvs[i].Val, true
+}
+
+// appendTo() appends the key/value pairs of the trie, and their secondary
+// hashes, in the order of the secondary hashes.
+func (t *collisionTrie) appendTo(
+	kvs []KeyVal,
+	hashes []uint64,
+) ([]KeyVal, []uint64) {
+	if t.children == nil {
+		return append(kvs, t.kvs...), append(hashes, t.hashes...)
+	}
+	for _, c := range t.children {
+		if c != nil {
+			kvs, hashes = c.appendTo(kvs, hashes)
+		}
+	}
+	return kvs, hashes
+}
+
+// at() returns the i'th key/value pair of the trie, in the order of the
+// secondary hashes.
+func (t *collisionTrie) at(i int) KeyVal {
+	for t.children != nil {
+		for _, c := range t.children {
+			if c == nil {
+				continue
+			}
+			if i < c.size {
+				t = c
+				break
+			}
+			i -= c.size
+		}
+	}
+	return t.kvs[i]
+}
+
+// numBytes() returns the memory used by the trie.
+func (t *collisionTrie) numBytes() uint {
+	var n = uint(unsafe.Sizeof(*t))
+	if t.children == nil {
+		return n + uint(cap(t.kvs))*uint(unsafe.Sizeof(KeyVal{})) +
+			uint(cap(t.hashes))*uint(unsafe.Sizeof(uint64(0)))
+	}
+	n += uint(unsafe.Sizeof(*t.children))
+	for _, c := range t.children {
+		if c != nil {
+			n += c.numBytes()
+		}
+	}
+	return n
+}
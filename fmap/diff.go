@@ -0,0 +1,163 @@
+package fmap
+
+import (
+	"fmt"
+
+	"github.com/lleo/go-functional-collections/key"
+)
+
+// DiffType is the kind of difference between two Maps reported by Diff.
+type DiffType int
+
+const (
+	// DiffAdded means the key is only in the argument Map.
+	DiffAdded DiffType = iota
+	// DiffRemoved means the key is only in the receiver Map.
+	DiffRemoved
+	// DiffChanged means the key is in both Maps, but with different values.
+	DiffChanged
+)
+
+func (dt DiffType) String() string {
+	switch dt {
+	case DiffAdded:
+		return "DiffAdded"
+	case DiffRemoved:
+		return "DiffRemoved"
+	case DiffChanged:
+		return "DiffChanged"
+	}
+	return fmt.Sprintf("DiffType(%d)", int(dt))
+}
+
+// DiffEntry describes a key/value mapping that differs between two Maps.
+// OldVal is the value in the receiver Map and NewVal is the value in the
+// argument Map. The value for a Map without the key is nil.
+type DiffEntry struct {
+	Type   DiffType
+	Key    key.Hash
+	OldVal interface{}
+	NewVal interface{}
+}
+
+func (de DiffEntry) String() string {
+	return fmt.Sprintf("{%s, %q, %v, %v}", de.Type, de.Key, de.OldVal, de.NewVal)
+}
+
+// Diff calls the given function for every key/value mapping that differs
+// between the receiver Map and the argument Map; that is the mappings added,
+// removed or changed going from the receiver Map to the argument Map. If the
+// function returns false, Diff stops.
+//
+// Both Maps are walked together, and any table or leaf the Maps share is
+// skipped without looking inside it. So for Maps derived from one another
+// (like successive versions of the same Map), the cost of Diff is
+// proportional to the size of the changes, not the size of the Maps. Tables
+// which are not shared are always looked inside, even when their digests are
+// equal; see the merkle package.
+//
+// Maps with different Options do not share the shape of their HAMTs, so they
+// are compared key by key.
+//
+// Like Equiv, values are compared with ==, or with reflect.DeepEqual when
+// they are not comparable, like slices and maps.
+func (m *Map) Diff(other *Map, fn func(DiffEntry) bool) {
+	if m.layout() != other.layout() {
+		diffByKey(m, other, fn)
+		return
+	}
+	diffNodes(m.root, other.root, 0, fn)
+}
+
+// diffByKey() reports the differences between two Maps by looking up every
+// key of each Map in the other.
+func diffByKey(m, other *Map, fn func(DiffEntry) bool) {
+	var keepOn = true
+	m.Range(func(kv KeyVal) bool {
+		var v, found = other.Load(kv.Key)
+		if !found {
+			keepOn = fn(DiffEntry{DiffRemoved, kv.Key, kv.Val, nil})
+		} else if !valsEqual(v, kv.Val) {
+			keepOn = fn(DiffEntry{DiffChanged, kv.Key, kv.Val, v})
+		}
+		return keepOn
+	})
+	if !keepOn {
+		return
+	}
+	other.Range(func(kv KeyVal) bool {
+		if _, found := m.Load(kv.Key); !found {
+			return fn(DiffEntry{DiffAdded, kv.Key, nil, kv.Val})
+		}
+		return true
+	})
+}
+
+// diffNodes() reports the differences between the nodes a and b, which are at
+// the same position, of the given depth, in the two HAMTs. It returns false if
+// fn returned false.
+func diffNodes(a, b nodeI, depth uint, fn func(DiffEntry) bool) bool {
+	if a == b {
+		return true //shared, or both nil
+	}
+
+	var ta, aIsTable = a.(tableI)
+	var tb, bIsTable = b.(tableI)
+	if aIsTable && bIsTable {
+		for idx := uint(0); idx < tableLayout(ta).IndexLimit(); idx++ {
+			if !diffNodes(ta.get(idx), tb.get(idx), depth+1, fn) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return diffKeyVals(nodeKeyVals(a, depth), nodeKeyVals(b, depth), fn)
+}
+
+// nodeKeyVals() returns every key/value pair held by the node n, at the given
+// depth, which may be nil.
+func nodeKeyVals(n nodeI, depth uint) []KeyVal {
+	if n == nil {
+		return nil
+	}
+	var kvs []KeyVal
+	n.walkPreOrder(func(n nodeI, depth uint) bool {
+		if leaf, isLeaf := n.(leafI); isLeaf {
+			kvs = append(kvs, leaf.keyVals()...)
+		}
+		return true
+	}, depth)
+	return kvs
+}
+
+// diffKeyVals() reports the differences between the old and new key/value
+// pairs. At most one of the nodes they came from is a table, so one of the
+// lists holds no more than a leaf's worth of pairs, and the quadratic search
+// is cheap.
+func diffKeyVals(olds, news []KeyVal, fn func(DiffEntry) bool) bool {
+	var matched = make([]bool, len(news))
+OLDS:
+	for _, okv := range olds {
+		for j, nkv := range news {
+			if matched[j] || !okv.Key.Equals(nkv.Key) {
+				continue
+			}
+			matched[j] = true
+			if !valsEqual(okv.Val, nkv.Val) &&
+				!fn(DiffEntry{DiffChanged, okv.Key, okv.Val, nkv.Val}) {
+				return false
+			}
+			continue OLDS
+		}
+		if !fn(DiffEntry{DiffRemoved, okv.Key, okv.Val, nil}) {
+			return false
+		}
+	}
+	for j, nkv := range news {
+		if !matched[j] && !fn(DiffEntry{DiffAdded, nkv.Key, nil, nkv.Val}) {
+			return false
+		}
+	}
+	return true
+}
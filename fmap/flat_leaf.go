@@ -105,7 +105,7 @@ func (l *flatLeaf) equiv(other nodeI) bool {
 	if !l.Key.Equals(ol.Key) {
 		return false
 	}
-	if l.Val != ol.Val {
+	if !valsEqual(l.Val, ol.Val) {
 		return false
 	}
 	return true
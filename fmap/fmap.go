@@ -32,19 +32,23 @@ import (
 	"github.com/lleo/go-functional-collections/key/hash"
 )
 
-// downgradeThreshold is the constant that sets the threshold for the size of a
-// table, such that when a table decreases to the threshold size, the table is
-// converted from a fixedTable to a sparseTable.
-// downgradeThreshold = 8 for hash.numIndexBits=4 aka hash.IndexLimit=16
-// downgradeThreshold = 16 for hash.numIndexBits=5 aka hash.IndexLimit=32
-const downgradeThreshold uint = hash.IndexLimit / 2
-
-// upgradeThreshold is the constant that sets the threshold for the size of a
-// table, such that when a table increases to the threshold size, the table is
+// downgradeThreshold returns the threshold for the size of a table of the
+// given Layout, such that when a table decreases to the threshold size, the
+// table is converted from a fixedTable to a sparseTable.
+// downgradeThreshold = 8 for 16 slot tables, aka hash.DefaultLayout
+// downgradeThreshold = 16 for 32 slot tables
+func downgradeThreshold(l hash.Layout) uint {
+	return l.IndexLimit() / 2
+}
+
+// upgradeThreshold returns the threshold for the size of a table of the given
+// Layout, such that when a table increases to the threshold size, the table is
 // converted from a sparseTable to a fixedTable.
-// upgradeThreshold = 10 for hash.numIndexBits=4 aka hash.IndexLimit=16
-// upgradeThreshold = 20 for hash.numIndexBits=5 aka hash.IndexLimit=32
-const upgradeThreshold uint = hash.IndexLimit * 5 / 8
+// upgradeThreshold = 10 for 16 slot tables, aka hash.DefaultLayout
+// upgradeThreshold = 20 for 32 slot tables
+func upgradeThreshold(l hash.Layout) uint {
+	return l.IndexLimit() * 5 / 8
+}
 
 // The Map struct maintains a immutable collection of key/value mappings.
 type Map struct {
@@ -52,48 +56,105 @@ type Map struct {
 	numEnts int
 }
 
+// Options are the settings of a new Map.
+type Options struct {
+	// TableWidth is the number of slots of each table of the HAMT; 16, 32 or
+	// 64. Wider tables make the HAMT shallower, so lookups visit fewer tables,
+	// but every modification copies larger tables. The zero value means 16.
+	TableWidth int
+}
+
 // New returns a properly initialize pointer to a fmap.Map struct.
 func New() *Map {
+	return newMap(hash.DefaultLayout)
+}
+
+// NewWithOptions returns a new empty Map with the given Options. It panics if
+// the Options are invalid.
+//
+// The Maps derived from the new Map, by Put, Merge, Transient and so on, keep
+// its Options.
+func NewWithOptions(opts Options) *Map {
+	var l = hash.DefaultLayout
+	if opts.TableWidth != 0 {
+		var valid bool
+		if l, valid = hash.LayoutOf(opts.TableWidth); !valid {
+			panic(fmt.Sprintf("fmap.NewWithOptions: invalid TableWidth,%d",
+				opts.TableWidth))
+		}
+	}
+	return newMap(l)
+}
+
+func newMap(l hash.Layout) *Map {
 	var m = new(Map)
-	m.root = newRootTable()
+	m.root = newRootTable(l)
 	return m
 }
 
-func newRootTable() tableI {
+// Options returns the Options the Map was created with.
+func (m *Map) Options() Options {
+	return Options{TableWidth: int(m.layout().IndexLimit())}
+}
+
+// layout() returns the hash.Layout of every table of the Map.
+func (m *Map) layout() hash.Layout {
+	if m.root == nil {
+		return hash.DefaultLayout
+	}
+	return tableLayout(m.root)
+}
+
+func tableLayout(t tableI) hash.Layout {
+	switch x := t.(type) {
+	case *fixedTable:
+		return x.layout
+	case *sparseTable:
+		return x.layout
+	}
+	panic("tableLayout(): unknown table type")
+}
+
+func newRootTable(l hash.Layout) tableI {
 	// fixedTable at root makes a noticable perf diff on small & large Maps.
-	return newFixedTable(0, 0)
-	//return newSparseTable(0, 0, 0)
+	return newFixedTable(0, 0, l)
+	//return newSparseTable(0, 0, 0, l)
 }
 
 // newTable is a generic version of newSparseTable & newFixedTable
-func newTable(depth uint, hashVal hash.Val) tableI {
-	//return newFixedTable(depth, hashVal)
-	return newSparseTable(depth, hashVal, 0)
+func newTable(depth uint, hashVal hash.Val, l hash.Layout) tableI {
+	//return newFixedTable(depth, hashVal, l)
+	return newSparseTable(depth, hashVal, 0, l)
 }
 
 // createTable is a  generic version of createSparseTable & createFixedTable
-func createTable(depth uint, leaf1 leafI, leaf2 *flatLeaf) tableI {
+func createTable(
+	depth uint,
+	leaf1 leafI,
+	leaf2 *flatLeaf,
+	l hash.Layout,
+) tableI {
 	if assertOn {
 		assert(depth > 0, "createTable(): depth < 1")
-		assertf(leaf1.hash().HashPath(depth) == leaf2.hash().HashPath(depth),
+		assertf(l.HashPath(leaf1.hash(), depth) == l.HashPath(leaf2.hash(), depth),
 			"createTable(): hp1,%s != hp2,%s",
-			leaf1.hash().HashPath(depth),
-			leaf2.hash().HashPath(depth))
+			l.HashPath(leaf1.hash(), depth),
+			l.HashPath(leaf2.hash(), depth))
 	}
 
-	var retTable = newTable(depth, leaf1.hash())
+	var retTable = newTable(depth, leaf1.hash(), l)
 
-	var idx1 = leaf1.hash().Index(depth)
-	var idx2 = leaf2.hash().Index(depth)
+	var idx1 = l.Index(leaf1.hash(), depth)
+	var idx2 = l.Index(leaf2.hash(), depth)
 	if idx1 != idx2 {
 		retTable.insertInplace(idx1, leaf1)
 		retTable.insertInplace(idx2, leaf2)
 	} else { // idx1 == idx2
 		var node nodeI
-		if depth == hash.MaxDepth {
+		if depth == l.MaxDepth() {
 			node = newCollisionLeaf(append(leaf1.keyVals(), leaf2.keyVals()...))
 		} else {
-			node = createTable(depth+1, leaf1, leaf2)
+			node = createTable(depth+1, leaf1, leaf2, l)
 		}
 		retTable.insertInplace(idx1, node)
 	}
@@ -129,13 +190,14 @@ func (m *Map) Load(key key.Hash) (interface{}, bool) {
 
 	var hv = key.Hash()
 	var curTable = m.root
+	var l = m.layout()
 
 	var val interface{}
 	var found bool
 
 DepthIter:
-	for depth := uint(0); depth <= hash.MaxDepth; depth++ {
-		var idx = hv.Index(depth)
+	for depth, maxDepth := uint(0), l.MaxDepth(); depth <= maxDepth; depth++ {
+		var idx = l.Index(hv, depth)
 		var curNode = curTable.get(idx) // nodeI
 
 		switch n := curNode.(type) {
@@ -159,15 +221,16 @@ DepthIter:
 // leaf is at.
 func (m *Map) find(hv hash.Val) (*tableStack, leafI, uint) {
 	var curTable = m.root
+	var l = m.layout()
 
 	var path = newTableStack()
 	var leaf leafI
 	var idx uint
 
 DepthIter:
-	for depth := uint(0); depth <= hash.MaxDepth; depth++ {
+	for depth, maxDepth := uint(0), l.MaxDepth(); depth <= maxDepth; depth++ {
 		path.push(curTable)
-		idx = hv.Index(depth)
+		idx = l.Index(hv, depth)
 		var curNode = curTable.get(idx)
 
 		switch n := curNode.(type) {
@@ -190,8 +253,8 @@ DepthIter:
 func (m *Map) persist(oldTable, newTable tableI, path *tableStack) {
 	_ = assertOn && assert(m.root != nil, "m.root == nil")
 
-	// downgrade() & upgrade() can return an unmodified table in NewFromList(),
-	// BulkInsert(), BulkDelete(), and Merge(). Hence persist() is unnecessary.
+	// downgrade() & upgrade() can return an unmodified table. Hence persist()
+	// is unnecessary.
 	if newTable == oldTable {
 		return
 	}
@@ -204,7 +267,7 @@ func (m *Map) persist(oldTable, newTable tableI, path *tableStack) {
 	var depth = uint(path.len()) // guaranteed depth > 0
 	var parentDepth = depth - 1
 
-	var parentIdx = oldTable.hash().Index(parentDepth)
+	var parentIdx = m.layout().Index(oldTable.hash(), parentDepth)
 
 	var oldParent = path.pop()
 	var newParent tableI
@@ -253,10 +316,10 @@ func (m *Map) LoadOrStore(key key.Hash, val interface{}) (
 		// else
 
 		var node nodeI
-		if leaf.hash() != hv {
+		if l := m.layout(); !l.Collide(leaf.hash(), hv) {
 			// common case
-			//node = createSparseTable(depth+1, leaf, newFlatLeaf(key, val))
-			node = createTable(depth+1, leaf, newFlatLeaf(key, val))
+			//node = createSparseTable(depth+1, leaf, newFlatLeaf(key, val), l)
+			node = createTable(depth+1, leaf, newFlatLeaf(key, val), l)
 			added = true
 		} else {
 			// hash collision; very rare case; leaf.hash() == key.Hash()
@@ -309,10 +372,10 @@ func (m *Map) Store(key key.Hash, val interface{}) (*Map, bool) {
 	} else {
 		// This only happens when depth == MaxDepth
 		var node nodeI
-		if leaf.hash() != hv {
+		if l := m.layout(); !l.Collide(leaf.hash(), hv) {
 			// common case
-			//node = createSparseTable(depth+1, leaf, newFlatLeaf(key, val))
-			node = createTable(depth+1, leaf, newFlatLeaf(key, val))
+			//node = createSparseTable(depth+1, leaf, newFlatLeaf(key, val), l)
+			node = createTable(depth+1, leaf, newFlatLeaf(key, val), l)
 			added = true
 		} else {
 			// hash collision; very rare case; leaf.hash() == key.Hash()
@@ -490,16 +553,34 @@ func (m *Map) DeepCopy() *Map {
 	return nm
 }
 
-// Equiv compares two *Map's by value.
+// Equiv compares two *Map's by value. The HAMTs are walked together, like
+// Diff, so equal Maps built differently, whose tables and leaves differ in
+// shape, are still equivalent. Only the tables the two Maps share are taken to
+// be equal without being visited; equal digests, cached by RootDigest, prove
+// nothing, see the merkle package.
+//
+// Maps with different Options are compared key by key. Values are compared
+// with ==, or with reflect.DeepEqual when they are not comparable, like slices
+// and maps.
 func (m *Map) Equiv(m0 *Map) bool {
 	if m.NumEntries() != m0.NumEntries() {
 		return false
 	}
-	if !m.root.equiv(m0.root) {
-		return false
+	if m.layout() != m0.layout() {
+		var equiv = true
+		m.Range(func(kv KeyVal) bool {
+			var v, found = m0.Load(kv.Key)
+			equiv = found && valsEqual(v, kv.Val)
+			return equiv
+		})
+		return equiv
 	}
-	return true
-	//return m.NumEntries() == m0.NumEntries() && m.root.equiv(m0.root)
+	var equiv = true
+	diffNodes(m.root, m0.root, 0, func(DiffEntry) bool {
+		equiv = false
+		return false
+	})
+	return equiv
 }
 
 // Count recursively traverses the HAMT data structure to count every key,value
@@ -510,122 +591,34 @@ func (m *Map) Count() int {
 
 // NewFromList constructs a new *Map structure containing all the key,value
 // pairs of the given KeyVal slice.
+//
+// NewFromList is implemented more efficiently than repeated calls to Store.
 func NewFromList(kvs []KeyVal) *Map {
-	var m = New()
-	for _, kv := range kvs {
-		var k, v = kv.Key, kv.Val
-		var hv = k.Hash()
-		var path, leaf, idx = m.find(hv)
-		var curTable = path.pop()
-		var depth = uint(path.len())
-		var added bool
-		if leaf == nil {
-			curTable.insertInplace(idx, newFlatLeaf(k, v))
-			//var _, isSparseTable = curTable.(*sparseTable)
-			//if isSparseTable && curTable.slotsUsed() == upgradeThreshold {
-			//if curTable.slotsUsed() == upgradeThreshold {
-			if curTable.needsUpgrade() {
-				var newTable = curTable.upgrade()
-				m.persist(curTable, newTable, path)
-			}
-			added = true
-		} else {
-			var node nodeI
-			if leaf.hash() != hv {
-				//node = createSparseTable(depth+1, leaf, newFlatLeaf(k, v))
-				node = createTable(depth+1, leaf, newFlatLeaf(k, v))
-				added = true
-			} else {
-				node, added = leaf.put(k, v)
-			}
-			curTable.replaceInplace(idx, node)
-		}
-		if added {
-			m.numEnts++
-		}
-	}
-	return m
+	return newFromList(kvs, hash.DefaultLayout)
 }
 
-func insertPersist(
-	m *Map,
-	isOrigTable map[tableI]bool,
-	resolve ResolveConflictFunc,
-	k key.Hash,
-	v interface{},
-) {
-	var hv = k.Hash()
-	var path, leaf, idx = m.find(hv)
-	var curTable = path.pop()
-	var depth = uint(path.len())
-	var added bool
-	if isOrigTable[curTable] {
-		var newTable tableI
-		if leaf == nil {
-			newTable = curTable.insert(idx, newFlatLeaf(k, v))
-			added = true
-		} else {
-			var node nodeI
-			if leaf.hash() != hv {
-				//node = createSparseTable(depth+1, leaf, newFlatLeaf(k, v))
-				node = createTable(depth+1, leaf, newFlatLeaf(k, v))
-				added = true
-			} else {
-				node, added = leaf.putResolve(k, v, resolve)
-			}
-			newTable = curTable.replace(idx, node)
-		}
-		m.persist(curTable, newTable, path)
-	} else {
-		if leaf == nil {
-			curTable.insertInplace(idx, newFlatLeaf(k, v))
-			//var _, isSparseTable = curTable.(*sparseTable)
-			//if isSparseTable && curTable.slotsUsed() == upgradeThreshold {
-			//if curTable.slotsUsed() == upgradeThreshold {
-			if curTable.needsUpgrade() {
-				var newTable = curTable.upgrade()
-				m.persist(curTable, newTable, path)
-			}
-			added = true
-		} else {
-			var node nodeI
-			if leaf.hash() != hv {
-				//node = createSparseTable(depth+1, leaf, newFlatLeaf(k, v))
-				node = createTable(depth+1, leaf, newFlatLeaf(k, v))
-				added = true
-			} else {
-				node, added = leaf.putResolve(k, v, resolve)
-			}
-			curTable.replaceInplace(idx, node)
-		}
-	}
-	if added {
-		m.numEnts++
+func newFromList(kvs []KeyVal, l hash.Layout) *Map {
+	var t = newMap(l).Transient()
+	for _, kv := range kvs {
+		t.Store(kv.Key, kv.Val)
 	}
+	return t.Persistent()
 }
 
 // BulkInsert stores all the given key,value pairs into the Map while
-// resolving any conflict with the given resolve function.
+// resolving any conflict with the given resolve function; a nil resolve
+// function is TakeNewVal.
 //
 // The returned Map maintains the structure sharing relationship with the
 // original Map.
 //
 // BulkInsert is implemented more efficiently than repeated calls to Store.
 func (m *Map) BulkInsert(kvs []KeyVal, resolve ResolveConflictFunc) *Map {
-	var isOrigTable = make(map[tableI]bool)
-	m.walkPreOrder(func(n nodeI, depth uint) bool {
-		if t, isTable := n.(tableI); isTable {
-			isOrigTable[t] = true
-		}
-		return true
-	})
-
-	var nm = m.copy()
+	var t = m.Transient()
 	for _, kv := range kvs {
-		var k, v = kv.Key, kv.Val
-		insertPersist(nm, isOrigTable, resolve, k, v)
+		t.storeResolve(kv.Key, kv.Val, resolve)
 	}
-	return nm
+	return t.Persistent()
 }
 
 // Merge inserts all the key,value pairs from the Map provided as an argument.
@@ -636,20 +629,12 @@ func (m *Map) BulkInsert(kvs []KeyVal, resolve ResolveConflictFunc) *Map {
 // result of which will be stored as the new key,value pair in the resulting
 // Map.
 func (m *Map) Merge(om *Map, resolve ResolveConflictFunc) *Map {
-	var isOrigTable = make(map[tableI]bool)
-	m.walkPreOrder(func(n nodeI, depth uint) bool {
-		if t, isTable := n.(tableI); isTable {
-			isOrigTable[t] = true
-		}
-		return true
-	})
-
-	var nm = m.copy()
+	var t = m.Transient()
 	var it = om.Iter()
 	for kv := it.Next(); kv.Key != nil; kv = it.Next() {
-		insertPersist(nm, isOrigTable, resolve, kv.Key, kv.Val)
+		t.storeResolve(kv.Key, kv.Val, resolve)
 	}
-	return nm
+	return t.Persistent()
 }
 
 // BulkDelete removes all the keys in the given key.Hash slice. It then returns
@@ -657,157 +642,12 @@ func (m *Map) Merge(om *Map, resolve ResolveConflictFunc) *Map {
 // original Map. BulkDelete is implemented more efficiently than repeated calls
 // to Remove.
 func (m *Map) BulkDelete(keys []key.Hash) (*Map, []key.Hash) {
-	var isOrigTable = make(map[tableI]bool)
-	m.walkPreOrder(func(n nodeI, depth uint) bool {
-		if t, isTable := n.(tableI); isTable {
-			isOrigTable[t] = true
-		}
-		return true
-	})
-
 	var notFound []key.Hash
-	var nm = m.copy()
-KEYSLOOP:
+	var t = m.Transient()
 	for _, k := range keys {
-		var hv = k.Hash()
-		var path, leaf, idx = nm.find(hv)
-		if leaf == nil {
+		if _, found := t.Remove(k); !found {
 			notFound = append(notFound, k)
-			continue KEYSLOOP
-		}
-		var newLeaf, _, found = leaf.del(k)
-		if !found {
-			notFound = append(notFound, k)
-			continue KEYSLOOP
-		}
-		nm.numEnts--
-		var curTable = path.pop()
-		if isOrigTable[curTable] {
-			var newTable tableI
-			if newLeaf == nil {
-				newTable = curTable.remove(idx)
-			} else {
-				newTable = curTable.replace(idx, newLeaf)
-			}
-			nm.persist(curTable, newTable, path)
-		} else {
-			if newLeaf == nil {
-				if curTable.slotsUsed()-1 > 0 {
-					curTable.removeInplace(idx)
-					//if curTable.slotsUsed() == downgradeThreshold {
-					if curTable.needsDowngrade() {
-						var newTable = curTable.downgrade()
-						nm.persist(curTable, newTable, path)
-					}
-				} else { // curTable.slotsUsed()-1 <= 0
-					// we need to use persist cuz this will shrink empty tables
-					var newTable = curTable.remove(idx)
-					nm.persist(curTable, newTable, path)
-				}
-			} else {
-				curTable.replaceInplace(idx, newLeaf)
-			}
 		}
 	}
-	return nm, notFound
+	return t.Persistent(), notFound
 }
-
-//type Stats struct {
-//	DeepestKeys struct {
-//		Keys  []key.Hash
-//		Depth uint
-//	}
-//
-//	// Depth of deepest table
-//	MaxDepth uint
-//
-//	// TableCountsByNumEntries is a Hash table of the number of tables with each
-//	// given number of entries in the tatble. There are slots for
-//	// [0..IndexLimit] inclusive (so there are IndexLimit+1 slots). Technically,
-//	// there should never be a table with zero entries, but I allow counting
-//	// tables with zero entries just to catch those errors.
-//	// [0..IndexLimit] inclusive
-//	TableCountsByNumEntries [hash.IndexLimit + 1]uint
-//
-//	// TableCountsByDepth is a Hash table of the number of tables at a given
-//	// depth. There are slots for [0..DepthLimit).
-//	// [0..DepthLimit)
-//	TableCountsByDepth [hash.DepthLimit]uint
-//
-//	// Nils is the total count of allocated slots that are unused in the Map.
-//	Nils uint
-//
-//	// Nodes is the total count of nodeI capable structs in the Map.
-//	Nodes uint
-//
-//	// Tables is the total count of tableI capable structs in the Map.
-//	Tables uint
-//
-//	// Leafs is the total count of leafI capable structs in the Map.
-//	Leafs uint
-//
-//	// FixedTables is the total count of fixedTable structs in the Map.
-//	FixedTables uint
-//
-//	// SparseTables is the total count of sparseTable structs in the Map.
-//	SparseTables uint
-//
-//	// FlatLeafs is the total count of flatLeaf structs in the Map.
-//	FlatLeafs uint
-//
-//	// CollisionLeafs is the total count of collisionLeaf structs in the Map.
-//	CollisionLeafs uint
-//
-//	// KeyVals is the total number of KeyVal pairs int the Map.
-//	KeyVals uint
-//}
-//
-//// Stats walks the Hamt in a pre-order traversal and populates a Stats data
-//// struture which it returns.
-//func (m *Map) Stats() *Stats {
-//	var stats = new(Stats)
-//
-//	// statFn closes over the stats variable
-//	var statFn = func(n nodeI, depth uint) bool {
-//		var keepOn = true
-//		switch x := n.(type) {
-//		case nil:
-//			stats.Nils++
-//			keepOn = false
-//		case *fixedTable:
-//			stats.Nodes++
-//			stats.Tables++
-//			stats.FixedTables++
-//			stats.TableCountsByNumEntries[x.slotsUsed()]++
-//			stats.TableCountsByDepth[x.depth]++
-//			if x.depth > stats.MaxDepth {
-//				stats.MaxDepth = x.depth
-//			}
-//		case *sparseTable:
-//			stats.Nodes++
-//			stats.Tables++
-//			stats.SparseTables++
-//			stats.TableCountsByNumEntries[x.slotsUsed()]++
-//			stats.TableCountsByDepth[x.depth]++
-//			if x.depth > stats.MaxDepth {
-//				stats.MaxDepth = x.depth
-//			}
-//		case *flatLeaf:
-//			stats.Nodes++
-//			stats.Leafs++
-//			stats.FlatLeafs++
-//			stats.KeyVals += 1
-//			keepOn = false
-//		case *collisionLeaf:
-//			stats.Nodes++
-//			stats.Leafs++
-//			stats.CollisionLeafs++
-//			stats.KeyVals += uint(len(x.kvs))
-//			keepOn = false
-//		}
-//		return keepOn
-//	}
-//
-//	m.walkPreOrder(statFn)
-//	return stats
-//}
@@ -1,10 +1,20 @@
 package fmap_test
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"strings"
+	"sync/atomic"
 	"testing"
 
 	"github.com/lleo/go-functional-collections/fmap"
 	"github.com/lleo/go-functional-collections/key"
+	"github.com/lleo/go-functional-collections/key/hash"
+	"github.com/lleo/go-functional-collections/merkle"
+	"github.com/lleo/go-functional-collections/nodestore"
 )
 
 func TestBasicButildSimpleMap(t *testing.T) {
@@ -263,6 +273,19 @@ func TestBasicBulkInsertConflict(t *testing.T) {
 	}
 }
 
+func TestBasicBulkInsertNilResolve(t *testing.T) {
+	var kvs = buildKvs(10)
+	var m = fmap.NewFromList(kvs[:5])
+	var newKvs = []KeyVal{
+		{kvs[8].Key, -1}, {kvs[8].Key, -2}, {kvs[2].Key, -3},
+	}
+	var m0 = m.BulkInsert(newKvs, nil)
+	if m0.NumEntries() != 6 || m0.Get(kvs[8].Key) != -2 ||
+		m0.Get(kvs[2].Key) != -3 {
+		t.Fatal("BulkInsert(kvs, nil) did not take the new values")
+	}
+}
+
 func TestBasicMerge(t *testing.T) {
 	var kvs = buildKvs(100)
 
@@ -385,3 +408,958 @@ func TestBasicBulkDeleteNotFound(t *testing.T) {
 		}
 	}
 }
+
+func TestBasicTransient(t *testing.T) {
+	var kvs = buildKvs(10000)
+	var m0 = fmap.NewFromList(kvs[:5000])
+	var dupM0 = m0.DeepCopy()
+
+	var tr = m0.Transient()
+	for _, kv := range kvs[5000:] {
+		if !tr.Store(kv.Key, kv.Val) {
+			t.Fatalf("tr.Store(%s, %d) did not add a new entry", kv.Key, kv.Val)
+		}
+	}
+	for _, kv := range kvs[:2500] {
+		if val, found := tr.Remove(kv.Key); !found || val != kv.Val {
+			t.Fatalf("tr.Remove(%s),%v,%t != %d,true", kv.Key, val, found, kv.Val)
+		}
+	}
+	if _, found := tr.Remove(kvs[0].Key); found {
+		t.Fatalf("tr.Remove(%s) found a removed key", kvs[0].Key)
+	}
+
+	if tr.NumEntries() != 7500 {
+		t.Fatalf("tr.NumEntries(),%d != 7500", tr.NumEntries())
+	}
+
+	var m1 = tr.Persistent()
+
+	// the Transient is still usable, but does not change m1
+	tr.Put(kvs[0].Key, -1).Del(kvs[9999].Key)
+	if v := tr.Get(kvs[0].Key); v != -1 {
+		t.Fatalf("tr.Get(%s),%v != -1", kvs[0].Key, v)
+	}
+
+	if m1.NumEntries() != 7500 || m1.Count() != 7500 {
+		t.Fatalf("m1.NumEntries(),%d or m1.Count(),%d != 7500",
+			m1.NumEntries(), m1.Count())
+	}
+	for i, kv := range kvs {
+		var val, found = m1.Load(kv.Key)
+		if found != (i >= 2500) {
+			t.Fatalf("m1.Load(%s) found=%t", kv.Key, found)
+		}
+		if found && val != kv.Val {
+			t.Fatalf("m1.Load(%s),%v != %d", kv.Key, val, kv.Val)
+		}
+	}
+
+	if !m0.Equiv(dupM0) {
+		t.Fatal("orig Map m0 and duplicate of m0 are not identical.")
+	}
+}
+
+func TestBasicTransientRemoveAll(t *testing.T) {
+	var kvs = buildKvs(3000)
+	var m0 = fmap.NewFromList(kvs)
+	var dupM0 = m0.DeepCopy()
+
+	var tr = m0.Transient()
+	for _, kv := range randomizeKvs(kvs) {
+		tr.Del(kv.Key)
+	}
+
+	var m = tr.Persistent()
+	if m.NumEntries() != 0 || m.Count() != 0 {
+		t.Fatalf("m.NumEntries(),%d or m.Count(),%d != 0",
+			m.NumEntries(), m.Count())
+	}
+	if !m.Equiv(fmap.New()) {
+		t.Fatal("m is not equivalent to an empty Map")
+	}
+
+	if !m0.Equiv(dupM0) {
+		t.Fatal("orig Map m0 and duplicate of m0 are not identical.")
+	}
+}
+
+func TestBasicDiff(t *testing.T) {
+	var kvs = buildKvs(10000)
+	var m0 = fmap.NewFromList(kvs[:9000])
+
+	var m1 = m0
+	for _, kv := range kvs[9000:9100] {
+		m1 = m1.Put(kv.Key, kv.Val) //added
+	}
+	for _, kv := range kvs[:50] {
+		m1 = m1.Del(kv.Key) //removed
+	}
+	for _, kv := range kvs[100:130] {
+		m1 = m1.Put(kv.Key, -kv.Val.(int)) //changed
+	}
+	m1 = m1.Put(kvs[200].Key, kvs[200].Val) //same value
+
+	var counts = make(map[fmap.DiffType]int)
+	m0.Diff(m1, func(de fmap.DiffEntry) bool {
+		var oldVal, oldFound = m0.Load(de.Key)
+		var newVal, newFound = m1.Load(de.Key)
+		switch de.Type {
+		case fmap.DiffAdded:
+			if oldFound || !newFound || de.NewVal != newVal {
+				t.Fatalf("bad DiffAdded entry %s", de)
+			}
+		case fmap.DiffRemoved:
+			if !oldFound || newFound || de.OldVal != oldVal {
+				t.Fatalf("bad DiffRemoved entry %s", de)
+			}
+		case fmap.DiffChanged:
+			if de.OldVal != oldVal || de.NewVal != newVal || oldVal == newVal {
+				t.Fatalf("bad DiffChanged entry %s", de)
+			}
+		}
+		counts[de.Type]++
+		return true
+	})
+	if counts[fmap.DiffAdded] != 100 ||
+		counts[fmap.DiffRemoved] != 50 ||
+		counts[fmap.DiffChanged] != 30 {
+		t.Fatalf("counts,%v != added:100 removed:50 changed:30", counts)
+	}
+
+	// the reverse diff swaps added and removed
+	var numAdded, numRemoved int
+	m1.Diff(m0, func(de fmap.DiffEntry) bool {
+		switch de.Type {
+		case fmap.DiffAdded:
+			numAdded++
+		case fmap.DiffRemoved:
+			numRemoved++
+		}
+		return true
+	})
+	if numAdded != 50 || numRemoved != 100 {
+		t.Fatalf("numAdded,%d != 50 or numRemoved,%d != 100",
+			numAdded, numRemoved)
+	}
+
+	// unrelated but equivalent Maps have no differences
+	fmap.NewFromList(randomizeKvs(kvs[:9000])).Diff(m0,
+		func(de fmap.DiffEntry) bool {
+			t.Fatalf("equivalent Maps have a difference %s", de)
+			return false
+		})
+
+	var n int
+	m0.Diff(fmap.New(), func(de fmap.DiffEntry) bool {
+		n++
+		return n < 10
+	})
+	if n != 10 {
+		t.Fatalf("Diff did not stop after 10 entries; n=%d", n)
+	}
+}
+
+func TestBasicMarshalBinary(t *testing.T) {
+	var m0 = fmap.NewFromList(buildKvs(10000)).
+		Put(key.Int(42), "forty-two").
+		Put(key.ByteSlice("bytes"), 1.5).
+		Put(key.Str("nil"), nil).
+		Put(key.Str("bool"), true)
+
+	var data, err = m0.MarshalBinary()
+	if err != nil {
+		t.Fatalf("m0.MarshalBinary() failed: %s", err)
+	}
+
+	var m1 = fmap.New()
+	if err = m1.UnmarshalBinary(data); err != nil {
+		t.Fatalf("m1.UnmarshalBinary() failed: %s", err)
+	}
+	if m1.NumEntries() != m0.NumEntries() || m1.Count() != m0.NumEntries() {
+		t.Fatalf("m1.NumEntries(),%d or m1.Count(),%d != %d",
+			m1.NumEntries(), m1.Count(), m0.NumEntries())
+	}
+	m0.Range(func(kv fmap.KeyVal) bool {
+		if v, found := m1.Load(kv.Key); !found || v != kv.Val {
+			t.Fatalf("m1.Load(%s),%v,%t != %v,true", kv.Key, v, found, kv.Val)
+		}
+		return true
+	})
+
+	data, err = fmap.New().MarshalBinary()
+	if err != nil {
+		t.Fatalf("fmap.New().MarshalBinary() failed: %s", err)
+	}
+	var m2 = fmap.New()
+	if err = m2.UnmarshalBinary(data); err != nil || m2.NumEntries() != 0 {
+		t.Fatalf("m2.UnmarshalBinary() of an empty Map: err=%v, %d entries",
+			err, m2.NumEntries())
+	}
+}
+
+func TestBasicMarshalBinaryErrors(t *testing.T) {
+	type unregistered struct{}
+	var m = fmap.New().Put(key.Str("a"), unregistered{})
+	if _, err := m.MarshalBinary(); err == nil {
+		t.Fatal("m.MarshalBinary() of an unregistered value type did not fail")
+	}
+
+	var data, err = fmap.New().Put(key.Str("a"), 1).MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() failed: %s", err)
+	}
+	if err = fmap.New().UnmarshalBinary(data[:len(data)-1]); err == nil {
+		t.Fatal("UnmarshalBinary() of truncated data did not fail")
+	}
+	if err = fmap.New().UnmarshalBinary([]byte("FSET\x01\x00")); err == nil {
+		t.Fatal("UnmarshalBinary() of a Set's data did not fail")
+	}
+	if err = fmap.New().UnmarshalBinary([]byte("FMAP\x02\x00")); err == nil {
+		t.Fatal("UnmarshalBinary() of an unknown version did not fail")
+	}
+}
+
+func TestBasicJSON(t *testing.T) {
+	var m0 = fmap.New().
+		Put(key.Str("a"), 1.0).
+		Put(key.Str("b"), "two").
+		Put(key.Str("c"), nil)
+
+	var data, err = json.Marshal(m0)
+	if err != nil {
+		t.Fatalf("json.Marshal(m0) failed: %s", err)
+	}
+	if data[0] != '{' {
+		t.Fatalf("json.Marshal(m0) of key.Str keys is not an object: %s", data)
+	}
+
+	var m1 = fmap.New()
+	if err = json.Unmarshal(data, m1); err != nil {
+		t.Fatalf("json.Unmarshal(%s) failed: %s", data, err)
+	}
+	if !m1.Equiv(m0) {
+		t.Fatalf("m1,%s != m0,%s", m1, m0)
+	}
+
+	var m2 = fmap.New().
+		Put(key.Int(1), "one").
+		Put(key.Int(-2), "minus two")
+	data, err = json.Marshal(m2)
+	if err != nil {
+		t.Fatalf("json.Marshal(m2) failed: %s", err)
+	}
+	if data[0] != '[' {
+		t.Fatalf("json.Marshal(m2) of key.Int keys is not an array: %s", data)
+	}
+
+	var m3 = fmap.New()
+	if err = json.Unmarshal(data, m3); err != nil {
+		t.Fatalf("json.Unmarshal(%s) failed: %s", data, err)
+	}
+	if !m3.Equiv(m2) {
+		t.Fatalf("m3,%s != m2,%s", m3, m2)
+	}
+
+	if err = json.Unmarshal([]byte(`[[1.5, "x"]]`), fmap.New()); err == nil {
+		t.Fatal("json.Unmarshal() of a float key did not fail")
+	}
+}
+
+func TestBasicSnapshotStore(t *testing.T) {
+	var backend = nodestore.NewMemBackend()
+	var store = fmap.NewSnapshotStore(backend)
+
+	var m0 = fmap.NewFromList(buildKvs(10000))
+	var ref0, err = store.Save(m0)
+	if err != nil {
+		t.Fatalf("store.Save(m0) failed: %s", err)
+	}
+	var numBlobs0 = backend.Len()
+
+	var m1 = m0.Put(key.Str("a"), -1).Del(key.Str("b"))
+	ref1, err := store.Save(m1)
+	if err != nil {
+		t.Fatalf("store.Save(m1) failed: %s", err)
+	}
+	// two leaves, their tables up to the root, and the Map blob
+	if numNew := backend.Len() - numBlobs0; numNew > 2*int(hash.DepthLimit)+2 {
+		t.Fatalf("store.Save(m1) wrote %d new blobs", numNew)
+	}
+
+	if again, _ := store.Save(m0); again != ref0 {
+		t.Fatalf("store.Save(m0) again,%s != ref0,%s", again, ref0)
+	}
+
+	// a fresh SnapshotStore has none of the tables and leaves cached
+	for _, ref := range []nodestore.Ref{ref0, ref1} {
+		var m, err = fmap.NewSnapshotStore(backend).Load(ref)
+		if err != nil {
+			t.Fatalf("Load(%s) failed: %s", ref, err)
+		}
+		var orig = m0
+		if ref == ref1 {
+			orig = m1
+		}
+		if m.NumEntries() != orig.NumEntries() {
+			t.Fatalf("Load(%s).NumEntries(),%d != %d",
+				ref, m.NumEntries(), orig.NumEntries())
+		}
+		m.Diff(orig, func(de fmap.DiffEntry) bool {
+			t.Fatalf("Load(%s) is not the Map saved: %s", ref, de)
+			return false
+		})
+
+		// the loaded Map is as usable as any other
+		var nm = m.BulkInsert(buildKvs(20000)[10000:], fmap.TakeNewVal)
+		if nm.Count() != m.NumEntries()+10000 {
+			t.Fatalf("nm.Count(),%d != %d", nm.Count(), m.NumEntries()+10000)
+		}
+	}
+
+	var empty, _ = store.Save(fmap.New())
+	if m, err := store.Load(empty); err != nil || m.NumEntries() != 0 {
+		t.Fatalf("store.Load(empty) err=%v", err)
+	}
+}
+
+func TestBasicRootDigest(t *testing.T) {
+	var kvs = buildKvs(10000)
+
+	// m0 is built in order, m1 in reverse order with a detour through
+	// more entries, so their tables differ.
+	var m0 = fmap.NewFromList(kvs[:5000])
+	var m1 = fmap.New()
+	for i := 9999; i >= 0; i-- {
+		m1 = m1.Put(kvs[i].Key, kvs[i].Val)
+	}
+	for _, kv := range kvs[5000:] {
+		m1 = m1.Del(kv.Key)
+	}
+
+	var d0 = m0.RootDigest()
+	if m1.RootDigest() != d0 {
+		t.Fatal("equal Maps built differently have different RootDigests")
+	}
+	if fmap.New().RootDigest() != (merkle.Digest{}) {
+		t.Fatal("the RootDigest of an empty Map is not zero")
+	}
+	if !m0.Equiv(m1) {
+		t.Fatal("!m0.Equiv(m1) for Maps with equal RootDigests")
+	}
+
+	var m2 = m0.Put(kvs[0].Key, -1)
+	if m2.RootDigest() == d0 {
+		t.Fatal("m2.RootDigest() == m0.RootDigest() after a change")
+	}
+	if m2.Equiv(m1) {
+		t.Fatal("m2.Equiv(m1) after a change")
+	}
+	var n int
+	m1.Diff(m2, func(de fmap.DiffEntry) bool {
+		n++
+		return true
+	})
+	if n != 1 {
+		t.Fatalf("m1.Diff(m2) found %d differences, not 1", n)
+	}
+
+	if m2.Put(kvs[0].Key, kvs[0].Val).RootDigest() != d0 {
+		t.Fatal("RootDigest() after undoing the change != m0.RootDigest()")
+	}
+
+	var tr = m2.Transient()
+	tr.Put(kvs[0].Key, kvs[0].Val)
+	if tr.Persistent().RootDigest() != d0 {
+		t.Fatal("RootDigest() after undoing the change in a Transient != d0")
+	}
+}
+
+// pointVal is a value type without a codec, so its digest is taken from its
+// "%#v" form, which distinct pointers to equal pointVals share.
+type pointVal struct{ x, y int }
+
+func TestBasicInexactDigests(t *testing.T) {
+	var m0 = fmap.New().Put(key.Str("a"), &pointVal{1, 2})
+	var m1 = fmap.New().Put(key.Str("a"), &pointVal{1, 2})
+	if m0.RootDigest() != m1.RootDigest() {
+		t.Fatal("the RootDigests of equal *pointVals differ")
+	}
+	if m0.Equiv(m1) {
+		t.Fatal("m0.Equiv(m1) of distinct pointers with equal digests")
+	}
+	var n int
+	m0.Diff(m1, func(de fmap.DiffEntry) bool {
+		n++
+		return true
+	})
+	if n != 1 {
+		t.Fatalf("m0.Diff(m1) of distinct pointers found %d differences", n)
+	}
+
+	var f0 = fmap.New().Put(key.Str("a"), 0.0)
+	var f1 = fmap.New().Put(key.Str("a"), math.Copysign(0, -1))
+	if f0.RootDigest() == f1.RootDigest() {
+		t.Fatal("the RootDigests of 0.0 and -0.0 are equal")
+	}
+	if !f0.Equiv(f1) {
+		t.Fatal("!f0.Equiv(f1) of 0.0 and -0.0, which are ==")
+	}
+	f0.Diff(f1, func(de fmap.DiffEntry) bool {
+		t.Fatalf("f0.Diff(f1) of 0.0 and -0.0 found %s", de)
+		return false
+	})
+}
+
+func TestBasicCollidingDigests(t *testing.T) {
+	// A ValueHasher ignoring values makes the digests of Maps differing
+	// only in a value collide, like digests of entries chosen to collide.
+	merkle.SetValueHasher(func(v interface{}) []byte {
+		if k, isKey := v.(key.Hash); isKey {
+			return []byte(k.String())
+		}
+		return nil
+	})
+	defer merkle.SetValueHasher(merkle.DefaultValueHasher)
+
+	var kvs = buildKvs(10000)
+	var m0 = fmap.NewFromList(kvs)
+	var changed = append([]fmap.KeyVal(nil), kvs...)
+	changed[0].Val = -1
+	var m1 = fmap.NewFromList(changed)
+	if m0.RootDigest() != m1.RootDigest() {
+		t.Fatal("the RootDigests of Maps differing in a value differ")
+	}
+	if m0.Equiv(m1) || m1.Equiv(m0) {
+		t.Fatal("Maps differing in a value are Equiv for equal digests")
+	}
+	var n int
+	m0.Diff(m1, func(de fmap.DiffEntry) bool {
+		n++
+		return true
+	})
+	if n != 1 {
+		t.Fatalf("m0.Diff(m1) found %d differences != 1", n)
+	}
+}
+
+// countingConn counts the bytes read from a net.Conn.
+type countingConn struct {
+	net.Conn
+	n int
+}
+
+func (c *countingConn) Read(p []byte) (int, error) {
+	var n, err = c.Conn.Read(p)
+	c.n += n
+	return n, err
+}
+
+func syncMaps(t *testing.T, src, dst *fmap.Map) (*fmap.Map, int) {
+	var srcConn, dstConn = net.Pipe()
+	defer dstConn.Close()
+
+	var errc = make(chan error, 1)
+	go func() {
+		defer srcConn.Close()
+		errc <- src.ServeSync(srcConn)
+	}()
+
+	var cc = &countingConn{Conn: dstConn}
+	var m, err = dst.SyncFrom(cc)
+	if err != nil {
+		t.Fatalf("dst.SyncFrom() failed: %s", err)
+	}
+	if err = <-errc; err != nil {
+		t.Fatalf("src.ServeSync() failed: %s", err)
+	}
+	return m, cc.n
+}
+
+func TestBasicSync(t *testing.T) {
+	var kvs = buildKvs(10000)
+	var src = fmap.NewFromList(kvs)
+
+	var full, fullBytes = syncMaps(t, src, fmap.New())
+	if !full.Equiv(src) {
+		t.Fatal("syncing an empty Map did not converge to the source")
+	}
+
+	var dst = src.
+		Put(kvs[0].Key, -1).
+		Del(kvs[1].Key).
+		Put(key.Str("not-in-src"), 1)
+	var m, nBytes = syncMaps(t, src, dst)
+	if !m.Equiv(src) || m.RootDigest() != src.RootDigest() {
+		t.Fatal("syncing a changed Map did not converge to the source")
+	}
+	if nBytes*10 > fullBytes {
+		t.Fatalf("syncing three changes read %d bytes; a full sync %d",
+			nBytes, fullBytes)
+	}
+	if dst.Get(kvs[0].Key) != -1 {
+		t.Fatal("SyncFrom() modified the replica it was called on")
+	}
+
+	var same, _ = syncMaps(t, src, src)
+	if !same.Equiv(src) {
+		t.Fatal("syncing an equal Map changed it")
+	}
+
+	var empty, _ = syncMaps(t, fmap.New(), dst)
+	if empty.NumEntries() != 0 {
+		t.Fatalf("syncing from an empty Map left %d entries",
+			empty.NumEntries())
+	}
+}
+
+// collidingKey is a key.Hash whose hash.Val is always 0, like keys crafted
+// by an attacker to collide.
+type collidingKey string
+
+func (k collidingKey) Hash() hash.Val { return 0 }
+
+func (k collidingKey) Equals(okey key.Hash) bool {
+	var ok, isColliding = okey.(collidingKey)
+	return isColliding && k == ok
+}
+
+func (k collidingKey) String() string { return string(k) }
+
+func TestBasicCollisions(t *testing.T) {
+	var m = fmap.New()
+	var keys []collidingKey
+	for s := "a"; len(s) < 3; s = Inc(s) {
+		var k = collidingKey(s)
+		keys = append(keys, k)
+		m = m.Put(k, s)
+	}
+	if m.NumEntries() != len(keys) {
+		t.Fatalf("m.NumEntries(),%d != %d", m.NumEntries(), len(keys))
+	}
+	for _, k := range keys {
+		if v, found := m.Load(k); !found || v != string(k) {
+			t.Fatalf("m.Load(%q) = %v, %v", k, v, found)
+		}
+	}
+	if _, found := m.Load(collidingKey("zzz")); found {
+		t.Fatal("m.Load(\"zzz\") found a key never put")
+	}
+
+	var m2 = m.Put(keys[7], -1)
+	if m.Get(keys[7]) != string(keys[7]) || m2.Get(keys[7]) != -1 {
+		t.Fatal("m.Put() over a colliding key is not functional")
+	}
+	if m2.NumEntries() != m.NumEntries() {
+		t.Fatal("m.Put() over a colliding key changed NumEntries()")
+	}
+
+	// delete in a different order than the keys were put
+	for i := range keys {
+		var k = keys[(i*7)%len(keys)]
+		var v interface{}
+		var deleted bool
+		m, v, deleted = m.Remove(k)
+		if !deleted || v != string(k) {
+			t.Fatalf("m.Remove(%q) = %v, %v", k, v, deleted)
+		}
+		if _, found := m.Load(k); found {
+			t.Fatalf("m.Load(%q) found a removed key", k)
+		}
+	}
+	if m.NumEntries() != 0 {
+		t.Fatalf("m.NumEntries(),%d != 0", m.NumEntries())
+	}
+}
+
+// sameEntries reports whether the Maps hold the same key/value pairs, whatever
+// the shape of their HAMTs.
+func sameEntries(a, b *fmap.Map) bool {
+	var same = true
+	a.Diff(b, func(fmap.DiffEntry) bool {
+		same = false
+		return false
+	})
+	return same
+}
+
+func TestBasicOptions(t *testing.T) {
+	var kvs = buildKvs(10000)
+	var m16 = fmap.New()
+	for _, kv := range kvs {
+		m16 = m16.Put(kv.Key, kv.Val)
+	}
+	if m16.Options().TableWidth != 16 {
+		t.Fatalf("m16.Options().TableWidth,%d != 16", m16.Options().TableWidth)
+	}
+
+	for _, width := range []int{16, 32, 64} {
+		var opts = fmap.Options{TableWidth: width}
+		var m = fmap.NewWithOptions(opts)
+		for _, kv := range kvs {
+			m = m.Put(kv.Key, kv.Val)
+		}
+		if m.Options() != opts {
+			t.Fatalf("m.Options(),%v != %v", m.Options(), opts)
+		}
+		if m.NumEntries() != len(kvs) {
+			t.Fatalf("width %d: m.NumEntries(),%d != %d",
+				width, m.NumEntries(), len(kvs))
+		}
+		for _, kv := range kvs {
+			if v := m.Get(kv.Key); v != kv.Val {
+				t.Fatalf("width %d: m.Get(%s),%v != %v", width, kv.Key, v, kv.Val)
+			}
+		}
+
+		// Maps of different widths compare by their entries
+		if !m.Equiv(m16) || !m16.Equiv(m) || m.RootDigest() != m16.RootDigest() {
+			t.Fatalf("width %d: m is not equivalent to m16", width)
+		}
+		var numDiffs int
+		m.Diff(m16.Del(kvs[0].Key), func(de fmap.DiffEntry) bool {
+			if de.Type != fmap.DiffRemoved || de.Key != kvs[0].Key {
+				t.Fatalf("width %d: unexpected DiffEntry %s", width, de)
+			}
+			numDiffs++
+			return true
+		})
+		if numDiffs != 1 {
+			t.Fatalf("width %d: m.Diff() reported %d DiffEntries", width, numDiffs)
+		}
+
+		var tr = m.Transient()
+		for _, kv := range kvs[:len(kvs)/2] {
+			tr.Del(kv.Key)
+		}
+		var half = tr.Persistent()
+		if half.Options() != opts || half.NumEntries() != len(kvs)/2 {
+			t.Fatalf("width %d: half.Options(),%v half.NumEntries(),%d",
+				width, half.Options(), half.NumEntries())
+		}
+
+		var backend = nodestore.NewMemBackend()
+		var ref, err = fmap.NewSnapshotStore(backend).Save(half)
+		if err != nil {
+			t.Fatalf("width %d: store.Save(half) failed: %s", width, err)
+		}
+		loaded, err := fmap.NewSnapshotStore(backend).Load(ref)
+		if err != nil {
+			t.Fatalf("width %d: store.Load() failed: %s", width, err)
+		}
+		if loaded.Options() != opts || !sameEntries(loaded, half) {
+			t.Fatalf("width %d: the loaded Map is not the Map saved", width)
+		}
+
+		data, err := half.MarshalBinary()
+		if err != nil {
+			t.Fatalf("width %d: half.MarshalBinary() failed: %s", width, err)
+		}
+		var um = fmap.NewWithOptions(opts)
+		if err = um.UnmarshalBinary(data); err != nil {
+			t.Fatalf("width %d: um.UnmarshalBinary() failed: %s", width, err)
+		}
+		if um.Options() != opts || !sameEntries(um, half) {
+			t.Fatalf("width %d: the unmarshaled Map is not the Map marshaled",
+				width)
+		}
+
+		var synced, _ = syncMaps(t, m, half)
+		if synced.Options() != opts || !sameEntries(synced, m) {
+			t.Fatalf("width %d: syncing did not converge to the source", width)
+		}
+
+		for _, kv := range kvs {
+			m = m.Del(kv.Key)
+		}
+		if m.NumEntries() != 0 || m.Options() != opts {
+			t.Fatalf("width %d: m.NumEntries(),%d after deleting every key",
+				width, m.NumEntries())
+		}
+	}
+
+	// Maps of different widths do not share hash path prefixes; the source
+	// says so, rather than leaving the replica waiting for an answer.
+	var srcConn, dstConn = net.Pipe()
+	defer srcConn.Close()
+	defer dstConn.Close()
+	var errc = make(chan error, 1)
+	go func() {
+		errc <- m16.ServeSync(srcConn)
+	}()
+	var wide = fmap.NewWithOptions(fmap.Options{TableWidth: 32})
+	var _, err = wide.SyncFrom(dstConn)
+	if err == nil || !strings.Contains(err.Error(), "TableWidth") {
+		t.Fatalf("syncing Maps of different widths failed with %v", err)
+	}
+	if err := <-errc; err == nil {
+		t.Fatal("serving a replica of a different width did not fail")
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("fmap.NewWithOptions() of TableWidth 24 did not panic")
+			}
+		}()
+		fmap.NewWithOptions(fmap.Options{TableWidth: 24})
+	}()
+}
+
+func TestBasicStats(t *testing.T) {
+	var kvs = buildKvs(10000)
+	var m = fmap.NewFromList(kvs)
+
+	var stats = m.Stats()
+	if stats.KeyVals != uint(len(kvs)) {
+		t.Fatalf("stats.KeyVals,%d != %d", stats.KeyVals, len(kvs))
+	}
+	if stats.Tables != stats.FixedTables+stats.SparseTables ||
+		stats.Leafs != stats.FlatLeafs+stats.CollisionLeafs ||
+		stats.Nodes != stats.Tables+stats.Leafs {
+		t.Fatalf("inconsistent node counts: %+v", stats)
+	}
+	var byDepth, byNumEntries uint
+	for _, n := range stats.TableCountsByDepth {
+		byDepth += n
+	}
+	for _, n := range stats.TableCountsByNumEntries {
+		byNumEntries += n
+	}
+	if byDepth != stats.Tables || byNumEntries != stats.Tables {
+		t.Fatalf("byDepth,%d byNumEntries,%d != stats.Tables,%d",
+			byDepth, byNumEntries, stats.Tables)
+	}
+	if stats.TableCountsByDepth[0] != 1 {
+		t.Fatalf("stats.TableCountsByDepth[0],%d != 1",
+			stats.TableCountsByDepth[0])
+	}
+	if stats.DeepestKeys.Depth != stats.MaxDepth+1 {
+		t.Fatalf("stats.DeepestKeys.Depth,%d != stats.MaxDepth+1,%d",
+			stats.DeepestKeys.Depth, stats.MaxDepth+1)
+	}
+	for _, k := range stats.DeepestKeys.Keys {
+		if _, found := m.Load(k); !found {
+			t.Fatalf("deepest key %s is not in m", k)
+		}
+	}
+	if stats.AvgProbeDepth < 1 ||
+		stats.AvgProbeDepth > float64(stats.DeepestKeys.Depth) {
+		t.Fatalf("stats.AvgProbeDepth,%f", stats.AvgProbeDepth)
+	}
+	if stats.TableBytes == 0 || stats.LeafBytes == 0 {
+		t.Fatalf("stats.TableBytes,%d stats.LeafBytes,%d",
+			stats.TableBytes, stats.LeafBytes)
+	}
+
+	var cm = m
+	for i, s := range []string{"a", "b", "c"} {
+		cm = cm.Put(collidingKey(s), i)
+	}
+	var cstats = cm.Stats()
+	if cstats.MaxCollisions != 3 || cstats.CollisionLeafCountsByNumEntries[3] < 1 {
+		t.Fatalf("cstats.MaxCollisions,%d CollisionLeafCountsByNumEntries,%v",
+			cstats.MaxCollisions, cstats.CollisionLeafCountsByNumEntries)
+	}
+	if cstats.LeafBytes <= stats.LeafBytes {
+		t.Fatalf("cstats.LeafBytes,%d <= stats.LeafBytes,%d",
+			cstats.LeafBytes, stats.LeafBytes)
+	}
+
+	var empty = fmap.New().Stats()
+	if empty.KeyVals != 0 || empty.Tables != 1 || empty.AvgProbeDepth != 0 {
+		t.Fatalf("fmap.New().Stats(),%+v", empty)
+	}
+
+	var wide = fmap.NewWithOptions(fmap.Options{TableWidth: 64}).Stats()
+	if len(wide.TableCountsByNumEntries) != 65 {
+		t.Fatalf("len(wide.TableCountsByNumEntries),%d != 65",
+			len(wide.TableCountsByNumEntries))
+	}
+}
+
+func TestBasicAtomic(t *testing.T) {
+	var a fmap.Atomic // the zero value is ready to use
+
+	if _, found := a.Load(key.Str("a")); found {
+		t.Fatal("a zero Atomic is not empty")
+	}
+	a.Store(key.Str("a"), 1)
+	if v, loaded := a.LoadOrStore(key.Str("a"), 2); !loaded || v != 1 {
+		t.Fatalf("a.LoadOrStore(\"a\", 2) = %v, %v", v, loaded)
+	}
+	if v, loaded := a.LoadOrStore(key.Str("b"), 2); loaded || v != 2 {
+		t.Fatalf("a.LoadOrStore(\"b\", 2) = %v, %v", v, loaded)
+	}
+
+	var snap = a.Snapshot()
+	a.Delete(key.Str("a"))
+	if _, found := a.Load(key.Str("a")); found {
+		t.Fatal("a.Load(\"a\") found a deleted key")
+	}
+	if snap.Get(key.Str("a")) != 1 || snap.NumEntries() != 2 {
+		t.Fatal("a.Delete(\"a\") modified a Snapshot")
+	}
+
+	if a.CompareAndSwap(snap, fmap.New()) {
+		t.Fatal("a.CompareAndSwap() of a stale Snapshot succeeded")
+	}
+	if !a.CompareAndSwap(a.Snapshot(), snap) || a.Snapshot() != snap {
+		t.Fatal("a.CompareAndSwap() of the current Snapshot failed")
+	}
+
+	// concurrent increments are not lost
+	const numGoroutines, numIncrements = 8, 200
+	var counter = fmap.NewAtomic(nil)
+	var done = make(chan struct{})
+	for g := 0; g < numGoroutines; g++ {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			for i := 0; i < numIncrements; i++ {
+				counter.Update(func(m *fmap.Map) *fmap.Map {
+					var n, _ = m.Load(key.Str("n"))
+					if n == nil {
+						n = 0
+					}
+					return m.Put(key.Str("n"), n.(int)+1)
+				})
+			}
+		}()
+	}
+	for g := 0; g < numGoroutines; g++ {
+		<-done
+	}
+	if n, _ := counter.Load(key.Str("n")); n != numGoroutines*numIncrements {
+		t.Fatalf("counter is %v; expected %d", n, numGoroutines*numIncrements)
+	}
+}
+
+func TestBasicParallel(t *testing.T) {
+	var kvs = buildKvs(20000)
+
+	for _, width := range []int{16, 64} {
+		var opts = fmap.Options{TableWidth: width}
+		var base = fmap.NewWithOptions(opts)
+
+		var m = base.BulkInsertParallel(kvs[:10000], fmap.TakeNewVal)
+		var seq = base.BulkInsert(kvs[:10000], fmap.TakeNewVal)
+		if m.NumEntries() != 10000 || m.Options() != opts ||
+			!sameEntries(m, seq) {
+			t.Fatalf("width %d: BulkInsertParallel() != BulkInsert()", width)
+		}
+
+		// equal keys are resolved in order
+		var dups = []fmap.KeyVal{{kvs[0].Key, -1}, {kvs[0].Key, -2}}
+		if v := m.BulkInsertParallel(dups, fmap.TakeNewVal).Get(kvs[0].Key); v != -2 {
+			t.Fatalf("width %d: BulkInsertParallel() of dups stored %v", width, v)
+		}
+		if v := m.BulkInsertParallel(dups, fmap.KeepOrigVal).Get(kvs[0].Key); v != kvs[0].Val {
+			t.Fatalf("width %d: BulkInsertParallel() of dups stored %v", width, v)
+		}
+
+		var om = base.BulkInsert(kvs[5000:], fmap.TakeNewVal).
+			Put(kvs[5000].Key, -1)
+		var merged = m.MergeParallel(om, fmap.KeepOrigVal)
+		if merged.NumEntries() != len(kvs) ||
+			!sameEntries(merged, m.Merge(om, fmap.KeepOrigVal)) {
+			t.Fatalf("width %d: MergeParallel() != Merge()", width)
+		}
+		if m.NumEntries() != 10000 || merged.Get(kvs[5000].Key) != kvs[5000].Val {
+			t.Fatalf("width %d: MergeParallel() modified m or resolved wrongly",
+				width)
+		}
+	}
+
+	var m = fmap.NewFromListParallel(kvs)
+	if !sameEntries(m, fmap.NewFromList(kvs)) || m.RootDigest() !=
+		fmap.NewFromList(kvs).RootDigest() {
+		t.Fatal("NewFromListParallel() != NewFromList()")
+	}
+	if fmap.NewFromListParallel(nil).NumEntries() != 0 {
+		t.Fatal("NewFromListParallel(nil) is not empty")
+	}
+
+	var om = fmap.NewWithOptions(fmap.Options{TableWidth: 32}).
+		BulkInsert(kvs[5000:], fmap.TakeNewVal).
+		BulkInsert(buildKvs(len(kvs) + 1000)[len(kvs):], fmap.TakeNewVal).
+		Put(kvs[5000].Key, -1)
+	var merged = m.MergeParallel(om, fmap.KeepOrigVal)
+	if merged.NumEntries() != len(kvs)+1000 ||
+		!sameEntries(merged, m.Merge(om, fmap.KeepOrigVal)) ||
+		merged.Get(kvs[5000].Key) != kvs[5000].Val {
+		t.Fatal("MergeParallel() of Maps of different widths != Merge()")
+	}
+}
+
+func TestBasicFold(t *testing.T) {
+	var kvs = buildKvs(10000)
+	var m = fmap.NewFromList(kvs)
+	var ctx = context.Background()
+
+	var sum, err = m.Fold(ctx, 0, 0,
+		func(acc interface{}, kv KeyVal) interface{} {
+			return acc.(int) + kv.Val.(int)
+		},
+		func(acc0, acc1 interface{}) interface{} {
+			return acc0.(int) + acc1.(int)
+		})
+	if err != nil || sum != len(kvs)*(len(kvs)-1)/2 {
+		t.Fatalf("m.Fold() = %v, %v", sum, err)
+	}
+
+	var count int64
+	err = m.ParallelRange(ctx, 4, func(kv KeyVal) bool {
+		if m.Get(kv.Key) != kv.Val {
+			t.Errorf("m.ParallelRange() visited %s", kv)
+		}
+		atomic.AddInt64(&count, 1)
+		return true
+	})
+	if err != nil || count != int64(len(kvs)) {
+		t.Fatalf("m.ParallelRange() visited %d pairs; err=%v", count, err)
+	}
+
+	var cancelled, cancel = context.WithCancel(ctx)
+	cancel()
+	if sum, err = m.Fold(cancelled, 0, 0, nil, nil); err != context.Canceled ||
+		sum != nil {
+		t.Fatalf("m.Fold() with a cancelled ctx = %v, %v", sum, err)
+	}
+
+	if sum, err = fmap.New().Fold(ctx, 0, 0, nil, nil); err != nil || sum != 0 {
+		t.Fatalf("Fold() of an empty Map = %v, %v", sum, err)
+	}
+}
+
+func TestBasicEquivUncomparableVals(t *testing.T) {
+	var m16 = fmap.New()
+	var m64 = fmap.NewWithOptions(fmap.Options{TableWidth: 64})
+	for i := 0; i < 1000; i++ {
+		var k = key.Str(fmt.Sprintf("k%d", i))
+		m16 = m16.Put(k, []int{i, i + 1})
+		m64 = m64.Put(k, []int{i, i + 1})
+	}
+
+	if !m16.Equiv(m64) || !m64.Equiv(m16) {
+		t.Fatal("Maps of equal slice values across widths are not equivalent")
+	}
+	if !m16.Equiv(m16.DeepCopy()) {
+		t.Fatal("m16 is not equivalent to its DeepCopy")
+	}
+
+	var changed = m64.Put(key.Str("k7"), []int{0})
+	if m16.Equiv(changed) || changed.Equiv(m16) {
+		t.Fatal("Maps of different slice values across widths are equivalent")
+	}
+	var numDiffs int
+	m16.Diff(changed, func(de fmap.DiffEntry) bool {
+		if de.Type != fmap.DiffChanged || de.Key != key.Str("k7") {
+			t.Fatalf("unexpected DiffEntry %s", de)
+		}
+		numDiffs++
+		return true
+	})
+	if numDiffs != 1 {
+		t.Fatalf("m16.Diff(changed) reported %d DiffEntries", numDiffs)
+	}
+}
@@ -1,8 +1,10 @@
 package fmap_test
 
 import (
+	"context"
 	"log"
 	"math/rand"
+	"strconv"
 	"testing"
 
 	"github.com/lleo/go-functional-collections/fmap"
@@ -206,6 +208,40 @@ func BenchmarkPutOne10MM(b *testing.B) {
 	}
 }
 
+// buildFloodedMap returns a Map of n collidingKeys, which all fall in one
+// collisionLeaf, and a few more collidingKeys to put into it.
+func buildFloodedMap(n int) (*fmap.Map, []collidingKey) {
+	var m = fmap.New()
+	var xtra []collidingKey
+	for i := 0; i < n+n/10+1; i++ {
+		var k = collidingKey(strconv.Itoa(i))
+		if i < n {
+			m = m.Put(k, i)
+		} else {
+			xtra = append(xtra, k)
+		}
+	}
+	return m, xtra
+}
+
+// benchmarkPutFlooded puts one key into a collisionLeaf of n keys. The bytes
+// copied per op should grow like log(n), not like n.
+func benchmarkPutFlooded(b *testing.B, n int) {
+	var m, xtra = buildFloodedMap(n)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var k = xtra[rand.Int()%len(xtra)]
+		_ = m.Put(k, i)
+	}
+}
+
+func BenchmarkPutFlooded100(b *testing.B)  { benchmarkPutFlooded(b, NumKvs100) }
+func BenchmarkPutFlooded1M(b *testing.B)   { benchmarkPutFlooded(b, NumKvs1M) }
+func BenchmarkPutFlooded10M(b *testing.B)  { benchmarkPutFlooded(b, NumKvs10M) }
+func BenchmarkPutFlooded100M(b *testing.B) { benchmarkPutFlooded(b, NumKvs100M) }
+
 func xBenchmarkPutOne100MM(b *testing.B) {
 	log.Printf("BenchmarkPutOne100MM: b.N=%d\n", b.N)
 	var xtra = XtraKvs100MM
@@ -444,3 +480,100 @@ func BenchmarkBuildBulkInsert(b *testing.B) {
 	b.ResetTimer()
 	_ = m.BulkInsert(kvs, fmap.KeepOrigVal)
 }
+
+func buildMapWidth(kvs []KeyVal, width int) *fmap.Map {
+	var m = fmap.NewWithOptions(fmap.Options{TableWidth: width})
+	var t = m.Transient()
+	for _, kv := range kvs {
+		t.Put(kv.Key, kv.Val)
+	}
+	return t.Persistent()
+}
+
+func benchmarkPutOneWidth(b *testing.B, width int) {
+	log.Printf("BenchmarkPutOneWidth%d: b.N=%d\n", width, b.N)
+	var kvs, xtra = buildKvs2(NumKvs100M, NumKvsExtra100M)
+	var m = buildMapWidth(kvs, width)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var j = rand.Int() % len(xtra)
+		var kv = xtra[j]
+		_ = m.Put(kv.Key, kv.Val)
+	}
+}
+
+func BenchmarkPutOneWidth16(b *testing.B) {
+	benchmarkPutOneWidth(b, 16)
+}
+
+func BenchmarkPutOneWidth32(b *testing.B) {
+	benchmarkPutOneWidth(b, 32)
+}
+
+func BenchmarkPutOneWidth64(b *testing.B) {
+	benchmarkPutOneWidth(b, 64)
+}
+
+func benchmarkGetOneWidth(b *testing.B, width int) {
+	log.Printf("BenchmarkGetOneWidth%d: b.N=%d\n", width, b.N)
+	var kvs = buildKvs(NumKvs100M)
+	var m = buildMapWidth(kvs, width)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var j = rand.Int() % len(kvs)
+		_ = m.Get(kvs[j].Key)
+	}
+}
+
+func BenchmarkGetOneWidth16(b *testing.B) {
+	benchmarkGetOneWidth(b, 16)
+}
+
+func BenchmarkGetOneWidth32(b *testing.B) {
+	benchmarkGetOneWidth(b, 32)
+}
+
+func BenchmarkGetOneWidth64(b *testing.B) {
+	benchmarkGetOneWidth(b, 64)
+}
+
+func BenchmarkBuildNewFromListParallel(b *testing.B) {
+	log.Printf("BenchmarkBuildNewFromListParallel: b.N=%d\n", b.N)
+	var kvs = buildKvs(b.N)
+	b.ResetTimer()
+	_ = fmap.NewFromListParallel(kvs)
+}
+
+func BenchmarkBuildBulkInsertParallel(b *testing.B) {
+	log.Printf("BenchmarkBuildBulkInsertParallel: b.N=%d\n", b.N)
+	var kvs = buildKvs(b.N)
+	var m = fmap.New()
+	b.ResetTimer()
+	_ = m.BulkInsertParallel(kvs, fmap.KeepOrigVal)
+}
+
+func BenchmarkRangeSum(b *testing.B) {
+	log.Printf("BenchmarkRangeSum: b.N=%d\n", b.N)
+	var m = buildMap(buildKvs(b.N))
+	b.ResetTimer()
+	var sum int
+	m.Range(func(kv KeyVal) bool {
+		sum += kv.Val.(int)
+		return true
+	})
+}
+
+func BenchmarkFoldSum(b *testing.B) {
+	log.Printf("BenchmarkFoldSum: b.N=%d\n", b.N)
+	var m = buildMap(buildKvs(b.N))
+	b.ResetTimer()
+	_, _ = m.Fold(context.Background(), 0, 0,
+		func(acc interface{}, kv KeyVal) interface{} {
+			return acc.(int) + kv.Val.(int)
+		},
+		func(acc0, acc1 interface{}) interface{} {
+			return acc0.(int) + acc1.(int)
+		})
+}
@@ -41,11 +41,11 @@ LOOP:
 			it.setNextNode()
 			break LOOP
 		case *collisionLeaf:
-			if it.kvIdx >= len(*x) {
+			if it.kvIdx >= x.count() {
 				it.setNextNode()
 				continue LOOP
 			}
-			kv = (*x)[it.kvIdx]
+			kv = x.at(it.kvIdx)
 			it.kvIdx++
 			break LOOP
 		default:
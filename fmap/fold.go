@@ -0,0 +1,102 @@
+package fmap
+
+import (
+	"context"
+
+	"github.com/lleo/go-functional-collections/internal/parallel"
+)
+
+// The sub-trees under the slots of the root table hold disjoint sets of keys,
+// and a Map never changes, so they can be walked each in its own goroutine
+// with no locking. ParallelRange and Fold do that, with a pool of workers
+// taking the sub-trees one at a time.
+
+// ParallelRange calls fn for every key/value pair of the Map, from up to
+// workers goroutines at once, or runtime.GOMAXPROCS(0) if workers <= 0. So fn
+// MUST be safe for concurrent use, and the pairs are visited in no particular
+// order. If fn returns false, the remaining pairs are skipped, though calls
+// already under way in other goroutines still finish.
+//
+// ParallelRange also stops once ctx is done, and then returns ctx.Err().
+func (m *Map) ParallelRange(
+	ctx context.Context,
+	workers int,
+	fn func(KeyVal) bool,
+) error {
+	var subs = m.rootSubTrees()
+	return parallel.Parts(ctx, workers, len(subs),
+		func(part int, halted func() bool) bool {
+			return walkKeyVals(subs[part], func(kv KeyVal) bool {
+				return !halted() && fn(kv)
+			})
+		})
+}
+
+// Fold reduces the key/value pairs of the Map to one value, MapReduce style,
+// using up to workers goroutines at once, or runtime.GOMAXPROCS(0) if workers
+// <= 0. Each goroutine folds the pairs of a part of the Map, starting from
+// zero, and the results of the parts are then combined. So zero MUST be an
+// identity of combine, and combine MUST be associative and commutative, as the
+// parts are in no particular order; fold is only ever called by one goroutine
+// for a given accumulator.
+//
+// If ctx is done before the fold is, Fold returns nil and ctx.Err().
+func (m *Map) Fold(
+	ctx context.Context,
+	workers int,
+	zero interface{},
+	fold func(acc interface{}, kv KeyVal) interface{},
+	combine func(acc0, acc1 interface{}) interface{},
+) (interface{}, error) {
+	var subs = m.rootSubTrees()
+	var accs = make([]interface{}, len(subs))
+	var err = parallel.Parts(ctx, workers, len(subs),
+		func(part int, halted func() bool) bool {
+			var acc = zero
+			if walkKeyVals(subs[part], func(kv KeyVal) bool {
+				if halted() {
+					return false
+				}
+				acc = fold(acc, kv)
+				return true
+			}) {
+				accs[part] = acc
+			}
+			return true
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	var acc = zero
+	for _, partAcc := range accs {
+		acc = combine(acc, partAcc)
+	}
+	return acc, nil
+}
+
+// rootSubTrees() returns the nodes under the used slots of the root table.
+func (m *Map) rootSubTrees() []nodeI {
+	var subs []nodeI
+	for idx := uint(0); idx < m.layout().IndexLimit(); idx++ {
+		if n := m.root.get(idx); n != nil {
+			subs = append(subs, n)
+		}
+	}
+	return subs
+}
+
+// walkKeyVals() calls fn for every key/value pair held by the node n, found
+// under the root table, until fn returns false, and then returns false.
+func walkKeyVals(n nodeI, fn func(KeyVal) bool) bool {
+	return n.walkPreOrder(func(n nodeI, depth uint) bool {
+		if leaf, isLeaf := n.(leafI); isLeaf {
+			for _, kv := range leaf.keyVals() {
+				if !fn(kv) {
+					return false
+				}
+			}
+		}
+		return true
+	}, 1)
+}
@@ -0,0 +1,88 @@
+//go:build go1.23
+// +build go1.23
+
+package fmap
+
+import (
+	"iter"
+
+	"github.com/lleo/go-functional-collections/key"
+)
+
+// All returns an iterator over the key/value mappings of the *Map, for use
+// with a for-range loop. Like Range, the order of the mappings is determined
+// by the hash values of the keys.
+func (m *Map) All() iter.Seq2[key.Hash, interface{}] {
+	return func(yield func(key.Hash, interface{}) bool) {
+		m.walkPreOrder(func(n nodeI, depth uint) bool {
+			if leaf, isLeaf := n.(leafI); isLeaf {
+				for _, kv := range leaf.keyVals() {
+					if !yield(kv.Key, kv.Val) {
+						return false
+					}
+				}
+			}
+			return true
+		})
+	}
+}
+
+// Keys returns an iterator over the keys of the *Map, in the same order as
+// All.
+func (m *Map) Keys() iter.Seq[key.Hash] {
+	return func(yield func(key.Hash) bool) {
+		for k := range m.All() {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
+
+// Values returns an iterator over the values of the *Map, in the same order
+// as All.
+func (m *Map) Values() iter.Seq[interface{}] {
+	return func(yield func(interface{}) bool) {
+		for _, v := range m.All() {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// All returns an iterator over the key/value mappings of the *TypedMap, for
+// use with a for-range loop.
+func (m *TypedMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for k, v := range m.m.All() {
+			if !yield(k.(key.HashOf[K]).Key, typedVal[V](v)) {
+				return
+			}
+		}
+	}
+}
+
+// Keys returns an iterator over the keys of the *TypedMap, in the same order
+// as All.
+func (m *TypedMap[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for k := range m.m.All() {
+			if !yield(k.(key.HashOf[K]).Key) {
+				return
+			}
+		}
+	}
+}
+
+// Values returns an iterator over the values of the *TypedMap, in the same
+// order as All.
+func (m *TypedMap[K, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for _, v := range m.m.All() {
+			if !yield(typedVal[V](v)) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,88 @@
+//go:build go1.23
+// +build go1.23
+
+package fmap_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/lleo/go-functional-collections/fmap"
+	"github.com/lleo/go-functional-collections/key"
+)
+
+func TestIterAll(t *testing.T) {
+	var kvs = buildKvs(5000)
+	var m = fmap.NewFromList(kvs)
+
+	var seen = make(map[key.Hash]interface{})
+	for k, v := range m.All() {
+		if _, dup := seen[k]; dup {
+			t.Fatalf("m.All() yielded key %s twice", k)
+		}
+		seen[k] = v
+	}
+	if len(seen) != len(kvs) {
+		t.Fatalf("m.All() yielded %d keys; expected %d", len(seen), len(kvs))
+	}
+	for _, kv := range kvs {
+		if v, found := seen[kv.Key]; !found || v != kv.Val {
+			t.Fatalf("m.All() yielded %s:%v; expected %s:%v",
+				kv.Key, v, kv.Key, kv.Val)
+		}
+	}
+
+	var numKeys, sumVals int
+	for range m.Keys() {
+		numKeys++
+	}
+	for v := range m.Values() {
+		sumVals += v.(int)
+	}
+	if numKeys != 5000 || sumVals != 4999*5000/2 {
+		t.Fatalf("numKeys,%d != 5000 or sumVals,%d != %d",
+			numKeys, sumVals, 4999*5000/2)
+	}
+
+	var n int
+	for range m.All() {
+		n++
+		if n == 10 {
+			break
+		}
+	}
+	if n != 10 {
+		t.Fatalf("break out of m.All() loop after %d iterations", n)
+	}
+
+	for range fmap.New().All() {
+		t.Fatal("empty Map yielded a key/value pair")
+	}
+}
+
+func TestIterTypedMap(t *testing.T) {
+	var m = fmap.NewTypedMap[key.Int, string]()
+	for i := 0; i < 100; i++ {
+		m = m.Put(key.Int(i), key.Int(i).String())
+	}
+
+	var keys = slices.Sorted(m.Keys())
+	if len(keys) != 100 || keys[0] != 0 || keys[99] != 99 {
+		t.Fatalf("slices.Sorted(m.Keys()) = %v", keys)
+	}
+	for k, v := range m.All() {
+		if v != k.String() {
+			t.Fatalf("m.All() yielded %d:%q", k, v)
+		}
+	}
+	if vals := slices.Collect(m.Values()); len(vals) != 100 {
+		t.Fatalf("len(slices.Collect(m.Values())),%d != 100", len(vals))
+	}
+
+	var nilKeys = slices.Collect(fmap.NewTypedMap[key.Int, error]().
+		Put(key.Int(2), nil).Put(key.Int(1), nil).Keys())
+	slices.Sort(nilKeys)
+	if !slices.Equal(nilKeys, []key.Int{1, 2}) {
+		t.Fatalf("nilKeys,%v != [1 2]", nilKeys)
+	}
+}
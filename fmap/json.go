@@ -0,0 +1,61 @@
+package fmap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/lleo/go-functional-collections/codec"
+	"github.com/lleo/go-functional-collections/key"
+)
+
+var (
+	_ json.Marshaler   = (*Map)(nil)
+	_ json.Unmarshaler = (*Map)(nil)
+)
+
+// MarshalJSON implements the json.Marshaler interface. A Map with only key.Str
+// keys is encoded as a JSON object, any other Map as a JSON array of
+// [key, value] pairs. See the codec package for the details.
+func (m *Map) MarshalJSON() ([]byte, error) {
+	var keys = make([]interface{}, 0, m.numEnts)
+	var vals = make([]interface{}, 0, m.numEnts)
+	m.root.walkPreOrder(func(n nodeI, depth uint) bool {
+		if leaf, isLeaf := n.(leafI); isLeaf {
+			for _, kv := range leaf.keyVals() {
+				keys = append(keys, kv.Key)
+				vals = append(vals, kv.Val)
+			}
+		}
+		return true
+	}, 0)
+	return codec.EncodeJSONPairs(keys, vals)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. It replaces the
+// contents of the receiver, so it SHOULD only be called on a Map that no one
+// else holds, like one just returned by New. Keys are decoded by
+// codec.DecodeJSONKey and MUST implement key.Hash. The Map keeps the Options of
+// the receiver.
+func (m *Map) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil
+	}
+
+	var kvs []KeyVal
+	var err = codec.DecodeJSONPairs(data, func(k, v interface{}) error {
+		var hk, isHash = k.(key.Hash)
+		if !isHash {
+			return fmt.Errorf("fmap: decoded key of type %T is not a key.Hash",
+				k)
+		}
+		kvs = append(kvs, KeyVal{hk, v})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	*m = *newFromList(kvs, m.layout())
+	return nil
+}
@@ -2,6 +2,7 @@ package fmap
 
 import (
 	"fmt"
+	"reflect"
 
 	"github.com/lleo/go-functional-collections/key"
 )
@@ -16,3 +17,16 @@ type KeyVal struct {
 func (kv KeyVal) String() string {
 	return fmt.Sprintf("{%q, %v}", kv.Key, kv.Val)
 }
+
+// valsEqual reports whether the values a and b are equal; with == if both can
+// be compared with it, and with reflect.DeepEqual otherwise, so values like
+// slices and maps do not panic.
+func valsEqual(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if reflect.ValueOf(a).Comparable() && reflect.ValueOf(b).Comparable() {
+		return a == b
+	}
+	return reflect.DeepEqual(a, b)
+}
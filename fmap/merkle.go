@@ -0,0 +1,50 @@
+package fmap
+
+import "github.com/lleo/go-functional-collections/merkle"
+
+// RootDigest returns the content digest of the Map; the sum of the
+// merkle.Entry digests of its key/value pairs. Maps holding equal key/value
+// pairs have equal RootDigests, but equal RootDigests do not prove the
+// key/value pairs equal. See the merkle package.
+//
+// The digest of every table is cached in the table. So the first RootDigest
+// of a Map visits every key/value pair, but afterwards RootDigest is O(1),
+// and the RootDigest of a new version of the Map only visits the tables
+// created since.
+func (m *Map) RootDigest() merkle.Digest {
+	return nodeDigest(m.root)
+}
+
+// nodeDigest() returns the digest of the node, computing and caching it if the
+// node is a table without a cached digest.
+func nodeDigest(n nodeI) merkle.Digest {
+	var d merkle.Digest
+	if leaf, isLeaf := n.(leafI); isLeaf {
+		for _, kv := range leaf.keyVals() {
+			d = d.Add(merkle.Entry(kv.Key, kv.Val))
+		}
+		return d
+	}
+
+	var t = n.(tableI)
+	var cache = digestCache(t)
+	if cd, cached := cache.Get(); cached {
+		return cd
+	}
+	var next = t.iter()
+	for cn := next(); cn != nil; cn = next() {
+		d = d.Add(nodeDigest(cn))
+	}
+	cache.Set(d)
+	return d
+}
+
+func digestCache(t tableI) *merkle.Cache {
+	switch x := t.(type) {
+	case *fixedTable:
+		return &x.digest
+	case *sparseTable:
+		return &x.digest
+	}
+	panic("digestCache(): unknown table type")
+}
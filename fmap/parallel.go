@@ -0,0 +1,123 @@
+package fmap
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/lleo/go-functional-collections/key/hash"
+)
+
+// The sub-trees under the slots of the root table hold disjoint sets of keys,
+// those with the same Index at depth 0, so they can be built each in its own
+// goroutine, by its own Transient, and put together in a new root table at
+// the end. The Parallel variants of the bulk operations do that; they are
+// only worth it for large inputs, of about a hundred thousand entries or more.
+
+// NewFromListParallel is NewFromList, with the root sub-trees built in
+// parallel.
+func NewFromListParallel(kvs []KeyVal) *Map {
+	return New().BulkInsertParallel(kvs, TakeNewVal)
+}
+
+// BulkInsertParallel is BulkInsert, with the root sub-trees built in parallel.
+// The key/value pairs of equal keys are still resolved in the order they are
+// given, so the result is the same as BulkInsert's.
+func (m *Map) BulkInsertParallel(kvs []KeyVal, resolve ResolveConflictFunc) *Map {
+	var chunks = partitionKeyVals(kvs, m.layout())
+	return m.buildParallel(func(idx uint, t *Transient) {
+		for _, parts := range chunks {
+			for _, kv := range parts[idx] {
+				t.storeResolve(kv.Key, kv.Val, resolve)
+			}
+		}
+	})
+}
+
+// MergeParallel is Merge, with the root sub-trees merged in parallel. If the
+// Maps have different Options, their root sub-trees do not line up, so the
+// key/value pairs of om are partitioned again as BulkInsertParallel does.
+func (m *Map) MergeParallel(om *Map, resolve ResolveConflictFunc) *Map {
+	if om.layout() != m.layout() {
+		var kvs = make([]KeyVal, 0, om.NumEntries())
+		om.Range(func(kv KeyVal) bool {
+			kvs = append(kvs, kv)
+			return true
+		})
+		return m.BulkInsertParallel(kvs, resolve)
+	}
+	return m.buildParallel(func(idx uint, t *Transient) {
+		for _, kv := range nodeKeyVals(om.root.get(idx), 1) {
+			t.storeResolve(kv.Key, kv.Val, resolve)
+		}
+	})
+}
+
+// buildParallel() returns a new Map built from m by calling fn, in its own
+// goroutine, for the sub-tree under each slot of the root table. fn is given a
+// Transient holding only that sub-tree, and MUST only store keys with the
+// Index idx at depth 0.
+func (m *Map) buildParallel(fn func(idx uint, t *Transient)) *Map {
+	var l = m.layout()
+	var parts = make([]*Map, l.IndexLimit())
+
+	var wg sync.WaitGroup
+	for idx := range parts {
+		wg.Add(1)
+		go func(idx uint) {
+			defer wg.Done()
+			// The part starts with no entries, so its numEnts ends up being
+			// the change in the number of entries of the sub-tree.
+			var part = newMap(l)
+			if n := m.root.get(idx); n != nil {
+				part.root.insertInplace(idx, n)
+			}
+			var t = part.Transient()
+			fn(idx, t)
+			parts[idx] = t.Persistent()
+		}(uint(idx))
+	}
+	wg.Wait()
+
+	var nm = newMap(l)
+	nm.numEnts = m.numEnts
+	for idx, part := range parts {
+		if n := part.root.get(uint(idx)); n != nil {
+			nm.root.insertInplace(uint(idx), n)
+		}
+		nm.numEnts += part.numEnts
+	}
+	return nm
+}
+
+// partitionKeyVals() splits kvs into one chunk per CPU, and each chunk by the
+// Index of the keys at depth 0, hashing the chunks in parallel;
+// chunks[c][idx] holds the key/value pairs of chunk c with the Index idx, in
+// the order of kvs.
+func partitionKeyVals(kvs []KeyVal, l hash.Layout) [][][]KeyVal {
+	var numChunks = runtime.GOMAXPROCS(0)
+	var chunkSize = (len(kvs) + numChunks - 1) / numChunks
+	if chunkSize == 0 {
+		return nil
+	}
+
+	var chunks [][][]KeyVal
+	var wg sync.WaitGroup
+	for lo := 0; lo < len(kvs); lo += chunkSize {
+		var hi = lo + chunkSize
+		if hi > len(kvs) {
+			hi = len(kvs)
+		}
+		var parts = make([][]KeyVal, l.IndexLimit())
+		chunks = append(chunks, parts)
+		wg.Add(1)
+		go func(kvs []KeyVal) {
+			defer wg.Done()
+			for _, kv := range kvs {
+				var idx = l.Index(kv.Key.Hash(), 0)
+				parts[idx] = append(parts[idx], kv)
+			}
+		}(kvs[lo:hi])
+	}
+	wg.Wait()
+	return chunks
+}
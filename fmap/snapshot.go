@@ -0,0 +1,536 @@
+package fmap
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+	"weak"
+
+	"github.com/lleo/go-functional-collections/codec"
+	"github.com/lleo/go-functional-collections/key/hash"
+	"github.com/lleo/go-functional-collections/nodestore"
+)
+
+// The magic bytes of the three kinds of blob a SnapshotStore writes. The
+// current versions of their formats are:
+//
+//	"FMNR" 1 numEnts rootRef
+//	"FMNT" 2 layout depth hashPath numNodes (idx ref)*numNodes
+//	"FMNL" 1 numKeyVals (key val)*numKeyVals
+//
+// where numEnts, layout, depth, hashPath, numNodes, idx and numKeyVals are
+// uvarints, every ref is a byte string holding a nodestore.Ref, and every key
+// and val is a value encoded with the codec package. layout is the
+// hash.Layout of the table; version 1 table blobs, without it, hold tables of
+// hash.DefaultLayout. A table blob does not record whether it was a fixedTable
+// or a sparseTable, so equal tables always share one blob. The key/value pairs
+// of a leaf blob are ordered by the codec encoding of their keys, so equal
+// leaves do too.
+const (
+	snapshotRootMagic    = "FMNR"
+	snapshotTableMagic   = "FMNT"
+	snapshotLeafMagic    = "FMNL"
+	snapshotVersion      = 1
+	snapshotTableVersion = 2
+)
+
+// SnapshotStore saves versions of Maps to a nodestore.Backend, and loads them
+// back. Every table and leaf is written once, under the nodestore.Ref of its
+// content, so the versions of a Map share the blobs of the tables and leaves
+// they share in memory; keeping many versions only costs their differences.
+//
+// The SnapshotStore remembers the nodestore.Ref of every table and leaf it has
+// saved or loaded, so saving a version derived from one already saved or
+// loaded only visits the changed tables and leaves. Loading a version also
+// reuses the tables and leaves it shares with versions already saved or
+// loaded. It only holds them by weak pointers, so it never keeps a version in
+// memory; once no Map uses a table or leaf any more, the SnapshotStore forgets
+// it, and saving or loading it again encodes or decodes it again.
+//
+// Every key and value MUST have a codec registered with the codec package.
+// The tables are saved with their hash paths, so the Maps MUST be loaded with
+// the hash.Configure settings they were saved with.
+// A SnapshotStore is safe for concurrent use.
+type SnapshotStore struct {
+	backend nodestore.Backend
+
+	mu    sync.Mutex
+	known *nodeRefs
+}
+
+// NewSnapshotStore returns a *SnapshotStore using the given backend.
+func NewSnapshotStore(backend nodestore.Backend) *SnapshotStore {
+	return &SnapshotStore{
+		backend: backend,
+		known:   newNodeRefs(),
+	}
+}
+
+// Save writes every table and leaf of the Map not already in the backend, and
+// returns the nodestore.Ref of the Map. Saving equal Maps returns the same
+// nodestore.Ref.
+func (s *SnapshotStore) Save(m *Map) (nodestore.Ref, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var rootRef, err = s.saveNode(m.root)
+	if err != nil {
+		return nodestore.Ref{}, err
+	}
+
+	var enc = codec.NewEncoder(snapshotRootMagic, snapshotVersion)
+	enc.WriteUvarint(uint64(m.numEnts))
+	enc.WriteBytes(rootRef[:])
+	return s.put(enc.Bytes())
+}
+
+// saveNode() writes the node, after its children, unless it was already
+// saved or loaded, and returns its nodestore.Ref.
+func (s *SnapshotStore) saveNode(n nodeI) (nodestore.Ref, error) {
+	if ref, found := s.known.ref(n); found {
+		return ref, nil
+	}
+
+	var enc *codec.Encoder
+	switch x := n.(type) {
+	case tableI:
+		var ents = x.entries()
+		enc = codec.NewEncoder(snapshotTableMagic, snapshotTableVersion)
+		enc.WriteUvarint(uint64(tableLayout(x)))
+		enc.WriteUvarint(uint64(tableDepth(x)))
+		enc.WriteUvarint(uint64(tableHashPath(x)))
+		enc.WriteUvarint(uint64(len(ents)))
+		for _, ent := range ents {
+			var ref, err = s.saveNode(ent.node)
+			if err != nil {
+				return nodestore.Ref{}, err
+			}
+			enc.WriteUvarint(uint64(ent.idx))
+			enc.WriteBytes(ref[:])
+		}
+	case leafI:
+		var kvs, err = encodingOrder(x.keyVals())
+		if err != nil {
+			return nodestore.Ref{}, err
+		}
+		enc = codec.NewEncoder(snapshotLeafMagic, snapshotVersion)
+		enc.WriteUvarint(uint64(len(kvs)))
+		for _, kv := range kvs {
+			if err := enc.Encode(kv.Key); err != nil {
+				return nodestore.Ref{}, err
+			}
+			if err := enc.Encode(kv.Val); err != nil {
+				return nodestore.Ref{}, err
+			}
+		}
+	default:
+		panic("saveNode(): unknown node type")
+	}
+
+	var ref, err = s.put(enc.Bytes())
+	if err != nil {
+		return nodestore.Ref{}, err
+	}
+	s.known.add(n, ref)
+	return ref, nil
+}
+
+// encodingOrder() returns the key/value pairs of a leaf ordered by the codec
+// encoding of their keys. A collisionLeaf keeps its pairs ordered by the
+// hash.Secondary of the keys, which is seeded at random once per process, so
+// equal Maps would otherwise be saved to different blobs by different
+// processes.
+func encodingOrder(kvs []KeyVal) ([]KeyVal, error) {
+	if len(kvs) < 2 {
+		return kvs, nil
+	}
+
+	var encs = make([][]byte, len(kvs))
+	for i, kv := range kvs {
+		var enc = codec.NewEncoder("", 0)
+		if err := enc.Encode(kv.Key); err != nil {
+			return nil, err
+		}
+		encs[i] = enc.Bytes()
+	}
+
+	var order = make([]int, len(kvs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return bytes.Compare(encs[order[i]], encs[order[j]]) < 0
+	})
+
+	var sorted = make([]KeyVal, len(kvs))
+	for i, j := range order {
+		sorted[i] = kvs[j]
+	}
+	return sorted, nil
+}
+
+// put() writes the blob to the backend, if it is not already there, and
+// returns its nodestore.Ref.
+func (s *SnapshotStore) put(data []byte) (nodestore.Ref, error) {
+	var ref = nodestore.RefOf(data)
+	var has, err = s.backend.Has(ref)
+	if err != nil {
+		return nodestore.Ref{}, err
+	}
+	if !has {
+		if err = s.backend.Put(ref, data); err != nil {
+			return nodestore.Ref{}, err
+		}
+	}
+	return ref, nil
+}
+
+// get() reads the blob from the backend and checks it against its
+// nodestore.Ref.
+func (s *SnapshotStore) get(ref nodestore.Ref) ([]byte, error) {
+	var data, err = s.backend.Get(ref)
+	if err != nil {
+		return nil, err
+	}
+	if nodestore.RefOf(data) != ref {
+		return nil, nodestore.ErrCorrupt
+	}
+	return data, nil
+}
+
+// Load reads the Map saved under the nodestore.Ref.
+func (s *SnapshotStore) Load(ref nodestore.Ref) (*Map, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var data, err = s.get(ref)
+	if err != nil {
+		return nil, err
+	}
+	var dec *codec.Decoder
+	if dec, err = newSnapshotDecoder(data, snapshotRootMagic); err != nil {
+		return nil, err
+	}
+	numEnts, err := dec.ReadUvarint()
+	if err != nil {
+		return nil, err
+	}
+	rootRef, err := readRef(dec)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := s.loadNode(rootRef)
+	if err != nil {
+		return nil, err
+	}
+	var rootTable, isTable = root.(*fixedTable)
+	if !isTable || rootTable.depth != 0 {
+		return nil, codec.ErrCorrupt
+	}
+
+	return &Map{root: rootTable, numEnts: int(numEnts)}, nil
+}
+
+// loadNode() reads the node, and its children, unless it was already saved or
+// loaded.
+func (s *SnapshotStore) loadNode(ref nodestore.Ref) (nodeI, error) {
+	if n := s.known.node(ref); n != nil {
+		return n, nil
+	}
+
+	var data, err = s.get(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	var n nodeI
+	if len(data) >= len(snapshotTableMagic) &&
+		string(data[:len(snapshotTableMagic)]) == snapshotTableMagic {
+		n, err = s.loadTable(data)
+	} else {
+		n, err = loadLeaf(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.known.add(n, ref)
+	return n, nil
+}
+
+// loadTable() decodes a table blob. The root table is always a fixedTable,
+// like newRootTable(); any other table is a fixedTable only if it is large
+// enough to need an upgrade.
+func (s *SnapshotStore) loadTable(data []byte) (tableI, error) {
+	var dec, version, err = codec.NewDecoder(data, snapshotTableMagic)
+	if err != nil {
+		return nil, err
+	}
+	var l = hash.DefaultLayout
+	switch version {
+	case 1:
+	case snapshotTableVersion:
+		var ul, err = dec.ReadUvarint()
+		if err != nil {
+			return nil, err
+		}
+		if ul < uint64(hash.MinLayout) || ul > uint64(hash.MaxLayout) {
+			return nil, codec.ErrCorrupt
+		}
+		l = hash.Layout(ul)
+	default:
+		return nil, fmt.Errorf("fmap: unsupported snapshot version %d",
+			version)
+	}
+	depth, err := dec.ReadUvarint()
+	if err != nil {
+		return nil, err
+	}
+	hashPath, err := dec.ReadUvarint()
+	if err != nil {
+		return nil, err
+	}
+	numNodes, err := dec.ReadUvarint()
+	if err != nil {
+		return nil, err
+	}
+	if depth > uint64(l.MaxDepth()) || numNodes > uint64(l.IndexLimit()) {
+		return nil, codec.ErrCorrupt
+	}
+
+	var t tableI
+	if depth == 0 || uint(numNodes) >= upgradeThreshold(l) {
+		t = newFixedTable(uint(depth), hash.Val(hashPath), l)
+	} else {
+		t = newSparseTable(uint(depth), hash.Val(hashPath), uint(numNodes), l)
+	}
+	for i := uint64(0); i < numNodes; i++ {
+		var idx, err = dec.ReadUvarint()
+		if err != nil {
+			return nil, err
+		}
+		if idx >= uint64(l.IndexLimit()) || t.get(uint(idx)) != nil {
+			return nil, codec.ErrCorrupt
+		}
+		ref, err := readRef(dec)
+		if err != nil {
+			return nil, err
+		}
+		n, err := s.loadNode(ref)
+		if err != nil {
+			return nil, err
+		}
+		if ct, isTable := n.(tableI); isTable && tableLayout(ct) != l {
+			return nil, codec.ErrCorrupt
+		}
+		t.insertInplace(uint(idx), n)
+	}
+	if dec.Len() != 0 {
+		return nil, codec.ErrCorrupt
+	}
+
+	return t, nil
+}
+
+// loadLeaf() decodes a leaf blob.
+func loadLeaf(data []byte) (leafI, error) {
+	var dec, err = newSnapshotDecoder(data, snapshotLeafMagic)
+	if err != nil {
+		return nil, err
+	}
+	numKeyVals, err := dec.ReadUvarint()
+	if err != nil {
+		return nil, err
+	}
+	if numKeyVals == 0 || numKeyVals > uint64(dec.Len()/2) {
+		return nil, codec.ErrCorrupt
+	}
+
+	var kvs = make([]KeyVal, numKeyVals)
+	for i := range kvs {
+		if kvs[i], err = decodeKeyVal(dec); err != nil {
+			return nil, err
+		}
+	}
+	if dec.Len() != 0 {
+		return nil, codec.ErrCorrupt
+	}
+
+	if len(kvs) == 1 {
+		return newFlatLeaf(kvs[0].Key, kvs[0].Val), nil
+	}
+	return newCollisionLeaf(kvs), nil
+}
+
+func newSnapshotDecoder(data []byte, magic string) (*codec.Decoder, error) {
+	var dec, version, err = codec.NewDecoder(data, magic)
+	if err != nil {
+		return nil, err
+	}
+	if version != snapshotVersion {
+		return nil, fmt.Errorf("fmap: unsupported snapshot version %d",
+			version)
+	}
+	return dec, nil
+}
+
+func readRef(dec *codec.Decoder) (nodestore.Ref, error) {
+	var ref nodestore.Ref
+	var b, err = dec.ReadBytes()
+	if err != nil {
+		return ref, err
+	}
+	if len(b) != len(ref) {
+		return ref, codec.ErrCorrupt
+	}
+	copy(ref[:], b)
+	return ref, nil
+}
+
+func tableDepth(t tableI) uint {
+	switch x := t.(type) {
+	case *fixedTable:
+		return x.depth
+	case *sparseTable:
+		return x.depth
+	}
+	panic("tableDepth(): unknown table type")
+}
+
+func tableHashPath(t tableI) hash.Val {
+	switch x := t.(type) {
+	case *fixedTable:
+		return x.hashPath
+	case *sparseTable:
+		return x.hashPath
+	}
+	panic("tableHashPath(): unknown table type")
+}
+
+// nodeRefs maps the tables and leaves a SnapshotStore saved or loaded to their
+// nodestore.Refs, and back. It holds them by weak pointers, and forgets each
+// one once it is garbage collected. It has its own lock, as the forgetting is
+// done by a runtime cleanup, which must not wait for a Save or Load.
+type nodeRefs struct {
+	mu    sync.Mutex
+	refs  map[interface{}]nodestore.Ref // weak pointer of a node -> Ref
+	nodes map[nodestore.Ref]interface{} // Ref -> weak pointer of a node
+}
+
+// nodeRef is a node, by its weak pointer, and its nodestore.Ref.
+type nodeRef struct {
+	w   interface{}
+	ref nodestore.Ref
+}
+
+func newNodeRefs() *nodeRefs {
+	return &nodeRefs{
+		refs:  make(map[interface{}]nodestore.Ref),
+		nodes: make(map[nodestore.Ref]interface{}),
+	}
+}
+
+// ref() returns the nodestore.Ref of the node, if it is known.
+func (c *nodeRefs) ref(n nodeI) (nodestore.Ref, bool) {
+	var w = weakNode(n)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var ref, found = c.refs[w]
+	return ref, found
+}
+
+// node() returns a node saved or loaded under the nodestore.Ref, which is
+// still in memory, or nil.
+func (c *nodeRefs) node(ref nodestore.Ref) nodeI {
+	c.mu.Lock()
+	var w, found = c.nodes[ref]
+	c.mu.Unlock()
+	if !found {
+		return nil
+	}
+	return strongNode(w)
+}
+
+// add() remembers the nodestore.Ref of the node, until it is garbage
+// collected.
+func (c *nodeRefs) add(n nodeI, ref nodestore.Ref) {
+	var w = weakNode(n)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nodes[ref] = w
+	if _, found := c.refs[w]; found {
+		return
+	}
+	c.refs[w] = ref
+
+	var e = nodeRef{w, ref}
+	switch x := n.(type) {
+	case *fixedTable:
+		runtime.AddCleanup(x, c.forget, e)
+	case *sparseTable:
+		runtime.AddCleanup(x, c.forget, e)
+	case *flatLeaf:
+		runtime.AddCleanup(x, c.forget, e)
+	case *collisionLeaf:
+		runtime.AddCleanup(x, c.forget, e)
+	}
+}
+
+// forget() drops a garbage collected node.
+func (c *nodeRefs) forget(e nodeRef) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.refs, e.w)
+	if c.nodes[e.ref] == e.w {
+		delete(c.nodes, e.ref)
+	}
+}
+
+// len() returns the number of nodes remembered.
+func (c *nodeRefs) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.refs)
+}
+
+// weakNode() returns a weak pointer to the node; equal for the same node.
+func weakNode(n nodeI) interface{} {
+	switch x := n.(type) {
+	case *fixedTable:
+		return weak.Make(x)
+	case *sparseTable:
+		return weak.Make(x)
+	case *flatLeaf:
+		return weak.Make(x)
+	case *collisionLeaf:
+		return weak.Make(x)
+	}
+	panic("weakNode(): unknown node type")
+}
+
+// strongNode() returns the node of the weak pointer, or nil if it was garbage
+// collected.
+func strongNode(w interface{}) nodeI {
+	switch x := w.(type) {
+	case weak.Pointer[fixedTable]:
+		if t := x.Value(); t != nil {
+			return t
+		}
+	case weak.Pointer[sparseTable]:
+		if t := x.Value(); t != nil {
+			return t
+		}
+	case weak.Pointer[flatLeaf]:
+		if l := x.Value(); l != nil {
+			return l
+		}
+	case weak.Pointer[collisionLeaf]:
+		if l := x.Value(); l != nil {
+			return l
+		}
+	}
+	return nil
+}
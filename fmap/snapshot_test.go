@@ -0,0 +1,74 @@
+package fmap
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/lleo/go-functional-collections/key"
+	"github.com/lleo/go-functional-collections/nodestore"
+)
+
+func TestSnapshotCollisionLeafOrder(t *testing.T) {
+	// The same collisionLeaf, ordered by the secondary hashes of two
+	// processes with different seeds.
+	var kvs = []KeyVal{{key.Str("a"), 1}, {key.Str("b"), 2}, {key.Str("c"), 3}}
+	var leaf0 = &collisionLeaf{kvs: kvs, hashes: []uint64{1, 2, 3}}
+	var leaf1 = &collisionLeaf{
+		kvs:    []KeyVal{kvs[2], kvs[0], kvs[1]},
+		hashes: []uint64{1, 2, 3},
+	}
+
+	var ref0, err = NewSnapshotStore(nodestore.NewMemBackend()).saveNode(leaf0)
+	if err != nil {
+		t.Fatalf("saveNode(leaf0) failed: %s", err)
+	}
+	ref1, err := NewSnapshotStore(nodestore.NewMemBackend()).saveNode(leaf1)
+	if err != nil {
+		t.Fatalf("saveNode(leaf1) failed: %s", err)
+	}
+	if ref0 != ref1 {
+		t.Fatalf("equal collisionLeafs were saved as %s != %s", ref0, ref1)
+	}
+}
+
+func TestSnapshotForgetsUnusedNodes(t *testing.T) {
+	var s = NewSnapshotStore(nodestore.NewMemBackend())
+	var m = New()
+	for i := 0; i < 1000; i++ {
+		m = m.Put(key.Int(i), i)
+	}
+	var ref, err = s.Save(m)
+	if err != nil {
+		t.Fatalf("s.Save(m) failed: %s", err)
+	}
+	if s.known.len() == 0 {
+		t.Fatal("s remembers no nodes of m")
+	}
+
+	// Nodes are shared while they are in use.
+	m0, err := s.Load(ref)
+	if err != nil {
+		t.Fatalf("s.Load(ref) failed: %s", err)
+	}
+	if m0.root != m.root {
+		t.Fatal("s.Load(ref) did not reuse the root of m")
+	}
+
+	m, m0 = nil, nil
+	for i := 0; i < 100 && s.known.len() != 0; i++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := s.known.len(); n != 0 {
+		t.Fatalf("s still remembers %d nodes of a dropped Map", n)
+	}
+
+	m1, err := s.Load(ref)
+	if err != nil {
+		t.Fatalf("s.Load(ref) after GC failed: %s", err)
+	}
+	if m1.NumEntries() != 1000 {
+		t.Fatalf("m1.NumEntries() = %d != 1000", m1.NumEntries())
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/lleo/go-functional-collections/key/hash"
+	"github.com/lleo/go-functional-collections/merkle"
 )
 
 // sparseTableInitCap constant sets the default capacity of a new
@@ -16,21 +17,29 @@ type sparseTable struct {
 	nodes    []nodeI
 	depth    uint
 	hashPath hash.Val
+	layout   hash.Layout
 	nodeMap  bitmap
+	digest   merkle.Cache
 }
 
-func newSparseTable(depth uint, hashVal hash.Val, size uint) *sparseTable {
-	_ = assertOn && assertf(size <= hash.IndexLimit, "size,%d <= hash.IndexLimit", size)
+func newSparseTable(
+	depth uint,
+	hashVal hash.Val,
+	size uint,
+	l hash.Layout,
+) *sparseTable {
+	_ = assertOn && assertf(size <= l.IndexLimit(), "size,%d <= l.IndexLimit()", size)
 	var t = new(sparseTable)
 	var capacity int
-	if size < hash.IndexLimit {
+	if size < l.IndexLimit() {
 		capacity = pow2GreaterThan(size)
 	} else {
-		capacity = hash.IndexLimit
+		capacity = int(l.IndexLimit())
 	}
-	t.nodes = make([]nodeI, size, capacity)
+	t.nodes = make([]nodeI, 0, capacity)
 	t.depth = depth
-	t.hashPath = hashVal.HashPath(depth)
+	t.hashPath = l.HashPath(hashVal, depth)
+	t.layout = l
 	return t
 }
 
@@ -38,6 +47,7 @@ func (t *sparseTable) copy() tableI {
 	var nt = new(sparseTable)
 	nt.hashPath = t.hashPath
 	nt.depth = t.depth
+	nt.layout = t.layout
 	nt.nodeMap = t.nodeMap
 
 	nt.nodes = make([]nodeI, len(t.nodes), cap(t.nodes))
@@ -50,6 +60,7 @@ func (t *sparseTable) deepCopy() tableI {
 	var nt = new(sparseTable)
 	nt.hashPath = t.hashPath
 	nt.depth = t.depth
+	nt.layout = t.layout
 	nt.nodeMap = t.nodeMap
 
 	nt.nodes = make([]nodeI, len(t.nodes), cap(t.nodes))
@@ -90,6 +101,7 @@ func (t *sparseTable) equiv(other nodeI) bool {
 	}
 	ok = ok && t.depth == ot.depth
 	ok = ok && t.hashPath == ot.hashPath
+	ok = ok && t.layout == ot.layout
 	ok = ok && t.nodeMap == ot.nodeMap
 	ok = ok && len(t.nodes) == len(ot.nodes)
 	if !ok {
@@ -106,28 +118,33 @@ func (t *sparseTable) equiv(other nodeI) bool {
 	return true
 }
 
-func createSparseTable(depth uint, leaf1 leafI, leaf2 *flatLeaf) tableI {
+func createSparseTable(
+	depth uint,
+	leaf1 leafI,
+	leaf2 *flatLeaf,
+	l hash.Layout,
+) tableI {
 	if assertOn {
 		assert(depth > 0, "createSparseTable(): depth < 1")
-		assertf(leaf1.hash().HashPath(depth) == leaf2.hash().HashPath(depth),
+		assertf(l.HashPath(leaf1.hash(), depth) == l.HashPath(leaf2.hash(), depth),
 			"createSparseTable(): hp1,%s != hp2,%s",
-			leaf1.hash().HashPath(depth),
-			leaf2.hash().HashPath(depth))
+			l.HashPath(leaf1.hash(), depth),
+			l.HashPath(leaf2.hash(), depth))
 	}
 
-	var retTable = newSparseTable(depth, leaf1.hash(), 0)
+	var retTable = newSparseTable(depth, leaf1.hash(), 0, l)
 
-	var idx1 = leaf1.hash().Index(depth)
-	var idx2 = leaf2.hash().Index(depth)
+	var idx1 = l.Index(leaf1.hash(), depth)
+	var idx2 = l.Index(leaf2.hash(), depth)
 	if idx1 != idx2 {
 		retTable.insertInplace(idx1, leaf1)
 		retTable.insertInplace(idx2, leaf2)
 	} else { // idx1 == idx2
 		var node nodeI
-		if depth == hash.MaxDepth {
+		if depth == l.MaxDepth() {
 			node = newCollisionLeaf(append(leaf1.keyVals(), leaf2.keyVals()...))
 		} else {
-			node = createSparseTable(depth+1, leaf1, leaf2)
+			node = createSparseTable(depth+1, leaf1, leaf2, l)
 		}
 		retTable.insertInplace(idx1, node)
 	}
@@ -145,9 +162,11 @@ func downgradeToSparseTable(
 	hashPath hash.Val,
 	depth uint,
 	ents []tableEntry,
+	l hash.Layout,
 ) *sparseTable {
 	var nt = new(sparseTable)
 	nt.hashPath = hashPath
+	nt.layout = l
 	//nt.nodeMap = 0
 	nt.nodes = make([]nodeI, len(ents), len(ents)+1)
 
@@ -170,7 +189,7 @@ func (t *sparseTable) hash() hash.Val {
 // depth, and number of entries.
 func (t *sparseTable) String() string {
 	return fmt.Sprintf("sparseTable{hashPath:%s, depth=%d, slotsUsed()=%d}",
-		t.hashPath.HashPathString(t.depth), t.depth, t.slotsUsed())
+		t.layout.HashPathString(t.hashPath, t.depth), t.depth, t.slotsUsed())
 }
 
 // treeString returns a string representation of this table and all the tables
@@ -180,12 +199,12 @@ func (t *sparseTable) treeString(indent string, depth uint) string {
 
 	strs[0] = indent +
 		fmt.Sprintf("sparseTable{hashPath=%s, depth=%d, slotsUsed()=%d,",
-			t.hashPath.HashPathString(depth), t.depth, t.slotsUsed())
+			t.layout.HashPathString(t.hashPath, depth), t.depth, t.slotsUsed())
 
 	strs[1] = indent + "\tnodeMap=" + t.nodeMap.String() + ","
 
 	for i, n := range t.nodes {
-		var idx = n.hash().Index(depth)
+		var idx = t.layout.Index(n.hash(), depth)
 		if t, isTable := n.(tableI); isTable {
 			strs[2+i] = indent +
 				fmt.Sprintf("\tt.nodes[%d]:\n%s",
@@ -214,7 +233,7 @@ func (t *sparseTable) entries() []tableEntry {
 	var ents = make([]tableEntry, n)
 
 	for j := uint(0); j < n; j++ {
-		idx := t.nodes[j].hash().Index(t.depth)
+		idx := t.layout.Index(t.nodes[j].hash(), t.depth)
 		ents[j] = tableEntry{idx, t.nodes[j]}
 	}
 
@@ -232,7 +251,7 @@ func (t *sparseTable) get(idx uint) nodeI {
 }
 
 func (t *sparseTable) needsUpgrade() bool {
-	return t.slotsUsed() == upgradeThreshold
+	return t.slotsUsed() == upgradeThreshold(t.layout)
 }
 
 func (t *sparseTable) needsDowngrade() bool {
@@ -240,10 +259,10 @@ func (t *sparseTable) needsDowngrade() bool {
 }
 
 func (t *sparseTable) upgrade() tableI {
-	var nt = newFixedTable(t.depth, t.hashPath)
+	var nt = newFixedTable(t.depth, t.hashPath, t.layout)
 	var slots = t.slotsUsed()
 	for j := uint(0); j < slots; j++ {
-		var idx0 = t.nodes[j].hash().Index(t.depth)
+		var idx0 = t.layout.Index(t.nodes[j].hash(), t.depth)
 		nt.insertInplace(idx0, t.nodes[j])
 	}
 	return nt
@@ -255,6 +274,7 @@ func (t *sparseTable) downgrade() tableI {
 }
 
 func (t *sparseTable) insertInplace(idx uint, n nodeI) {
+	t.digest.Reset()
 	var j = int(t.nodeMap.count(idx))
 	if j == len(t.nodes) {
 		t.nodes = append(t.nodes, n)
@@ -286,6 +306,7 @@ func (t *sparseTable) insert(idx uint, n nodeI) tableI {
 }
 
 func (t *sparseTable) replaceInplace(idx uint, n nodeI) {
+	t.digest.Reset()
 	var j = t.nodeMap.count(idx)
 	t.nodes[j] = n
 }
@@ -303,6 +324,7 @@ func (t *sparseTable) replace(idx uint, n nodeI) tableI {
 }
 
 func (t *sparseTable) removeInplace(idx uint) {
+	t.digest.Reset()
 	var j = int(t.nodeMap.count(idx))
 	if j == len(t.nodes)-1 {
 		t.nodes = t.nodes[:j]
@@ -0,0 +1,158 @@
+package fmap
+
+import (
+	"unsafe"
+
+	"github.com/lleo/go-functional-collections/key"
+)
+
+// Stats describes the shape of the HAMT of a Map, as returned by Map.Stats. It
+// is meant for tuning the hashing of keys and watching the collision leaves
+// grow; none of it is needed to use a Map.
+type Stats struct {
+	// DeepestKeys are the keys of the leaves found at the greatest Depth.
+	DeepestKeys struct {
+		Keys  []key.Hash
+		Depth uint
+	}
+
+	// Depth of deepest table
+	MaxDepth uint
+
+	// TableCountsByNumEntries is the number of tables with each given number
+	// of entries in the table. There are slots for [0..TableWidth] inclusive
+	// (so there are TableWidth+1 slots). Technically, there should never be a
+	// table with zero entries, but I allow counting tables with zero entries
+	// just to catch those errors.
+	TableCountsByNumEntries []uint
+
+	// TableCountsByDepth is the number of tables at a given depth. There are
+	// slots for [0..DepthLimit) of the Map's TableWidth.
+	TableCountsByDepth []uint
+
+	// CollisionLeafCountsByNumEntries is the number of collisionLeaf structs
+	// with each given number of KeyVal pairs. A collisionLeaf has at least 2.
+	CollisionLeafCountsByNumEntries map[uint]uint
+
+	// MaxCollisions is the number of KeyVal pairs in the largest
+	// collisionLeaf, or 0 if there is none.
+	MaxCollisions uint
+
+	// Nils is the total count of allocated slots that are unused in the Map.
+	Nils uint
+
+	// Nodes is the total count of nodeI capable structs in the Map.
+	Nodes uint
+
+	// Tables is the total count of tableI capable structs in the Map.
+	Tables uint
+
+	// Leafs is the total count of leafI capable structs in the Map.
+	Leafs uint
+
+	// FixedTables is the total count of fixedTable structs in the Map.
+	FixedTables uint
+
+	// SparseTables is the total count of sparseTable structs in the Map.
+	SparseTables uint
+
+	// FlatLeafs is the total count of flatLeaf structs in the Map.
+	FlatLeafs uint
+
+	// CollisionLeafs is the total count of collisionLeaf structs in the Map.
+	CollisionLeafs uint
+
+	// KeyVals is the total number of KeyVal pairs in the Map.
+	KeyVals uint
+
+	// AvgProbeDepth is the average number of tables a lookup of a key of the
+	// Map visits, or 0 if the Map is empty.
+	AvgProbeDepth float64
+
+	// TableBytes and LeafBytes estimate the memory used by the tables and the
+	// leaves of the Map, not counting the keys and values. Tables and leaves
+	// shared with other Maps are counted in full.
+	TableBytes uint
+	LeafBytes  uint
+}
+
+// Stats walks the Hamt in a pre-order traversal and populates a Stats data
+// struture which it returns.
+func (m *Map) Stats() *Stats {
+	var l = m.layout()
+	var stats = new(Stats)
+	stats.TableCountsByNumEntries = make([]uint, l.IndexLimit()+1)
+	stats.TableCountsByDepth = make([]uint, l.DepthLimit())
+	stats.CollisionLeafCountsByNumEntries = make(map[uint]uint)
+
+	var sizeofNode = uint(unsafe.Sizeof(nodeI(nil)))
+	var probes uint
+
+	// leafFn counts what the flatLeaf and collisionLeaf cases share; depth is
+	// the number of tables above the leaf.
+	var leafFn = func(lf leafI, numKeyVals uint, depth uint) {
+		stats.Nodes++
+		stats.Leafs++
+		stats.KeyVals += numKeyVals
+		probes += numKeyVals * depth
+		if depth > stats.DeepestKeys.Depth {
+			stats.DeepestKeys.Depth = depth
+			stats.DeepestKeys.Keys = nil
+		}
+		if depth == stats.DeepestKeys.Depth {
+			for _, kv := range lf.keyVals() {
+				stats.DeepestKeys.Keys = append(stats.DeepestKeys.Keys, kv.Key)
+			}
+		}
+	}
+
+	// statFn closes over the stats variable
+	var statFn = func(n nodeI, depth uint) bool {
+		switch x := n.(type) {
+		case *fixedTable:
+			stats.Nodes++
+			stats.Tables++
+			stats.FixedTables++
+			stats.TableCountsByNumEntries[x.slotsUsed()]++
+			stats.TableCountsByDepth[x.depth]++
+			if x.depth > stats.MaxDepth {
+				stats.MaxDepth = x.depth
+			}
+			stats.Nils += uint(len(x.nodes)) - x.slotsUsed()
+			stats.TableBytes += uint(unsafe.Sizeof(*x)) +
+				uint(len(x.nodes))*sizeofNode
+		case *sparseTable:
+			stats.Nodes++
+			stats.Tables++
+			stats.SparseTables++
+			stats.TableCountsByNumEntries[x.slotsUsed()]++
+			stats.TableCountsByDepth[x.depth]++
+			if x.depth > stats.MaxDepth {
+				stats.MaxDepth = x.depth
+			}
+			stats.Nils += uint(cap(x.nodes) - len(x.nodes))
+			stats.TableBytes += uint(unsafe.Sizeof(*x)) +
+				uint(cap(x.nodes))*sizeofNode
+		case *flatLeaf:
+			stats.FlatLeafs++
+			stats.LeafBytes += uint(unsafe.Sizeof(*x))
+			leafFn(x, 1, depth)
+		case *collisionLeaf:
+			var numKeyVals = uint(x.count())
+			stats.CollisionLeafs++
+			stats.CollisionLeafCountsByNumEntries[numKeyVals]++
+			if numKeyVals > stats.MaxCollisions {
+				stats.MaxCollisions = numKeyVals
+			}
+			stats.LeafBytes += x.numBytes()
+			leafFn(x, numKeyVals, depth)
+		}
+		return true
+	}
+
+	m.walkPreOrder(statFn)
+	if stats.KeyVals > 0 {
+		stats.AvgProbeDepth = float64(probes) / float64(stats.KeyVals)
+	}
+	return stats
+}
@@ -1,6 +1,9 @@
 package string_keyed_fmap
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -132,18 +135,11 @@ type Iter fmap.Iter
 // Next returns each sucessive key/value mapping in the *Map. When all enrties
 // have been returned it will return an empty string as the key.
 func (it *Iter) Next() (string, interface{}) {
-	var k, val = (*fmap.Iter)(it).Next()
-	var sk key.Str
-	var s string
-
-	if k == nil {
-		s = ""
-	} else {
-		sk = k.(key.Str)
-		s = string(sk)
-
+	var kv = (*fmap.Iter)(it).Next()
+	if kv.Key == nil {
+		return "", nil
 	}
-	return s, val
+	return string(kv.Key.(key.Str)), kv.Val
 }
 
 // Iter returns a *Iter structure. You can call the Next() method on the *Iter
@@ -158,19 +154,9 @@ func (m *StringKeyedMap) Iter() *Iter {
 // data structure. Given that the *Map is immutable there is no danger with
 // concurrent use of the *Map while the Range method is executing.
 func (m *StringKeyedMap) Range(f func(string, interface{}) bool) {
-	var fn = func(k key.Hash, v interface{}) bool {
-		var sk key.Str
-		var s string
-		if k == nil {
-			s = ""
-		} else {
-			sk = k.(key.Str)
-			s = string(sk)
-		}
-		return f(s, v)
-	}
-	(*fmap.Map)(m).Range(fn)
-	return
+	(*fmap.Map)(m).Range(func(kv fmap.KeyVal) bool {
+		return f(string(kv.Key.(key.Str)), kv.Val)
+	})
 }
 
 // NumEntries() returns the number of key/value entries in the *Map. This
@@ -201,3 +187,35 @@ func (m *StringKeyedMap) String() string {
 
 	return "StringKeyedMap{" + strings.Join(ents, ",") + "}"
 }
+
+// MarshalJSON implements the json.Marshaler interface. A StringKeyedMap is
+// encoded as a plain JSON object.
+func (m *StringKeyedMap) MarshalJSON() ([]byte, error) {
+	return (*fmap.Map)(m).MarshalJSON()
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. The data MUST be a
+// JSON object with no empty string keys. It replaces the contents of the
+// receiver, so it SHOULD only be called on a StringKeyedMap that no one else
+// holds, like one just returned by New.
+func (m *StringKeyedMap) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil
+	}
+
+	var obj map[string]interface{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+
+	var kvs = make([]fmap.KeyVal, 0, len(obj))
+	for k, v := range obj {
+		if k == "" {
+			return errors.New("key is empty string")
+		}
+		kvs = append(kvs, fmap.KeyVal{Key: key.Str(k), Val: v})
+	}
+
+	*m = *(*StringKeyedMap)(fmap.NewFromList(kvs))
+	return nil
+}
@@ -1,6 +1,7 @@
 package string_keyed_fmap_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 	"testing"
@@ -271,3 +272,31 @@ func TestString(t *testing.T) {
 		t.Fatalf("str,%s != expected_str,%s", str, expected_str)
 	}
 }
+
+func TestJSON(t *testing.T) {
+	var data = []byte(`{"a":1,"b":"two","c":null}`)
+
+	var m = string_keyed_fmap.New()
+	if err := json.Unmarshal(data, m); err != nil {
+		t.Fatalf("json.Unmarshal(%s) failed: %s", data, err)
+	}
+	if m.NumEntries() != 3 || m.Get("a") != 1.0 || m.Get("b") != "two" {
+		t.Fatalf("json.Unmarshal(%s) => %s", data, m)
+	}
+
+	var out, err = json.Marshal(m)
+	if err != nil {
+		t.Fatalf("json.Marshal(m) failed: %s", err)
+	}
+	var obj map[string]interface{}
+	if err = json.Unmarshal(out, &obj); err != nil {
+		t.Fatalf("json.Marshal(m),%s is not a JSON object: %s", out, err)
+	}
+	if len(obj) != 3 || obj["a"] != 1.0 || obj["b"] != "two" || obj["c"] != nil {
+		t.Fatalf("json.Marshal(m),%s did not round-trip", out)
+	}
+
+	if err = json.Unmarshal([]byte(`{"":1}`), string_keyed_fmap.New()); err == nil {
+		t.Fatal("json.Unmarshal() of an empty string key did not fail")
+	}
+}
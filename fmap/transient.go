@@ -0,0 +1,211 @@
+package fmap
+
+import (
+	"github.com/lleo/go-functional-collections/key"
+)
+
+// Transient is a mutable version of a Map, for making many modifications in a
+// row. It is obtained from a Map with the Transient method, and is frozen back
+// into a Map with the Persistent method.
+//
+// The Transient records the tables it owns, which are the tables it created
+// itself. Owned tables are modified in place; every other table is shared
+// with some Map, so it is copied, and the copy becomes owned. Hence each
+// table is copied at most once, no matter how many modifications are made.
+//
+// A Transient is NOT safe for concurrent use.
+type Transient struct {
+	m     *Map
+	owned map[tableI]bool
+}
+
+// Transient returns a new *Transient holding the same key/value mappings as
+// the Map. The Map is never modified by the Transient.
+func (m *Map) Transient() *Transient {
+	var t = new(Transient)
+	t.m = m.copy()
+	t.owned = make(map[tableI]bool)
+	return t
+}
+
+// Persistent returns a new persistent *Map holding the current key/value
+// mappings of the Transient.
+//
+// The Transient can still be used afterwards; since its tables are now shared
+// with the returned Map, they will be copied again as they are modified.
+func (t *Transient) Persistent() *Map {
+	t.owned = make(map[tableI]bool)
+	return t.m.copy()
+}
+
+// NumEntries returns the number of key/value entries in the *Transient.
+func (t *Transient) NumEntries() int {
+	return t.m.numEnts
+}
+
+// Load retrieves the value related to the key.Hash in the Transient. It also
+// returns a bool to indicate the value was found.
+func (t *Transient) Load(key key.Hash) (interface{}, bool) {
+	return t.m.Load(key)
+}
+
+// Get loads the value stored for the given key. If the key doesn't exist in the
+// Transient a nil is returned.
+func (t *Transient) Get(key key.Hash) interface{} {
+	return t.m.Get(key)
+}
+
+// Put stores a new key/value mapping in place. It returns the *Transient so
+// that calls can be chained.
+func (t *Transient) Put(key key.Hash, val interface{}) *Transient {
+	t.Store(key, val)
+	return t
+}
+
+// Store stores a new key/value mapping in place. It returns a bool indicating
+// if a new pair was added (true) or if the value merely replaced a prior value
+// (false).
+func (t *Transient) Store(key key.Hash, val interface{}) bool {
+	return t.storeResolve(key, val, TakeNewVal)
+}
+
+// storeResolve() stores the key/value mapping; if the key already exists, the
+// stored value is chosen by the resolve function, or is v if resolve is nil.
+func (t *Transient) storeResolve(
+	k key.Hash,
+	v interface{},
+	resolve ResolveConflictFunc,
+) bool {
+	if resolve == nil {
+		resolve = TakeNewVal
+	}
+	var hv = k.Hash()
+	var l = t.m.layout()
+	var path, leaf, idx = t.m.find(hv)
+	var curTable = path.pop()
+	var depth = uint(path.len())
+
+	var node nodeI
+	var added bool
+	if leaf == nil {
+		node = newFlatLeaf(k, v)
+		added = true
+	} else if !l.Collide(leaf.hash(), hv) {
+		var newTable = createTable(depth+1, leaf, newFlatLeaf(k, v), l)
+		t.owned[newTable] = true
+		node = newTable
+		added = true
+	} else {
+		node, added = leaf.putResolve(k, v, resolve)
+	}
+
+	var table = t.own(curTable)
+	if leaf == nil {
+		table.insertInplace(idx, node)
+		if table.needsUpgrade() {
+			table = table.upgrade()
+		}
+	} else {
+		table.replaceInplace(idx, node)
+	}
+	t.persist(curTable, table, path)
+
+	if added {
+		t.m.numEnts++
+	}
+	return added
+}
+
+// Del deletes any entry with the given key in place. It returns the
+// *Transient so that calls can be chained.
+func (t *Transient) Del(key key.Hash) *Transient {
+	t.Remove(key)
+	return t
+}
+
+// Remove deletes any key/value mapping for the given key in place. It returns
+// the value that was stored for that key, and a bool indicating if the key was
+// found and deleted.
+func (t *Transient) Remove(key key.Hash) (interface{}, bool) {
+	if t.m.numEnts == 0 {
+		return nil, false
+	}
+
+	var path, leaf, idx = t.m.find(key.Hash())
+	if leaf == nil {
+		return nil, false
+	}
+
+	var newLeaf, val, deleted = leaf.del(key)
+	if !deleted {
+		return nil, false
+	}
+
+	var curTable = path.pop()
+
+	var table = t.own(curTable)
+	if newLeaf == nil {
+		table = t.removeInplace(table, idx, path.len() == 0)
+	} else {
+		table.replaceInplace(idx, newLeaf)
+	}
+	t.persist(curTable, table, path)
+
+	t.m.numEnts--
+	return val, true
+}
+
+// own() returns the table if it is owned, otherwise a copy of it. The copy
+// becomes owned once it is passed to persist().
+func (t *Transient) own(table tableI) tableI {
+	if t.owned[table] {
+		return table
+	}
+	return table.copy()
+}
+
+// removeInplace() removes the entry at idx from the owned table. It returns
+// the resulting table, which is nil if the non-root table is now empty.
+func (t *Transient) removeInplace(table tableI, idx uint, isRoot bool) tableI {
+	table.removeInplace(idx)
+	if isRoot {
+		return table
+	}
+	if table.slotsUsed() == 0 {
+		return nil
+	}
+	if table.needsDowngrade() {
+		return table.downgrade()
+	}
+	return table
+}
+
+// persist() replaces oldTable, at the top of the path, with newTable. Unlike
+// Map.persist(), the parent tables are only copied if they are not already
+// owned, and newTable becomes owned.
+func (t *Transient) persist(oldTable, newTable tableI, path *tableStack) {
+	if newTable == oldTable {
+		return
+	}
+
+	if newTable != nil {
+		t.owned[newTable] = true
+	}
+
+	if t.m.root == oldTable {
+		t.m.root = newTable
+		return
+	}
+
+	var parentIdx = t.m.layout().Index(oldTable.hash(), uint(path.len())-1)
+
+	var oldParent = path.pop()
+	var parent = t.own(oldParent)
+	if newTable == nil {
+		parent = t.removeInplace(parent, parentIdx, path.len() == 0)
+	} else {
+		parent.replaceInplace(parentIdx, newTable)
+	}
+
+	t.persist(oldParent, parent, path)
+}
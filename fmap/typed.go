@@ -0,0 +1,111 @@
+//go:build go1.21
+// +build go1.21
+
+package fmap
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lleo/go-functional-collections/key"
+)
+
+// TypedMap is a type safe wrapper of the Map structure. Keys are of the Hasher
+// type K, and values are of type V, so no type assertions are needed on the
+// values returned.
+//
+// Internally every key is stored as a key.HashOf[K] and every value as an
+// interface{} in the same HAMT used by Map.
+type TypedMap[K key.Hasher, V any] struct {
+	m *Map
+}
+
+// NewTypedMap returns a properly initialized pointer to an empty TypedMap.
+func NewTypedMap[K key.Hasher, V any]() *TypedMap[K, V] {
+	return &TypedMap[K, V]{New()}
+}
+
+func typedVal[V any](v interface{}) V {
+	var tv, _ = v.(V) //v is nil only for zero values of interface types
+	return tv
+}
+
+// Get loads the value stored for the given key. If the key doesn't exist in the
+// TypedMap the zero value of V is returned.
+func (m *TypedMap[K, V]) Get(k K) V {
+	return typedVal[V](m.m.Get(key.HashOf[K]{Key: k}))
+}
+
+// Load retrieves the value related to the key in the TypedMap. It also returns
+// a bool to indicate the value was found.
+func (m *TypedMap[K, V]) Load(k K) (V, bool) {
+	var v, found = m.m.Load(key.HashOf[K]{Key: k})
+	return typedVal[V](v), found
+}
+
+// LoadOrStore returns the existing value for the key if present. Otherwise, it
+// stores the given value and returns it, with a new persistent *TypedMap. The
+// bool is true if the value was loaded.
+func (m *TypedMap[K, V]) LoadOrStore(k K, v V) (*TypedMap[K, V], V, bool) {
+	var nm, val, found = m.m.LoadOrStore(key.HashOf[K]{Key: k}, v)
+	if !found {
+		return &TypedMap[K, V]{nm}, v, false
+	}
+	return m, typedVal[V](val), true
+}
+
+// Put stores a new key/value mapping. It returns a new persistent *TypedMap.
+func (m *TypedMap[K, V]) Put(k K, v V) *TypedMap[K, V] {
+	return &TypedMap[K, V]{m.m.Put(key.HashOf[K]{Key: k}, v)}
+}
+
+// Store stores a new key/value mapping. It returns a new persistent *TypedMap
+// and a bool indicating if a new pair was added (true) or if the value merely
+// replaced a prior value (false).
+func (m *TypedMap[K, V]) Store(k K, v V) (*TypedMap[K, V], bool) {
+	var nm, added = m.m.Store(key.HashOf[K]{Key: k}, v)
+	return &TypedMap[K, V]{nm}, added
+}
+
+// Del deletes any entry with the given key. If the key did not exist the
+// original *TypedMap is returned.
+func (m *TypedMap[K, V]) Del(k K) *TypedMap[K, V] {
+	var nm, _, _ = m.Remove(k)
+	return nm
+}
+
+// Remove deletes any key/value mapping for the given key. It returns the new
+// *TypedMap, the value that was stored for that key, and a bool indicating if
+// the key was found and deleted. If the key didn't exist the original
+// *TypedMap is returned.
+func (m *TypedMap[K, V]) Remove(k K) (*TypedMap[K, V], V, bool) {
+	var nm, val, removed = m.m.Remove(key.HashOf[K]{Key: k})
+	if !removed {
+		return m, typedVal[V](val), false
+	}
+	return &TypedMap[K, V]{nm}, typedVal[V](val), true
+}
+
+// NumEntries returns the number of key/value entries in the *TypedMap.
+func (m *TypedMap[K, V]) NumEntries() int {
+	return m.m.NumEntries()
+}
+
+// Range applies the given function for every key/value mapping in the
+// *TypedMap. If the function returns false, Range stops.
+func (m *TypedMap[K, V]) Range(fn func(K, V) bool) {
+	m.m.Range(func(kv KeyVal) bool {
+		return fn(kv.Key.(key.HashOf[K]).Key, typedVal[V](kv.Val))
+	})
+}
+
+// String prints a string list all the key/value mappings in the *TypedMap. It
+// is intended to be simmilar to fmt.Printf("%#v") of a golang builtin map.
+func (m *TypedMap[K, V]) String() string {
+	var ents = make([]string, 0, m.NumEntries())
+	m.Range(func(k K, v V) bool {
+		ents = append(ents, fmt.Sprintf("%#v:%#v", k, v))
+		return true
+	})
+	return "Map{" + strings.Join(ents, ",") + "}"
+}
@@ -0,0 +1,64 @@
+//go:build go1.21
+// +build go1.21
+
+package fmap_test
+
+import (
+	"testing"
+
+	"github.com/lleo/go-functional-collections/fmap"
+	"github.com/lleo/go-functional-collections/key"
+)
+
+func TestTypedMap(t *testing.T) {
+	var m0 = fmap.NewTypedMap[key.Str, int]()
+	var m = m0
+	for i, s := range buildStrings(1000) {
+		m = m.Put(key.Str(s), i)
+	}
+
+	if m0.NumEntries() != 0 {
+		t.Fatalf("m0.NumEntries(),%d != 0", m0.NumEntries())
+	}
+	if m.NumEntries() != 1000 {
+		t.Fatalf("m.NumEntries(),%d != 1000", m.NumEntries())
+	}
+
+	for i, s := range buildStrings(1000) {
+		if v, found := m.Load(key.Str(s)); !found || v != i {
+			t.Fatalf("m.Load(%q),%d,%t != %d,true", s, v, found, i)
+		}
+	}
+	if v, found := m.Load(key.Str("not-a-key")); found || v != 0 {
+		t.Fatalf("m.Load(\"not-a-key\"),%d,%t != 0,false", v, found)
+	}
+
+	var nm, v, removed = m.Remove(key.Str("a"))
+	if !removed || v != 0 || nm.NumEntries() != 999 || nm.Get(key.Str("a")) != 0 {
+		t.Fatalf("m.Remove(\"a\") failed; v=%d removed=%t", v, removed)
+	}
+	if m.Del(key.Str("not-a-key")) != m {
+		t.Fatal("m.Del(\"not-a-key\") did not return the original TypedMap")
+	}
+
+	var sum int
+	m.Range(func(k key.Str, v int) bool {
+		sum += v
+		return true
+	})
+	if sum != 999*1000/2 {
+		t.Fatalf("sum of values,%d != %d", sum, 999*1000/2)
+	}
+}
+
+func TestTypedMapInterfaceVal(t *testing.T) {
+	var m = fmap.NewTypedMap[key.Int, error]().Put(key.Int(1), nil)
+
+	var v, found = m.Load(key.Int(1))
+	if !found || v != nil {
+		t.Fatalf("m.Load(1),%v,%t != <nil>,true", v, found)
+	}
+	if s := m.String(); s != "Map{1:<nil>}" {
+		t.Fatalf("m.String(),%q != \"Map{1:<nil>}\"", s)
+	}
+}
@@ -0,0 +1,79 @@
+// Package parallel runs the parts of a ParallelRange or Fold over one of the
+// collections from a pool of goroutines; each collection splits itself into
+// parts, and Parts walks them.
+package parallel
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Workers returns the number of workers to use; runtime.GOMAXPROCS(0) if
+// workers <= 0.
+func Workers(workers int) int {
+	if workers <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return workers
+}
+
+// Parts calls fn for each part in [0, numParts), from up to Workers(workers)
+// goroutines at once, until fn returns false or ctx is done. fn is given a
+// halted function to check, between the elements of its part, whether it
+// should stop early.
+//
+// Parts returns ctx.Err() if ctx being done made it skip a part, or made
+// halted return true, and nil otherwise; so a ctx done only after every part
+// finished is not reported.
+func Parts(
+	ctx context.Context,
+	workers, numParts int,
+	fn func(part int, halted func() bool) bool,
+) error {
+	workers = Workers(workers)
+	if workers > numParts {
+		workers = numParts
+	}
+
+	var next = int64(-1)
+	var stopped, cancelled int32
+	var done = ctx.Done()
+	var halted = func() bool {
+		if atomic.LoadInt32(&stopped) != 0 {
+			return true
+		}
+		select {
+		case <-done:
+			atomic.StoreInt32(&cancelled, 1)
+			atomic.StoreInt32(&stopped, 1)
+			return true
+		default:
+			return false
+		}
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				var part = int(atomic.AddInt64(&next, 1))
+				if part >= numParts || halted() {
+					return
+				}
+				if !fn(part, halted) {
+					atomic.StoreInt32(&stopped, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if atomic.LoadInt32(&cancelled) != 0 {
+		return ctx.Err()
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package parallel_test
+
+import (
+	"context"
+	"runtime"
+	"sync/atomic"
+	"testing"
+
+	"github.com/lleo/go-functional-collections/internal/parallel"
+)
+
+func TestWorkers(t *testing.T) {
+	if n := parallel.Workers(0); n != runtime.GOMAXPROCS(0) {
+		t.Fatalf("parallel.Workers(0),%d != runtime.GOMAXPROCS(0)", n)
+	}
+	if n := parallel.Workers(3); n != 3 {
+		t.Fatalf("parallel.Workers(3),%d != 3", n)
+	}
+}
+
+func TestParts(t *testing.T) {
+	var ctx = context.Background()
+
+	var ran = make([]int32, 100)
+	var err = parallel.Parts(ctx, 4, len(ran),
+		func(part int, halted func() bool) bool {
+			atomic.AddInt32(&ran[part], 1)
+			return true
+		})
+	if err != nil {
+		t.Fatalf("parallel.Parts() returned %v", err)
+	}
+	for part, n := range ran {
+		if n != 1 {
+			t.Fatalf("part %d ran %d times", part, n)
+		}
+	}
+
+	var numRan int32
+	err = parallel.Parts(ctx, 1, 100, func(int, func() bool) bool {
+		atomic.AddInt32(&numRan, 1)
+		return false
+	})
+	if err != nil || numRan != 1 {
+		t.Fatalf("parallel.Parts() did not stop; numRan,%d err=%v", numRan, err)
+	}
+
+	if err = parallel.Parts(ctx, 0, 0, nil); err != nil {
+		t.Fatalf("parallel.Parts() of no parts returned %v", err)
+	}
+}
+
+func TestPartsCancelled(t *testing.T) {
+	var cancelled, cancel = context.WithCancel(context.Background())
+	cancel()
+	var numRan int32
+	var err = parallel.Parts(cancelled, 0, 100, func(int, func() bool) bool {
+		atomic.AddInt32(&numRan, 1)
+		return true
+	})
+	if err != context.Canceled || numRan != 0 {
+		t.Fatalf("parallel.Parts() with a cancelled ctx: numRan,%d err=%v",
+			numRan, err)
+	}
+
+	// a part halted by the ctx is reported
+	var ctx, cancelPart = context.WithCancel(context.Background())
+	err = parallel.Parts(ctx, 1, 1, func(part int, halted func() bool) bool {
+		cancelPart()
+		return !halted()
+	})
+	if err != context.Canceled {
+		t.Fatalf("parallel.Parts() halted by the ctx returned %v", err)
+	}
+
+	// a ctx done once every part finished is not
+	ctx, cancelPart = context.WithCancel(context.Background())
+	err = parallel.Parts(ctx, 2, 10, func(part int, halted func() bool) bool {
+		if part == 9 {
+			cancelPart()
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatalf("parallel.Parts() cancelled after the last part returned %v",
+			err)
+	}
+}
@@ -0,0 +1,215 @@
+package hash
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"hash/fnv"
+	"hash/maphash"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// Algorithm selects the hash function Calculate applies to the bytes of a key.
+type Algorithm int
+
+const (
+	// FNV1 is the 32 or 64 bit FNV-1 hash of package hash/fnv. It is the
+	// default.
+	FNV1 Algorithm = iota
+
+	// FNV1a is the 32 or 64 bit FNV-1a hash of package hash/fnv. It mixes
+	// the last bytes of a key better than FNV1.
+	FNV1a
+
+	// XXHash is the XXH32 or XXH64 hash, with a seed of 0. It is faster than
+	// FNV1 and FNV1a for long keys.
+	XXHash
+
+	// MapHash is the hash of package hash/maphash, with a seed chosen at
+	// random once per process; for a size of 32 bits its two halves are
+	// xor'ed. The Vals it calculates differ between processes, so it MUST
+	// NOT be used with Maps or Sets saved by one process and loaded by
+	// another, or synchronized between processes.
+	MapHash
+
+	// SipHash is the SipHash-2-4 keyed hash; for a size of 32 bits its two
+	// halves are xor'ed. Without knowing its key, which is chosen at random
+	// once per process or set with SetSeed, nobody can pick keys whose Vals
+	// collide, so it protects Maps and Sets holding keys chosen by clients
+	// of a public service from hash flooding. Processes sharing Maps or Sets
+	// MUST share the key with SetSeed.
+	SipHash
+
+	numAlgorithms
+)
+
+var algorithmNames = [numAlgorithms]string{
+	"FNV1", "FNV1a", "XXHash", "MapHash", "SipHash",
+}
+
+// String returns the name of the Algorithm.
+func (a Algorithm) String() string {
+	if a < 0 || a >= numAlgorithms {
+		return "Algorithm(" + strconv.Itoa(int(a)) + ")"
+	}
+	return algorithmNames[a]
+}
+
+var mapHashSeed = maphash.MakeSeed()
+
+// sipHashKey is the key of SipHash.
+var sipHashKey = randomSipHashKey()
+
+func randomSipHashKey() [2]uint64 {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(errors.Wrap(err, "hash: failed to read a random SipHash key"))
+	}
+	return [2]uint64{
+		binary.LittleEndian.Uint64(b[:8]),
+		binary.LittleEndian.Uint64(b[8:]),
+	}
+}
+
+// sum() returns the size bit hash of bs; size is 32 or 64.
+func (a Algorithm) sum(bs []byte, size uint) uint64 {
+	switch a {
+	case FNV1a:
+		if size == 32 {
+			var h = fnv.New32a()
+			h.Write(bs)
+			return uint64(h.Sum32())
+		}
+		var h = fnv.New64a()
+		h.Write(bs)
+		return h.Sum64()
+	case XXHash:
+		if size == 32 {
+			return uint64(xxh32(bs))
+		}
+		return xxh64(bs)
+	case MapHash:
+		var h maphash.Hash
+		h.SetSeed(mapHashSeed)
+		h.Write(bs)
+		var v = h.Sum64()
+		if size == 32 {
+			return (v >> 32) ^ (v & mask(32))
+		}
+		return v
+	case SipHash:
+		var v = sipHash(sipHashKey[0], sipHashKey[1], bs)
+		if size == 32 {
+			return (v >> 32) ^ (v & mask(32))
+		}
+		return v
+	}
+
+	if size == 32 {
+		var h = fnv.New32()
+		h.Write(bs)
+		return uint64(h.Sum32())
+	}
+	var h = fnv.New64()
+	h.Write(bs)
+	return h.Sum64()
+}
+
+// hashSize is the size of the Vals calculated, in bits; 32 or 64.
+var hashSize uint = 32
+
+var algorithm = FNV1
+
+// configured is set by the first call to Calculate; after that the
+// configuration can no longer change.
+var configured atomic.Bool
+
+// Configure selects the Algorithm and the size, 32 or 64 bits, of the Vals
+// Calculate returns, and sets DepthLimit and MaxDepth to match. The default is
+// FNV1 and 32 bits. A size of 64 bits doubles the depth of the Hamt, but makes
+// collisions, which are kept in slow collision leaves, about four billion
+// times less likely.
+//
+// Configure MUST be called before any key is hashed, usually from an init()
+// function, and it is not safe to call concurrently with anything else in
+// this package. Once a Val has been calculated, Configure returns an error
+// unless it is asked for the configuration already in use. Every process
+// sharing Maps or Sets, by saving and loading them or synchronizing them,
+// MUST use the same configuration.
+func Configure(alg Algorithm, size uint) error {
+	if alg < 0 || alg >= numAlgorithms {
+		return errors.Errorf("hash.Configure: unknown algorithm %s", alg)
+	}
+	if size != 32 && size != 64 {
+		return errors.Errorf("hash.Configure: size,%d is not 32 or 64", size)
+	}
+	if alg == algorithm && size == hashSize {
+		return nil
+	}
+	if configured.Load() {
+		return errors.New(
+			"hash.Configure: called after a Val was calculated")
+	}
+
+	algorithm = alg
+	hashSize = size
+	DepthLimit = hashSize / NumIndexBits
+	remainder = hashSize - (DepthLimit * NumIndexBits)
+	MaxDepth = DepthLimit - 1
+	return nil
+}
+
+// Config returns the Algorithm and the size, in bits, of the Vals Calculate
+// returns.
+func Config() (Algorithm, uint) {
+	return algorithm, hashSize
+}
+
+// SetSeed sets the 128 bit key, k0 and k1, of the SipHash Algorithm. Like
+// Configure, it MUST be called before any key is hashed; once a Val has been
+// calculated it returns an error unless the key is already k0 and k1. The key
+// MUST be kept secret, or hash flooding is possible again.
+func SetSeed(k0, k1 uint64) error {
+	var key = [2]uint64{k0, k1}
+	if key == sipHashKey {
+		return nil
+	}
+	if configured.Load() {
+		return errors.New("hash.SetSeed: called after a Val was calculated")
+	}
+	sipHashKey = key
+	return nil
+}
+
+// SeedDigest returns a digest of the seed of the Algorithm in use, or 0 if it
+// has none. Processes can compare their SeedDigests to check that they share
+// the seed, without revealing it.
+func SeedDigest() uint64 {
+	switch algorithm {
+	case MapHash, SipHash:
+		return algorithm.sum([]byte("hash.SeedDigest"), 64)
+	}
+	return 0
+}
+
+// Secondary returns a hash of s, seeded at random once per process. The Maps
+// and Sets keep the keys whose Vals collide ordered by the Secondary hash of
+// their String(), so finding a key among them costs O(log(n)) even when an
+// attacker has made many Vals collide; the attacker cannot make the Secondary
+// hashes collide without knowing the seed. Strings that are equal have equal
+// Secondary hashes, so keys with the same String() are only told apart by a
+// linear scan.
+//
+// As the seed differs between processes, nothing written out may depend on
+// the order of the Secondary hashes; the fmap SnapshotStore sorts the keys of
+// a leaf by their encoding instead.
+func Secondary(s string) uint64 {
+	return maphash.String(mapHashSeed, s)
+}
+
+// Size returns the size, in bits, of the Vals Calculate returns; 32 or 64.
+func Size() uint {
+	return hashSize
+}
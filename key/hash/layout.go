@@ -0,0 +1,93 @@
+package hash
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Layout is the number of bits of a Val used as the Index at each depth of a
+// HAMT, so its tables have 1<<Layout slots. The package level NumIndexBits,
+// IndexLimit, DepthLimit and MaxDepth describe DefaultLayout; the methods of
+// a Layout describe any other.
+//
+// When Size() is not a multiple of the Layout, the bits of a Val above
+// DepthLimit() Indexes are not used; Vals differing only in those bits
+// Collide.
+type Layout uint8
+
+// DefaultLayout is the Layout of 16 slot tables; NumIndexBits bits.
+const DefaultLayout = Layout(NumIndexBits)
+
+// MinLayout and MaxLayout are the narrowest and widest Layouts supported; 16
+// and 64 slot tables.
+const (
+	MinLayout Layout = 4
+	MaxLayout Layout = 6
+)
+
+// MaxIndexLimit is the IndexLimit of MaxLayout.
+const MaxIndexLimit = 1 << MaxLayout
+
+// LayoutOf returns the Layout of tables with the given number of slots, and
+// false if the number is not a power of 2 between 1<<MinLayout and
+// 1<<MaxLayout.
+func LayoutOf(indexLimit int) (Layout, bool) {
+	for l := MinLayout; l <= MaxLayout; l++ {
+		if indexLimit == 1<<l {
+			return l, true
+		}
+	}
+	return 0, false
+}
+
+// IndexLimit is the number of slots of a table.
+func (l Layout) IndexLimit() uint {
+	return 1 << l
+}
+
+// DepthLimit is the maximum number of levels of the Hamt;
+// floor(Size() / l).
+func (l Layout) DepthLimit() uint {
+	return hashSize / uint(l)
+}
+
+// MaxDepth is the maximum value of a depth variable; DepthLimit() - 1.
+func (l Layout) MaxDepth() uint {
+	return l.DepthLimit() - 1
+}
+
+// Index returns the Index of v at the given depth; l bits of v starting
+// depth*l bits in.
+func (l Layout) Index(v Val, depth uint) uint {
+	_ = assertOn && assert(depth < l.DepthLimit(), "Index: depth > MaxDepth")
+
+	return uint(v>>(depth*uint(l))) & (1<<l - 1)
+}
+
+// HashPath truncates v to 'depth' number of Indexes.
+func (l Layout) HashPath(v Val, depth uint) Val {
+	_ = assertOn && assert(depth < l.DepthLimit(), "HashPath(): dept > MaxDepth")
+
+	return v & (Val(1)<<(depth*uint(l)) - 1)
+}
+
+// HashPathString returns a string representation of the first limit Indexes
+// of v, like Val.HashPathString.
+func (l Layout) HashPathString(v Val, limit uint) string {
+	if limit == 0 {
+		return "/"
+	}
+
+	var strs = make([]string, limit)
+	for d := uint(0); d < limit; d++ {
+		strs[d] = fmt.Sprintf("%02d", l.Index(v, d))
+	}
+
+	return "/" + strings.Join(strs, "/")
+}
+
+// Collide reports whether a and b have the same Index at every depth, so
+// the keys they are the Vals of must share a leaf.
+func (l Layout) Collide(a, b Val) bool {
+	return (a^b)&(Val(1)<<(l.DepthLimit()*uint(l))-1) == 0
+}
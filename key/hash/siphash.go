@@ -0,0 +1,52 @@
+package hash
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// sipHash() returns the SipHash-2-4 of bs with the key k0, k1, see
+// https://www.aumasson.jp/siphash/siphash.pdf
+func sipHash(k0, k1 uint64, bs []byte) uint64 {
+	var v0 = k0 ^ 0x736f6d6570736575
+	var v1 = k1 ^ 0x646f72616e646f6d
+	var v2 = k0 ^ 0x6c7967656e657261
+	var v3 = k1 ^ 0x7465646279746573
+
+	var b = uint64(len(bs)) << 56
+	for ; len(bs) >= 8; bs = bs[8:] {
+		var m = binary.LittleEndian.Uint64(bs)
+		v3 ^= m
+		v0, v1, v2, v3 = sipRound(sipRound(v0, v1, v2, v3))
+		v0 ^= m
+	}
+	for i, c := range bs {
+		b |= uint64(c) << (8 * uint(i))
+	}
+	v3 ^= b
+	v0, v1, v2, v3 = sipRound(sipRound(v0, v1, v2, v3))
+	v0 ^= b
+
+	v2 ^= 0xff
+	v0, v1, v2, v3 = sipRound(sipRound(v0, v1, v2, v3))
+	v0, v1, v2, v3 = sipRound(sipRound(v0, v1, v2, v3))
+	return v0 ^ v1 ^ v2 ^ v3
+}
+
+func sipRound(v0, v1, v2, v3 uint64) (uint64, uint64, uint64, uint64) {
+	v0 += v1
+	v1 = bits.RotateLeft64(v1, 13)
+	v1 ^= v0
+	v0 = bits.RotateLeft64(v0, 32)
+	v2 += v3
+	v3 = bits.RotateLeft64(v3, 16)
+	v3 ^= v2
+	v0 += v3
+	v3 = bits.RotateLeft64(v3, 21)
+	v3 ^= v0
+	v2 += v1
+	v1 = bits.RotateLeft64(v1, 17)
+	v1 ^= v2
+	v2 = bits.RotateLeft64(v2, 32)
+	return v0, v1, v2, v3
+}
@@ -1 +0,0 @@
-val.go-32
\ No newline at end of file
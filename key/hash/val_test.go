@@ -8,7 +8,7 @@ import (
 
 func TestCalcHash(t *testing.T) {
 	var key = "a"
-	var v = CalcHash([]byte(key))
+	var v = Calculate([]byte(key))
 	//log.Println(v.String())
 	if hashSize == 32 {
 		var h = fnv.New32()
@@ -31,7 +31,7 @@ func TestCalcHash(t *testing.T) {
 
 func TestValString(t *testing.T) {
 	var key = "a"
-	var v = CalcHash([]byte(key))
+	var v = Calculate([]byte(key))
 
 	var expected string
 	if hashSize == 32 {
@@ -48,3 +48,147 @@ func TestValString(t *testing.T) {
 		t.Fatalf("got %q expected %q", got, expected)
 	}
 }
+
+func TestAlgorithms(t *testing.T) {
+	var tests = []struct {
+		alg  Algorithm
+		in   string
+		size uint
+		out  uint64
+	}{
+		{FNV1, "a", 32, 0x050c5d7e},
+		{FNV1, "a", 64, 0xaf63bd4c8601b7be},
+		{FNV1a, "a", 32, 0xe40c292c},
+		{FNV1a, "a", 64, 0xaf63dc4c8601ec8c},
+		{XXHash, "", 32, 0x02cc5d05},
+		{XXHash, "a", 32, 0x550d7456},
+		{XXHash, "abc", 32, 0x32d153ff},
+		{XXHash, "", 64, 0xef46db3751d8e999},
+		{XXHash, "a", 64, 0xd24ec4f1a98c6e5b},
+		{XXHash, "abc", 64, 0x44bc2cf5ad770999},
+	}
+	for _, test := range tests {
+		var out = test.alg.sum([]byte(test.in), test.size)
+		if out != test.out {
+			t.Fatalf("%s(%q) with size %d = %#x; expected %#x",
+				test.alg, test.in, test.size, out, test.out)
+		}
+	}
+
+	// the long inputs take the four lane paths of XXH32 and XXH64
+	var long = []byte("Nobody inspects the spammish repetition")
+	if out := xxh32(long); out != 0xe2293b2f {
+		t.Fatalf("xxh32(%q) = %#x", long, out)
+	}
+	if out := xxh64(long); out != 0xfbcea83c8a378bf1 {
+		t.Fatalf("xxh64(%q) = %#x", long, out)
+	}
+
+	// the test vectors of the SipHash paper, with the key 00 01 .. 0f
+	var msg []byte
+	for i := 0; i < 20; i++ {
+		var out = sipHash(0x0706050403020100, 0x0f0e0d0c0b0a0908, msg)
+		var expected, found = map[int]uint64{
+			0:  0x726fdb47dd0e0e31,
+			7:  0xab0200f58b01d137,
+			8:  0x93f5f5799a932462,
+			15: 0xa129ca6149be45e5,
+			19: 0xbb6dc91da77961bd,
+		}[i]
+		if found && out != expected {
+			t.Fatalf("sipHash() of %d bytes = %#x; expected %#x",
+				i, out, expected)
+		}
+		msg = append(msg, byte(i))
+	}
+
+	var v = MapHash.sum(long, 32)
+	if v>>32 != 0 || MapHash.sum(long, 32) != v {
+		t.Fatalf("MapHash.sum(long, 32) = %#x", v)
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	var alg, size = Config()
+	defer func() {
+		algorithm, hashSize = alg, size
+		DepthLimit = hashSize / NumIndexBits
+		remainder = hashSize - (DepthLimit * NumIndexBits)
+		MaxDepth = DepthLimit - 1
+	}()
+
+	var wasConfigured = configured.Load()
+	defer configured.Store(wasConfigured)
+	configured.Store(false)
+
+	if err := Configure(XXHash, 48); err == nil {
+		t.Fatal("Configure(XXHash, 48) did not fail")
+	}
+	if err := Configure(Algorithm(42), 64); err == nil {
+		t.Fatal("Configure(Algorithm(42), 64) did not fail")
+	}
+
+	if err := Configure(XXHash, 64); err != nil {
+		t.Fatalf("Configure(XXHash, 64) failed: %s", err)
+	}
+	if DepthLimit != 16 || MaxDepth != 15 || remainder != 0 {
+		t.Fatalf("DepthLimit,%d MaxDepth,%d remainder,%d for 64 bits",
+			DepthLimit, MaxDepth, remainder)
+	}
+
+	var v = Calculate([]byte("a"))
+	if uint64(v) != xxh64([]byte("a")) {
+		t.Fatalf("Calculate(\"a\") = %#x with XXHash and 64 bits", uint64(v))
+	}
+	if s := v.String(); s != "/11/05/14/06/12/08/09/10/01/15/04/12/14/04/02/13" {
+		t.Fatalf("v.String() = %q", s)
+	}
+
+	if err := Configure(FNV1, 32); err == nil {
+		t.Fatal("Configure(FNV1, 32) after Calculate() did not fail")
+	}
+	if err := Configure(XXHash, 64); err != nil {
+		t.Fatalf("Configure(XXHash, 64) again failed: %s", err)
+	}
+}
+
+func TestSetSeed(t *testing.T) {
+	var alg, size = Config()
+	var key = sipHashKey
+	defer func() {
+		algorithm, hashSize, sipHashKey = alg, size, key
+	}()
+
+	var wasConfigured = configured.Load()
+	defer configured.Store(wasConfigured)
+	configured.Store(false)
+
+	if err := Configure(SipHash, 32); err != nil {
+		t.Fatalf("Configure(SipHash, 32) failed: %s", err)
+	}
+	if err := SetSeed(1, 2); err != nil {
+		t.Fatalf("SetSeed(1, 2) failed: %s", err)
+	}
+	var digest = SeedDigest()
+	if digest == 0 || digest == sipHashKey[0] || digest == sipHashKey[1] {
+		t.Fatalf("SeedDigest() = %#x for the key {1, 2}", digest)
+	}
+	sipHashKey = [2]uint64{3, 4}
+	if SeedDigest() == digest {
+		t.Fatal("SeedDigest() did not change with the key")
+	}
+	sipHashKey = [2]uint64{1, 2}
+
+	var v = Calculate([]byte("a"))
+	var expected = sipHash(1, 2, []byte("a"))
+	if uint64(v) != (expected>>32)^(expected&mask(32)) {
+		t.Fatalf("Calculate(\"a\") = %#x with SipHash and 32 bits", uint64(v))
+	}
+
+	if err := SetSeed(3, 4); err == nil {
+		t.Fatal("SetSeed(3, 4) after Calculate() did not fail")
+	}
+	if err := SetSeed(1, 2); err != nil {
+		t.Fatalf("SetSeed(1, 2) again failed: %s", err)
+	}
+}
@@ -0,0 +1,127 @@
+package hash
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// The primes of the XXH32 and XXH64 hashes, see
+// https://github.com/Cyan4973/xxHash/blob/dev/doc/xxhash_spec.md
+// They are variables so the initial values of the lanes may wrap around.
+var (
+	prime32x1 uint32 = 2654435761
+	prime32x2 uint32 = 2246822519
+	prime32x3 uint32 = 3266489917
+	prime32x4 uint32 = 668265263
+	prime32x5 uint32 = 374761393
+
+	prime64x1 uint64 = 11400714785074694791
+	prime64x2 uint64 = 14029467366897019727
+	prime64x3 uint64 = 1609587929392839161
+	prime64x4 uint64 = 9650029242287828579
+	prime64x5 uint64 = 2870177450012600261
+)
+
+// xxh32() returns the XXH32 hash of bs with a seed of 0.
+func xxh32(bs []byte) uint32 {
+	var n = uint32(len(bs))
+	var h uint32
+
+	if len(bs) >= 16 {
+		var v1 = prime32x1 + prime32x2
+		var v2 = prime32x2
+		var v3 uint32
+		var v4 = -prime32x1
+		for ; len(bs) >= 16; bs = bs[16:] {
+			v1 = xxh32Round(v1, binary.LittleEndian.Uint32(bs[0:]))
+			v2 = xxh32Round(v2, binary.LittleEndian.Uint32(bs[4:]))
+			v3 = xxh32Round(v3, binary.LittleEndian.Uint32(bs[8:]))
+			v4 = xxh32Round(v4, binary.LittleEndian.Uint32(bs[12:]))
+		}
+		h = bits.RotateLeft32(v1, 1) + bits.RotateLeft32(v2, 7) +
+			bits.RotateLeft32(v3, 12) + bits.RotateLeft32(v4, 18)
+	} else {
+		h = prime32x5
+	}
+	h += n
+
+	for ; len(bs) >= 4; bs = bs[4:] {
+		h += binary.LittleEndian.Uint32(bs) * prime32x3
+		h = bits.RotateLeft32(h, 17) * prime32x4
+	}
+	for _, b := range bs {
+		h += uint32(b) * prime32x5
+		h = bits.RotateLeft32(h, 11) * prime32x1
+	}
+
+	h ^= h >> 15
+	h *= prime32x2
+	h ^= h >> 13
+	h *= prime32x3
+	h ^= h >> 16
+	return h
+}
+
+func xxh32Round(acc, input uint32) uint32 {
+	acc += input * prime32x2
+	return bits.RotateLeft32(acc, 13) * prime32x1
+}
+
+// xxh64() returns the XXH64 hash of bs with a seed of 0.
+func xxh64(bs []byte) uint64 {
+	var n = uint64(len(bs))
+	var h uint64
+
+	if len(bs) >= 32 {
+		var v1 = prime64x1 + prime64x2
+		var v2 = prime64x2
+		var v3 uint64
+		var v4 = -prime64x1
+		for ; len(bs) >= 32; bs = bs[32:] {
+			v1 = xxh64Round(v1, binary.LittleEndian.Uint64(bs[0:]))
+			v2 = xxh64Round(v2, binary.LittleEndian.Uint64(bs[8:]))
+			v3 = xxh64Round(v3, binary.LittleEndian.Uint64(bs[16:]))
+			v4 = xxh64Round(v4, binary.LittleEndian.Uint64(bs[24:]))
+		}
+		h = bits.RotateLeft64(v1, 1) + bits.RotateLeft64(v2, 7) +
+			bits.RotateLeft64(v3, 12) + bits.RotateLeft64(v4, 18)
+		h = xxh64Merge(h, v1)
+		h = xxh64Merge(h, v2)
+		h = xxh64Merge(h, v3)
+		h = xxh64Merge(h, v4)
+	} else {
+		h = prime64x5
+	}
+	h += n
+
+	for ; len(bs) >= 8; bs = bs[8:] {
+		h ^= xxh64Round(0, binary.LittleEndian.Uint64(bs))
+		h = bits.RotateLeft64(h, 27)*prime64x1 + prime64x4
+	}
+	if len(bs) >= 4 {
+		h ^= uint64(binary.LittleEndian.Uint32(bs)) * prime64x1
+		h = bits.RotateLeft64(h, 23)*prime64x2 + prime64x3
+		bs = bs[4:]
+	}
+	for _, b := range bs {
+		h ^= uint64(b) * prime64x5
+		h = bits.RotateLeft64(h, 11) * prime64x1
+	}
+
+	h ^= h >> 33
+	h *= prime64x2
+	h ^= h >> 29
+	h *= prime64x3
+	h ^= h >> 32
+	return h
+}
+
+func xxh64Round(acc, input uint64) uint64 {
+	acc += input * prime64x2
+	return bits.RotateLeft64(acc, 31) * prime64x1
+}
+
+func xxh64Merge(acc, v uint64) uint64 {
+	acc ^= xxh64Round(0, v)
+	return acc*prime64x1 + prime64x4
+}
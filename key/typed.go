@@ -0,0 +1,65 @@
+//go:build go1.21
+// +build go1.21
+
+package key
+
+import (
+	"cmp"
+	"fmt"
+
+	"github.com/lleo/go-functional-collections/key/hash"
+)
+
+// Hasher is the constraint on the key type of the typed HAMT collections,
+// fmap.TypedMap and set.TypedSet. The keys are compared with ==. Str and Int
+// satisfy Hasher.
+type Hasher interface {
+	comparable
+	Hash() hash.Val
+}
+
+// HashOf wraps a value of a Hasher type so it can be used as a Hash.
+type HashOf[K Hasher] struct {
+	Key K
+}
+
+// Hash returns the hash.Val of the wrapped key.
+func (hk HashOf[K]) Hash() hash.Val {
+	return hk.Key.Hash()
+}
+
+// Equals determines if the given Key is a HashOf[K] wrapping a key equal, by
+// ==, to the key wrapped by the receiver.
+func (hk HashOf[K]) Equals(okey Hash) bool {
+	var ohk, ok = okey.(HashOf[K])
+	if !ok {
+		return false
+	}
+	return hk.Key == ohk.Key
+}
+
+// String returns a string representation of the wrapped key.
+func (hk HashOf[K]) String() string {
+	return fmt.Sprint(hk.Key)
+}
+
+// SortOf wraps a value of a cmp.Ordered type so it can be used as a Sort. It
+// is the key type used by the typed sorted collections, sortedMap.TypedMap and
+// sortedSet.TypedSet.
+type SortOf[K cmp.Ordered] struct {
+	Key K
+}
+
+// Less returns true if the wrapped key is less than the key wrapped by okey.
+func (sk SortOf[K]) Less(okey Sort) bool {
+	var osk, ok = okey.(SortOf[K])
+	if !ok {
+		panic("okey is not the same key.SortOf type")
+	}
+	return cmp.Less(sk.Key, osk.Key)
+}
+
+// String returns a string representation of the wrapped key.
+func (sk SortOf[K]) String() string {
+	return fmt.Sprint(sk.Key)
+}
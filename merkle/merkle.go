@@ -0,0 +1,138 @@
+// Package merkle implements the content digests cached in the tables and
+// nodes of the collections, which make a RootDigest O(1) once computed.
+//
+// The digest of an entry (a key/value pair, or a key of a Set) is the SHA-256
+// of the bytes of its key and value, as returned by the ValueHasher. The
+// digest of a sub-tree is the sum, modulo 2^256, of the digests of its
+// entries. So the digest depends only on the entries, not on the shape of the
+// tree; which, for equal collections, can differ with the order of the
+// operations that built them.
+//
+// Such a sum (an AdHash) catches accidental differences, which makes the
+// digests suitable for reconciling replicas of a collection across processes,
+// but it is not collision resistant: whoever chooses the entries of two
+// collections can find different entries with equal sums. Nor do equal
+// digests mean entries equal by ==; a pointer value, say, is only hashed by
+// its "%#v" form, which two distinct pointers can share. So equal digests are
+// never taken as proof of equal entries; Equiv and Diff only skip the
+// sub-trees two collections share.
+//
+// Digests are computed lazily and cached. The first RootDigest of a collection
+// visits every entry; later ones, even on new versions of the collection, only
+// visit the tables or nodes created since.
+package merkle
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/lleo/go-functional-collections/codec"
+)
+
+// Digest is the content digest of an entry or a sub-tree.
+type Digest [sha256.Size]byte
+
+// String returns the Digest as a hexadecimal string.
+func (d Digest) String() string {
+	return hex.EncodeToString(d[:])
+}
+
+// Add returns the sum, modulo 2^256, of d and od; it is how the digests of
+// entries are combined.
+func (d Digest) Add(od Digest) Digest {
+	var sum Digest
+	var carry uint64
+	for i := len(d) - 8; i >= 0; i -= 8 {
+		var a = binary.BigEndian.Uint64(d[i:])
+		var b = binary.BigEndian.Uint64(od[i:])
+		var s = a + b + carry
+		if s < a || (carry == 1 && s == a) {
+			carry = 1
+		} else {
+			carry = 0
+		}
+		binary.BigEndian.PutUint64(sum[i:], s)
+	}
+	return sum
+}
+
+// ValueHasher returns the bytes identifying a key or value in the digest of an
+// entry. Equal keys and values MUST return equal bytes.
+type ValueHasher func(v interface{}) []byte
+
+var (
+	hasherMu    sync.RWMutex
+	valueHasher ValueHasher = DefaultValueHasher
+)
+
+// SetValueHasher replaces the ValueHasher used by Entry. Since digests are
+// cached, it MUST be called before any digest is computed, normally from an
+// init() function.
+func SetValueHasher(fn ValueHasher) {
+	hasherMu.Lock()
+	defer hasherMu.Unlock()
+	valueHasher = fn
+}
+
+// DefaultValueHasher is the ValueHasher used unless SetValueHasher is called.
+// Values with a codec registered in the codec package are identified by their
+// codec name and encoding. Any other value is identified by its fmt "%T %#v"
+// form, which is adequate for plain data, but not for values holding pointers.
+func DefaultValueHasher(v interface{}) []byte {
+	var enc = codec.NewEncoder("", 0)
+	if err := enc.Encode(v); err == nil {
+		return enc.Bytes()
+	}
+	return []byte(fmt.Sprintf("%T %#v", v, v))
+}
+
+// Entry returns the digest of a key/value pair. The digest of a key of a Set
+// is the digest of the key with a nil value.
+func Entry(k, v interface{}) Digest {
+	hasherMu.RLock()
+	var hasher = valueHasher
+	hasherMu.RUnlock()
+	var kb, vb = hasher(k), hasher(v)
+
+	var h = sha256.New()
+	var tmp [binary.MaxVarintLen64]byte
+	h.Write(tmp[:binary.PutUvarint(tmp[:], uint64(len(kb)))])
+	h.Write(kb)
+	h.Write(vb)
+
+	var d Digest
+	h.Sum(d[:0])
+	return d
+}
+
+// Cache holds the digest of a table or node once it is computed. The zero
+// value holds no digest. Get and Set are safe for concurrent use, so the
+// digest can be cached in a table or node shared by many collections.
+type Cache struct {
+	p unsafe.Pointer //*Digest
+}
+
+// Get returns the cached digest, and false if there is none.
+func (c *Cache) Get() (Digest, bool) {
+	var p = (*Digest)(atomic.LoadPointer(&c.p))
+	if p == nil {
+		return Digest{}, false
+	}
+	return *p, true
+}
+
+// Set caches the digest.
+func (c *Cache) Set(d Digest) {
+	atomic.StorePointer(&c.p, unsafe.Pointer(&d))
+}
+
+// Reset discards the cached digest. It is only for tables and nodes being
+// modified in place, which no other collection can see yet.
+func (c *Cache) Reset() {
+	c.p = nil
+}
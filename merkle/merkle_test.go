@@ -0,0 +1,94 @@
+package merkle_test
+
+import (
+	"math/big"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/lleo/go-functional-collections/key"
+	"github.com/lleo/go-functional-collections/merkle"
+)
+
+func TestAdd(t *testing.T) {
+	var mod = new(big.Int).Lsh(big.NewInt(1), 256)
+	var rnd = rand.New(rand.NewSource(1))
+
+	var ones merkle.Digest
+	for i := range ones {
+		ones[i] = 0xff
+	}
+	var one = merkle.Digest{31: 1}
+	var cases = [][2]merkle.Digest{{ones, one}, {ones, ones}, {one, {}}}
+	for i := 0; i < 100; i++ {
+		var a, b merkle.Digest
+		rnd.Read(a[:])
+		rnd.Read(b[:])
+		cases = append(cases, [2]merkle.Digest{a, b})
+	}
+
+	for _, c := range cases {
+		var want = new(big.Int).Add(
+			new(big.Int).SetBytes(c[0][:]), new(big.Int).SetBytes(c[1][:]))
+		want.Mod(want, mod)
+
+		var sum = c[0].Add(c[1])
+		if new(big.Int).SetBytes(sum[:]).Cmp(want) != 0 {
+			t.Fatalf("%s.Add(%s),%s != %x", c[0], c[1], sum, want)
+		}
+		if c[1].Add(c[0]) != sum {
+			t.Fatalf("%s.Add(%s) is not commutative", c[0], c[1])
+		}
+	}
+}
+
+func TestEntry(t *testing.T) {
+	if merkle.Entry(key.Str("a"), 1) != merkle.Entry(key.Str("a"), 1) {
+		t.Fatal("merkle.Entry() is not deterministic")
+	}
+	var entries = []merkle.Digest{
+		merkle.Entry(key.Str("a"), 1),
+		merkle.Entry(key.Str("a"), 2),
+		merkle.Entry(key.Str("a"), nil),
+		merkle.Entry(key.Str("a"), "1"),
+		merkle.Entry(key.Int(1), 1),
+		merkle.Entry(key.Str("a1"), nil),
+		merkle.Entry(struct{ a int }{1}, nil),
+		merkle.Entry(struct{ a int }{2}, nil),
+	}
+	for i := range entries {
+		for j := i + 1; j < len(entries); j++ {
+			if entries[i] == entries[j] {
+				t.Fatalf("entries[%d] == entries[%d]", i, j)
+			}
+		}
+	}
+}
+
+func TestCache(t *testing.T) {
+	var c0, c1 merkle.Cache
+	if _, cached := c0.Get(); cached {
+		t.Fatal("the zero Cache holds a digest")
+	}
+
+	var d = merkle.Entry(key.Str("a"), 1)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c0.Set(d)
+			c0.Get()
+		}()
+	}
+	wg.Wait()
+	c1.Set(d)
+
+	if got, cached := c0.Get(); !cached || got != d {
+		t.Fatalf("c0.Get(),%s,%t != %s,true", got, cached, d)
+	}
+	c1.Reset()
+	if _, cached := c1.Get(); cached {
+		t.Fatal("c1.Get() after c1.Reset() holds a digest")
+	}
+}
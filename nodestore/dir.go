@@ -0,0 +1,79 @@
+package nodestore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// DirBackend is a Backend storing every blob in its own file under a
+// directory. The file for a Ref is named by its hexadecimal string, in a
+// subdirectory named by the first two hexadecimal digits.
+type DirBackend struct {
+	dir string
+}
+
+// NewDirBackend returns a *DirBackend storing blobs under the given
+// directory, which is created if it does not exist.
+func NewDirBackend(dir string) (*DirBackend, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &DirBackend{dir: dir}, nil
+}
+
+func (b *DirBackend) path(ref Ref) string {
+	var s = ref.String()
+	return filepath.Join(b.dir, s[:2], s)
+}
+
+// Has returns true if a blob is stored under the Ref.
+func (b *DirBackend) Has(ref Ref) (bool, error) {
+	var _, err = os.Stat(b.path(ref))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Get returns the blob stored under the Ref, or ErrNotFound.
+func (b *DirBackend) Get(ref Ref) ([]byte, error) {
+	var data, err = ioutil.ReadFile(b.path(ref))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// Put stores the blob under the Ref. The blob is written to a temporary file
+// which is then renamed, so a blob file is never seen partially written.
+func (b *DirBackend) Put(ref Ref, data []byte) error {
+	var path = b.path(ref)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+
+	var dir = filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	var f, err = ioutil.TempFile(dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err = f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err = os.Rename(f.Name(), path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package nodestore
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"os"
+	"sync"
+)
+
+// FileBackend is a Backend appending every blob to a single file. Each record
+// in the file is the Ref, the uvarint length of the blob, then the blob. The
+// index of Refs to file offsets is held in memory and rebuilt by
+// OpenFileBackend.
+type FileBackend struct {
+	mu    sync.RWMutex
+	f     *os.File
+	size  int64
+	index map[Ref]fileRecord
+}
+
+type fileRecord struct {
+	off int64 //offset of the blob, not the record
+	len int
+}
+
+// OpenFileBackend opens, or creates, the file at the given path and returns a
+// *FileBackend using it. A partially written record at the end of the file,
+// left by a crash, is discarded. Any other error reading the file is returned,
+// and the file is left as it is.
+func OpenFileBackend(path string) (*FileBackend, error) {
+	var f, err = os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	var b = &FileBackend{f: f, index: make(map[Ref]fileRecord)}
+	if err = b.loadIndex(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return b, nil
+}
+
+// loadIndex() reads every record in the file to rebuild the index, then
+// truncates any partial record at the end.
+func (b *FileBackend) loadIndex() error {
+	if _, err := b.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	var off, err = b.readIndex(bufio.NewReader(b.f))
+	if err != nil {
+		return err
+	}
+
+	b.size = off
+	return b.f.Truncate(off)
+}
+
+// readIndex() adds the records read from r to the index, and returns the
+// offset of the end of the last whole record. Only running out of data, at
+// the end or in the middle of the last record, ends the records; any other
+// error is returned, as the records after it may be fine.
+func (b *FileBackend) readIndex(r *bufio.Reader) (int64, error) {
+	var off int64
+	for {
+		var ref Ref
+		if _, err := io.ReadFull(r, ref[:]); err != nil {
+			return off, tornTail(err)
+		}
+		var l, err = binary.ReadUvarint(r)
+		if err != nil {
+			return off, tornTail(err)
+		}
+		var blobOff = off + int64(len(ref)) + int64(uvarintLen(l))
+		if _, err = r.Discard(int(l)); err != nil {
+			return off, tornTail(err)
+		}
+		b.index[ref] = fileRecord{off: blobOff, len: int(l)}
+		off = blobOff + int64(l)
+	}
+}
+
+// tornTail() returns nil if err is from running out of data, as when the file
+// ends in a partial record, and err otherwise.
+func tornTail(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil
+	}
+	return err
+}
+
+func uvarintLen(x uint64) int {
+	var tmp [binary.MaxVarintLen64]byte
+	return binary.PutUvarint(tmp[:], x)
+}
+
+// Has returns true if a blob is stored under the Ref.
+func (b *FileBackend) Has(ref Ref) (bool, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	var _, found = b.index[ref]
+	return found, nil
+}
+
+// Get returns the blob stored under the Ref, or ErrNotFound.
+func (b *FileBackend) Get(ref Ref) ([]byte, error) {
+	b.mu.RLock()
+	var rec, found = b.index[ref]
+	b.mu.RUnlock()
+	if !found {
+		return nil, ErrNotFound
+	}
+
+	var data = make([]byte, rec.len)
+	if _, err := b.f.ReadAt(data, rec.off); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Put appends the blob to the file, unless a blob is already stored under the
+// Ref.
+func (b *FileBackend) Put(ref Ref, data []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, found := b.index[ref]; found {
+		return nil
+	}
+
+	var rec = make([]byte, 0, len(ref)+binary.MaxVarintLen64+len(data))
+	rec = append(rec, ref[:]...)
+	var tmp [binary.MaxVarintLen64]byte
+	rec = append(rec, tmp[:binary.PutUvarint(tmp[:], uint64(len(data)))]...)
+	var blobOff = b.size + int64(len(rec))
+	rec = append(rec, data...)
+
+	if _, err := b.f.WriteAt(rec, b.size); err != nil {
+		return err
+	}
+	b.size += int64(len(rec))
+	b.index[ref] = fileRecord{off: blobOff, len: len(data)}
+	return nil
+}
+
+// Sync commits the file to stable storage.
+func (b *FileBackend) Sync() error {
+	return b.f.Sync()
+}
+
+// Close closes the file.
+func (b *FileBackend) Close() error {
+	return b.f.Close()
+}
@@ -0,0 +1,66 @@
+package nodestore
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+var errRead = errors.New("read failed")
+
+// failingReader returns the bytes of data, then errRead.
+type failingReader struct {
+	data []byte
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, errRead
+	}
+	var n = copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func fileRecordBytes(blob []byte) []byte {
+	var ref = RefOf(blob)
+	var rec = append([]byte(nil), ref[:]...)
+	rec = binary.AppendUvarint(rec, uint64(len(blob)))
+	return append(rec, blob...)
+}
+
+func TestFileBackendReadIndex(t *testing.T) {
+	var first = fileRecordBytes([]byte("a"))
+	var data = append(first, fileRecordBytes([]byte("bb"))...)
+
+	// running out of data, at the end or within the last record, ends the
+	// records
+	var cases = []struct {
+		n, numRecs int
+		off        int64
+	}{
+		{len(data), 2, int64(len(data))},
+		{len(data) - 1, 1, int64(len(first))},
+		{len(first) + 3, 1, int64(len(first))},
+	}
+	for _, c := range cases {
+		var b = &FileBackend{index: make(map[Ref]fileRecord)}
+		var off, err = b.readIndex(bufio.NewReader(bytes.NewReader(data[:c.n])))
+		if err != nil || off != c.off || len(b.index) != c.numRecs {
+			t.Fatalf("b.readIndex() of %d bytes = %d, %v; %d records",
+				c.n, off, err, len(b.index))
+		}
+	}
+
+	// any other error is returned, wherever it happens
+	for _, c := range cases {
+		var b = &FileBackend{index: make(map[Ref]fileRecord)}
+		var r = bufio.NewReader(&failingReader{data: data[:c.n]})
+		if _, err := b.readIndex(r); err != errRead {
+			t.Fatalf("b.readIndex() of %d bytes then a failure returned %v",
+				c.n, err)
+		}
+	}
+}
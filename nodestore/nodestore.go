@@ -0,0 +1,112 @@
+// Package nodestore implements content-addressed storage for the nodes of the
+// persistent collections.
+//
+// Every node is stored as a blob of bytes under its Ref, the SHA-256 of those
+// bytes. A node that refers to other nodes does so by their Refs, so a blob
+// is written only once no matter how many versions of a collection share it.
+// Saving a new version of a collection only writes the nodes that changed.
+//
+// The Backend interface is the actual storage. This package provides an in
+// memory backend, a directory backend with one file per blob, and a single
+// file backend which appends every blob to one file.
+package nodestore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Ref is the content address of a blob; the SHA-256 of its bytes.
+type Ref [sha256.Size]byte
+
+// RefOf returns the Ref of the given blob.
+func RefOf(data []byte) Ref {
+	return Ref(sha256.Sum256(data))
+}
+
+// String returns the Ref as a hexadecimal string.
+func (r Ref) String() string {
+	return hex.EncodeToString(r[:])
+}
+
+// ParseRef converts a string returned by Ref.String back to a Ref.
+func ParseRef(s string) (Ref, error) {
+	var r Ref
+	var b, err = hex.DecodeString(s)
+	if err != nil {
+		return r, err
+	}
+	if len(b) != len(r) {
+		return r, fmt.Errorf("nodestore: ref %q is not %d bytes", s, len(r))
+	}
+	copy(r[:], b)
+	return r, nil
+}
+
+var (
+	// ErrNotFound is returned by Backend.Get when no blob is stored under the
+	// Ref.
+	ErrNotFound = errors.New("nodestore: blob not found")
+
+	// ErrCorrupt is returned when a stored blob does not match its Ref.
+	ErrCorrupt = errors.New("nodestore: corrupt blob")
+)
+
+// Backend stores blobs under their Refs. The Ref given to Put is always the
+// RefOf the data, so putting a Ref already stored is a noop. A Backend MUST be
+// safe for concurrent use.
+type Backend interface {
+	Has(ref Ref) (bool, error)
+	Get(ref Ref) ([]byte, error)
+	Put(ref Ref, data []byte) error
+}
+
+// MemBackend is a Backend holding the blobs in memory.
+type MemBackend struct {
+	mu    sync.RWMutex
+	blobs map[Ref][]byte
+}
+
+// NewMemBackend returns an empty *MemBackend.
+func NewMemBackend() *MemBackend {
+	return &MemBackend{blobs: make(map[Ref][]byte)}
+}
+
+// Has returns true if a blob is stored under the Ref.
+func (b *MemBackend) Has(ref Ref) (bool, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	var _, found = b.blobs[ref]
+	return found, nil
+}
+
+// Get returns the blob stored under the Ref, or ErrNotFound.
+func (b *MemBackend) Get(ref Ref) ([]byte, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	var data, found = b.blobs[ref]
+	if !found {
+		return nil, ErrNotFound
+	}
+	return data, nil
+}
+
+// Put stores a copy of the blob under the Ref.
+func (b *MemBackend) Put(ref Ref, data []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, found := b.blobs[ref]; !found {
+		b.blobs[ref] = append([]byte(nil), data...)
+	}
+	return nil
+}
+
+// Len returns the number of blobs stored.
+func (b *MemBackend) Len() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.blobs)
+}
@@ -0,0 +1,105 @@
+package nodestore_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lleo/go-functional-collections/nodestore"
+)
+
+func testBackend(t *testing.T, name string, b nodestore.Backend) {
+	t.Helper()
+
+	var blobs = [][]byte{[]byte("a"), []byte("bb"), {}, []byte("a")}
+	for _, blob := range blobs {
+		if err := b.Put(nodestore.RefOf(blob), blob); err != nil {
+			t.Fatalf("%s.Put(%q) failed: %s", name, blob, err)
+		}
+	}
+	for _, blob := range blobs {
+		var ref = nodestore.RefOf(blob)
+		if has, err := b.Has(ref); err != nil || !has {
+			t.Fatalf("%s.Has(%s),%t,%v != true,nil", name, ref, has, err)
+		}
+		if data, err := b.Get(ref); err != nil || string(data) != string(blob) {
+			t.Fatalf("%s.Get(%s),%q,%v != %q,nil", name, ref, data, err, blob)
+		}
+	}
+
+	var missing = nodestore.RefOf([]byte("missing"))
+	if has, err := b.Has(missing); err != nil || has {
+		t.Fatalf("%s.Has(missing),%t,%v != false,nil", name, has, err)
+	}
+	if _, err := b.Get(missing); err != nodestore.ErrNotFound {
+		t.Fatalf("%s.Get(missing) returned %v", name, err)
+	}
+}
+
+func tempDir(t *testing.T) string {
+	var dir, err = ioutil.TempDir("", "nodestore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestMemBackend(t *testing.T) {
+	var b = nodestore.NewMemBackend()
+	testBackend(t, "MemBackend", b)
+	if b.Len() != 3 {
+		t.Fatalf("b.Len(),%d != 3", b.Len())
+	}
+}
+
+func TestDirBackend(t *testing.T) {
+	var dir = tempDir(t)
+	defer os.RemoveAll(dir)
+
+	var b, err = nodestore.NewDirBackend(filepath.Join(dir, "blobs"))
+	if err != nil {
+		t.Fatalf("nodestore.NewDirBackend() failed: %s", err)
+	}
+	testBackend(t, "DirBackend", b)
+}
+
+func TestFileBackend(t *testing.T) {
+	var dir = tempDir(t)
+	defer os.RemoveAll(dir)
+	var path = filepath.Join(dir, "blobs")
+
+	var b, err = nodestore.OpenFileBackend(path)
+	if err != nil {
+		t.Fatalf("nodestore.OpenFileBackend() failed: %s", err)
+	}
+	testBackend(t, "FileBackend", b)
+	if err = b.Close(); err != nil {
+		t.Fatalf("b.Close() failed: %s", err)
+	}
+
+	// simulate a crash part way through appending a record
+	var f, _ = os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	var partial = nodestore.RefOf([]byte("partial"))
+	f.Write(append(partial[:], 100, 'x'))
+	f.Close()
+
+	if b, err = nodestore.OpenFileBackend(path); err != nil {
+		t.Fatalf("nodestore.OpenFileBackend() again failed: %s", err)
+	}
+	defer b.Close()
+	if has, _ := b.Has(partial); has {
+		t.Fatal("the partial record was not discarded")
+	}
+	testBackend(t, "reopened FileBackend", b)
+}
+
+func TestParseRef(t *testing.T) {
+	var ref = nodestore.RefOf([]byte("abc"))
+	if r, err := nodestore.ParseRef(ref.String()); err != nil || r != ref {
+		t.Fatalf("nodestore.ParseRef(%s),%s,%v != %s,nil", ref, r, err, ref)
+	}
+	if _, err := nodestore.ParseRef("abcd"); err == nil {
+		t.Fatal("nodestore.ParseRef(\"abcd\") did not fail")
+	}
+}
@@ -0,0 +1,478 @@
+package set
+
+import (
+	"github.com/lleo/go-functional-collections/key"
+	"github.com/lleo/go-functional-collections/key/hash"
+)
+
+// The set algebra walks the HAMTs of both Sets at once, slot by slot, rather
+// than looking up the keys of one Set in the other. Keys are only hashed where
+// the Sets hold different leaves under the same slot, and a sub-tree found
+// under the same slot of both Sets, or kept whole from one of them, is reused
+// by pointer. So the result shares its structure with the operands, and an
+// operation on two versions of one Set only visits the tables where they
+// differ.
+//
+// The same slot of the two Sets may hold different kinds of nodes, since the
+// shape of a HAMT depends on the order of its modifications; a leaf facing a
+// table is looked up in that table by the hash of the leaf.
+//
+// Sets with different Options do not share the shape of their HAMTs, so the
+// argument Set is first rebuilt with the Options of the receiver Set.
+
+// setOp is a set operation, given by the keys it keeps; those only in the
+// first Set, those only in the second, and those in both.
+type setOp struct {
+	aOnly, bOnly, both bool
+
+	// layout is the hash.Layout of both Sets; set by apply() and
+	// applyParallel().
+	layout hash.Layout
+}
+
+var (
+	unionOp         = setOp{aOnly: true, bOnly: true, both: true}
+	intersectOp     = setOp{both: true}
+	differenceOp    = setOp{aOnly: true}
+	symDifferenceOp = setOp{aOnly: true, bOnly: true}
+)
+
+// apply() returns the Set resulting from the operation on a and b.
+func (op setOp) apply(a, b *Set) *Set {
+	op.layout = a.layout()
+	b = withLayout(b, op.layout)
+
+	var root, delta = op.combine(a.root, b.root, 0)
+	var ns = new(Set)
+	if root == nil {
+		// the Sets share their root, and every key was dropped
+		ns.root = newRootTable(op.layout)
+	} else {
+		ns.root = root.(tableI)
+	}
+	ns.numEnts = a.numEnts + delta
+	return ns
+}
+
+// withLayout() returns s if its tables have the hash.Layout l, and otherwise a
+// copy of s rebuilt with l.
+func withLayout(s *Set, l hash.Layout) *Set {
+	if s.layout() == l {
+		return s
+	}
+	return newFromList(s.Keys(), l)
+}
+
+// combine() returns the node resulting from the operation on the nodes a and
+// b, found under the same slot of the two Sets, where a table would be at the
+// given depth; and the change in the number of keys from a to the result.
+//
+// Every table keeps count of the keys under it, so the sub-trees kept or
+// dropped whole are counted without walking them.
+func (op setOp) combine(a, b nodeI, depth uint) (nodeI, int) {
+	switch {
+	case a == b:
+		if a == nil || op.both {
+			return a, 0
+		}
+		return nil, -a.count()
+	case b == nil:
+		if op.aOnly {
+			return a, 0
+		}
+		return nil, -a.count()
+	case a == nil:
+		if op.bOnly {
+			return b, b.count()
+		}
+		return nil, 0
+	}
+
+	var ta, aIsTable = a.(tableI)
+	var tb, bIsTable = b.(tableI)
+	switch {
+	case aIsTable && bIsTable:
+		return op.combineTables(ta, tb, depth)
+	case aIsTable:
+		return op.combineLeafTable(b.(leafI), false, ta, depth)
+	case bIsTable:
+		return op.combineLeafTable(a.(leafI), true, tb, depth)
+	}
+	var la, lb = a.(leafI), b.(leafI)
+	var n = op.combineLeaves(la, la.hash(), lb, lb.hash(), depth)
+	return n, nodeCount(n) - la.count()
+}
+
+// combineTables() combines the tables slot by slot. If every slot of the
+// result holds the node of the same slot of ta, or of tb, that table is
+// returned.
+func (op setOp) combineTables(ta, tb tableI, depth uint) (nodeI, int) {
+	var nodes [hash.MaxIndexLimit]nodeI
+	var delta int
+	var used uint
+	var sameA, sameB = true, true
+	for idx := uint(0); idx < op.layout.IndexLimit(); idx++ {
+		var ca, cb = ta.get(idx), tb.get(idx)
+		if ca == nil && cb == nil {
+			continue
+		}
+		var n, d = op.combine(ca, cb, depth+1)
+		delta += d
+		sameA = sameA && n == ca
+		sameB = sameB && n == cb
+		if n != nil {
+			nodes[idx] = n
+			used++
+		}
+	}
+
+	switch {
+	case sameA:
+		return ta, delta
+	case sameB:
+		return tb, delta
+	}
+	return buildTable(depth, ta.hash(), nodes[:op.layout.IndexLimit()], used,
+		op.layout), delta
+}
+
+// combineLeafTable() combines the leaf l with the table t; l is from the
+// first Set if lIsA is true, and t from the other one.
+func (op setOp) combineLeafTable(
+	l leafI,
+	lIsA bool,
+	t tableI,
+	depth uint,
+) (nodeI, int) {
+	var hv = l.hash()
+	var keepL, keepT = op.aOnly, op.bOnly
+	if !lIsA {
+		keepL, keepT = keepT, keepL
+	}
+
+	// Unless the keys only in t are kept, the result holds only keys of l, so
+	// it is enough to look them up in the leaf along hv, even if that leaf
+	// holds keys of another hash.
+	if !keepT {
+		var o = findLeaf(t, hv, depth, op.layout)
+		if lIsA {
+			var n = op.combineLeaves(l, hv, o, hv, depth)
+			return n, nodeCount(n) - l.count()
+		}
+		var n = op.combineLeaves(o, hv, l, hv, depth)
+		return n, nodeCount(n) - t.count()
+	}
+
+	var n, d = op.combineLeafPath(l, lIsA, keepL, hv, t, depth)
+	if lIsA {
+		return n, t.count() + d - l.count()
+	}
+	return n, d
+}
+
+// combineLeafPath() returns the table t with the leaf l combined into the
+// slot along the hash hv of l, and the change in the number of keys from t.
+// The keys of t with another hash are all kept.
+func (op setOp) combineLeafPath(
+	l leafI,
+	lIsA, keepL bool,
+	hv hash.Val,
+	t tableI,
+	depth uint,
+) (nodeI, int) {
+	var idx = op.layout.Index(hv, depth)
+	var c = t.get(idx)
+
+	var n nodeI
+	var d int
+	switch x := c.(type) {
+	case nil:
+		if keepL {
+			n = l
+		}
+		d = nodeCount(n)
+	case tableI:
+		n, d = op.combineLeafPath(l, lIsA, keepL, hv, x, depth+1)
+	case leafI:
+		if lIsA {
+			n = op.combineLeaves(l, hv, x, x.hash(), depth+1)
+		} else {
+			n = op.combineLeaves(x, x.hash(), l, hv, depth+1)
+		}
+		d = nodeCount(n) - x.count()
+	}
+
+	switch {
+	case n == c:
+		return t, d
+	case c == nil:
+		return t.insert(idx, n), d
+	case n == nil:
+		return t.remove(idx), d
+	}
+	return t.replace(idx, n), d
+}
+
+// combineLeaves() returns the node resulting from the operation on the leaves
+// la and lb, of the hashes ha and hb, found under the same slot where a table
+// would be at the given depth. Either leaf may be nil.
+func (op setOp) combineLeaves(
+	la leafI,
+	ha hash.Val,
+	lb leafI,
+	hb hash.Val,
+	depth uint,
+) nodeI {
+	switch {
+	case la == nil && lb == nil:
+		return nil
+	case lb == nil:
+		return keepLeaf(la, op.aOnly)
+	case la == nil:
+		return keepLeaf(lb, op.bOnly)
+	case !op.layout.Collide(ha, hb):
+		// no key is in both leaves
+		switch {
+		case op.aOnly && op.bOnly:
+			return joinLeaves(la, ha, lb, hb, depth, op.layout)
+		case op.aOnly:
+			return la
+		case op.bOnly:
+			return lb
+		}
+		return nil
+	}
+
+	var keys []key.Hash
+	var inBoth, keptBoth, keptAOnly, keptBOnly int
+	for _, k := range la.keys() {
+		var inB = lb.get(k)
+		if inB {
+			inBoth++
+		}
+		switch {
+		case inB && op.both:
+			keptBoth++
+		case !inB && op.aOnly:
+			keptAOnly++
+		default:
+			continue
+		}
+		keys = append(keys, k)
+	}
+	if op.bOnly && inBoth < lb.count() {
+		for _, k := range lb.keys() {
+			if !la.get(k) {
+				keys = append(keys, k)
+				keptBOnly++
+			}
+		}
+	}
+
+	switch {
+	case keptBOnly == 0 && keptBoth+keptAOnly == la.count():
+		return la
+	case keptAOnly == 0 && keptBoth+keptBOnly == lb.count():
+		return lb
+	}
+	return newLeaf(keys)
+}
+
+// keepLeaf() returns the leaf if keep is true, and nil otherwise.
+func keepLeaf(l leafI, keep bool) nodeI {
+	if keep {
+		return l
+	}
+	return nil
+}
+
+// newLeaf() returns a leaf holding the keys, which all have the same hash, or
+// nil if there are none.
+func newLeaf(keys []key.Hash) nodeI {
+	switch len(keys) {
+	case 0:
+		return nil
+	case 1:
+		return newFlatLeaf(keys[0])
+	}
+	return newCollisionLeaf(keys)
+}
+
+// joinLeaves() returns a new table, at the given depth, holding the leaves of
+// the different hashes h1 and h2, each in the first slot they do not share.
+func joinLeaves(
+	l1 leafI,
+	h1 hash.Val,
+	l2 leafI,
+	h2 hash.Val,
+	depth uint,
+	l hash.Layout,
+) tableI {
+	_ = assertOn && assertf(!l.Collide(h1, h2), "joinLeaves(): h1,%s == h2,%s",
+		h1, h2)
+
+	var t = newTable(depth, h1, l)
+	var idx1, idx2 = l.Index(h1, depth), l.Index(h2, depth)
+	if idx1 != idx2 {
+		t.insertInplace(idx1, l1)
+		t.insertInplace(idx2, l2)
+	} else {
+		t.insertInplace(idx1, joinLeaves(l1, h1, l2, h2, depth+1, l))
+	}
+	return t
+}
+
+// buildTable() returns a new table at the given depth holding the used nodes,
+// one per slot of a table of the hash.Layout l. A table below the root holding
+// no nodes is nil, and one holding just a leaf is replaced by that leaf.
+func buildTable(
+	depth uint,
+	hashPath hash.Val,
+	nodes []nodeI,
+	used uint,
+	l hash.Layout,
+) nodeI {
+	if depth > 0 {
+		if used == 0 {
+			return nil
+		}
+		if used == 1 {
+			for _, n := range nodes {
+				if leaf, isLeaf := n.(leafI); isLeaf {
+					return leaf
+				}
+			}
+		}
+	}
+
+	var t tableI
+	if depth == 0 || used >= upgradeThreshold(l) {
+		t = newFixedTable(depth, hashPath, l)
+	} else {
+		t = newSparseTable(depth, hashPath, used, l)
+	}
+	for idx, n := range nodes {
+		if n != nil {
+			t.insertInplace(uint(idx), n)
+		}
+	}
+	return t
+}
+
+// findLeaf() returns the leaf under the table t, at the given depth, along the
+// hash hv, or nil if there is none. The leaf may hold keys of another hash.
+func findLeaf(t tableI, hv hash.Val, depth uint, l hash.Layout) leafI {
+	for ; ; depth++ {
+		switch n := t.get(l.Index(hv, depth)).(type) {
+		case nil:
+			return nil
+		case leafI:
+			return n
+		case tableI:
+			t = n
+		}
+	}
+}
+
+// subset() reports whether every key of the node a is in the node b, both
+// found under the same slot where a table would be at the given depth.
+func subset(a, b nodeI, depth uint) bool {
+	switch {
+	case a == b || a == nil:
+		return true
+	case b == nil:
+		return false
+	}
+
+	var ta, aIsTable = a.(tableI)
+	var tb, bIsTable = b.(tableI)
+	switch {
+	case aIsTable && bIsTable:
+		for idx := uint(0); idx < tableLayout(ta).IndexLimit(); idx++ {
+			if !subset(ta.get(idx), tb.get(idx), depth+1) {
+				return false
+			}
+		}
+		return true
+	case aIsTable:
+		var lb = b.(leafI)
+		return ta.walkPreOrder(func(n nodeI, _ uint) bool {
+			if la, isLeaf := n.(leafI); isLeaf {
+				return leafSubset(la, lb)
+			}
+			return true
+		}, depth)
+	case bIsTable:
+		var la = a.(leafI)
+		return leafSubset(la, findLeaf(tb, la.hash(), depth, tableLayout(tb)))
+	}
+	return leafSubset(a.(leafI), b.(leafI))
+}
+
+// leafSubset() reports whether every key of the leaf la is in the leaf lb,
+// which may be nil.
+func leafSubset(la, lb leafI) bool {
+	if lb == nil || la.count() > lb.count() {
+		return false
+	}
+	for _, k := range la.keys() {
+		if !lb.get(k) {
+			return false
+		}
+	}
+	return true
+}
+
+// disjoint() reports whether no key of the node a is in the node b, both
+// found under the same slot where a table would be at the given depth. Tables
+// below the root are never empty, so a node found in both Sets is not
+// disjoint.
+func disjoint(a, b nodeI, depth uint) bool {
+	switch {
+	case a == nil || b == nil:
+		return true
+	case a == b:
+		return false
+	}
+
+	var ta, aIsTable = a.(tableI)
+	var tb, bIsTable = b.(tableI)
+	switch {
+	case aIsTable && bIsTable:
+		for idx := uint(0); idx < tableLayout(ta).IndexLimit(); idx++ {
+			if !disjoint(ta.get(idx), tb.get(idx), depth+1) {
+				return false
+			}
+		}
+		return true
+	case aIsTable:
+		var lb = b.(leafI)
+		return leafDisjoint(lb, findLeaf(ta, lb.hash(), depth, tableLayout(ta)))
+	case bIsTable:
+		var la = a.(leafI)
+		return leafDisjoint(la, findLeaf(tb, la.hash(), depth, tableLayout(tb)))
+	}
+	return leafDisjoint(a.(leafI), b.(leafI))
+}
+
+// leafDisjoint() reports whether no key of the leaf la is in the leaf lb,
+// which may be nil.
+func leafDisjoint(la, lb leafI) bool {
+	if lb == nil {
+		return true
+	}
+	for _, k := range la.keys() {
+		if lb.get(k) {
+			return false
+		}
+	}
+	return true
+}
+
+// nodeCount() returns the number of keys held by the node n, which may be nil.
+func nodeCount(n nodeI) int {
+	if n == nil {
+		return 0
+	}
+	return n.count()
+}
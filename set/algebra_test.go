@@ -0,0 +1,103 @@
+package set
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/lleo/go-functional-collections/key"
+	"github.com/lleo/go-functional-collections/key/hash"
+)
+
+func TestAlgebraSharing(t *testing.T) {
+	var keys = make([]key.Hash, 5000)
+	for i := range keys {
+		keys[i] = key.Str(strconv.Itoa(i))
+	}
+	var s = NewFromList(keys)
+	var added = key.Str("added")
+	var s2 = s.Set(added)
+	var addedIdx = added.Hash().Index(0)
+
+	if s.Union(s2).root != s2.root {
+		t.Fatal("s.Union(s2) did not return the root of s2, a superset of s")
+	}
+	if s.Intersect(s2).root != s.root || s2.Intersect(s).root != s.root {
+		t.Fatal("s.Intersect(s2) did not return the root of s, a subset of s2")
+	}
+
+	// only the slot along the differing key is not shared
+	var s3 = s.Unset(keys[0]).Set(added)
+	for _, ns := range []*Set{s.Union(s3), s3.Union(s), s3.Intersect(s),
+		s.UnionParallel(s3), s3.IntersectParallel(s)} {
+		for idx := uint(0); idx < hash.IndexLimit; idx++ {
+			if idx == addedIdx || idx == keys[0].Hash().Index(0) {
+				continue
+			}
+			if ns.root.get(idx) != s.root.get(idx) {
+				t.Fatalf("the slot %d of the root is not shared", idx)
+			}
+		}
+	}
+
+	var diff = s2.Difference(s)
+	if diff.NumEntries() != 1 || diff.root.slotsUsed() != 1 {
+		t.Fatalf("s2.Difference(s) holds %d keys in %d slots",
+			diff.NumEntries(), diff.root.slotsUsed())
+	}
+	if _, isLeaf := diff.root.get(addedIdx).(*flatLeaf); !isLeaf {
+		t.Fatal("s2.Difference(s) did not hoist the only key to the root")
+	}
+}
+
+// walkCount() returns the number of keys under the node n, found by walking
+// it, and fails the test if any table under it keeps another count.
+func walkCount(t *testing.T, n nodeI) int {
+	var tn, isTable = n.(tableI)
+	if !isTable {
+		return n.count()
+	}
+	var num int
+	var next = tn.iter()
+	for cn := next(); cn != nil; cn = next() {
+		num += walkCount(t, cn)
+	}
+	if tn.count() != num {
+		t.Fatalf("the table at %s counts %d keys, but holds %d",
+			tn.hash(), tn.count(), num)
+	}
+	return num
+}
+
+func TestAlgebraNumKeys(t *testing.T) {
+	var keys = make([]key.Hash, 5000)
+	for i := range keys {
+		keys[i] = key.Str(strconv.Itoa(i))
+	}
+	var small = NewFromList(keys[:10])
+	var big = NewFromList(keys[5:])
+
+	// transients modify owned tables in place, deep inside the HAMT
+	var tr = big.Transient()
+	for _, k := range keys[:2500] {
+		tr.Remove(k)
+	}
+	for _, k := range keys[:1000] {
+		tr.Add(k)
+	}
+	var mid = tr.Persistent()
+
+	var sets = []*Set{small, big, mid, big.Unset(keys[100]).Set(keys[0])}
+	for _, a := range sets {
+		for _, b := range sets {
+			for _, s := range []*Set{a, a.Union(b), a.Intersect(b),
+				a.Difference(b), a.SymmetricDifference(b),
+				a.UnionParallel(b), a.IntersectParallel(b),
+				a.MergeParallel(b)} {
+				if n := walkCount(t, s.root); n != s.NumEntries() {
+					t.Fatalf("a Set holds %d keys != NumEntries(),%d",
+						n, s.NumEntries())
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package set
+
+import (
+	"sync/atomic"
+
+	"github.com/lleo/go-functional-collections/key"
+)
+
+// Atomic is a mutable Set safe for concurrent use. It holds a pointer to a
+// persistent *Set, and every modification builds a new *Set and swaps it in
+// with an atomic compare-and-swap, retrying if another goroutine swapped in its
+// own first. So readers never wait, and Snapshot returns a consistent view of
+// the Set that later modifications do not affect.
+//
+// The zero value is an empty Atomic ready to use. An Atomic MUST NOT be
+// copied after first use.
+type Atomic struct {
+	p atomic.Pointer[Set]
+}
+
+// NewAtomic returns a new *Atomic holding the given Set; an empty one if s is
+// nil.
+func NewAtomic(s *Set) *Atomic {
+	var a = new(Atomic)
+	a.p.Store(s)
+	return a
+}
+
+// load() returns the Set the Atomic holds, after storing an empty Set in a
+// zero Atomic.
+func (a *Atomic) load() *Set {
+	var p = a.p.Load()
+	if p == nil {
+		a.p.CompareAndSwap(nil, New())
+		p = a.p.Load()
+	}
+	return p
+}
+
+// Snapshot returns the Set the Atomic holds now. Being persistent, the Set
+// never changes.
+func (a *Atomic) Snapshot() *Set {
+	return a.load()
+}
+
+// Load reports whether the key is in the Set.
+func (a *Atomic) Load(key key.Hash) bool {
+	return a.Snapshot().IsSet(key)
+}
+
+// Store adds the key to the Set.
+func (a *Atomic) Store(key key.Hash) {
+	a.Update(func(s *Set) *Set {
+		return s.Set(key)
+	})
+}
+
+// LoadOrStore adds the key to the Set, unless it is already there, and
+// reports whether it was.
+func (a *Atomic) LoadOrStore(key key.Hash) bool {
+	var added bool
+	a.Update(func(s *Set) *Set {
+		var ns *Set
+		ns, added = s.Add(key)
+		return ns
+	})
+	return !added
+}
+
+// Delete removes the key from the Set.
+func (a *Atomic) Delete(key key.Hash) {
+	a.Update(func(s *Set) *Set {
+		return s.Unset(key)
+	})
+}
+
+// Range calls fn for every key of a Snapshot, until fn returns false.
+func (a *Atomic) Range(fn func(key.Hash) bool) {
+	a.Snapshot().Range(fn)
+}
+
+// CompareAndSwap replaces the Set the Atomic holds with new, if it still holds
+// old, and reports whether it did. The Sets are compared by pointer, so old is
+// usually a Snapshot.
+func (a *Atomic) CompareAndSwap(old, new *Set) bool {
+	a.load()
+	return a.p.CompareAndSwap(old, new)
+}
+
+// Update replaces the Set the Atomic holds, s, with fn(s), and returns it.
+// If another goroutine modifies the Atomic while fn runs, fn is called again
+// with the new Set, so fn SHOULD have no side effects. Update is how several
+// keys are modified together; no other goroutine sees only some of the
+// changes.
+func (a *Atomic) Update(fn func(*Set) *Set) *Set {
+	for {
+		var p = a.load()
+		var ns = fn(p)
+		if a.p.CompareAndSwap(p, ns) {
+			return ns
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package set
+
+import (
+	"encoding"
+	"fmt"
+
+	"github.com/lleo/go-functional-collections/codec"
+	"github.com/lleo/go-functional-collections/key"
+)
+
+// binaryMagic and binaryVersion identify the binary format of a Set.
+//
+// Version 1 of the format is:
+//
+//	"FSET" 1 numEnts key*numEnts
+//
+// where numEnts is a uvarint, and every key is a value encoded with the codec
+// package, in the order they are found in the HAMT.
+const (
+	binaryMagic   = "FSET"
+	binaryVersion = 1
+)
+
+var (
+	_ encoding.BinaryMarshaler   = (*Set)(nil)
+	_ encoding.BinaryUnmarshaler = (*Set)(nil)
+)
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface. Every key
+// in the Set MUST have a codec registered with the codec package, or an error
+// is returned.
+func (s *Set) MarshalBinary() ([]byte, error) {
+	var enc = codec.NewEncoder(binaryMagic, binaryVersion)
+	enc.WriteUvarint(uint64(s.numEnts))
+
+	var err error
+	s.root.walkPreOrder(func(n nodeI, depth uint) bool {
+		if leaf, isLeaf := n.(leafI); isLeaf {
+			for _, k := range leaf.keys() {
+				if err = enc.Encode(k); err != nil {
+					return false
+				}
+			}
+		}
+		return true
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return enc.Bytes(), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface. It
+// replaces the contents of the receiver, so it SHOULD only be called on a Set
+// that no one else holds, like one just returned by New.
+//
+// The HAMT is rebuilt in bulk, like NewFromList, rather than by repeated calls
+// to Add. The Set keeps the Options of the receiver.
+func (s *Set) UnmarshalBinary(data []byte) error {
+	var dec, version, err = codec.NewDecoder(data, binaryMagic)
+	if err != nil {
+		return err
+	}
+	if version != binaryVersion {
+		return fmt.Errorf("set: unsupported binary format version %d",
+			version)
+	}
+
+	numEnts, err := dec.ReadUvarint()
+	if err != nil {
+		return err
+	}
+	// every key takes at least two bytes
+	if numEnts > uint64(dec.Len()/2) {
+		return codec.ErrCorrupt
+	}
+
+	var keys = make([]key.Hash, numEnts)
+	for i := range keys {
+		var k, err = dec.Decode()
+		if err != nil {
+			return err
+		}
+		var hk, isHash = k.(key.Hash)
+		if !isHash {
+			return fmt.Errorf("set: decoded key of type %T is not a key.Hash",
+				k)
+		}
+		keys[i] = hk
+	}
+	if dec.Len() != 0 {
+		return codec.ErrCorrupt
+	}
+
+	*s = *newFromList(keys, s.layout())
+	return nil
+}
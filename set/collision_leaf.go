@@ -3,6 +3,7 @@ package set
 import (
 	"fmt"
 	"log"
+	"sort"
 	"strings"
 
 	"github.com/lleo/go-functional-collections/key"
@@ -11,22 +12,47 @@ import (
 
 // implements nodeI
 // implements leafI
+//
+// The keys of a collisionLeaf are ordered by the hash.Secondary of their
+// String(), and found by binary search. So even if an attacker makes many keys
+// collide, finding one costs O(log(n)). Keys with the same String() that are
+// not Equal share a secondary hash, so finding one among them is a linear
+// scan; key types SHOULD give unequal keys unequal Strings.
 type collisionLeaf struct {
-	ks []key.Hash
+	ks     []key.Hash
+	hashes []uint64 // hashes[i] == secondaryHash(ks[i])
+}
+
+func secondaryHash(k key.Hash) uint64 {
+	return hash.Secondary(k.String())
 }
 
 func newCollisionLeaf(keys []key.Hash) *collisionLeaf {
 	var leaf = new(collisionLeaf)
 	leaf.ks = append(leaf.ks, keys...)
+	leaf.hashes = make([]uint64, len(keys))
+	for i, k := range leaf.ks {
+		leaf.hashes[i] = secondaryHash(k)
+	}
+	sort.Stable(leaf)
 
 	//log.Println("newCollisionLeaf:", leaf)
 
 	return leaf
 }
 
+// Len, Less and Swap implement sort.Interface for newCollisionLeaf().
+func (l *collisionLeaf) Len() int           { return len(l.ks) }
+func (l *collisionLeaf) Less(i, j int) bool { return l.hashes[i] < l.hashes[j] }
+func (l *collisionLeaf) Swap(i, j int) {
+	l.ks[i], l.ks[j] = l.ks[j], l.ks[i]
+	l.hashes[i], l.hashes[j] = l.hashes[j], l.hashes[i]
+}
+
 func (l *collisionLeaf) copy() *collisionLeaf {
 	var nl = new(collisionLeaf)
 	nl.ks = append(nl.ks, l.ks...)
+	nl.hashes = append(nl.hashes, l.hashes...)
 	return nl
 }
 
@@ -45,29 +71,41 @@ func (l *collisionLeaf) String() string {
 		l.ks[0].Hash(), jkeystr)
 }
 
-func (l *collisionLeaf) get(key key.Hash) bool {
-	for _, keyN := range l.ks {
-		if keyN.Equals(key) {
-			return true
+// find() returns the index of the key, and true, or, if the key is not in the
+// collisionLeaf, the index where it should be inserted, and false. sh is the
+// secondaryHash of the key.
+func (l *collisionLeaf) find(key key.Hash, sh uint64) (int, bool) {
+	var i = sort.Search(len(l.hashes), func(i int) bool {
+		return l.hashes[i] >= sh
+	})
+	for j := i; j < len(l.hashes) && l.hashes[j] == sh; j++ {
+		if l.ks[j].Equals(key) {
+			return j, true
 		}
 	}
-	return false
+	return i, false
+}
+
+func (l *collisionLeaf) get(key key.Hash) bool {
+	var _, found = l.find(key, secondaryHash(key))
+	return found
 }
 
 func (l *collisionLeaf) put(k key.Hash) (leafI, bool) {
-	for _, keyN := range l.ks {
-		if keyN.Equals(k) {
-			//var nl = l.copy()
-			//return nl, false //replaced
-			return l, false
-		}
+	var sh = secondaryHash(k)
+	var i, found = l.find(k, sh)
+	if found {
+		return l, false
 	}
 	var nl = new(collisionLeaf)
 	nl.ks = make([]key.Hash, len(l.ks)+1)
-	copy(nl.ks, l.ks)
-	nl.ks[len(l.ks)] = k
-	// v-- this, instead of that --^ make&copy&assign
-	//nl.ks = append(nl.ks, append(l.ks, k)...)
+	nl.hashes = make([]uint64, len(l.hashes)+1)
+	copy(nl.ks, l.ks[:i])
+	copy(nl.hashes, l.hashes[:i])
+	nl.ks[i] = k
+	nl.hashes[i] = sh
+	copy(nl.ks[i+1:], l.ks[i:])
+	copy(nl.hashes[i+1:], l.hashes[i:])
 
 	//log.Printf("%s : %d\n", l.hash(), len(l.ks))
 
@@ -75,23 +113,24 @@ func (l *collisionLeaf) put(k key.Hash) (leafI, bool) {
 }
 
 func (l *collisionLeaf) del(key key.Hash) (leafI, bool) {
-	for i, lkey := range l.ks {
-		if lkey.Equals(key) {
-			var nl leafI
-			if len(l.ks) == 2 {
-				// think about the index... it works, really :)
-				nl = newFlatLeaf(l.ks[1-i])
-			} else {
-				var cl = l.copy()
-				cl.ks = append(cl.ks[:i], cl.ks[i+1:]...)
-				nl = cl // needed access to cl.ks; nl is type leafI
-			}
-			//log.Printf("l.del(); kv=%s removed; returning %s", kv, nl)
-			return nl, true
-		}
+	var i, found = l.find(key, secondaryHash(key))
+	if !found {
+		//log.Printf("cl.del(%s) removed nothing.", k)
+		return l, false
+	}
+
+	var nl leafI
+	if len(l.ks) == 2 {
+		// think about the index... it works, really :)
+		nl = newFlatLeaf(l.ks[1-i])
+	} else {
+		var cl = l.copy()
+		cl.ks = append(cl.ks[:i], cl.ks[i+1:]...)
+		cl.hashes = append(cl.hashes[:i], cl.hashes[i+1:]...)
+		nl = cl // needed access to cl.ks; nl is type leafI
 	}
-	//log.Printf("cl.del(%s) removed nothing.", k)
-	return l, false
+	//log.Printf("l.del(); kv=%s removed; returning %s", kv, nl)
+	return nl, true
 }
 
 func (l *collisionLeaf) keys() []key.Hash {
@@ -116,10 +155,9 @@ func (l *collisionLeaf) equiv(other nodeI) bool {
 		log.Printf("len(l.ks),%d != len(ol.ks),%d", len(l.ks), len(ol.ks))
 		return false
 	}
-	// This assumes the ks are in the same order.
 	for i, key := range l.ks {
-		if !key.Equals(ol.ks[i]) {
-			log.Printf("l.ks[%d],%s != ol.ks[%d],%s", i, l.ks[i], i, ol.ks[i])
+		if _, found := ol.find(key, l.hashes[i]); !found {
+			log.Printf("l.ks[%d],%s not in ol", i, l.ks[i])
 			return false
 		}
 	}
@@ -6,34 +6,50 @@ import (
 	"strings"
 
 	"github.com/lleo/go-functional-collections/key/hash"
+	"github.com/lleo/go-functional-collections/merkle"
 )
 
 type fixedTable struct {
-	nodes     [hash.IndexLimit]nodeI
+	nodes     []nodeI // len(nodes) == layout.IndexLimit()
 	depth     uint
 	usedSlots uint //numEnts  uint
+	numKeys   int //keys held by the nodes, and the tables under them
 	hashPath  hash.Val
+	layout    hash.Layout
+	digest    merkle.Cache
 }
 
-func newFixedTable(depth uint, hashVal hash.Val) *fixedTable {
+func newFixedTable(depth uint, hashVal hash.Val, l hash.Layout) *fixedTable {
 	var t = new(fixedTable)
+	t.nodes = make([]nodeI, l.IndexLimit())
 	t.depth = depth
-	t.hashPath = hashVal.HashPath(depth)
+	t.hashPath = l.HashPath(hashVal, depth)
+	t.layout = l
 	return t
 }
 
 func (t *fixedTable) copy() tableI {
+	// not *nt = *t, the digest is read atomically and is reset anyway
 	var nt = new(fixedTable)
-	*nt = *t
+	nt.nodes = make([]nodeI, len(t.nodes))
+	copy(nt.nodes, t.nodes)
+	nt.depth = t.depth
+	nt.usedSlots = t.usedSlots
+	nt.numKeys = t.numKeys
+	nt.hashPath = t.hashPath
+	nt.layout = t.layout
 	return nt
 }
 
 func (t *fixedTable) deepCopy() tableI {
 	var nt = new(fixedTable)
 
+	nt.nodes = make([]nodeI, len(t.nodes))
 	nt.hashPath = t.hashPath
 	nt.depth = t.depth
 	nt.usedSlots = t.usedSlots
+	nt.numKeys = t.numKeys
+	nt.layout = t.layout
 
 	//for i := 0; i < len(t.nodes); i++ {
 	//	if table, isTable := t.nodes[i].(tableI); isTable {
@@ -82,6 +98,10 @@ func (t *fixedTable) equiv(other nodeI) bool {
 		log.Printf("t.hashPath,%s != ot.hashPath,%s", t.hashPath, ot.hashPath)
 		return false
 	}
+	if t.layout != ot.layout {
+		log.Printf("t.layout,%d != ot.layout,%d", t.layout, ot.layout)
+		return false
+	}
 	//ok = ok && t.depth == ot.depth
 	//ok = ok && t.usedSlots == ot.usedSlots
 	//ok = ok && t.hashPath == ot.hashPath
@@ -102,28 +122,33 @@ func (t *fixedTable) equiv(other nodeI) bool {
 	return true
 }
 
-func createFixedTable(depth uint, leaf1 leafI, leaf2 *flatLeaf) tableI {
+func createFixedTable(
+	depth uint,
+	leaf1 leafI,
+	leaf2 *flatLeaf,
+	l hash.Layout,
+) tableI {
 	if assertOn {
 		assertf(depth > 0, "createFixedTable(): depth,%d < 1", depth)
-		assertf(leaf1.hash().HashPath(depth) == leaf2.hash().HashPath(depth),
+		assertf(l.HashPath(leaf1.hash(), depth) == l.HashPath(leaf2.hash(), depth),
 			"createFixedTable(): hp1,%s != hp2,%s",
-			leaf1.hash().HashPath(depth),
-			leaf2.hash().HashPath(depth))
+			l.HashPath(leaf1.hash(), depth),
+			l.HashPath(leaf2.hash(), depth))
 	}
 
-	var retTable = newFixedTable(depth, leaf1.hash())
+	var retTable = newFixedTable(depth, leaf1.hash(), l)
 
-	var idx1 = leaf1.hash().Index(depth)
-	var idx2 = leaf2.hash().Index(depth)
+	var idx1 = l.Index(leaf1.hash(), depth)
+	var idx2 = l.Index(leaf2.hash(), depth)
 	if idx1 != idx2 {
 		retTable.insertInplace(idx1, leaf1)
 		retTable.insertInplace(idx2, leaf2)
 	} else { // idx1 == idx2
 		var node nodeI
-		if depth == hash.MaxDepth {
+		if depth == l.MaxDepth() {
 			node = newCollisionLeaf(append(leaf1.keys(), leaf2.keys()...))
 		} else {
-			node = createFixedTable(depth+1, leaf1, leaf2)
+			node = createFixedTable(depth+1, leaf1, leaf2, l)
 		}
 		retTable.insertInplace(idx1, node)
 	}
@@ -135,15 +160,19 @@ func upgradeToFixedTable(
 	hashPath hash.Val,
 	depth uint,
 	ents []tableEntry,
+	l hash.Layout,
 ) *fixedTable {
 	var ft = new(fixedTable)
 
+	ft.nodes = make([]nodeI, l.IndexLimit())
 	ft.hashPath = hashPath
 	ft.depth = depth
 	ft.usedSlots = uint(len(ents))
+	ft.layout = l
 
 	for _, ent := range ents {
 		ft.nodes[ent.idx] = ent.node
+		ft.numKeys += ent.node.count()
 	}
 
 	return ft
@@ -159,7 +188,7 @@ func (t *fixedTable) hash() hash.Val {
 // depth, and number of entries.
 func (t *fixedTable) String() string {
 	return fmt.Sprintf("fixedTable{hashPath=%s, depth=%d, slotsUsed()=%d}",
-		t.hashPath.HashPathString(t.depth), t.depth, t.slotsUsed())
+		t.layout.HashPathString(t.hashPath, t.depth), t.depth, t.slotsUsed())
 }
 
 // treeString returns a string representation of this table and all the tables
@@ -169,7 +198,7 @@ func (t *fixedTable) treeString(indent string, depth uint) string {
 
 	strs[0] = indent + "fixedTable{"
 	strs[1] = indent + fmt.Sprintf("\thashPath=%s, depth=%d, slotsUsed()=%d,",
-		t.hashPath.HashPathString(depth), t.depth, t.slotsUsed())
+		t.layout.HashPathString(t.hashPath, depth), t.depth, t.slotsUsed())
 
 	var j = 0
 	for i, n := range t.nodes {
@@ -201,7 +230,7 @@ func (t *fixedTable) entries() []tableEntry {
 	var n = t.slotsUsed()
 	var ents = make([]tableEntry, n)
 	var i, j uint
-	for i, j = 0, 0; j < n && i < hash.IndexLimit; i++ {
+	for i, j = 0, 0; j < n && i < uint(len(t.nodes)); i++ {
 		if t.nodes[i] != nil {
 			ents[j] = tableEntry{i, t.nodes[i]}
 			j++
@@ -219,7 +248,7 @@ func (t *fixedTable) needsUpgrade() bool {
 }
 
 func (t *fixedTable) needsDowngrade() bool {
-	return t.slotsUsed() == downgradeThreshold
+	return t.slotsUsed() == downgradeThreshold(t.layout)
 }
 
 func (t *fixedTable) upgrade() tableI {
@@ -228,8 +257,8 @@ func (t *fixedTable) upgrade() tableI {
 }
 
 func (t *fixedTable) downgrade() tableI {
-	var nt = newSparseTable(t.depth, t.hashPath, t.slotsUsed())
-	for idx := uint(0); idx < hash.IndexLimit; idx++ {
+	var nt = newSparseTable(t.depth, t.hashPath, t.slotsUsed(), t.layout)
+	for idx := uint(0); idx < uint(len(t.nodes)); idx++ {
 		if t.nodes[idx] != nil {
 			nt.insertInplace(idx, t.nodes[idx])
 		}
@@ -238,8 +267,10 @@ func (t *fixedTable) downgrade() tableI {
 }
 
 func (t *fixedTable) insertInplace(idx uint, n nodeI) {
+	t.digest.Reset()
 	t.nodes[idx] = n
 	t.usedSlots++
+	t.numKeys += n.count()
 }
 
 func (t *fixedTable) insert(idx uint, n nodeI) tableI {
@@ -249,10 +280,13 @@ func (t *fixedTable) insert(idx uint, n nodeI) tableI {
 	var nt = t.copy().(*fixedTable)
 	nt.nodes[idx] = n
 	nt.usedSlots++
+	nt.numKeys += n.count()
 	return nt
 }
 
 func (t *fixedTable) replaceInplace(idx uint, n nodeI) {
+	t.digest.Reset()
+	t.numKeys += n.count() - t.nodes[idx].count()
 	t.nodes[idx] = n
 }
 
@@ -267,6 +301,8 @@ func (t *fixedTable) replace(idx uint, n nodeI) tableI {
 }
 
 func (t *fixedTable) removeInplace(idx uint) {
+	t.digest.Reset()
+	t.numKeys -= t.nodes[idx].count()
 	t.nodes[idx] = nil
 	t.usedSlots--
 }
@@ -280,7 +316,7 @@ func (t *fixedTable) remove(idx uint) tableI {
 			return nil
 		}
 
-		if t.slotsUsed()-1 == downgradeThreshold {
+		if t.slotsUsed()-1 == downgradeThreshold(t.layout) {
 			var nt = t.downgrade()
 			nt.removeInplace(idx)
 			return nt
@@ -322,7 +358,7 @@ func (t *fixedTable) iter() tableIterFunc {
 	var i = -1
 
 	return func() nodeI {
-		for i < int(hash.IndexLimit-1) {
+		for i < len(t.nodes)-1 {
 			i++
 			if t.nodes[i] != nil {
 				return t.nodes[i]
@@ -334,11 +370,5 @@ func (t *fixedTable) iter() tableIterFunc {
 }
 
 func (t *fixedTable) count() int {
-	var i int
-	for _, n := range t.nodes {
-		if n != nil {
-			i += n.count()
-		}
-	}
-	return i
+	return t.numKeys
 }
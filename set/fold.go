@@ -0,0 +1,103 @@
+package set
+
+import (
+	"context"
+
+	"github.com/lleo/go-functional-collections/internal/parallel"
+	"github.com/lleo/go-functional-collections/key"
+)
+
+// The sub-trees under the slots of the root table hold disjoint sets of keys,
+// and a Set never changes, so they can be walked each in its own goroutine
+// with no locking. ParallelRange and Fold do that, with a pool of workers
+// taking the sub-trees one at a time.
+
+// ParallelRange calls fn for every key of the Set, from up to
+// workers goroutines at once, or runtime.GOMAXPROCS(0) if workers <= 0. So fn
+// MUST be safe for concurrent use, and the keys are visited in no particular
+// order. If fn returns false, the remaining keys are skipped, though calls
+// already under way in other goroutines still finish.
+//
+// ParallelRange also stops once ctx is done, and then returns ctx.Err().
+func (s *Set) ParallelRange(
+	ctx context.Context,
+	workers int,
+	fn func(key.Hash) bool,
+) error {
+	var subs = s.rootSubTrees()
+	return parallel.Parts(ctx, workers, len(subs),
+		func(part int, halted func() bool) bool {
+			return walkKeys(subs[part], func(k key.Hash) bool {
+				return !halted() && fn(k)
+			})
+		})
+}
+
+// Fold reduces the keys of the Set to one value, MapReduce style, using up to
+// workers goroutines at once, or runtime.GOMAXPROCS(0) if workers <= 0. Each
+// goroutine folds the keys of a part of the Set, starting from zero, and the
+// results of the parts are then combined. So zero MUST be an identity of
+// combine, and combine MUST be associative and commutative, as the parts are
+// in no particular order; fold is only ever called by one goroutine for a
+// given accumulator.
+//
+// If ctx is done before the fold is, Fold returns nil and ctx.Err().
+func (s *Set) Fold(
+	ctx context.Context,
+	workers int,
+	zero interface{},
+	fold func(acc interface{}, k key.Hash) interface{},
+	combine func(acc0, acc1 interface{}) interface{},
+) (interface{}, error) {
+	var subs = s.rootSubTrees()
+	var accs = make([]interface{}, len(subs))
+	var err = parallel.Parts(ctx, workers, len(subs),
+		func(part int, halted func() bool) bool {
+			var acc = zero
+			if walkKeys(subs[part], func(k key.Hash) bool {
+				if halted() {
+					return false
+				}
+				acc = fold(acc, k)
+				return true
+			}) {
+				accs[part] = acc
+			}
+			return true
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	var acc = zero
+	for _, partAcc := range accs {
+		acc = combine(acc, partAcc)
+	}
+	return acc, nil
+}
+
+// rootSubTrees() returns the nodes under the used slots of the root table.
+func (s *Set) rootSubTrees() []nodeI {
+	var subs []nodeI
+	for idx := uint(0); idx < s.layout().IndexLimit(); idx++ {
+		if n := s.root.get(idx); n != nil {
+			subs = append(subs, n)
+		}
+	}
+	return subs
+}
+
+// walkKeys() calls fn for every key held by the node n, found under the root
+// table, until fn returns false, and then returns false.
+func walkKeys(n nodeI, fn func(key.Hash) bool) bool {
+	return n.walkPreOrder(func(n nodeI, depth uint) bool {
+		if leaf, isLeaf := n.(leafI); isLeaf {
+			for _, k := range leaf.keys() {
+				if !fn(k) {
+					return false
+				}
+			}
+		}
+		return true
+	}, 1)
+}
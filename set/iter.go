@@ -0,0 +1,39 @@
+//go:build go1.23
+// +build go1.23
+
+package set
+
+import (
+	"iter"
+
+	"github.com/lleo/go-functional-collections/key"
+)
+
+// All returns an iterator over the keys of the *Set, for use with a for-range
+// loop. Like Range, the order of the keys is determined by their hash values.
+func (s *Set) All() iter.Seq[key.Hash] {
+	return func(yield func(key.Hash) bool) {
+		s.walkPreOrder(func(n nodeI, depth uint) bool {
+			if leaf, isLeaf := n.(leafI); isLeaf {
+				for _, k := range leaf.keys() {
+					if !yield(k) {
+						return false
+					}
+				}
+			}
+			return true
+		})
+	}
+}
+
+// All returns an iterator over the keys of the *TypedSet, for use with a
+// for-range loop.
+func (s *TypedSet[K]) All() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for k := range s.s.All() {
+			if !yield(k.(key.HashOf[K]).Key) {
+				return
+			}
+		}
+	}
+}
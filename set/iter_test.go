@@ -0,0 +1,53 @@
+//go:build go1.23
+// +build go1.23
+
+package set_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/lleo/go-functional-collections/key"
+	"github.com/lleo/go-functional-collections/set"
+)
+
+func TestIterAll(t *testing.T) {
+	var keys = buildKeys(5000)
+	var s = set.NewFromList(keys)
+
+	var seen = make(map[key.Hash]bool)
+	for k := range s.All() {
+		if seen[k] {
+			t.Fatalf("s.All() yielded key %s twice", k)
+		}
+		seen[k] = true
+	}
+	if len(seen) != len(keys) {
+		t.Fatalf("s.All() yielded %d keys; expected %d", len(seen), len(keys))
+	}
+	for _, k := range keys {
+		if !seen[k] {
+			t.Fatalf("s.All() did not yield key %s", k)
+		}
+	}
+
+	var n int
+	for range s.All() {
+		n++
+		if n == 10 {
+			break
+		}
+	}
+	if n != 10 {
+		t.Fatalf("break out of s.All() loop after %d iterations", n)
+	}
+
+	for range set.New().All() {
+		t.Fatal("empty Set yielded a key")
+	}
+
+	var ts = set.NewTypedSetFromList([]key.Int{3, 1, 2})
+	if got := slices.Sorted(ts.All()); !slices.Equal(got, []key.Int{1, 2, 3}) {
+		t.Fatalf("slices.Sorted(ts.All()),%v != [1 2 3]", got)
+	}
+}
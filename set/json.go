@@ -0,0 +1,58 @@
+package set
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/lleo/go-functional-collections/codec"
+	"github.com/lleo/go-functional-collections/key"
+)
+
+var (
+	_ json.Marshaler   = (*Set)(nil)
+	_ json.Unmarshaler = (*Set)(nil)
+)
+
+// MarshalJSON implements the json.Marshaler interface. A Set is encoded as a
+// JSON array of its keys.
+func (s *Set) MarshalJSON() ([]byte, error) {
+	var keys = make([]interface{}, 0, s.numEnts)
+	s.root.walkPreOrder(func(n nodeI, depth uint) bool {
+		if leaf, isLeaf := n.(leafI); isLeaf {
+			for _, k := range leaf.keys() {
+				keys = append(keys, k)
+			}
+		}
+		return true
+	}, 0)
+	return codec.EncodeJSONKeys(keys)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. It replaces the
+// contents of the receiver, so it SHOULD only be called on a Set that no one
+// else holds, like one just returned by New. Keys are decoded by
+// codec.DecodeJSONKey and MUST implement key.Hash. The Set keeps the Options of
+// the receiver.
+func (s *Set) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil
+	}
+
+	var keys []key.Hash
+	var err = codec.DecodeJSONKeys(data, func(k interface{}) error {
+		var hk, isHash = k.(key.Hash)
+		if !isHash {
+			return fmt.Errorf("set: decoded key of type %T is not a key.Hash",
+				k)
+		}
+		keys = append(keys, hk)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	*s = *newFromList(keys, s.layout())
+	return nil
+}
@@ -0,0 +1,49 @@
+package set
+
+import "github.com/lleo/go-functional-collections/merkle"
+
+// RootDigest returns the content digest of the Set; the sum of the
+// merkle.Entry digests of its keys. Sets holding equal keys have equal
+// RootDigests, but equal RootDigests do not prove the keys equal. See the
+// merkle package.
+//
+// The digest of every table is cached in the table. So the first RootDigest
+// of a Set visits every key, but afterwards RootDigest is O(1), and the
+// RootDigest of a new version of the Set only visits the tables created since.
+func (s *Set) RootDigest() merkle.Digest {
+	return nodeDigest(s.root)
+}
+
+// nodeDigest() returns the digest of the node, computing and caching it if the
+// node is a table without a cached digest.
+func nodeDigest(n nodeI) merkle.Digest {
+	var d merkle.Digest
+	if leaf, isLeaf := n.(leafI); isLeaf {
+		for _, k := range leaf.keys() {
+			d = d.Add(merkle.Entry(k, nil))
+		}
+		return d
+	}
+
+	var t = n.(tableI)
+	var cache = digestCache(t)
+	if cd, cached := cache.Get(); cached {
+		return cd
+	}
+	var next = t.iter()
+	for cn := next(); cn != nil; cn = next() {
+		d = d.Add(nodeDigest(cn))
+	}
+	cache.Set(d)
+	return d
+}
+
+func digestCache(t tableI) *merkle.Cache {
+	switch x := t.(type) {
+	case *fixedTable:
+		return &x.digest
+	case *sparseTable:
+		return &x.digest
+	}
+	panic("digestCache(): unknown table type")
+}
@@ -0,0 +1,186 @@
+package set
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/lleo/go-functional-collections/key"
+	"github.com/lleo/go-functional-collections/key/hash"
+)
+
+// The sub-trees under the slots of the root table hold disjoint sets of keys,
+// those with the same Index at depth 0, so they can be built each in its own
+// goroutine, by its own Transient, and put together in a new root table at
+// the end. The Parallel variants of the bulk operations do that, and those of
+// the set algebra combine the sub-trees of both Sets under each slot in their
+// own goroutine; they are only worth it for large Sets, of about a hundred
+// thousand keys or more.
+
+// NewFromListParallel is NewFromList, with the root sub-trees built in
+// parallel.
+func NewFromListParallel(keys []key.Hash) *Set {
+	return New().BulkInsertParallel(keys)
+}
+
+// BulkInsertParallel is BulkInsert, with the root sub-trees built in parallel.
+func (s *Set) BulkInsertParallel(keys []key.Hash) *Set {
+	var chunks = partitionKeys(keys, s.layout())
+	return buildParallel(s, func(idx uint, t *Transient) {
+		for _, parts := range chunks {
+			for _, k := range parts[idx] {
+				t.Add(k)
+			}
+		}
+	})
+}
+
+// MergeParallel is Merge, with the root sub-trees merged in parallel. If the
+// Sets have different Options, their root sub-trees do not line up, so the
+// keys of other are partitioned again as BulkInsertParallel does.
+func (s *Set) MergeParallel(other *Set) *Set {
+	if s.layout() != other.layout() {
+		return s.BulkInsertParallel(other.Keys())
+	}
+
+	var big, sml = s, other
+	if s.NumEntries() < other.NumEntries() {
+		big, sml = other, s
+	}
+	// big is bigger then sml
+
+	return buildParallel(big, func(idx uint, t *Transient) {
+		for _, k := range nodeKeys(sml.root.get(idx), 1) {
+			t.Add(k)
+		}
+	})
+}
+
+// UnionParallel is Union, with the root sub-trees combined in parallel. Like
+// Union, the returned Set shares every sub-tree it can with the receiver Set
+// and the argument Set.
+func (s *Set) UnionParallel(other *Set) *Set {
+	return unionOp.applyParallel(s, other)
+}
+
+// IntersectParallel is Intersect, with the root sub-trees combined in
+// parallel. Like Intersect, the returned Set shares every sub-tree it can with
+// the receiver Set and the argument Set.
+func (s *Set) IntersectParallel(other *Set) *Set {
+	return intersectOp.applyParallel(s, other)
+}
+
+// applyParallel() is apply(), with the nodes under each slot of the root
+// tables combined in their own goroutine.
+func (op setOp) applyParallel(a, b *Set) *Set {
+	op.layout = a.layout()
+	b = withLayout(b, op.layout)
+
+	var nodes = make([]nodeI, op.layout.IndexLimit())
+	var deltas = make([]int, op.layout.IndexLimit())
+
+	var wg sync.WaitGroup
+	for idx := range nodes {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			nodes[idx], deltas[idx] = op.combine(a.root.get(uint(idx)),
+				b.root.get(uint(idx)), 1)
+		}(idx)
+	}
+	wg.Wait()
+
+	var ns = newSet(op.layout)
+	ns.numEnts = a.numEnts
+	for idx, n := range nodes {
+		if n != nil {
+			ns.root.insertInplace(uint(idx), n)
+		}
+		ns.numEnts += deltas[idx]
+	}
+	return ns
+}
+
+// buildParallel() returns a new Set built from s by calling fn, in its own
+// goroutine, for the sub-tree under each slot of the root table. fn is given a
+// Transient holding only that sub-tree, and MUST only add keys with the Index
+// idx at depth 0.
+func buildParallel(s *Set, fn func(idx uint, t *Transient)) *Set {
+	var l = s.layout()
+	var parts = make([]*Set, l.IndexLimit())
+
+	var wg sync.WaitGroup
+	for idx := range parts {
+		wg.Add(1)
+		go func(idx uint) {
+			defer wg.Done()
+			// The part starts with no entries, so its numEnts ends up being
+			// the change in the number of entries of the sub-tree.
+			var part = newSet(l)
+			if n := s.root.get(idx); n != nil {
+				part.root.insertInplace(idx, n)
+			}
+			var t = part.Transient()
+			fn(idx, t)
+			parts[idx] = t.Persistent()
+		}(uint(idx))
+	}
+	wg.Wait()
+
+	var ns = newSet(l)
+	ns.numEnts = s.numEnts
+	for idx, part := range parts {
+		if n := part.root.get(uint(idx)); n != nil {
+			ns.root.insertInplace(uint(idx), n)
+		}
+		ns.numEnts += part.numEnts
+	}
+	return ns
+}
+
+// partitionKeys() splits keys into one chunk per CPU, and each chunk by the
+// Index of the keys at depth 0, hashing the chunks in parallel;
+// chunks[c][idx] holds the keys of chunk c with the Index idx.
+func partitionKeys(keys []key.Hash, l hash.Layout) [][][]key.Hash {
+	var numChunks = runtime.GOMAXPROCS(0)
+	var chunkSize = (len(keys) + numChunks - 1) / numChunks
+	if chunkSize == 0 {
+		return nil
+	}
+
+	var chunks [][][]key.Hash
+	var wg sync.WaitGroup
+	for lo := 0; lo < len(keys); lo += chunkSize {
+		var hi = lo + chunkSize
+		if hi > len(keys) {
+			hi = len(keys)
+		}
+		var parts = make([][]key.Hash, l.IndexLimit())
+		chunks = append(chunks, parts)
+		wg.Add(1)
+		go func(keys []key.Hash) {
+			defer wg.Done()
+			for _, k := range keys {
+				var idx = l.Index(k.Hash(), 0)
+				parts[idx] = append(parts[idx], k)
+			}
+		}(keys[lo:hi])
+	}
+	wg.Wait()
+	return chunks
+}
+
+// nodeKeys() returns every key held by the node n, at the given depth, which
+// may be nil.
+func nodeKeys(n nodeI, depth uint) []key.Hash {
+	if n == nil {
+		return nil
+	}
+	var keys []key.Hash
+	n.walkPreOrder(func(n nodeI, depth uint) bool {
+		if leaf, isLeaf := n.(leafI); isLeaf {
+			keys = append(keys, leaf.keys()...)
+		}
+		return true
+	}, depth)
+	return keys
+}
@@ -26,19 +26,23 @@ import (
 	"github.com/lleo/go-functional-collections/key/hash"
 )
 
-// downgradeThreshold is the constant that sets the threshold for the size of a
-// table, such that when a table decreases to the threshold size, the table is
-// converted from a fixedTable to a sparseTable.
-// downgradeThreshold = 8 for hash.numIndexBits=4 aka hash.IndexLimit=16
-// downgradeThreshold = 16 for hash.numIndexBits=5 aka hash.IndexLimit=32
-const downgradeThreshold uint = hash.IndexLimit / 2
-
-// upgradeThreshold is the constant that sets the threshold for the size of a
-// table, such that when a table increases to the threshold size, the table is
+// downgradeThreshold returns the threshold for the size of a table of the
+// given Layout, such that when a table decreases to the threshold size, the
+// table is converted from a fixedTable to a sparseTable.
+// downgradeThreshold = 8 for 16 slot tables, aka hash.DefaultLayout
+// downgradeThreshold = 16 for 32 slot tables
+func downgradeThreshold(l hash.Layout) uint {
+	return l.IndexLimit() / 2
+}
+
+// upgradeThreshold returns the threshold for the size of a table of the given
+// Layout, such that when a table increases to the threshold size, the table is
 // converted from a sparseTable to a fixedTable.
-// upgradeThreshold = 10 for hash.numIndexBits=4 aka hash.IndexLimit=16
-// upgradeThreshold = 20 for hash.numIndexBits=5 aka hash.IndexLimit=32
-const upgradeThreshold uint = hash.IndexLimit * 5 / 8
+// upgradeThreshold = 10 for 16 slot tables, aka hash.DefaultLayout
+// upgradeThreshold = 20 for 32 slot tables
+func upgradeThreshold(l hash.Layout) uint {
+	return l.IndexLimit() * 5 / 8
+}
 
 // Set struct mainains an immutable collection of key.Hash entries.
 type Set struct {
@@ -46,49 +50,106 @@ type Set struct {
 	numEnts int
 }
 
+// Options are the settings of a new Set.
+type Options struct {
+	// TableWidth is the number of slots of each table of the HAMT; 16, 32 or
+	// 64. Wider tables make the HAMT shallower, so lookups visit fewer tables,
+	// but every modification copies larger tables. The zero value means 16.
+	TableWidth int
+}
+
 // New returns a properly initialized pointer to a Set struct.
 func New() *Set {
+	return newSet(hash.DefaultLayout)
+}
+
+// NewWithOptions returns a new empty Set with the given Options. It panics if
+// the Options are invalid.
+//
+// The Sets derived from the new Set, by Add, Union, Transient and so on, keep
+// its Options.
+func NewWithOptions(opts Options) *Set {
+	var l = hash.DefaultLayout
+	if opts.TableWidth != 0 {
+		var valid bool
+		if l, valid = hash.LayoutOf(opts.TableWidth); !valid {
+			panic(fmt.Sprintf("set.NewWithOptions: invalid TableWidth,%d",
+				opts.TableWidth))
+		}
+	}
+	return newSet(l)
+}
+
+func newSet(l hash.Layout) *Set {
 	var s = new(Set)
-	s.root = newRootTable()
+	s.root = newRootTable(l)
 	return s
 }
 
-func newRootTable() tableI {
+// Options returns the Options the Set was created with.
+func (s *Set) Options() Options {
+	return Options{TableWidth: int(s.layout().IndexLimit())}
+}
+
+// layout() returns the hash.Layout of every table of the Set.
+func (s *Set) layout() hash.Layout {
+	if s.root == nil {
+		return hash.DefaultLayout
+	}
+	return tableLayout(s.root)
+}
+
+func tableLayout(t tableI) hash.Layout {
+	switch x := t.(type) {
+	case *fixedTable:
+		return x.layout
+	case *sparseTable:
+		return x.layout
+	}
+	panic("tableLayout(): unknown table type")
+}
+
+func newRootTable(l hash.Layout) tableI {
 	// fixedTable at root makes a noticable perf diff on small & large Maps.
-	return newFixedTable(0, 0)
-	//return newSparseTable(0, 0, 0)
+	return newFixedTable(0, 0, l)
+	//return newSparseTable(0, 0, 0, l)
 }
 
 // FIXME: generic version of newSparseTable & newFixedTable
-func newTable(depth uint, hashVal hash.Val) tableI {
-	//return newFixedTable(depth, hashVal)
-	return newSparseTable(depth, hashVal, 0)
+func newTable(depth uint, hashVal hash.Val, l hash.Layout) tableI {
+	//return newFixedTable(depth, hashVal, l)
+	return newSparseTable(depth, hashVal, 0, l)
 }
 
 // FIXME: generic version of createSparseTable & createFixedTable
 // FIXME: This should obviate createSparseTable & createFixedTable.
-func createTable(depth uint, leaf1 leafI, leaf2 *flatLeaf) tableI {
+func createTable(
+	depth uint,
+	leaf1 leafI,
+	leaf2 *flatLeaf,
+	l hash.Layout,
+) tableI {
 	if assertOn {
 		assert(depth > 0, "createTable(): depth < 1")
-		assertf(leaf1.hash().HashPath(depth) == leaf2.hash().HashPath(depth),
+		assertf(l.HashPath(leaf1.hash(), depth) == l.HashPath(leaf2.hash(), depth),
 			"createTable(): hp1,%s != hp2,%s",
-			leaf1.hash().HashPath(depth),
-			leaf2.hash().HashPath(depth))
+			l.HashPath(leaf1.hash(), depth),
+			l.HashPath(leaf2.hash(), depth))
 	}
 
-	var retTable = newTable(depth, leaf1.hash())
+	var retTable = newTable(depth, leaf1.hash(), l)
 
-	var idx1 = leaf1.hash().Index(depth)
-	var idx2 = leaf2.hash().Index(depth)
+	var idx1 = l.Index(leaf1.hash(), depth)
+	var idx2 = l.Index(leaf2.hash(), depth)
 	if idx1 != idx2 {
 		retTable.insertInplace(idx1, leaf1)
 		retTable.insertInplace(idx2, leaf2)
 	} else { // idx1 == idx2
 		var node nodeI
-		if depth == hash.MaxDepth {
+		if depth == l.MaxDepth() {
 			node = newCollisionLeaf(append(leaf1.keys(), leaf2.keys()...))
 		} else {
-			node = createTable(depth+1, leaf1, leaf2)
+			node = createTable(depth+1, leaf1, leaf2, l)
 		}
 		retTable.insertInplace(idx1, node)
 	}
@@ -114,12 +175,13 @@ func (s *Set) IsSet(key key.Hash) bool {
 
 	var hv = key.Hash()
 	var curTable tableI = s.root
+	var l = s.layout()
 
 	var found bool
 
 DepthIter:
-	for depth := uint(0); depth <= hash.MaxDepth; depth++ {
-		var idx = hv.Index(depth)
+	for depth, maxDepth := uint(0), l.MaxDepth(); depth <= maxDepth; depth++ {
+		var idx = l.Index(hv, depth)
 		var curNode = curTable.get(idx) //nodeI
 
 		switch n := curNode.(type) {
@@ -143,15 +205,16 @@ DepthIter:
 //func (m *Set) find(hv hash.Val) (*tableStack, tableI, uint) {
 func (s *Set) find(hv hash.Val) (*tableStack, leafI, uint) {
 	var curTable tableI = s.root
+	var l = s.layout()
 
 	var path = newTableStack()
 	var leaf leafI
 	var idx uint
 
 DepthIter:
-	for depth := uint(0); depth <= hash.MaxDepth; depth++ {
+	for depth, maxDepth := uint(0), l.MaxDepth(); depth <= maxDepth; depth++ {
 		path.push(curTable)
-		idx = hv.Index(depth)
+		idx = l.Index(hv, depth)
 		var curNode = curTable.get(idx)
 
 		switch n := curNode.(type) {
@@ -188,7 +251,7 @@ func (s *Set) persist(oldTable, newTable tableI, path *tableStack) {
 	var depth = uint(path.len()) //guaranteed depth > 0
 	var parentDepth = depth - 1
 
-	var parentIdx = oldTable.hash().Index(parentDepth)
+	var parentIdx = s.layout().Index(oldTable.hash(), parentDepth)
 
 	var oldParent = path.pop()
 	var newParent tableI
@@ -243,10 +306,10 @@ func (s *Set) Add(key key.Hash) (*Set, bool) {
 	} else {
 		// This only happens when depth == MaxDepth
 		var node nodeI
-		if leaf.hash() != hv {
+		if l := s.layout(); !l.Collide(leaf.hash(), hv) {
 			// common case
-			//node = createSparseTable(depth+1, leaf, newFlatLeaf(key))
-			node = createTable(depth+1, leaf, newFlatLeaf(key))
+			//node = createSparseTable(depth+1, leaf, newFlatLeaf(key), l)
+			node = createTable(depth+1, leaf, newFlatLeaf(key), l)
 			added = true
 		} else {
 			node, added = leaf.put(key)
@@ -443,7 +506,14 @@ func (s *Set) DeepCopy() *Set {
 	return ns
 }
 
-// Equiv compares two *Set's by value.
+// Equiv compares two *Set's by value. As they hold as many keys, they are
+// equivalent if one is a subset of the other, so equal Sets built
+// differently, whose tables and leaves differ in shape, are still equivalent.
+// Only the tables the two Sets share are taken to be equal without being
+// visited; equal digests, cached by RootDigest, prove nothing, see the merkle
+// package.
+//
+// Sets with different Options are compared key by key.
 func (s *Set) Equiv(s0 *Set) bool {
 	//log.Printf("Set#Equiv: s.NumEntries(),%d != s0.NumEntries(),%d",
 	//	s.NumEntries(), s0.NumEntries())
@@ -451,14 +521,19 @@ func (s *Set) Equiv(s0 *Set) bool {
 	if s.NumEntries() != s0.NumEntries() {
 		return false
 	}
-	if !s.root.equiv(s0.root) {
-		return false
+	if s.layout() != s0.layout() {
+		var equiv = true
+		s.Range(func(k key.Hash) bool {
+			equiv = s0.IsSet(k)
+			return equiv
+		})
+		return equiv
 	}
-	return true
-	//return s.NumEntries() == s0.NumEntries() && s.root.equiv(s0.root)
+	return subset(s.root, s0.root, 0)
 }
 
-// Count recursively traverses the HAMT data structure to count every key.
+// Count returns the number of keys held by the root table of the HAMT, as
+// every table keeps count of the keys under it. It always equals NumEntries.
 func (s *Set) Count() int {
 	return s.root.count()
 }
@@ -468,94 +543,15 @@ func (s *Set) Count() int {
 //
 // NewFromList is implemented more efficiently than repeated calls to Add.
 func NewFromList(keys []key.Hash) *Set {
-	var s = New()
-	for _, k := range keys {
-		var hv = k.Hash()
-		var path, leaf, idx = s.find(hv)
-		var curTable = path.pop()
-		var depth = uint(path.len())
-		var added bool
-		if leaf == nil {
-			curTable.insertInplace(idx, newFlatLeaf(k))
-			//var _, isSparseTable = curTable.(*sparseTable)
-			//if isSparseTable && curTable.slotsUsed() == upgradeThreshold {
-			//if curTable.slotsUsed() == upgradeThreshold {
-			if curTable.needsUpgrade() {
-				var newTable = curTable.upgrade()
-				s.persist(newTable, curTable, path)
-			}
-			added = true
-		} else {
-			var node nodeI
-			if leaf.hash() != hv {
-				//node = createSparseTable(depth+1, leaf, newFlatLeaf(k))
-				node = createTable(depth+1, leaf, newFlatLeaf(k))
-				added = true
-			} else {
-				node, added = leaf.put(k)
-			}
-			curTable.replaceInplace(idx, node)
-		}
-		if added {
-			s.numEnts++
-		}
-	}
-	return s
+	return newFromList(keys, hash.DefaultLayout)
 }
 
-func insertPersist(
-	s *Set,
-	isOrigTable map[tableI]bool,
-	k key.Hash,
-) {
-	var hv = k.Hash()
-	var path, leaf, idx = s.find(hv)
-	var curTable = path.pop()
-	var depth = uint(path.len())
-	var added bool
-	if isOrigTable[curTable] {
-		var newTable tableI
-		if leaf == nil {
-			newTable = curTable.insert(idx, newFlatLeaf(k))
-			added = true
-		} else {
-			var node nodeI
-			if leaf.hash() != hv {
-				//node = createSparseTable(depth+1, leaf, newFlatLeaf(k))
-				node = createTable(depth+1, leaf, newFlatLeaf(k))
-				added = true
-			} else {
-				node, added = leaf.put(k)
-			}
-			newTable = curTable.replace(idx, node)
-		}
-		s.persist(curTable, newTable, path)
-	} else {
-		if leaf == nil {
-			curTable.insertInplace(idx, newFlatLeaf(k))
-			//var _, isSparseTable = curTable.(*sparseTable)
-			//if isSparseTable && curTable.slotsUsed() == upgradeThreshold {
-			//if curTable.slotsUsed() == upgradeThreshold {
-			if curTable.needsUpgrade() {
-				var newTable = curTable.upgrade()
-				s.persist(curTable, newTable, path)
-			}
-			added = true
-		} else {
-			var node nodeI
-			if leaf.hash() != hv {
-				//node = createSparseTable(depth+1, leaf, newFlatLeaf(k))
-				node = createTable(depth+1, leaf, newFlatLeaf(k))
-				added = true
-			} else {
-				node, added = leaf.put(k)
-			}
-			curTable.replaceInplace(idx, node)
-		}
-	}
-	if added {
-		s.numEnts++
+func newFromList(keys []key.Hash, l hash.Layout) *Set {
+	var t = newSet(l).Transient()
+	for _, k := range keys {
+		t.Add(k)
 	}
+	return t.Persistent()
 }
 
 // BulkInsert stores all the given keys from the argument key.Hash slice  into
@@ -566,88 +562,33 @@ func insertPersist(
 //
 // BulkInsert is implemented more efficiently than repeated calls to Add.
 func (s *Set) BulkInsert(keys []key.Hash) *Set {
-	var isOrigTable = make(map[tableI]bool)
-	s.walkPreOrder(func(n nodeI, depth uint) bool {
-		if t, isTable := n.(tableI); isTable {
-			isOrigTable[t] = true
-		}
-		return true
-	})
-
-	var ns = s.copy()
+	var t = s.Transient()
 	for _, k := range keys {
-		insertPersist(ns, isOrigTable, k)
+		t.Add(k)
 	}
-	return ns
+	return t.Persistent()
 }
 
 // Merge returns a Set that contains all the entries from the receiver Set and
-// the argument Set.
+// the argument Set. If the Sets have different Options, the returned Set keeps
+// those of the receiver Set.
 func (s *Set) Merge(other *Set) *Set {
+	if s.layout() != other.layout() {
+		return s.BulkInsert(other.Keys())
+	}
+
 	var big, sml = s, other
 	if s.NumEntries() < other.NumEntries() {
 		big, sml = other, s
 	}
 	// big is bigger then sml
 
-	var isOrigTable = make(map[tableI]bool)
-	big.walkPreOrder(func(n nodeI, depth uint) bool {
-		if t, isTable := n.(tableI); isTable {
-			isOrigTable[t] = true
-		}
-		return true
-	})
-
-	var ns = big.copy()
+	var t = big.Transient()
 	var it = sml.Iter()
 	for k := it.Next(); k != nil; k = it.Next() {
-		insertPersist(ns, isOrigTable, k)
+		t.Add(k)
 	}
-	return ns
-}
-
-func removePersist(
-	s *Set,
-	isOrigTable map[tableI]bool,
-	k key.Hash,
-) bool {
-	var hv = k.Hash()
-	var path, leaf, idx = s.find(hv)
-	if leaf == nil {
-		return false // did not remove k
-	}
-	var newLeaf, found = leaf.del(k)
-	if !found {
-		return false // did not remove k
-	}
-	s.numEnts--
-	var curTable = path.pop()
-	if isOrigTable[curTable] {
-		var newTable tableI
-		if newLeaf == nil {
-			newTable = curTable.remove(idx)
-		} else {
-			newTable = curTable.replace(idx, newLeaf)
-		}
-		s.persist(curTable, newTable, path)
-	} else {
-		if newLeaf == nil {
-			if curTable.slotsUsed()-1 > 0 {
-				curTable.removeInplace(idx)
-				if curTable.needsDowngrade() {
-					var newTable = curTable.downgrade()
-					s.persist(curTable, newTable, path)
-				}
-			} else { // curTable.slotsUsed()-1 <= 0
-				// we need to use persist cuz this will shrink empty tables
-				var newTable = curTable.remove(idx)
-				s.persist(curTable, newTable, path)
-			}
-		} else {
-			curTable.replaceInplace(idx, newLeaf)
-		}
-	}
-	return true // removed k
+	return t.Persistent()
 }
 
 // BulkDelete removes all the keys in the given key.Hash slice. It returns a new
@@ -655,23 +596,14 @@ func removePersist(
 //
 // BulkDelete is implemented more efficiently than repeated calls to Remove.
 func (s *Set) BulkDelete(keys []key.Hash) (*Set, []key.Hash) {
-	var isOrigTable = make(map[tableI]bool)
-	s.walkPreOrder(func(n nodeI, depth uint) bool {
-		if t, isTable := n.(tableI); isTable {
-			isOrigTable[t] = true
-		}
-		return true
-	})
-
 	var notFound []key.Hash
-	var ns = s.copy()
-	//KEYSLOOP:
+	var t = s.Transient()
 	for _, k := range keys {
-		if !removePersist(ns, isOrigTable, k) {
+		if !t.Remove(k) {
 			notFound = append(notFound, k)
 		}
 	}
-	return ns, notFound
+	return t.Persistent(), notFound
 }
 
 // BulkDelete2 removes all the keys in the given key.Hash slice. It returns a
@@ -679,20 +611,11 @@ func (s *Set) BulkDelete(keys []key.Hash) (*Set, []key.Hash) {
 //
 // BulkDelete2 is implemented more efficiently than repeated calls to Remove.
 func (s *Set) BulkDelete2(keys []key.Hash) *Set {
-	var isOrigTable = make(map[tableI]bool)
-	s.walkPreOrder(func(n nodeI, depth uint) bool {
-		if t, isTable := n.(tableI); isTable {
-			isOrigTable[t] = true
-		}
-		return true
-	})
-
-	var ns = s.copy()
-	//KEYSLOOP:
+	var t = s.Transient()
 	for _, k := range keys {
-		removePersist(ns, isOrigTable, k)
+		t.Remove(k)
 	}
-	return ns
+	return t.Persistent()
 }
 
 // Union returns a Set that contains all entries of all given Sets.
@@ -719,30 +642,24 @@ func Union(sets ...*Set) *Set {
 	})
 	// sets is now sorted from largest to smallest
 
-	var resultSet = sets[0].copy()
-	var rest = sets[1:]
-
-	var isOrigTable = make(map[tableI]bool)
-	resultSet.walkPreOrder(func(n nodeI, depth uint) bool {
-		if t, isTable := n.(tableI); isTable {
-			isOrigTable[t] = true
-		}
-		return true
-	})
-
-	for _, s := range rest {
-		var it = s.Iter()
-		for k := it.Next(); k != nil; k = it.Next() {
-			insertPersist(resultSet, isOrigTable, k)
-		}
+	var res = sets[0] // largest
+	for _, s := range sets[1:] {
+		res = res.Union(s)
 	}
-	return resultSet
+	return res
 }
 
 // Union returns a Set that contains all entries for the receiver Set and the
 // argument Set.
+//
+// Union walks the HAMTs of both Sets at once, and the returned Set shares
+// every sub-tree it can with the receiver Set and the argument Set.
+//
+// Like every operation of the set algebra, the returned Set keeps the Options
+// of the receiver Set. An argument Set with other Options is rebuilt with
+// those first, so it shares no sub-tree with the returned Set.
 func (s *Set) Union(other *Set) *Set {
-	return s.Merge(other)
+	return unionOp.apply(s, other)
 }
 
 // Intersection returns a Set that is the Intersection  of all the given sets.
@@ -779,55 +696,86 @@ func Intersection(sets ...*Set) *Set {
 // Intersect returns a Set that contains only the entries that the receiver Set
 // and the argument Set have in common.
 //
-// There is no structural sharing with either the receiver Set or argument Set.
+// Intersect walks the HAMTs of both Sets at once, and the returned Set shares
+// every sub-tree it can with the receiver Set and the argument Set.
 func (s *Set) Intersect(other *Set) *Set {
-	var intersectKeys []key.Hash
-	var it = other.Iter()
-	for k := it.Next(); k != nil; k = it.Next() {
-		if s.IsSet(k) {
-			intersectKeys = append(intersectKeys, k)
-		}
-	}
-	return NewFromList(intersectKeys)
+	return intersectOp.apply(s, other)
 }
 
 // Difference returns a new Set based on the receiver Set that contains none of
 // the entries from the argument Set.
 //
-// Difference is implemented by repeated calls to Unset.
+// Difference walks the HAMTs of both Sets at once, and the returned Set shares
+// every sub-tree it can with the receiver Set.
 //
 // NOTE: a.Difference(b) != b.Difference(a)
 func (s *Set) Difference(other *Set) *Set {
-	var diffSet = s
-	var it = other.Iter()
-	for k := it.Next(); k != nil; k = it.Next() {
-		diffSet = diffSet.Unset(k)
+	return differenceOp.apply(s, other)
+}
+
+// SymmetricDifference returns a new Set that contains the entries of either
+// the receiver Set or the argument Set, but not of both.
+//
+// SymmetricDifference walks the HAMTs of both Sets at once, and the returned
+// Set shares every sub-tree it can with the receiver Set and the argument Set.
+func (s *Set) SymmetricDifference(other *Set) *Set {
+	return symDifferenceOp.apply(s, other)
+}
+
+// IsSubset returns true if every entry of the receiver Set is in the argument
+// Set. Sub-trees shared by both Sets are not visited.
+func (s *Set) IsSubset(other *Set) bool {
+	if s.NumEntries() > other.NumEntries() {
+		return false
+	}
+	if s.layout() != other.layout() {
+		var isSubset = true
+		s.Range(func(k key.Hash) bool {
+			isSubset = other.IsSet(k)
+			return isSubset
+		})
+		return isSubset
 	}
-	return diffSet
+	return subset(s.root, other.root, 0)
+}
+
+// IsSuperset returns true if every entry of the argument Set is in the
+// receiver Set. Sub-trees shared by both Sets are not visited.
+func (s *Set) IsSuperset(other *Set) bool {
+	return other.IsSubset(s)
+}
+
+// IsDisjoint returns true if the receiver Set and the argument Set have no
+// entries in common. It stops at the first sub-tree shared by both Sets.
+func (s *Set) IsDisjoint(other *Set) bool {
+	if s.NumEntries() == 0 || other.NumEntries() == 0 {
+		return true
+	}
+	if s.layout() != other.layout() {
+		var isDisjoint = true
+		s.Range(func(k key.Hash) bool {
+			isDisjoint = !other.IsSet(k)
+			return isDisjoint
+		})
+		return isDisjoint
+	}
+	return disjoint(s.root, other.root, 0)
 }
 
 // // Difference2 returns a new Set based on the receiver Set that contains none of
 // // the entries from the argument Set.
 // //
-// // Difference2 is implemented by repeated calls to removePersist, the basic
-// // function of BulkDelete.
+// // Difference2 is implemented by repeated calls to Transient.Remove, the
+// // basic function of BulkDelete.
 // //
 // // NOTE: a.Difference2(b) != b.Difference2(a)
 // func (s *Set) Difference2(other *Set) *Set {
-// 	var isOrigTable = make(map[tableI]bool)
-// 	s.walkPreOrder(func(n nodeI, depth uint) bool {
-// 		if t, isTable := n.(tableI); isTable {
-// 			isOrigTable[t] = true
-// 		}
-// 		return true
-// 	})
-//
-// 	var diffSet = s.copy()
+// 	var t = s.Transient()
 // 	var it = other.Iter()
 // 	for k := it.Next(); k != nil; k = it.Next() {
-// 		removePersist(diffSet, isOrigTable, k)
+// 		t.Remove(k)
 // 	}
-// 	return diffSet
+// 	return t.Persistent()
 // }
 
 // // Difference1 returns a new Set based on the receiver Set that contains none of
@@ -855,97 +803,3 @@ func (s *Set) Difference(other *Set) *Set {
 // 	var diff = s.BulkDelete2(otherKeys)
 // 	return diff
 // }
-
-//type Stats struct {
-//	DeepestKeys struct {
-//		Keys  []key.Hash
-//		Depth uint
-//	}
-//
-//	// Depth of deepest table
-//	MaxDepth uint
-//
-//	// TableCountsByNumEntries is a Hash table of the number of tables with each
-//	// given number of entries in the tatble. There are slots for
-//	// [0..IndexLimit] inclusive (so there are IndexLimit+1 slots). Technically,
-//	// there should never be a table with zero entries, but I allow counting
-//	// tables with zero entries just to catch those errors.
-//	// [0..IndexLimit] inclusive
-//	TableCountsByNumEntries [hash.IndexLimit + 1]uint
-//
-//	// TableCountsByDepth is a Hash table of the number of tables at a given
-//	// depth. There are slots for [0..DepthLimit).
-//	// [0..DepthLimit)
-//	TableCountsByDepth [hash.DepthLimit]uint
-//
-//	// Nodes is the total count of nodeI capable structs in the Set.
-//	Nodes uint
-//
-//	// Tables is the total count of tableI capable structs in the Set.
-//	Tables uint
-//
-//	// Leafs is the total count of leafI capable structs in the Set.
-//	Leafs uint
-//
-//	// FixedTables is the total count of fixedTable structs in the Set.
-//	FixedTables uint
-//
-//	// SparseTables is the total count of sparseTable structs in the Set.
-//	SparseTables uint
-//
-//	// FlatLeafs is the total count of flatLeaf structs in the Set.
-//	FlatLeafs uint
-//
-//	// CollisionLeafs is the total count of collisionLeaf structs in the Set.
-//	CollisionLeafs uint
-//
-//	// Keys is the total number of Keys in the Set.
-//	Keys uint
-//}
-//
-//// Stats walks the Hamt in a pre-order traversal and populates a Stats data
-//// struture which it returns.
-//func (s *Set) Stats() *Stats {
-//	var stats = new(Stats)
-//
-//	// statFn closes over the stats variable
-//	var statFn = func(n nodeI, depth uint) bool {
-//		var keepOn = true
-//		switch x := n.(type) {
-//		case *fixedTable:
-//			stats.Nodes++
-//			stats.Tables++
-//			stats.FixedTables++
-//			stats.TableCountsByNumEntries[x.slotsUsed()]++
-//			stats.TableCountsByDepth[x.depth]++
-//			if x.depth > stats.MaxDepth {
-//				stats.MaxDepth = x.depth
-//			}
-//		case *sparseTable:
-//			stats.Nodes++
-//			stats.Tables++
-//			stats.SparseTables++
-//			stats.TableCountsByNumEntries[x.slotsUsed()]++
-//			stats.TableCountsByDepth[x.depth]++
-//			if x.depth > stats.MaxDepth {
-//				stats.MaxDepth = x.depth
-//			}
-//		case *flatLeaf:
-//			stats.Nodes++
-//			stats.Leafs++
-//			stats.FlatLeafs++
-//			stats.Keys += 1
-//			keepOn = false
-//		case *collisionLeaf:
-//			stats.Nodes++
-//			stats.Leafs++
-//			stats.CollisionLeafs++
-//			stats.Keys += uint(len(x.keys_))
-//			keepOn = false
-//		}
-//		return keepOn
-//	}
-//
-//	s.walk(statFn)
-//	return stats
-//}
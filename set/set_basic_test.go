@@ -1,10 +1,16 @@
 package set_test
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
 	"sort"
+	"sync/atomic"
 	"testing"
 
 	"github.com/lleo/go-functional-collections/key"
+	"github.com/lleo/go-functional-collections/key/hash"
 	"github.com/lleo/go-functional-collections/set"
 )
 
@@ -429,3 +435,676 @@ func TestBasicDifference(t *testing.T) {
 //		t.Fatal("!copySetB.Equiv(setB)")
 //	}
 //}
+
+func TestBasicTransient(t *testing.T) {
+	var keys = buildKeys(10000)
+	var origSet = set.NewFromList(keys[:5000])
+	var copySet = origSet.DeepCopy()
+
+	var tr = origSet.Transient()
+	for _, k := range keys[5000:] {
+		if !tr.Add(k) {
+			t.Fatalf("tr.Add(%s) did not add a new key", k)
+		}
+	}
+	if tr.Add(keys[0]) {
+		t.Fatalf("tr.Add(%s) added an existing key", keys[0])
+	}
+	for _, k := range keys[:2500] {
+		if !tr.Remove(k) {
+			t.Fatalf("tr.Remove(%s) did not find the key", k)
+		}
+	}
+	if tr.Remove(keys[0]) {
+		t.Fatalf("tr.Remove(%s) found a removed key", keys[0])
+	}
+
+	if tr.NumEntries() != 7500 {
+		t.Fatalf("tr.NumEntries(),%d != 7500", tr.NumEntries())
+	}
+
+	var s = tr.Persistent()
+
+	// the Transient is still usable, but does not change s
+	tr.Set(keys[0]).Unset(keys[9999])
+	if !tr.IsSet(keys[0]) || tr.IsSet(keys[9999]) {
+		t.Fatal("tr was not modified after tr.Persistent()")
+	}
+
+	if s.NumEntries() != 7500 || s.Count() != 7500 {
+		t.Fatalf("s.NumEntries(),%d or s.Count(),%d != 7500",
+			s.NumEntries(), s.Count())
+	}
+	for i, k := range keys {
+		if s.IsSet(k) != (i >= 2500) {
+			t.Fatalf("s.IsSet(%s) != %t", k, i >= 2500)
+		}
+	}
+
+	if !origSet.Equiv(copySet) {
+		t.Fatal("origSet != copySet after Transient modifications")
+	}
+}
+
+func TestBasicTransientRemoveAll(t *testing.T) {
+	var keys = buildKeys(3000)
+	var origSet = set.NewFromList(keys)
+	var copySet = origSet.DeepCopy()
+
+	var tr = origSet.Transient()
+	for _, k := range randomizeKeys(keys) {
+		tr.Unset(k)
+	}
+
+	var s = tr.Persistent()
+	if s.NumEntries() != 0 || s.Count() != 0 {
+		t.Fatalf("s.NumEntries(),%d or s.Count(),%d != 0",
+			s.NumEntries(), s.Count())
+	}
+	if !s.Equiv(set.New()) {
+		t.Fatal("s is not equivalent to an empty Set")
+	}
+
+	if !origSet.Equiv(copySet) {
+		t.Fatal("origSet != copySet after Transient modifications")
+	}
+}
+
+func TestBasicMarshalBinary(t *testing.T) {
+	var s0 = set.NewFromList(buildKeys(10000)).
+		Set(key.Int(42)).
+		Set(key.ByteSlice("bytes"))
+
+	var data, err = s0.MarshalBinary()
+	if err != nil {
+		t.Fatalf("s0.MarshalBinary() failed: %s", err)
+	}
+
+	var s1 = set.New()
+	if err = s1.UnmarshalBinary(data); err != nil {
+		t.Fatalf("s1.UnmarshalBinary() failed: %s", err)
+	}
+	if s1.NumEntries() != s0.NumEntries() || s1.Count() != s0.NumEntries() {
+		t.Fatalf("s1.NumEntries(),%d or s1.Count(),%d != %d",
+			s1.NumEntries(), s1.Count(), s0.NumEntries())
+	}
+	s0.Range(func(k key.Hash) bool {
+		if !s1.IsSet(k) {
+			t.Fatalf("!s1.IsSet(%s)", k)
+		}
+		return true
+	})
+
+	var s2 = set.New()
+	if err = s2.UnmarshalBinary([]byte("FSET\x01\x00")); err != nil ||
+		s2.NumEntries() != 0 {
+		t.Fatalf("s2.UnmarshalBinary() of an empty Set: err=%v, %d entries",
+			err, s2.NumEntries())
+	}
+	if err = s2.UnmarshalBinary(data[:len(data)-1]); err == nil {
+		t.Fatal("s2.UnmarshalBinary() of truncated data did not fail")
+	}
+}
+
+func TestBasicJSON(t *testing.T) {
+	var s0 = set.New().
+		Set(key.Str("a")).
+		Set(key.Int(2)).
+		Set(key.ByteSlice("a"))
+
+	var data, err = json.Marshal(s0)
+	if err != nil {
+		t.Fatalf("json.Marshal(s0) failed: %s", err)
+	}
+
+	var s1 = set.New()
+	if err = json.Unmarshal(data, s1); err != nil {
+		t.Fatalf("json.Unmarshal(%s) failed: %s", data, err)
+	}
+	if !s1.Equiv(s0) || !s1.IsSet(key.ByteSlice("a")) {
+		t.Fatalf("s1,%s != s0,%s", s1, s0)
+	}
+
+	data, err = json.Marshal(set.New())
+	if err != nil || string(data) != "[]" {
+		t.Fatalf("json.Marshal(set.New()),%s,%v != [],nil", data, err)
+	}
+}
+
+func TestBasicRootDigest(t *testing.T) {
+	var keys = buildKeys(10000)
+
+	var s0 = set.NewFromList(keys[:5000])
+	var s1 = set.New()
+	for _, k := range randomizeKeys(keys) {
+		s1 = s1.Set(k)
+	}
+	for _, k := range keys[5000:] {
+		s1 = s1.Unset(k)
+	}
+
+	var d0 = s0.RootDigest()
+	if s1.RootDigest() != d0 {
+		t.Fatal("equal Sets built differently have different RootDigests")
+	}
+	if !s0.Equiv(s1) {
+		t.Fatal("!s0.Equiv(s1) for Sets with equal RootDigests")
+	}
+
+	var s2 = s0.Unset(keys[0])
+	if s2.RootDigest() == d0 || s2.Equiv(s1) {
+		t.Fatal("s2 is equivalent to s1 after a change")
+	}
+	if s2.Set(keys[0]).RootDigest() != d0 {
+		t.Fatal("RootDigest() after undoing the change != s0.RootDigest()")
+	}
+}
+
+// pointKey is a key type without a codec, so its digest is taken from its
+// "%#v" form, which distinct *pointKeys share; they are only Equal to
+// themselves.
+type pointKey struct{ x, y int }
+
+func (k *pointKey) Hash() hash.Val {
+	return hash.Calculate([]byte{byte(k.x), byte(k.y)})
+}
+
+func (k *pointKey) Equals(okey key.Hash) bool {
+	return k == okey
+}
+
+func (k *pointKey) String() string {
+	return fmt.Sprintf("%d,%d", k.x, k.y)
+}
+
+func TestBasicInexactDigests(t *testing.T) {
+	var s0 = set.New().Set(&pointKey{1, 2})
+	var s1 = set.New().Set(&pointKey{1, 2})
+	if s0.RootDigest() != s1.RootDigest() {
+		t.Fatal("the RootDigests of equal *pointKeys differ")
+	}
+	if s0.Equiv(s1) {
+		t.Fatal("s0.Equiv(s1) of distinct pointers with equal digests")
+	}
+}
+
+// collidingKey is a key.Hash whose hash.Val is always 0, like keys crafted
+// by an attacker to collide.
+type collidingKey string
+
+func (k collidingKey) Hash() hash.Val { return 0 }
+
+func (k collidingKey) Equals(okey key.Hash) bool {
+	var ok, isColliding = okey.(collidingKey)
+	return isColliding && k == ok
+}
+
+func (k collidingKey) String() string { return string(k) }
+
+func TestBasicCollisions(t *testing.T) {
+	var s = set.New()
+	var keys []collidingKey
+	for str := "a"; len(str) < 3; str = Inc(str) {
+		var k = collidingKey(str)
+		keys = append(keys, k)
+		s = s.Set(k)
+	}
+	if s.NumEntries() != len(keys) {
+		t.Fatalf("s.NumEntries(),%d != %d", s.NumEntries(), len(keys))
+	}
+	for _, k := range keys {
+		if !s.IsSet(k) {
+			t.Fatalf("!s.IsSet(%q)", k)
+		}
+	}
+	if s.IsSet(collidingKey("zzz")) {
+		t.Fatal("s.IsSet(\"zzz\") for a key never set")
+	}
+
+	var s2 = s.Unset(keys[7]).Set(keys[7])
+	if !s2.Equiv(s) {
+		t.Fatal("!s2.Equiv(s) after unsetting and setting a colliding key")
+	}
+
+	// remove in a different order than the keys were set
+	for i := range keys {
+		var k = keys[(i*7)%len(keys)]
+		var removed bool
+		if s, removed = s.Remove(k); !removed {
+			t.Fatalf("s.Remove(%q) removed nothing", k)
+		}
+		if s.IsSet(k) {
+			t.Fatalf("s.IsSet(%q) for a removed key", k)
+		}
+	}
+	if s.NumEntries() != 0 {
+		t.Fatalf("s.NumEntries(),%d != 0", s.NumEntries())
+	}
+}
+
+func TestBasicStats(t *testing.T) {
+	var keys = buildKeys(10000)
+	var s = set.NewFromList(keys)
+
+	var stats = s.Stats()
+	if stats.Keys != uint(len(keys)) {
+		t.Fatalf("stats.Keys,%d != %d", stats.Keys, len(keys))
+	}
+	if stats.Tables != stats.FixedTables+stats.SparseTables ||
+		stats.Leafs != stats.FlatLeafs+stats.CollisionLeafs ||
+		stats.Nodes != stats.Tables+stats.Leafs {
+		t.Fatalf("inconsistent node counts: %+v", stats)
+	}
+	var byDepth uint
+	for _, n := range stats.TableCountsByDepth {
+		byDepth += n
+	}
+	if byDepth != stats.Tables {
+		t.Fatalf("byDepth,%d != stats.Tables,%d", byDepth, stats.Tables)
+	}
+	if stats.DeepestKeys.Depth != stats.MaxDepth+1 {
+		t.Fatalf("stats.DeepestKeys.Depth,%d != stats.MaxDepth+1,%d",
+			stats.DeepestKeys.Depth, stats.MaxDepth+1)
+	}
+	for _, k := range stats.DeepestKeys.Keys {
+		if !s.IsSet(k) {
+			t.Fatalf("deepest key %s is not in s", k)
+		}
+	}
+	if stats.AvgProbeDepth < 1 ||
+		stats.AvgProbeDepth > float64(stats.DeepestKeys.Depth) {
+		t.Fatalf("stats.AvgProbeDepth,%f", stats.AvgProbeDepth)
+	}
+	if stats.TableBytes == 0 || stats.LeafBytes == 0 {
+		t.Fatalf("stats.TableBytes,%d stats.LeafBytes,%d",
+			stats.TableBytes, stats.LeafBytes)
+	}
+
+	var cs = s.Set(collidingKey("a")).Set(collidingKey("b")).Set(collidingKey("c"))
+	var cstats = cs.Stats()
+	if cstats.MaxCollisions != 3 || cstats.CollisionLeafCountsByNumEntries[3] < 1 {
+		t.Fatalf("cstats.MaxCollisions,%d CollisionLeafCountsByNumEntries,%v",
+			cstats.MaxCollisions, cstats.CollisionLeafCountsByNumEntries)
+	}
+
+	if empty := set.New().Stats(); empty.Keys != 0 || empty.AvgProbeDepth != 0 {
+		t.Fatalf("set.New().Stats(),%+v", empty)
+	}
+}
+
+func TestBasicAtomic(t *testing.T) {
+	var a set.Atomic // the zero value is ready to use
+
+	if a.Load(key.Str("a")) {
+		t.Fatal("a zero Atomic is not empty")
+	}
+	a.Store(key.Str("a"))
+	if !a.LoadOrStore(key.Str("a")) || a.LoadOrStore(key.Str("b")) {
+		t.Fatal("a.LoadOrStore() did not report the keys already set")
+	}
+
+	var snap = a.Snapshot()
+	a.Delete(key.Str("a"))
+	if a.Load(key.Str("a")) || !snap.IsSet(key.Str("a")) {
+		t.Fatal("a.Delete(\"a\") did not delete only from the Atomic")
+	}
+
+	if a.CompareAndSwap(snap, set.New()) {
+		t.Fatal("a.CompareAndSwap() of a stale Snapshot succeeded")
+	}
+	if !a.CompareAndSwap(a.Snapshot(), snap) || a.Snapshot() != snap {
+		t.Fatal("a.CompareAndSwap() of the current Snapshot failed")
+	}
+
+	// concurrent additions are not lost
+	var keys = buildKeys(1000)
+	var as = set.NewAtomic(nil)
+	var done = make(chan struct{})
+	for g := 0; g < 4; g++ {
+		go func(g int) {
+			defer func() { done <- struct{}{} }()
+			for i := g; i < len(keys); i += 4 {
+				as.Store(keys[i])
+			}
+		}(g)
+	}
+	for g := 0; g < 4; g++ {
+		<-done
+	}
+	if as.Snapshot().NumEntries() != len(keys) {
+		t.Fatalf("as.Snapshot().NumEntries(),%d != %d",
+			as.Snapshot().NumEntries(), len(keys))
+	}
+}
+
+func TestBasicParallel(t *testing.T) {
+	var keys = buildKeys(20000)
+
+	var s = set.NewFromListParallel(keys[:10000])
+	if s.NumEntries() != 10000 ||
+		s.RootDigest() != set.NewFromList(keys[:10000]).RootDigest() {
+		t.Fatal("NewFromListParallel() != NewFromList()")
+	}
+	if s.BulkInsertParallel(keys[5000:15000]).RootDigest() !=
+		s.BulkInsert(keys[5000:15000]).RootDigest() {
+		t.Fatal("BulkInsertParallel() != BulkInsert()")
+	}
+
+	var other = set.NewFromList(keys[5000:])
+	var union = s.UnionParallel(other)
+	if union.NumEntries() != len(keys) ||
+		union.RootDigest() != s.Union(other).RootDigest() {
+		t.Fatalf("UnionParallel() != Union(); union.NumEntries(),%d",
+			union.NumEntries())
+	}
+	var inter = other.IntersectParallel(s)
+	if inter.NumEntries() != 5000 ||
+		inter.RootDigest() != s.Intersect(other).RootDigest() {
+		t.Fatalf("IntersectParallel() != Intersect(); inter.NumEntries(),%d",
+			inter.NumEntries())
+	}
+	for _, k := range keys[5000:10000] {
+		if !inter.IsSet(k) {
+			t.Fatalf("inter is missing %s", k)
+		}
+	}
+	if s.NumEntries() != 10000 || other.NumEntries() != 15000 {
+		t.Fatal("the Parallel methods modified their arguments")
+	}
+
+	if set.NewFromListParallel(nil).NumEntries() != 0 ||
+		s.IntersectParallel(set.New()).NumEntries() != 0 {
+		t.Fatal("the Parallel methods of empty Sets are not empty")
+	}
+}
+
+func TestBasicFold(t *testing.T) {
+	var keys = buildKeys(10000)
+	var s = set.NewFromList(keys)
+	var ctx = context.Background()
+
+	var count, err = s.Fold(ctx, 0, 0,
+		func(acc interface{}, k key.Hash) interface{} {
+			return acc.(int) + 1
+		},
+		func(acc0, acc1 interface{}) interface{} {
+			return acc0.(int) + acc1.(int)
+		})
+	if err != nil || count != len(keys) {
+		t.Fatalf("s.Fold() = %v, %v", count, err)
+	}
+
+	var visited int64
+	err = s.ParallelRange(ctx, 4, func(k key.Hash) bool {
+		if !s.IsSet(k) {
+			t.Errorf("s.ParallelRange() visited %s", k)
+		}
+		atomic.AddInt64(&visited, 1)
+		return true
+	})
+	if err != nil || visited != int64(len(keys)) {
+		t.Fatalf("s.ParallelRange() visited %d keys; err=%v", visited, err)
+	}
+
+	var cancelled, cancel = context.WithCancel(ctx)
+	cancel()
+	if count, err = s.Fold(cancelled, 0, 0, nil, nil); err != context.Canceled ||
+		count != nil {
+		t.Fatalf("s.Fold() with a cancelled ctx = %v, %v", count, err)
+	}
+}
+
+// algebraCase is a pair of Sets, and the keys of each.
+type algebraCase struct {
+	name     string
+	a, b     *set.Set
+	aIn, bIn map[key.Hash]bool
+}
+
+// checkAlgebraResult fails the test unless s holds exactly the keys of the
+// universe for which keep returns true.
+func checkAlgebraResult(
+	t *testing.T,
+	what string,
+	s *set.Set,
+	universe []key.Hash,
+	keep func(k key.Hash) bool,
+) {
+	var expected []key.Hash
+	for _, k := range universe {
+		if keep(k) {
+			expected = append(expected, k)
+		}
+		if s.IsSet(k) != keep(k) {
+			t.Fatalf("%s: s.IsSet(%s),%v", what, k, s.IsSet(k))
+		}
+	}
+	if s.NumEntries() != len(expected) || s.Count() != len(expected) {
+		t.Fatalf("%s: s.NumEntries(),%d s.Count(),%d != %d",
+			what, s.NumEntries(), s.Count(), len(expected))
+	}
+	if s.RootDigest() != set.NewFromList(expected).RootDigest() {
+		t.Fatalf("%s: s.RootDigest() != the digest of the expected keys", what)
+	}
+}
+
+func TestBasicAlgebra(t *testing.T) {
+	var rnd = rand.New(rand.NewSource(25))
+
+	var universe = buildKeys(3000)
+	for str := "a"; len(str) < 2; str = Inc(str) {
+		universe = append(universe, collidingKey(str))
+	}
+
+	var pick = func(frac float64) ([]key.Hash, map[key.Hash]bool) {
+		var keys []key.Hash
+		var in = make(map[key.Hash]bool)
+		for _, i := range rnd.Perm(len(universe)) {
+			if rnd.Float64() < frac {
+				keys = append(keys, universe[i])
+				in[universe[i]] = true
+			}
+		}
+		return keys, in
+	}
+
+	var aKeys, aIn = pick(0.5)
+	var a = set.NewFromList(aKeys)
+
+	// b shares most of its structure with a, and, after the Unsets, holds
+	// tables with a single leaf where a holds a deeper table
+	var b = a
+	var bIn = make(map[key.Hash]bool)
+	for k := range aIn {
+		bIn[k] = true
+	}
+	for _, i := range rnd.Perm(len(universe))[:len(universe)/10] {
+		var k = universe[i]
+		if bIn[k] {
+			b = b.Unset(k)
+			delete(bIn, k)
+		} else {
+			b = b.Set(k)
+			bIn[k] = true
+		}
+	}
+
+	var cKeys, cIn = pick(0.3)
+	var c = set.NewFromList(cKeys)
+
+	var cases = []algebraCase{
+		{"a,b", a, b, aIn, bIn},
+		{"b,a", b, a, bIn, aIn},
+		{"a,c", a, c, aIn, cIn},
+		{"c,b", c, b, cIn, bIn},
+		{"a,a", a, a, aIn, aIn},
+		{"a,empty", a, set.New(), aIn, map[key.Hash]bool{}},
+		{"empty,c", set.New(), c, map[key.Hash]bool{}, cIn},
+	}
+	for _, tc := range cases {
+		var aIn, bIn = tc.aIn, tc.bIn
+		checkAlgebraResult(t, tc.name+" Union", tc.a.Union(tc.b), universe,
+			func(k key.Hash) bool { return aIn[k] || bIn[k] })
+		checkAlgebraResult(t, tc.name+" Intersect", tc.a.Intersect(tc.b),
+			universe,
+			func(k key.Hash) bool { return aIn[k] && bIn[k] })
+		checkAlgebraResult(t, tc.name+" Difference", tc.a.Difference(tc.b),
+			universe,
+			func(k key.Hash) bool { return aIn[k] && !bIn[k] })
+		checkAlgebraResult(t, tc.name+" SymmetricDifference",
+			tc.a.SymmetricDifference(tc.b), universe,
+			func(k key.Hash) bool { return aIn[k] != bIn[k] })
+
+		var isSubset, isDisjoint = true, true
+		for k := range aIn {
+			isSubset = isSubset && bIn[k]
+			isDisjoint = isDisjoint && !bIn[k]
+		}
+		if tc.a.IsSubset(tc.b) != isSubset ||
+			tc.b.IsSuperset(tc.a) != isSubset {
+			t.Fatalf("%s: IsSubset(),%v IsSuperset(),%v != %v", tc.name,
+				tc.a.IsSubset(tc.b), tc.b.IsSuperset(tc.a), isSubset)
+		}
+		if tc.a.IsDisjoint(tc.b) != isDisjoint ||
+			tc.b.IsDisjoint(tc.a) != isDisjoint {
+			t.Fatalf("%s: IsDisjoint() != %v", tc.name, isDisjoint)
+		}
+	}
+
+	var inter = a.Intersect(c)
+	if !inter.IsSubset(a) || !inter.IsSubset(c) || !a.IsSuperset(inter) ||
+		!inter.IsDisjoint(a.SymmetricDifference(c)) {
+		t.Fatal("a.Intersect(c) is not a subset of a and c")
+	}
+	if !a.Equiv(set.NewFromList(aKeys)) || !c.Equiv(set.NewFromList(cKeys)) {
+		t.Fatal("the set algebra modified its operands")
+	}
+
+	var d = set.Union(set.NewFromList(aKeys), b, c)
+	checkAlgebraResult(t, "Union(a, b, c)", d, universe,
+		func(k key.Hash) bool { return aIn[k] || bIn[k] || cIn[k] })
+}
+
+// highKey is a key.Hash whose hash.Val differs from those of the other
+// highKeys only in its top bits, which the 32 and 64 slot tables do not use.
+type highKey uint
+
+func (k highKey) Hash() hash.Val { return hash.Val(k) << 60 }
+
+func (k highKey) Equals(okey key.Hash) bool {
+	var ok, isHigh = okey.(highKey)
+	return isHigh && k == ok
+}
+
+func (k highKey) String() string { return fmt.Sprintf("highKey(%d)", uint(k)) }
+
+func TestBasicOptions(t *testing.T) {
+	var keys = buildKeys(10000)
+	for i := 0; i < 4; i++ {
+		keys = append(keys, highKey(i))
+	}
+	var s16 = set.New()
+	for _, k := range keys {
+		s16 = s16.Set(k)
+	}
+	if s16.Options().TableWidth != 16 {
+		t.Fatalf("s16.Options().TableWidth,%d != 16", s16.Options().TableWidth)
+	}
+
+	for _, width := range []int{16, 32, 64} {
+		var opts = set.Options{TableWidth: width}
+		var s = set.NewWithOptions(opts)
+		for _, k := range keys {
+			s = s.Set(k)
+		}
+		if s.Options() != opts {
+			t.Fatalf("s.Options(),%v != %v", s.Options(), opts)
+		}
+		if s.NumEntries() != len(keys) || s.Count() != len(keys) {
+			t.Fatalf("width %d: s.NumEntries(),%d s.Count(),%d != %d",
+				width, s.NumEntries(), s.Count(), len(keys))
+		}
+		for _, k := range keys {
+			if !s.IsSet(k) {
+				t.Fatalf("width %d: !s.IsSet(%s)", width, k)
+			}
+		}
+
+		// Sets of different widths compare by their keys
+		if !s.Equiv(s16) || !s16.Equiv(s) || s.RootDigest() != s16.RootDigest() {
+			t.Fatalf("width %d: s is not equivalent to s16", width)
+		}
+		var fewer = s16.Unset(keys[0])
+		if s.Equiv(fewer.Set(key.Str("x"))) || !fewer.IsSubset(s) ||
+			s.IsSubset(fewer) || s.IsDisjoint(fewer) {
+			t.Fatalf("width %d: s compared to s16 less a key", width)
+		}
+
+		// the set algebra keeps the Options of the receiver
+		var half = set.NewFromList(keys[:len(keys)/2])
+		var ops = []struct {
+			name string
+			s    *set.Set
+			num  int
+		}{
+			{"Union", s.Union(half), len(keys)},
+			{"Intersect", s.Intersect(half), len(keys) / 2},
+			{"Difference", s.Difference(half), len(keys) - len(keys)/2},
+			{"UnionParallel", s.UnionParallel(half), len(keys)},
+			{"IntersectParallel", s.IntersectParallel(half), len(keys) / 2},
+			{"Merge", s.Difference(half).Merge(half), len(keys)},
+			{"MergeParallel", s.Difference(half).MergeParallel(half), len(keys)},
+		}
+		for _, op := range ops {
+			if op.s.Options() != opts || op.s.NumEntries() != op.num ||
+				op.s.Count() != op.num {
+				t.Fatalf("width %d: %s: Options(),%v NumEntries(),%d != %d",
+					width, op.name, op.s.Options(), op.s.NumEntries(), op.num)
+			}
+		}
+		if s.Difference(half).Union(half).RootDigest() != s16.RootDigest() {
+			t.Fatalf("width %d: s.Difference(half).Union(half) != s16", width)
+		}
+
+		// the highKeys, at the end of keys, have no binary encoding
+		var tr = s.Transient()
+		for _, k := range keys[len(keys)/2:] {
+			tr.Remove(k)
+		}
+		var rest = tr.Persistent()
+		if rest.Options() != opts || rest.NumEntries() != len(keys)/2 {
+			t.Fatalf("width %d: rest.Options(),%v rest.NumEntries(),%d",
+				width, rest.Options(), rest.NumEntries())
+		}
+
+		data, err := rest.MarshalBinary()
+		if err != nil {
+			t.Fatalf("width %d: rest.MarshalBinary() failed: %s", width, err)
+		}
+		var us = set.NewWithOptions(opts)
+		if err = us.UnmarshalBinary(data); err != nil {
+			t.Fatalf("width %d: us.UnmarshalBinary() failed: %s", width, err)
+		}
+		if us.Options() != opts || us.RootDigest() != rest.RootDigest() {
+			t.Fatalf("width %d: the unmarshaled Set is not the Set marshaled",
+				width)
+		}
+
+		for _, k := range keys {
+			s = s.Unset(k)
+		}
+		if s.NumEntries() != 0 || s.Options() != opts {
+			t.Fatalf("width %d: s.NumEntries(),%d after unsetting every key",
+				width, s.NumEntries())
+		}
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("set.NewWithOptions() of TableWidth 24 did not panic")
+			}
+		}()
+		set.NewWithOptions(set.Options{TableWidth: 24})
+	}()
+}
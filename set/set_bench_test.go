@@ -315,6 +315,55 @@ func BenchmarkDifference(b *testing.B) {
 	}
 }
 
+// initDiffSets builds diffKeys, setA and setB for the set algebra benchmarks.
+func initDiffSets() {
+	if diffKeys == nil {
+		log.Printf("tot=%d; big=%d; sml=%d;\n", tot, big, sml)
+		diffKeys = buildKeys(tot)
+		setA = set.NewFromList(diffKeys[:big])
+		setB = set.NewFromList(diffKeys[sml:])
+	}
+}
+
+func BenchmarkUnion(b *testing.B) {
+	initDiffSets()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_ = setA.Union(setB)
+	}
+}
+
+func BenchmarkIntersect(b *testing.B) {
+	initDiffSets()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_ = setA.Intersect(setB)
+	}
+}
+
+func BenchmarkSymmetricDifference(b *testing.B) {
+	initDiffSets()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_ = setA.SymmetricDifference(setB)
+	}
+}
+
+// BenchmarkUnionVersions takes the union of two versions of one Set, which
+// only differ by a few keys.
+func BenchmarkUnionVersions(b *testing.B) {
+	initDiffSets()
+	var setA2 = setA.Unset(diffKeys[0]).Set(diffKeys[big])
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_ = setA.Union(setA2)
+	}
+}
+
 //func BenchmarkDifference2(b *testing.B) {
 //	if diffKeys == nil {
 //		log.Printf("tot=%d; big=%d; sml=%d;\n", tot, big, sml)
@@ -356,3 +405,98 @@ func BenchmarkDifference(b *testing.B) {
 //		_ = setA.Difference3(setB)
 //	}
 //}
+
+func BenchmarkBulkInsertParallel(b *testing.B) {
+	if bulkKeys == nil {
+		log.Printf("BenchBulkInsertParallel: building bulkKeys.")
+		bulkKeys = buildKeys(bulkSize)
+	}
+	b.ResetTimer()
+	var s = set.New()
+	for i := 0; i < b.N; i++ {
+		s.BulkInsertParallel(bulkKeys)
+	}
+}
+
+func BenchmarkUnionParallel(b *testing.B) {
+	if bulkKeys == nil {
+		log.Printf("BenchUnionParallel: building bulkKeys.")
+		bulkKeys = buildKeys(bulkSize)
+	}
+	var s0 = set.NewFromList(bulkKeys[:bulkSize*6/10])
+	var s1 = set.NewFromList(bulkKeys[bulkSize*4/10:])
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = s0.UnionParallel(s1)
+	}
+}
+
+func BenchmarkIntersectParallel(b *testing.B) {
+	if bulkKeys == nil {
+		log.Printf("BenchIntersectParallel: building bulkKeys.")
+		bulkKeys = buildKeys(bulkSize)
+	}
+	var s0 = set.NewFromList(bulkKeys[:bulkSize*6/10])
+	var s1 = set.NewFromList(bulkKeys[bulkSize*4/10:])
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = s0.IntersectParallel(s1)
+	}
+}
+
+func buildSetWidth(keys []key.Hash, width int) *set.Set {
+	var s = set.NewWithOptions(set.Options{TableWidth: width})
+	var t = s.Transient()
+	for _, k := range keys {
+		t.Add(k)
+	}
+	return t.Persistent()
+}
+
+func benchmarkSetOneWidth(b *testing.B, width int) {
+	log.Printf("BenchmarkSetOneWidth%d: b.N=%d\n", width, b.N)
+	var keys, xtra = buildKeysBench(NumKeys100M, NumKeysExtra100M)
+	var s = buildSetWidth(keys, width)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var j = rand.Int() % len(xtra)
+		_ = s.Set(xtra[j])
+	}
+}
+
+func BenchmarkSetOneWidth16(b *testing.B) {
+	benchmarkSetOneWidth(b, 16)
+}
+
+func BenchmarkSetOneWidth32(b *testing.B) {
+	benchmarkSetOneWidth(b, 32)
+}
+
+func BenchmarkSetOneWidth64(b *testing.B) {
+	benchmarkSetOneWidth(b, 64)
+}
+
+func benchmarkIsSetOneWidth(b *testing.B, width int) {
+	log.Printf("BenchmarkIsSetOneWidth%d: b.N=%d\n", width, b.N)
+	var keys, _ = buildKeysBench(NumKeys100M, 0)
+	var s = buildSetWidth(keys, width)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var j = rand.Int() % len(keys)
+		_ = s.IsSet(keys[j])
+	}
+}
+
+func BenchmarkIsSetOneWidth16(b *testing.B) {
+	benchmarkIsSetOneWidth(b, 16)
+}
+
+func BenchmarkIsSetOneWidth32(b *testing.B) {
+	benchmarkIsSetOneWidth(b, 32)
+}
+
+func BenchmarkIsSetOneWidth64(b *testing.B) {
+	benchmarkIsSetOneWidth(b, 64)
+}
@@ -0,0 +1,157 @@
+package set
+
+import (
+	"unsafe"
+
+	"github.com/lleo/go-functional-collections/key"
+)
+
+// Stats describes the shape of the HAMT of a Set, as returned by Set.Stats. It
+// is meant for tuning the hashing of keys and watching the collision leaves
+// grow; none of it is needed to use a Set.
+type Stats struct {
+	// DeepestKeys are the keys of the leaves found at the greatest Depth.
+	DeepestKeys struct {
+		Keys  []key.Hash
+		Depth uint
+	}
+
+	// Depth of deepest table
+	MaxDepth uint
+
+	// TableCountsByNumEntries is the number of tables with each given number
+	// of entries in the table. There are slots for [0..TableWidth] inclusive
+	// (so there are TableWidth+1 slots). Technically, there should never be a
+	// table with zero entries, but I allow counting tables with zero entries
+	// just to catch those errors.
+	TableCountsByNumEntries []uint
+
+	// TableCountsByDepth is the number of tables at a given depth. There are
+	// slots for [0..DepthLimit) of the Set's TableWidth.
+	TableCountsByDepth []uint
+
+	// CollisionLeafCountsByNumEntries is the number of collisionLeaf structs
+	// with each given number of keys. A collisionLeaf has at least 2.
+	CollisionLeafCountsByNumEntries map[uint]uint
+
+	// MaxCollisions is the number of keys in the largest
+	// collisionLeaf, or 0 if there is none.
+	MaxCollisions uint
+
+	// Nils is the total count of allocated slots that are unused in the Set.
+	Nils uint
+
+	// Nodes is the total count of nodeI capable structs in the Set.
+	Nodes uint
+
+	// Tables is the total count of tableI capable structs in the Set.
+	Tables uint
+
+	// Leafs is the total count of leafI capable structs in the Set.
+	Leafs uint
+
+	// FixedTables is the total count of fixedTable structs in the Set.
+	FixedTables uint
+
+	// SparseTables is the total count of sparseTable structs in the Set.
+	SparseTables uint
+
+	// FlatLeafs is the total count of flatLeaf structs in the Set.
+	FlatLeafs uint
+
+	// CollisionLeafs is the total count of collisionLeaf structs in the Set.
+	CollisionLeafs uint
+
+	// Keys is the total number of Keys in the Set.
+	Keys uint
+
+	// AvgProbeDepth is the average number of tables a lookup of a key of the
+	// Set visits, or 0 if the Set is empty.
+	AvgProbeDepth float64
+
+	// TableBytes and LeafBytes estimate the memory used by the tables and the
+	// leaves of the Set, not counting the keys. Tables and leaves shared with
+	// other Sets are counted in full.
+	TableBytes uint
+	LeafBytes  uint
+}
+
+// Stats walks the Hamt in a pre-order traversal and populates a Stats data
+// struture which it returns.
+func (s *Set) Stats() *Stats {
+	var l = s.layout()
+	var stats = new(Stats)
+	stats.TableCountsByNumEntries = make([]uint, l.IndexLimit()+1)
+	stats.TableCountsByDepth = make([]uint, l.DepthLimit())
+	stats.CollisionLeafCountsByNumEntries = make(map[uint]uint)
+
+	var sizeofNode = uint(unsafe.Sizeof(nodeI(nil)))
+	var probes uint
+
+	// leafFn counts what the flatLeaf and collisionLeaf cases share; depth is
+	// the number of tables above the leaf.
+	var leafFn = func(lf leafI, numKeys uint, depth uint) {
+		stats.Nodes++
+		stats.Leafs++
+		stats.Keys += numKeys
+		probes += numKeys * depth
+		if depth > stats.DeepestKeys.Depth {
+			stats.DeepestKeys.Depth = depth
+			stats.DeepestKeys.Keys = nil
+		}
+		if depth == stats.DeepestKeys.Depth {
+			stats.DeepestKeys.Keys = append(stats.DeepestKeys.Keys, lf.keys()...)
+		}
+	}
+
+	// statFn closes over the stats variable
+	var statFn = func(n nodeI, depth uint) bool {
+		switch x := n.(type) {
+		case *fixedTable:
+			stats.Nodes++
+			stats.Tables++
+			stats.FixedTables++
+			stats.TableCountsByNumEntries[x.slotsUsed()]++
+			stats.TableCountsByDepth[x.depth]++
+			if x.depth > stats.MaxDepth {
+				stats.MaxDepth = x.depth
+			}
+			stats.Nils += uint(len(x.nodes)) - x.slotsUsed()
+			stats.TableBytes += uint(unsafe.Sizeof(*x))
+		case *sparseTable:
+			stats.Nodes++
+			stats.Tables++
+			stats.SparseTables++
+			stats.TableCountsByNumEntries[x.slotsUsed()]++
+			stats.TableCountsByDepth[x.depth]++
+			if x.depth > stats.MaxDepth {
+				stats.MaxDepth = x.depth
+			}
+			stats.Nils += uint(cap(x.nodes) - len(x.nodes))
+			stats.TableBytes += uint(unsafe.Sizeof(*x)) +
+				uint(cap(x.nodes))*sizeofNode
+		case *flatLeaf:
+			stats.FlatLeafs++
+			stats.LeafBytes += uint(unsafe.Sizeof(*x))
+			leafFn(x, 1, depth)
+		case *collisionLeaf:
+			var numKeys = uint(len(x.ks))
+			stats.CollisionLeafs++
+			stats.CollisionLeafCountsByNumEntries[numKeys]++
+			if numKeys > stats.MaxCollisions {
+				stats.MaxCollisions = numKeys
+			}
+			stats.LeafBytes += uint(unsafe.Sizeof(*x)) +
+				uint(cap(x.ks))*uint(unsafe.Sizeof(key.Hash(nil))) +
+				uint(cap(x.hashes))*uint(unsafe.Sizeof(uint64(0)))
+			leafFn(x, numKeys, depth)
+		}
+		return true
+	}
+
+	s.walkPreOrder(statFn)
+	if stats.Keys > 0 {
+		stats.AvgProbeDepth = float64(probes) / float64(stats.Keys)
+	}
+	return stats
+}
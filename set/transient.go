@@ -0,0 +1,219 @@
+package set
+
+import (
+	"github.com/lleo/go-functional-collections/key"
+)
+
+// Transient is a mutable version of a Set, for making many modifications in a
+// row. It is obtained from a Set with the Transient method, and is frozen back
+// into a Set with the Persistent method.
+//
+// The Transient records the tables it owns, which are the tables it created
+// itself. Owned tables are modified in place; every other table is shared
+// with some Set, so it is copied, and the copy becomes owned. Hence each
+// table is copied at most once, no matter how many modifications are made.
+//
+// A Transient is NOT safe for concurrent use.
+type Transient struct {
+	s     *Set
+	owned map[tableI]bool
+}
+
+// Transient returns a new *Transient holding the same keys as the Set. The
+// Set is never modified by the Transient.
+func (s *Set) Transient() *Transient {
+	var t = new(Transient)
+	t.s = s.copy()
+	t.owned = make(map[tableI]bool)
+	return t
+}
+
+// Persistent returns a new persistent *Set holding the current keys of the
+// Transient.
+//
+// The Transient can still be used afterwards; since its tables are now shared
+// with the returned Set, they will be copied again as they are modified.
+func (t *Transient) Persistent() *Set {
+	t.owned = make(map[tableI]bool)
+	return t.s.copy()
+}
+
+// NumEntries returns the number of keys in the *Transient.
+func (t *Transient) NumEntries() int {
+	return t.s.numEnts
+}
+
+// IsSet returns true if an equivalent key is in the Transient, otherwise it
+// returns false.
+func (t *Transient) IsSet(key key.Hash) bool {
+	return t.s.IsSet(key)
+}
+
+// Set inserts the given key in place. It returns the *Transient so that calls
+// can be chained.
+func (t *Transient) Set(key key.Hash) *Transient {
+	t.Add(key)
+	return t
+}
+
+// Add inserts the given key in place. It returns a bool indicating if the key
+// was added (true) or if an equivalent key was already in the Transient
+// (false).
+func (t *Transient) Add(k key.Hash) bool {
+	var hv = k.Hash()
+	var l = t.s.layout()
+	var path, leaf, idx = t.s.find(hv)
+	var curTable = path.pop()
+	var depth = uint(path.len())
+
+	var node nodeI
+	var added bool
+	if leaf == nil {
+		node = newFlatLeaf(k)
+		added = true
+	} else if !l.Collide(leaf.hash(), hv) {
+		var newTable = createTable(depth+1, leaf, newFlatLeaf(k), l)
+		t.owned[newTable] = true
+		node = newTable
+		added = true
+	} else {
+		node, added = leaf.put(k)
+	}
+
+	if !added {
+		return false
+	}
+
+	var table = t.own(curTable)
+	if leaf == nil {
+		table.insertInplace(idx, node)
+		if table.needsUpgrade() {
+			table = table.upgrade()
+		}
+	} else {
+		table.replaceInplace(idx, node)
+	}
+	t.persist(curTable, table, path, 1)
+
+	t.s.numEnts++
+	return true
+}
+
+// Unset removes any equivalent key in place. It returns the *Transient so
+// that calls can be chained.
+func (t *Transient) Unset(key key.Hash) *Transient {
+	t.Remove(key)
+	return t
+}
+
+// Remove deletes any equivalent key in place. It returns a bool indicating if
+// the key was found and deleted.
+func (t *Transient) Remove(key key.Hash) bool {
+	if t.s.numEnts == 0 {
+		return false
+	}
+
+	var path, leaf, idx = t.s.find(key.Hash())
+	if leaf == nil {
+		return false
+	}
+
+	var newLeaf, deleted = leaf.del(key)
+	if !deleted {
+		return false
+	}
+
+	var curTable = path.pop()
+
+	var table = t.own(curTable)
+	if newLeaf == nil {
+		table = t.removeInplace(table, idx, path.len() == 0)
+	} else {
+		table.replaceInplace(idx, newLeaf)
+	}
+	t.persist(curTable, table, path, -1)
+
+	t.s.numEnts--
+	return true
+}
+
+// own() returns the table if it is owned, otherwise a copy of it. The copy
+// becomes owned once it is passed to persist().
+func (t *Transient) own(table tableI) tableI {
+	if t.owned[table] {
+		return table
+	}
+	return table.copy()
+}
+
+// removeInplace() removes the entry at idx from the owned table. It returns
+// the resulting table, which is nil if the non-root table is now empty.
+func (t *Transient) removeInplace(table tableI, idx uint, isRoot bool) tableI {
+	table.removeInplace(idx)
+	if isRoot {
+		return table
+	}
+	if table.slotsUsed() == 0 {
+		return nil
+	}
+	if table.needsDowngrade() {
+		return table.downgrade()
+	}
+	return table
+}
+
+// persist() replaces oldTable, at the top of the path, with newTable, which
+// holds delta more keys. Unlike Set.persist(), the parent tables are only
+// copied if they are not already owned, and newTable becomes owned.
+func (t *Transient) persist(
+	oldTable, newTable tableI,
+	path *tableStack,
+	delta int,
+) {
+	if newTable == oldTable {
+		// oldTable was modified in place, so it is owned, and so are the
+		// tables above it; only their key counts are left to update.
+		for path.len() > 0 {
+			var parent = path.pop()
+			setNumKeys(parent, parent.count()+delta)
+		}
+		return
+	}
+
+	if newTable != nil {
+		t.owned[newTable] = true
+	}
+
+	if t.s.root == oldTable {
+		t.s.root = newTable
+		return
+	}
+
+	var parentIdx = t.s.layout().Index(oldTable.hash(), uint(path.len())-1)
+
+	var oldParent = path.pop()
+	var parent = t.own(oldParent)
+	// If oldTable was owned, it was modified in place, and the parent can no
+	// longer tell how many keys it held; hence numKeys is set, not adjusted.
+	var numKeys = parent.count() + delta
+	if newTable == nil {
+		parent = t.removeInplace(parent, parentIdx, path.len() == 0)
+	} else {
+		parent.replaceInplace(parentIdx, newTable)
+	}
+	if parent != nil {
+		setNumKeys(parent, numKeys)
+	}
+
+	t.persist(oldParent, parent, path, delta)
+}
+
+// setNumKeys() sets the number of keys held by the table, which is owned.
+func setNumKeys(table tableI, numKeys int) {
+	switch x := table.(type) {
+	case *fixedTable:
+		x.numKeys = numKeys
+	case *sparseTable:
+		x.numKeys = numKeys
+	}
+}
@@ -0,0 +1,173 @@
+//go:build go1.21
+// +build go1.21
+
+package set
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lleo/go-functional-collections/key"
+)
+
+// TypedSet is a type safe wrapper of the Set structure. Keys are of the Hasher
+// type K, so no type assertions are needed on the keys returned.
+//
+// Internally every key is stored as a key.HashOf[K] in the same HAMT used by
+// Set.
+type TypedSet[K key.Hasher] struct {
+	s *Set
+}
+
+// NewTypedSet returns a properly initialized pointer to an empty TypedSet.
+func NewTypedSet[K key.Hasher]() *TypedSet[K] {
+	return &TypedSet[K]{New()}
+}
+
+// NewTypedSetFromList constructs a new *TypedSet containing all the given
+// keys.
+func NewTypedSetFromList[K key.Hasher](keys []K) *TypedSet[K] {
+	var t = New().Transient()
+	for _, k := range keys {
+		t.Add(key.HashOf[K]{Key: k})
+	}
+	return &TypedSet[K]{t.Persistent()}
+}
+
+// IsSet returns true if the key is in the TypedSet, otherwise it returns false.
+func (s *TypedSet[K]) IsSet(k K) bool {
+	return s.s.IsSet(key.HashOf[K]{Key: k})
+}
+
+// Set inserts the given key into the TypedSet and returns a new *TypedSet. If
+// the key is already in the TypedSet the original *TypedSet is returned.
+func (s *TypedSet[K]) Set(k K) *TypedSet[K] {
+	var ns, _ = s.Add(k)
+	return ns
+}
+
+// Add inserts the given key into the TypedSet. It returns the new *TypedSet
+// and a bool indicating if the key was added. If the key was already in the
+// TypedSet the original *TypedSet is returned with a false value.
+func (s *TypedSet[K]) Add(k K) (*TypedSet[K], bool) {
+	var ns, added = s.s.Add(key.HashOf[K]{Key: k})
+	if !added {
+		return s, false
+	}
+	return &TypedSet[K]{ns}, true
+}
+
+// Unset removes the given key and returns the new *TypedSet. If the key is not
+// in the TypedSet the original *TypedSet is returned.
+func (s *TypedSet[K]) Unset(k K) *TypedSet[K] {
+	var ns, _ = s.Remove(k)
+	return ns
+}
+
+// Remove deletes the given key. It returns the new *TypedSet and a bool
+// indicating if the key was found. If the key was not found the original
+// *TypedSet is returned with a false value.
+func (s *TypedSet[K]) Remove(k K) (*TypedSet[K], bool) {
+	var ns, removed = s.s.Remove(key.HashOf[K]{Key: k})
+	if !removed {
+		return s, false
+	}
+	return &TypedSet[K]{ns}, true
+}
+
+// NumEntries returns the number of keys in the *TypedSet.
+func (s *TypedSet[K]) NumEntries() int {
+	return s.s.NumEntries()
+}
+
+// Range applies the given function to every key in the *TypedSet. If the
+// function returns false the Range operation stops.
+func (s *TypedSet[K]) Range(fn func(K) bool) {
+	if s.s.NumEntries() == 0 {
+		return //Set.Iter() returns nil for an empty Set
+	}
+	s.s.Range(func(k key.Hash) bool {
+		return fn(k.(key.HashOf[K]).Key)
+	})
+}
+
+// Keys returns a new slice holding all the keys of the *TypedSet.
+func (s *TypedSet[K]) Keys() []K {
+	var keys = make([]K, 0, s.NumEntries())
+	s.Range(func(k K) bool {
+		keys = append(keys, k)
+		return true
+	})
+	return keys
+}
+
+// Union returns a *TypedSet that contains all the keys of the receiver and the
+// argument *TypedSet.
+func (s *TypedSet[K]) Union(other *TypedSet[K]) *TypedSet[K] {
+	if other.NumEntries() == 0 {
+		return s
+	}
+	if s.NumEntries() == 0 {
+		return other
+	}
+	return &TypedSet[K]{s.s.Union(other.s)}
+}
+
+// Intersect returns a *TypedSet that contains only the keys that the receiver
+// and the argument *TypedSet have in common.
+func (s *TypedSet[K]) Intersect(other *TypedSet[K]) *TypedSet[K] {
+	if s.NumEntries() == 0 || other.NumEntries() == 0 {
+		return NewTypedSet[K]()
+	}
+	return &TypedSet[K]{s.s.Intersect(other.s)}
+}
+
+// Difference returns a *TypedSet that contains the keys of the receiver that
+// are not in the argument *TypedSet.
+func (s *TypedSet[K]) Difference(other *TypedSet[K]) *TypedSet[K] {
+	if s.NumEntries() == 0 || other.NumEntries() == 0 {
+		return s
+	}
+	return &TypedSet[K]{s.s.Difference(other.s)}
+}
+
+// SymmetricDifference returns a *TypedSet that contains the keys of either the
+// receiver or the argument *TypedSet, but not of both.
+func (s *TypedSet[K]) SymmetricDifference(other *TypedSet[K]) *TypedSet[K] {
+	if other.NumEntries() == 0 {
+		return s
+	}
+	if s.NumEntries() == 0 {
+		return other
+	}
+	return &TypedSet[K]{s.s.SymmetricDifference(other.s)}
+}
+
+// IsSubset returns true if every key of the receiver is in the argument
+// *TypedSet.
+func (s *TypedSet[K]) IsSubset(other *TypedSet[K]) bool {
+	return s.s.IsSubset(other.s)
+}
+
+// IsSuperset returns true if every key of the argument *TypedSet is in the
+// receiver.
+func (s *TypedSet[K]) IsSuperset(other *TypedSet[K]) bool {
+	return s.s.IsSuperset(other.s)
+}
+
+// IsDisjoint returns true if the receiver and the argument *TypedSet have no
+// keys in common.
+func (s *TypedSet[K]) IsDisjoint(other *TypedSet[K]) bool {
+	return s.s.IsDisjoint(other.s)
+}
+
+// String prints a string representation of the TypedSet. It is intended to be
+// simmilar to fmt.Printf("%#v") of a golang set[].
+func (s *TypedSet[K]) String() string {
+	var ents = make([]string, 0, s.NumEntries())
+	s.Range(func(k K) bool {
+		ents = append(ents, fmt.Sprintf("%#v", k))
+		return true
+	})
+	return "Set{" + strings.Join(ents, ",") + "}"
+}
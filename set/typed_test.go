@@ -0,0 +1,61 @@
+//go:build go1.21
+// +build go1.21
+
+package set_test
+
+import (
+	"testing"
+
+	"github.com/lleo/go-functional-collections/key"
+	"github.com/lleo/go-functional-collections/set"
+)
+
+func TestTypedSet(t *testing.T) {
+	var strs = buildStrings(1000)
+	var keys = make([]key.Str, len(strs))
+	for i, s := range strs {
+		keys[i] = key.Str(s)
+	}
+
+	var s = set.NewTypedSetFromList(keys[:600])
+	var o = set.NewTypedSetFromList(keys[400:])
+
+	if s.NumEntries() != 600 || !s.IsSet(keys[0]) || s.IsSet(keys[999]) {
+		t.Fatal("NewTypedSetFromList(keys[:600]) is wrong")
+	}
+
+	if n := s.Union(o).NumEntries(); n != 1000 {
+		t.Fatalf("s.Union(o).NumEntries(),%d != 1000", n)
+	}
+	if n := s.Intersect(o).NumEntries(); n != 200 {
+		t.Fatalf("s.Intersect(o).NumEntries(),%d != 200", n)
+	}
+	var d = s.Difference(o)
+	if d.NumEntries() != 400 || d.IsSet(keys[500]) || !d.IsSet(keys[0]) {
+		t.Fatal("s.Difference(o) is wrong")
+	}
+	if n := s.SymmetricDifference(o).NumEntries(); n != 800 {
+		t.Fatalf("s.SymmetricDifference(o).NumEntries(),%d != 800", n)
+	}
+	if !d.IsSubset(s) || !s.IsSuperset(d) || !d.IsDisjoint(o) ||
+		s.IsSubset(o) || s.IsDisjoint(o) {
+		t.Fatal("IsSubset(), IsSuperset() or IsDisjoint() is wrong")
+	}
+
+	var ns, added = s.Add(keys[0])
+	if added || ns != s {
+		t.Fatal("s.Add(keys[0]) added an existing key")
+	}
+	ns, removed := s.Remove(keys[0])
+	if !removed || ns.IsSet(keys[0]) || !s.IsSet(keys[0]) {
+		t.Fatal("s.Remove(keys[0]) failed")
+	}
+
+	var e = set.NewTypedSet[key.Str]()
+	if len(e.Keys()) != 0 || e.String() != "Set{}" {
+		t.Fatal("empty TypedSet is not empty")
+	}
+	if len(s.Keys()) != 600 {
+		t.Fatalf("len(s.Keys()),%d != 600", len(s.Keys()))
+	}
+}
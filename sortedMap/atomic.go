@@ -0,0 +1,109 @@
+package sortedMap
+
+import (
+	"sync/atomic"
+
+	"github.com/lleo/go-functional-collections/key"
+)
+
+// Atomic is a mutable Map safe for concurrent use, like sync.Map. It holds a
+// pointer to a persistent *Map, and every modification builds a new *Map and
+// swaps it in with an atomic compare-and-swap, retrying if another goroutine
+// swapped in its own first. So readers never wait, and Snapshot returns a
+// consistent view of the Map that later modifications do not affect.
+//
+// The zero value is an empty Atomic ready to use. An Atomic MUST NOT be
+// copied after first use.
+type Atomic struct {
+	p atomic.Pointer[Map]
+}
+
+// NewAtomic returns a new *Atomic holding the given Map; an empty one if m is
+// nil.
+func NewAtomic(m *Map) *Atomic {
+	var a = new(Atomic)
+	a.p.Store(m)
+	return a
+}
+
+// load() returns the Map the Atomic holds, after storing an empty Map in a
+// zero Atomic.
+func (a *Atomic) load() *Map {
+	var p = a.p.Load()
+	if p == nil {
+		a.p.CompareAndSwap(nil, New())
+		p = a.p.Load()
+	}
+	return p
+}
+
+// Snapshot returns the Map the Atomic holds now. Being persistent, the Map
+// never changes.
+func (a *Atomic) Snapshot() *Map {
+	return a.load()
+}
+
+// Load returns the value stored for the key, and true, or nil and false if
+// there is none.
+func (a *Atomic) Load(key key.Sort) (interface{}, bool) {
+	return a.Snapshot().Load(key)
+}
+
+// Store sets the value for the key.
+func (a *Atomic) Store(key key.Sort, val interface{}) {
+	a.Update(func(m *Map) *Map {
+		return m.Put(key, val)
+	})
+}
+
+// LoadOrStore returns the value stored for the key, and true, if there is one.
+// Otherwise it stores the given value and returns it, and false.
+func (a *Atomic) LoadOrStore(key key.Sort, val interface{}) (interface{}, bool) {
+	var actual interface{}
+	var loaded bool
+	a.Update(func(m *Map) *Map {
+		var nm *Map
+		nm, actual, loaded = m.LoadOrStore(key, val)
+		return nm
+	})
+	if !loaded {
+		actual = val
+	}
+	return actual, loaded
+}
+
+// Delete deletes the value for the key.
+func (a *Atomic) Delete(key key.Sort) {
+	a.Update(func(m *Map) *Map {
+		return m.Del(key)
+	})
+}
+
+// Range calls fn for every key/value pair of a Snapshot, in order, until fn
+// returns false.
+func (a *Atomic) Range(fn func(key.Sort, interface{}) bool) {
+	a.Snapshot().Range(fn)
+}
+
+// CompareAndSwap replaces the Map the Atomic holds with new, if it still holds
+// old, and reports whether it did. The Maps are compared by pointer, so old is
+// usually a Snapshot.
+func (a *Atomic) CompareAndSwap(old, new *Map) bool {
+	a.load()
+	return a.p.CompareAndSwap(old, new)
+}
+
+// Update replaces the Map the Atomic holds, m, with fn(m), and returns it.
+// If another goroutine modifies the Atomic while fn runs, fn is called again
+// with the new Map, so fn SHOULD have no side effects. Update is how several
+// keys are modified together; no other goroutine sees only some of the
+// changes.
+func (a *Atomic) Update(fn func(*Map) *Map) *Map {
+	for {
+		var p = a.load()
+		var nm = fn(p)
+		if a.p.CompareAndSwap(p, nm) {
+			return nm
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package sortedMap
+
+import (
+	"fmt"
+
+	"github.com/lleo/go-functional-collections/key"
+)
+
+// DiffType is the kind of difference between two Maps reported by Diff.
+type DiffType int
+
+const (
+	// DiffAdded means the key is only in the argument Map.
+	DiffAdded DiffType = iota
+	// DiffRemoved means the key is only in the receiver Map.
+	DiffRemoved
+	// DiffChanged means the key is in both Maps, but with different values.
+	DiffChanged
+)
+
+func (dt DiffType) String() string {
+	switch dt {
+	case DiffAdded:
+		return "DiffAdded"
+	case DiffRemoved:
+		return "DiffRemoved"
+	case DiffChanged:
+		return "DiffChanged"
+	}
+	return fmt.Sprintf("DiffType(%d)", int(dt))
+}
+
+// DiffEntry describes a key/value mapping that differs between two Maps.
+// OldVal is the value in the receiver Map and NewVal is the value in the
+// argument Map. The value for a Map without the key is nil.
+type DiffEntry struct {
+	Type   DiffType
+	Key    key.Sort
+	OldVal interface{}
+	NewVal interface{}
+}
+
+func (de DiffEntry) String() string {
+	return fmt.Sprintf("{%s, %s, %v, %v}", de.Type, de.Key, de.OldVal, de.NewVal)
+}
+
+// Diff calls the given function, in key order, for every key/value mapping
+// that differs between the receiver Map and the argument Map; that is the
+// mappings added, removed or changed going from the receiver Map to the
+// argument Map. If the function returns false, Diff stops.
+//
+// Diff splits the argument Map by each key of the receiver Map, like Union,
+// and skips any sub-tree the two Maps share. So for Maps derived from one
+// another (like successive versions of the same Map), the cost of Diff tracks
+// the size of the changes, not the size of the Maps. Sub-trees which are not
+// shared are always looked inside, even when their digests are equal; see the
+// merkle package.
+//
+// Values are compared with ==, so they MUST be comparable.
+func (m *Map) Diff(other *Map, fn func(DiffEntry) bool) {
+	diff(m.root, other.root, other.root.blackHeight(), fn)
+}
@@ -0,0 +1,119 @@
+package sortedMap
+
+import (
+	"context"
+
+	"github.com/lleo/go-functional-collections/internal/parallel"
+	"github.com/lleo/go-functional-collections/key"
+)
+
+// The Red-Black Tree of a Map is balanced, so cutting it a few levels down
+// gives sub-trees of about the same size, and a Map never changes, so they can
+// be walked each in its own goroutine with no locking. ParallelRange and Fold
+// do that, with a pool of workers taking the parts one at a time.
+
+// treePart is a part of the tree: the node first, if not nil, followed by
+// every node of the sub-tree sub, in order.
+type treePart struct {
+	first *node
+	sub   *node
+}
+
+// walk() calls fn on the nodes of the part in ascending order, until fn
+// returns false, and then returns false.
+func (p treePart) walk(fn func(*node) bool) bool {
+	if p.first != nil && !fn(p.first) {
+		return false
+	}
+	return p.sub.walk(key.Inf(-1), key.Inf(1), false, fn)
+}
+
+// ParallelRange calls fn for every key/value pair of the Map, from up to
+// workers goroutines at once, or runtime.GOMAXPROCS(0) if workers <= 0. So fn
+// MUST be safe for concurrent use; each goroutine visits the pairs of its part
+// of the Map in order, but the parts are in no particular order. If fn returns
+// false, the remaining pairs are skipped, though calls already under way in
+// other goroutines still finish.
+//
+// ParallelRange also stops once ctx is done, and then returns ctx.Err().
+func (m *Map) ParallelRange(
+	ctx context.Context,
+	workers int,
+	fn func(key.Sort, interface{}) bool,
+) error {
+	workers = parallel.Workers(workers)
+	var parts = m.split(workers)
+	return parallel.Parts(ctx, workers, len(parts),
+		func(part int, halted func() bool) bool {
+			return parts[part].walk(func(n *node) bool {
+				return !halted() && fn(n.key, n.val)
+			})
+		})
+}
+
+// Fold reduces the key/value pairs of the Map to one value, MapReduce style,
+// using up to workers goroutines at once, or runtime.GOMAXPROCS(0) if workers
+// <= 0. Each goroutine folds the pairs of a part of the Map, in order,
+// starting from zero, and the results of the parts are then combined in order.
+// So zero MUST be an identity of combine, and combine MUST be associative; it
+// need not be commutative.
+//
+// If ctx is done before the fold is, Fold returns nil and ctx.Err().
+func (m *Map) Fold(
+	ctx context.Context,
+	workers int,
+	zero interface{},
+	fold func(acc interface{}, k key.Sort, v interface{}) interface{},
+	combine func(acc0, acc1 interface{}) interface{},
+) (interface{}, error) {
+	workers = parallel.Workers(workers)
+	var parts = m.split(workers)
+	var accs = make([]interface{}, len(parts))
+	var err = parallel.Parts(ctx, workers, len(parts),
+		func(part int, halted func() bool) bool {
+			var acc = zero
+			if parts[part].walk(func(n *node) bool {
+				if halted() {
+					return false
+				}
+				acc = fold(acc, n.key, n.val)
+				return true
+			}) {
+				accs[part] = acc
+			}
+			return true
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	var acc = zero
+	for _, partAcc := range accs {
+		acc = combine(acc, partAcc)
+	}
+	return acc, nil
+}
+
+// split() cuts the tree of the Map into about four parts per worker, so a
+// worker held up by a slow part does not hold up the others; the parts are in
+// ascending order.
+func (m *Map) split(workers int) []treePart {
+	var depth int
+	for 1<<uint(depth) < 4*workers {
+		depth++
+	}
+	return splitTree(nil, m.root, depth, nil)
+}
+
+// splitTree() appends the parts of the tree n, preceded by the node first, cut
+// the given depth down.
+func splitTree(parts []treePart, n *node, depth int, first *node) []treePart {
+	if n == nil || depth == 0 {
+		if n == nil && first == nil {
+			return parts
+		}
+		return append(parts, treePart{first, n})
+	}
+	parts = splitTree(parts, n.ln, depth-1, first)
+	return splitTree(parts, n.rn, depth-1, n)
+}
@@ -0,0 +1,120 @@
+//go:build go1.23
+// +build go1.23
+
+package sortedMap
+
+import (
+	"cmp"
+	"iter"
+
+	"github.com/lleo/go-functional-collections/key"
+)
+
+// seq() returns an iterator over the key/value pairs of the Map with a key
+// between lo and hi inclusive.
+func (m *Map) seq(
+	lo, hi key.Sort,
+	reverse bool,
+) iter.Seq2[key.Sort, interface{}] {
+	return func(yield func(key.Sort, interface{}) bool) {
+		m.root.walk(lo, hi, reverse, func(n *node) bool {
+			return yield(n.key, n.val)
+		})
+	}
+}
+
+// All returns an iterator over the key/value pairs of the *Map in ascending
+// order of the keys, for use with a for-range loop.
+func (m *Map) All() iter.Seq2[key.Sort, interface{}] {
+	return m.seq(key.Inf(-1), key.Inf(1), false)
+}
+
+// Backward returns an iterator over the key/value pairs of the *Map in
+// descending order of the keys.
+func (m *Map) Backward() iter.Seq2[key.Sort, interface{}] {
+	return m.seq(key.Inf(-1), key.Inf(1), true)
+}
+
+// Between returns an iterator over the key/value pairs of the *Map, in
+// ascending order, whose key is between lo and hi inclusive. Like CountRange,
+// lo and hi may be given in either order.
+func (m *Map) Between(lo, hi key.Sort) iter.Seq2[key.Sort, interface{}] {
+	if key.Less(hi, lo) {
+		lo, hi = hi, lo
+	}
+	return m.seq(lo, hi, false)
+}
+
+// Keys returns an iterator over the keys of the *Map in ascending order.
+func (m *Map) Keys() iter.Seq[key.Sort] {
+	return func(yield func(key.Sort) bool) {
+		m.root.walk(key.Inf(-1), key.Inf(1), false, func(n *node) bool {
+			return yield(n.key)
+		})
+	}
+}
+
+// Values returns an iterator over the values of the *Map in ascending order
+// of their keys.
+func (m *Map) Values() iter.Seq[interface{}] {
+	return func(yield func(interface{}) bool) {
+		m.root.walk(key.Inf(-1), key.Inf(1), false, func(n *node) bool {
+			return yield(n.val)
+		})
+	}
+}
+
+// typedSeq() converts an iterator returned by a Map method to K and V.
+func typedSeq[K cmp.Ordered, V any](
+	seq iter.Seq2[key.Sort, interface{}],
+) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for k, v := range seq {
+			if !yield(k.(key.SortOf[K]).Key, typedVal[V](v)) {
+				return
+			}
+		}
+	}
+}
+
+// All returns an iterator over the key/value pairs of the *TypedMap in
+// ascending order of the keys, for use with a for-range loop.
+func (m *TypedMap[K, V]) All() iter.Seq2[K, V] {
+	return typedSeq[K, V](m.m.All())
+}
+
+// Backward returns an iterator over the key/value pairs of the *TypedMap in
+// descending order of the keys.
+func (m *TypedMap[K, V]) Backward() iter.Seq2[K, V] {
+	return typedSeq[K, V](m.m.Backward())
+}
+
+// Between returns an iterator over the key/value pairs of the *TypedMap, in
+// ascending order, whose key is between lo and hi inclusive.
+func (m *TypedMap[K, V]) Between(lo, hi K) iter.Seq2[K, V] {
+	var l, h = key.SortOf[K]{Key: lo}, key.SortOf[K]{Key: hi}
+	return typedSeq[K, V](m.m.Between(l, h))
+}
+
+// Keys returns an iterator over the keys of the *TypedMap in ascending order.
+func (m *TypedMap[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for k := range m.m.Keys() {
+			if !yield(k.(key.SortOf[K]).Key) {
+				return
+			}
+		}
+	}
+}
+
+// Values returns an iterator over the values of the *TypedMap in ascending
+// order of their keys.
+func (m *TypedMap[K, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for v := range m.m.Values() {
+			if !yield(typedVal[V](v)) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+//go:build go1.23
+// +build go1.23
+
+package sortedMap
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/lleo/go-functional-collections/key"
+)
+
+func TestIterAll(t *testing.T) {
+	var kvs = genIntKeyVals(1000)
+	var m = NewFromList(randomizeKeyVals(kvs))
+
+	var i int
+	for k, v := range m.All() {
+		if k != kvs[i].Key || v != kvs[i].Val {
+			t.Fatalf("m.All() yielded %s:%v; expected %s:%v",
+				k, v, kvs[i].Key, kvs[i].Val)
+		}
+		i++
+	}
+	if i != len(kvs) {
+		t.Fatalf("m.All() yielded %d pairs; expected %d", i, len(kvs))
+	}
+
+	i = len(kvs)
+	for k := range m.Backward() {
+		i--
+		if k != kvs[i].Key {
+			t.Fatalf("m.Backward() yielded %s; expected %s", k, kvs[i].Key)
+		}
+	}
+	if i != 0 {
+		t.Fatalf("m.Backward() stopped at %d", i)
+	}
+
+	var keys = slices.Collect(m.Keys())
+	var vals = slices.Collect(m.Values())
+	if len(keys) != 1000 || keys[999] != key.Int(10000) || vals[0] != 10 {
+		t.Fatal("m.Keys() or m.Values() is wrong")
+	}
+
+	for range New().All() {
+		t.Fatal("empty Map yielded a key/value pair")
+	}
+}
+
+func TestIterBetween(t *testing.T) {
+	var m = NewFromList(genIntKeyVals(100)) //keys 10, 20, ..., 1000
+
+	var keys []key.Sort
+	for k := range m.Between(key.Int(255), key.Int(50)) {
+		keys = append(keys, k)
+	}
+	var exp = []key.Sort{key.Int(50), key.Int(60), key.Int(70), key.Int(80),
+		key.Int(90), key.Int(100), key.Int(110), key.Int(120), key.Int(130),
+		key.Int(140), key.Int(150), key.Int(160), key.Int(170), key.Int(180),
+		key.Int(190), key.Int(200), key.Int(210), key.Int(220), key.Int(230),
+		key.Int(240), key.Int(250)}
+	if !slices.Equal(keys, exp) {
+		t.Fatalf("m.Between(255, 50) keys,%v != %v", keys, exp)
+	}
+
+	var n int
+	for range m.Between(key.Inf(-1), key.Inf(1)) {
+		n++
+		if n == 5 {
+			break
+		}
+	}
+	if n != 5 {
+		t.Fatalf("break out of m.Between() loop after %d iterations", n)
+	}
+
+	var tm = NewTypedMap[int, int]()
+	for i := 0; i < 10; i++ {
+		tm = tm.Put(i, i*i)
+	}
+	var tkeys []int
+	for k, v := range tm.Between(3, 5) {
+		if v != k*k {
+			t.Fatalf("tm.Between(3, 5) yielded %d:%d", k, v)
+		}
+		tkeys = append(tkeys, k)
+	}
+	if !slices.Equal(tkeys, []int{3, 4, 5}) {
+		t.Fatalf("tm.Between(3, 5) keys,%v != [3 4 5]", tkeys)
+	}
+	if got := slices.Collect(tm.Keys()); len(got) != 10 || got[9] != 9 {
+		t.Fatalf("slices.Collect(tm.Keys()),%v is wrong", got)
+	}
+}
@@ -0,0 +1,57 @@
+package sortedMap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/lleo/go-functional-collections/codec"
+	"github.com/lleo/go-functional-collections/key"
+)
+
+var (
+	_ json.Marshaler   = (*Map)(nil)
+	_ json.Unmarshaler = (*Map)(nil)
+)
+
+// MarshalJSON implements the json.Marshaler interface. A Map with only key.Str
+// keys is encoded as a JSON object, any other Map as a JSON array of
+// [key, value] pairs. Either way the key/value pairs are written in order. See
+// the codec package for the details.
+func (m *Map) MarshalJSON() ([]byte, error) {
+	var keys = make([]interface{}, 0, m.numEnts)
+	var vals = make([]interface{}, 0, m.numEnts)
+	m.Range(func(k key.Sort, v interface{}) bool {
+		keys = append(keys, k)
+		vals = append(vals, v)
+		return true
+	})
+	return codec.EncodeJSONPairs(keys, vals)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. It replaces the
+// contents of the receiver, so it SHOULD only be called on a Map that no one
+// else holds, like one just returned by New. Keys are decoded by
+// codec.DecodeJSONKey and MUST implement key.Sort.
+func (m *Map) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil
+	}
+
+	var kvs []KeyVal
+	var err = codec.DecodeJSONPairs(data, func(k, v interface{}) error {
+		var sk, isSort = k.(key.Sort)
+		if !isSort {
+			return fmt.Errorf(
+				"sortedMap: decoded key of type %T is not a key.Sort", k)
+		}
+		kvs = append(kvs, KeyVal{sk, v})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	*m = *NewFromList(kvs)
+	return nil
+}
@@ -0,0 +1,18 @@
+package sortedMap
+
+import (
+	"fmt"
+
+	"github.com/lleo/go-functional-collections/key"
+)
+
+// KeyVal is a simple struct used to transfer lists ([]KeyVal) from one
+// function to another.
+type KeyVal struct {
+	Key key.Sort
+	Val interface{}
+}
+
+func (kv KeyVal) String() string {
+	return fmt.Sprintf("{%q, %v}", kv.Key, kv.Val)
+}
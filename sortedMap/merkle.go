@@ -0,0 +1,16 @@
+package sortedMap
+
+import "github.com/lleo/go-functional-collections/merkle"
+
+// RootDigest returns the content digest of the Map; the sum of the
+// merkle.Entry digests of its key/value pairs. Maps holding equal key/value
+// pairs have equal RootDigests, whatever the shape of their trees, but equal
+// RootDigests do not prove the key/value pairs equal. See the merkle package.
+//
+// The digest of every sub-tree is cached in its root node. So the first
+// RootDigest of a Map visits every node, but afterwards RootDigest is O(1),
+// and the RootDigest of a new version of the Map only visits the nodes created
+// since.
+func (m *Map) RootDigest() merkle.Digest {
+	return m.root.treeDigest()
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/lleo/go-functional-collections/key"
+	"github.com/lleo/go-functional-collections/merkle"
 )
 
 type colorType bool
@@ -36,6 +37,29 @@ type node struct {
 	color colorType //default node is RED aka false
 	ln    *node
 	rn    *node
+	size  int //number of nodes in this sub-tree; zero means not calculated
+	//digest of this sub-tree, once calculated by treeDigest()
+	digest merkle.Cache
+}
+
+// ResolveConflictFunc is the signature of functions used to choose between, or
+// create a new value from, two key,value pairs where the keys are equal (this
+// is defined by key.Cmp(k0, k1) == 0, hence only the Map key is passed in).
+type ResolveConflictFunc func(
+	key key.Sort,
+	origVal, newVal interface{},
+) interface{}
+
+// KeepOrigVal is an implementation of ResolveConflictFunc type which returns
+// the first (origVal) value.
+func KeepOrigVal(key key.Sort, origVal, newVal interface{}) interface{} {
+	return origVal
+}
+
+// TakeNewVal is an implementation of ResolveConflictFunc type which returns
+// the second (newVal) value.
+func TakeNewVal(key key.Sort, origVal, newVal interface{}) interface{} {
+	return newVal
 }
 
 func newNode(k key.Sort, v interface{}) *node {
@@ -48,12 +72,60 @@ func newNode(k key.Sort, v interface{}) *node {
 	return n
 }
 
+// copy() returns a shallow copy of n. The copy is about to be modified, so its
+// size is left to be recalculated by fixSize(), and its digest by
+// treeDigest(). The digest is read atomically, so n is not copied by *nn = *n.
 func (n *node) copy() *node {
 	var nn = new(node)
-	*nn = *n
+	nn.key = n.key
+	nn.val = n.val
+	nn.color = n.color
+	nn.ln = n.ln
+	nn.rn = n.rn
 	return nn
 }
 
+// size() returns the number of nodes in the sub-tree rooted at n. Like color(),
+// it treats nil *node values as empty sub-trees.
+func size(n *node) int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+// fixSize() recalculates the size of every node in the sub-tree rooted at n
+// whose size was reset by copy(), and returns the size of n.
+//
+// Only new or modified nodes have their size reset, and every parent of such a
+// node is itself new or modified. So fixSize() stops at the first node with a
+// size, and only visits the nodes touched by the last operation.
+func (n *node) fixSize() int {
+	if n == nil {
+		return 0
+	}
+	if n.size == 0 {
+		n.size = n.ln.fixSize() + n.rn.fixSize() + 1
+	}
+	return n.size
+}
+
+// treeDigest() returns the digest of the sub-tree rooted at n; the sum of the
+// merkle.Entry digests of its nodes. The digest of every node visited is
+// cached, so treeDigest() stops at the first node with a digest, like
+// fixSize().
+func (n *node) treeDigest() merkle.Digest {
+	if n == nil {
+		return merkle.Digest{}
+	}
+	if d, cached := n.digest.Get(); cached {
+		return d
+	}
+	var d = n.ln.treeDigest().Add(merkle.Entry(n.key, n.val)).Add(n.rn.treeDigest())
+	n.digest.Set(d)
+	return d
+}
+
 //count() sums up the number of sub-nodes plus this node.
 func (n *node) count() int {
 	if n == nil {
@@ -89,6 +161,12 @@ func (n *node) valid() (int, error) {
 		return -1, errors.New(errStr)
 	}
 
+	if n.size != size(n.ln)+size(n.rn)+1 {
+		var errStr = fmt.Sprintf("node size,%d != %d+%d+1",
+			n.size, size(n.ln), size(n.rn))
+		return -1, errors.New(errStr)
+	}
+
 	//RBT#3
 	if n.isRed() {
 		if n.ln.isRed() || n.rn.isRed() {
@@ -112,6 +190,7 @@ func (n *node) dup() *node {
 		color: n.color,
 		ln:    n.ln.dup(),
 		rn:    n.rn.dup(),
+		size:  n.size,
 	}
 	return nn
 }
@@ -202,6 +281,126 @@ func (n *node) findNode(k key.Sort) *node {
 	return nil
 }
 
+// rank() returns the number of nodes in the tree n with keys less than k, or
+// if inclusive is true, with keys less than or equal to k.
+func (n *node) rank(k key.Sort, inclusive bool) int {
+	var r int
+	var cur = n
+	for cur != nil {
+		if key.Less(cur.key, k) || (inclusive && !key.Less(k, cur.key)) {
+			r += size(cur.ln) + 1
+			cur = cur.rn
+		} else {
+			cur = cur.ln
+		}
+	}
+	return r
+}
+
+// selectNode() returns the node at index i of the in-order walk of the tree n,
+// or nil if i is out of range.
+func (n *node) selectNode(i int) *node {
+	var cur = n
+	for cur != nil {
+		var ls = size(cur.ln)
+		switch {
+		case i < ls:
+			cur = cur.ln
+		case i > ls:
+			i -= ls + 1
+			cur = cur.rn
+		default: //i == ls
+			return cur
+		}
+	}
+	return nil
+}
+
+// floor() returns the node of the tree n with the greatest key less than k, or
+// if inclusive is true, less than or equal to k. It returns nil if there is no
+// such node.
+func (n *node) floor(k key.Sort, inclusive bool) *node {
+	var found *node
+	var cur = n
+	for cur != nil {
+		if key.Less(cur.key, k) || (inclusive && !key.Less(k, cur.key)) {
+			found = cur
+			cur = cur.rn
+		} else {
+			cur = cur.ln
+		}
+	}
+	return found
+}
+
+// ceiling() returns the node of the tree n with the least key greater than k,
+// or if inclusive is true, greater than or equal to k. It returns nil if there
+// is no such node.
+func (n *node) ceiling(k key.Sort, inclusive bool) *node {
+	var found *node
+	var cur = n
+	for cur != nil {
+		if key.Less(k, cur.key) || (inclusive && !key.Less(cur.key, k)) {
+			found = cur
+			cur = cur.ln
+		} else {
+			cur = cur.rn
+		}
+	}
+	return found
+}
+
+// min() returns the node of the tree n with the least key, or nil if n is nil.
+func (n *node) min() *node {
+	if n == nil {
+		return nil
+	}
+	for n.ln != nil {
+		n = n.ln
+	}
+	return n
+}
+
+// max() returns the node of the tree n with the greatest key, or nil if n is
+// nil.
+func (n *node) max() *node {
+	if n == nil {
+		return nil
+	}
+	for n.rn != nil {
+		n = n.rn
+	}
+	return n
+}
+
+// walk() calls fn on every node of the tree n with a key between lo and hi
+// inclusive, in ascending order, or in descending order if reverse is true.
+// Sub-trees wholly outside of lo and hi are skipped. It returns false if fn
+// returned false.
+func (n *node) walk(lo, hi key.Sort, reverse bool, fn func(*node) bool) bool {
+	if n == nil {
+		return true
+	}
+
+	var first, second = n.ln, n.rn
+	var doFirst, doSecond = key.Less(lo, n.key), key.Less(n.key, hi)
+	if reverse {
+		first, second = second, first
+		doFirst, doSecond = doSecond, doFirst
+	}
+
+	if doFirst && !first.walk(lo, hi, reverse, fn) {
+		return false
+	}
+	if !key.Less(n.key, lo) && !key.Less(hi, n.key) && !fn(n) {
+		return false
+	}
+	if doSecond && !second.walk(lo, hi, reverse, fn) {
+		return false
+	}
+	return true
+}
+
 func (n *node) findNodeWithPath(k key.Sort) (*node, *nodeStack) {
 	var path = newNodeStack(0)
 	var cur = n
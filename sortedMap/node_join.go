@@ -0,0 +1,386 @@
+package sortedMap
+
+import "github.com/lleo/go-functional-collections/key"
+
+// The functions in this file implement the join-based algorithms for
+// Red-Black Trees described in "Just Join for Parallel Ordered Sets" by
+// Blelloch, Ferizovic, and Sun. Every operation is built on join(), which
+// stitches together two trees and a middle node, and split(), which cuts a
+// tree into the nodes less than and greater than a key.
+//
+// None of these functions modify their arguments; new nodes are only created
+// along the paths that change, so the resulting trees share every other
+// subtree with their inputs. The sizes of the new nodes are left for fixSize()
+// to calculate once the final tree is built.
+//
+// The black height of each tree is passed along with it, so that join() never
+// has to walk a tree to find out its black height. The black height counts the
+// black nodes from the given node down to, but not including, the nil leaves.
+// The roots of the trees passed around may be red.
+
+// blackHeight() counts the black nodes on the path from n down to the nil
+// leaves. Every such path has the same count, so the left-most path is used.
+func (n *node) blackHeight() int {
+	var h int
+	for ; n != nil; n = n.ln {
+		if n.isBlack() {
+			h++
+		}
+	}
+	return h
+}
+
+// childHeight() returns the black height of the children of n given the black
+// height, h, of n.
+func (n *node) childHeight(h int) int {
+	if n.isBlack() {
+		return h - 1
+	}
+	return h
+}
+
+// blackRoot() returns n with a black root. The root node is copied if it has
+// to be recolored.
+func blackRoot(n *node) *node {
+	if n.isRed() {
+		return n.copy().setBlack()
+	}
+	return n
+}
+
+// rotateLeft() rotates the fresh node n and its fresh right child left, and
+// returns the new root of the subtree.
+func (n *node) rotateLeft() *node {
+	var r = n.rn
+	n.rn = r.ln
+	r.ln = n
+	return r
+}
+
+// rotateRight() rotates the fresh node n and its fresh left child right, and
+// returns the new root of the subtree.
+func (n *node) rotateRight() *node {
+	var l = n.ln
+	n.ln = l.rn
+	l.rn = n
+	return l
+}
+
+// join() returns a tree containing every node of l, then m's key and value,
+// then every node of r, along with the black height of that tree. Every key of
+// l MUST be less than m's key, which MUST be less than every key of r.
+//
+// The returned tree always has a black root.
+func join(l *node, hl int, m *node, r *node, hr int) (*node, int) {
+	// With black roots on both sides, the red node joining them can only ever
+	// cause a red-red violation above itself, which joinRight() and
+	// joinLeft() repair on their way back up.
+	if l.isRed() {
+		l = l.copy().setBlack()
+		hl++
+	}
+	if r.isRed() {
+		r = r.copy().setBlack()
+		hr++
+	}
+
+	var t *node
+	var h int
+	switch {
+	case hl > hr:
+		t, h = joinRight(l, hl, m, r, hr), hl
+	case hl < hr:
+		t, h = joinLeft(l, hl, m, r, hr), hr
+	default:
+		t, h = newNode(m.key, m.val), hl
+		t.ln, t.rn = l, r
+	}
+
+	if t.isRed() {
+		t.setBlack() //t is always a new node
+		h++
+	}
+	return t, h
+}
+
+// joinRight() descends the right spine of l to the black node with the same
+// black height as r, and replaces it with a red node, created from m, that
+// has it and r as children. The red-red violations this causes are rotated
+// away on the way back up, except for possibly one at the returned root.
+func joinRight(l *node, hl int, m *node, r *node, hr int) *node {
+	if hl == hr && l.isBlack() {
+		var nn = newNode(m.key, m.val) //nn.isRed ALWAYS!
+		nn.ln, nn.rn = l, r
+		return nn
+	}
+
+	var nl = l.copy()
+	nl.rn = joinRight(l.rn, l.childHeight(hl), m, r, hr)
+
+	if l.isBlack() && nl.rn.isRed() && nl.rn.rn.isRed() {
+		nl.rn.rn = nl.rn.rn.copy().setBlack()
+		return nl.rotateLeft()
+	}
+	return nl
+}
+
+// joinLeft() is the mirror image of joinRight(). It descends the left spine
+// of r.
+func joinLeft(l *node, hl int, m *node, r *node, hr int) *node {
+	if hl == hr && r.isBlack() {
+		var nn = newNode(m.key, m.val) //nn.isRed ALWAYS!
+		nn.ln, nn.rn = l, r
+		return nn
+	}
+
+	var nr = r.copy()
+	nr.ln = joinLeft(l, hl, m, r.ln, r.childHeight(hr))
+
+	if r.isBlack() && nr.ln.isRed() && nr.ln.ln.isRed() {
+		nr.ln.ln = nr.ln.ln.copy().setBlack()
+		return nr.rotateRight()
+	}
+	return nr
+}
+
+// split() divides the tree n, with black height h, into a tree of the nodes
+// with keys less than k and a tree of the nodes with keys greater than k. It
+// also returns the node with a key equal to k, or nil if there is no such
+// node.
+func split(n *node, h int, k key.Sort) (
+	l *node, hl int, m *node, r *node, hr int,
+) {
+	if n == nil {
+		return nil, 0, nil, nil, 0
+	}
+
+	var ch = n.childHeight(h)
+
+	switch {
+	case key.Less(k, n.key):
+		l, hl, m, r, hr = split(n.ln, ch, k)
+		r, hr = join(r, hr, n, n.rn, ch)
+	case key.Less(n.key, k):
+		l, hl, m, r, hr = split(n.rn, ch, k)
+		l, hl = join(n.ln, ch, n, l, hl)
+	default: //n.key == k
+		l, hl, m, r, hr = n.ln, ch, n, n.rn, ch
+	}
+
+	return l, hl, m, r, hr
+}
+
+// splitLast() removes the node with the greatest key from the non-nil tree n,
+// with black height h. It returns the remaining tree and the removed node.
+func splitLast(n *node, h int) (*node, int, *node) {
+	var ch = n.childHeight(h)
+
+	if n.rn == nil {
+		return n.ln, ch, n
+	}
+
+	var t, ht, last = splitLast(n.rn, ch)
+	t, ht = join(n.ln, ch, n, t, ht)
+
+	return t, ht, last
+}
+
+// join2() is join() without a middle node. Every key of l MUST be less than
+// every key of r.
+func join2(l *node, hl int, r *node, hr int) (*node, int) {
+	if l == nil {
+		return r, hr
+	}
+	var t, ht, last = splitLast(l, hl)
+	return join(t, ht, last, r, hr)
+}
+
+// splitAt() divides the tree n, with black height h, into a tree of the first
+// i nodes of n and a tree of the rest of the nodes of n. The sizes of the
+// nodes of n MUST be calculated.
+func splitAt(n *node, h int, i int) (l *node, hl int, r *node, hr int) {
+	if n == nil {
+		return nil, 0, nil, 0
+	}
+
+	var ch = n.childHeight(h)
+	var ls = size(n.ln)
+
+	switch {
+	case i < ls:
+		l, hl, r, hr = splitAt(n.ln, ch, i)
+		r, hr = join(r, hr, n, n.rn, ch)
+	case i > ls:
+		l, hl, r, hr = splitAt(n.rn, ch, i-ls-1)
+		l, hl = join(n.ln, ch, n, l, hl)
+	default: //i == ls
+		l, hl = n.ln, ch
+		r, hr = join(nil, 0, n, n.rn, ch)
+	}
+
+	return l, hl, r, hr
+}
+
+// union() returns the tree of every key in either a or b. For the keys found in
+// both a and b, the value is resolve(key, aVal, bVal), or aVal if resolve is
+// nil. It also returns the number of keys found in both a and b.
+func union(a *node, ha int, b *node, hb int, resolve ResolveConflictFunc) (
+	*node, int, int,
+) {
+	if a == b && resolve == nil {
+		return a, ha, size(a)
+	}
+	if a == nil {
+		return b, hb, 0
+	}
+	if b == nil {
+		return a, ha, 0
+	}
+
+	var ch = a.childHeight(ha)
+
+	var lb, hlb, m, rb, hrb = split(b, hb, a.key)
+	var l, hl, nl = union(a.ln, ch, lb, hlb, resolve)
+	var r, hr, nr = union(a.rn, ch, rb, hrb, resolve)
+
+	var common = nl + nr
+	var mid = a
+	if m != nil {
+		common++
+		if resolve != nil {
+			mid = newNode(a.key, resolve(a.key, a.val, m.val))
+		}
+	}
+
+	if mid == a && l == a.ln && r == a.rn {
+		return a, ha, common
+	}
+
+	var t, ht = join(l, hl, mid, r, hr)
+	return t, ht, common
+}
+
+// intersect() returns the tree of every key in both a and b, along with the
+// values from a. It also returns the number of keys in that tree.
+func intersect(a *node, ha int, b *node, hb int) (*node, int, int) {
+	if a == nil || b == nil {
+		return nil, 0, 0
+	}
+	if a == b {
+		return a, ha, size(a)
+	}
+
+	var ch = a.childHeight(ha)
+
+	var lb, hlb, m, rb, hrb = split(b, hb, a.key)
+	var l, hl, nl = intersect(a.ln, ch, lb, hlb)
+	var r, hr, nr = intersect(a.rn, ch, rb, hrb)
+
+	if m == nil {
+		var t, ht = join2(l, hl, r, hr)
+		return t, ht, nl + nr
+	}
+
+	if l == a.ln && r == a.rn {
+		return a, ha, nl + nr + 1
+	}
+
+	var t, ht = join(l, hl, a, r, hr)
+	return t, ht, nl + nr + 1
+}
+
+// difference() returns the tree of every key in a that is not in b. It also
+// returns the number of keys of a that were removed.
+func difference(a *node, ha int, b *node, hb int) (*node, int, int) {
+	if a == nil {
+		return nil, 0, 0
+	}
+	if b == nil {
+		return a, ha, 0
+	}
+	if a == b {
+		return nil, 0, size(a)
+	}
+
+	var ch = b.childHeight(hb)
+
+	var la, hla, m, ra, hra = split(a, ha, b.key)
+	var l, hl, nl = difference(la, hla, b.ln, ch)
+	var r, hr, nr = difference(ra, hra, b.rn, ch)
+
+	var removed = nl + nr
+	if m != nil {
+		removed++
+	}
+
+	if removed == 0 {
+		return a, ha, 0
+	}
+
+	var t, ht = join2(l, hl, r, hr)
+	return t, ht, removed
+}
+
+// symmetricDifference() returns the tree of every key in either a or b but
+// not in both. It also returns the number of keys found in both a and b.
+func symmetricDifference(a *node, ha int, b *node, hb int) (
+	*node, int, int,
+) {
+	if a == b {
+		return nil, 0, size(a)
+	}
+	if a == nil {
+		return b, hb, 0
+	}
+	if b == nil {
+		return a, ha, 0
+	}
+
+	var ch = a.childHeight(ha)
+
+	var lb, hlb, m, rb, hrb = split(b, hb, a.key)
+	var l, hl, nl = symmetricDifference(a.ln, ch, lb, hlb)
+	var r, hr, nr = symmetricDifference(a.rn, ch, rb, hrb)
+
+	if m != nil {
+		var t, ht = join2(l, hl, r, hr)
+		return t, ht, nl + nr + 1
+	}
+
+	var t, ht = join(l, hl, a, r, hr)
+	return t, ht, nl + nr
+}
+
+// diff() calls fn, in key order, for every difference going from the tree a to
+// the tree b, with black height hb. It returns false if fn returned false.
+func diff(a *node, b *node, hb int, fn func(DiffEntry) bool) bool {
+	if a == b {
+		return true
+	}
+	if a == nil {
+		return b.walk(key.Inf(-1), key.Inf(1), false, func(n *node) bool {
+			return fn(DiffEntry{DiffAdded, n.key, nil, n.val})
+		})
+	}
+	if b == nil {
+		return a.walk(key.Inf(-1), key.Inf(1), false, func(n *node) bool {
+			return fn(DiffEntry{DiffRemoved, n.key, n.val, nil})
+		})
+	}
+
+	var lb, hlb, m, rb, hrb = split(b, hb, a.key)
+
+	if !diff(a.ln, lb, hlb, fn) {
+		return false
+	}
+	if m == nil {
+		if !fn(DiffEntry{DiffRemoved, a.key, a.val, nil}) {
+			return false
+		}
+	} else if m.val != a.val {
+		if !fn(DiffEntry{DiffChanged, a.key, a.val, m.val}) {
+			return false
+		}
+	}
+	return diff(a.rn, rb, hrb, fn)
+}
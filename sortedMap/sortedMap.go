@@ -18,6 +18,7 @@ package sortedMap
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/lleo/go-functional-collections/key"
@@ -37,14 +38,14 @@ func New() *Map {
 }
 
 func (m *Map) valid() error {
-	var _, err = m.root.valid()
-	if err != nil {
-		return err
-	}
 	var count = m.root.count()
 	if count != m.numEnts {
 		return errors.New("enumerated count of subnodes != m.NumEntries()")
 	}
+	var _, err = m.root.valid()
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -107,6 +108,132 @@ func (m *Map) Load(k key.Sort) (interface{}, bool) {
 	return n.val, true
 }
 
+// Rank returns the number of keys in the Map that are less than k. If k is in
+// the Map, that is the index of k in sorted order. Rank is O(log(n)).
+func (m *Map) Rank(k key.Sort) int {
+	return m.root.rank(k, false)
+}
+
+// Select returns the key/value pair at index i in sorted order. If i is out of
+// range the key returned is nil. Select is O(log(n)).
+func (m *Map) Select(i int) (key.Sort, interface{}) {
+	var n = m.root.selectNode(i)
+	if n == nil {
+		return nil, nil
+	}
+	return n.key, n.val
+}
+
+// CountRange returns the number of keys in the Map between lo and hi,
+// including lo and hi. Like IterLimit, lo and hi may be given in either order.
+// CountRange is O(log(n)).
+func (m *Map) CountRange(lo, hi key.Sort) int {
+	if key.Less(hi, lo) {
+		lo, hi = hi, lo
+	}
+	return m.root.rank(hi, true) - m.root.rank(lo, false)
+}
+
+// Slice returns a new Map containing the key/value pairs at indexes i through
+// j-1 in sorted order. The indexes are clamped to the range of the Map, like a
+// Go slice expression that never panics.
+//
+// Slice is O(log(n)) and the returned Map maintains the structure sharing
+// relationship with the receiver Map.
+func (m *Map) Slice(i, j int) *Map {
+	if i < 0 {
+		i = 0
+	}
+	if j > m.numEnts {
+		j = m.numEnts
+	}
+	if i >= j {
+		return New()
+	}
+	if i == 0 && j == m.numEnts {
+		return m
+	}
+
+	var l, hl, _, _ = splitAt(m.root, m.root.blackHeight(), j)
+	l.fixSize()
+	var _, _, r, _ = splitAt(l, hl, i)
+
+	var nm = new(Map)
+	nm.root = blackRoot(r)
+	nm.root.fixSize()
+	nm.numEnts = j - i
+	return nm
+}
+
+// Floor returns the key/value pair with the greatest key in the Map less than
+// or equal to k. The bool is false, and the key nil, if there is no such key.
+func (m *Map) Floor(k key.Sort) (key.Sort, interface{}, bool) {
+	return nodeKeyVal(m.root.floor(k, true))
+}
+
+// Ceiling returns the key/value pair with the least key in the Map greater than
+// or equal to k. The bool is false, and the key nil, if there is no such key.
+func (m *Map) Ceiling(k key.Sort) (key.Sort, interface{}, bool) {
+	return nodeKeyVal(m.root.ceiling(k, true))
+}
+
+// Lower returns the key/value pair with the greatest key in the Map strictly
+// less than k. The bool is false, and the key nil, if there is no such key.
+func (m *Map) Lower(k key.Sort) (key.Sort, interface{}, bool) {
+	return nodeKeyVal(m.root.floor(k, false))
+}
+
+// Higher returns the key/value pair with the least key in the Map strictly
+// greater than k. The bool is false, and the key nil, if there is no such key.
+func (m *Map) Higher(k key.Sort) (key.Sort, interface{}, bool) {
+	return nodeKeyVal(m.root.ceiling(k, false))
+}
+
+// Min returns the key/value pair with the least key in the Map. The bool is
+// false if the Map is empty.
+func (m *Map) Min() (key.Sort, interface{}, bool) {
+	return nodeKeyVal(m.root.min())
+}
+
+// Max returns the key/value pair with the greatest key in the Map. The bool is
+// false if the Map is empty.
+func (m *Map) Max() (key.Sort, interface{}, bool) {
+	return nodeKeyVal(m.root.max())
+}
+
+// PopMin returns a new Map without the entry with the least key, along with
+// that key and its value. If the Map is empty it returns the receiver Map, a
+// nil key, a nil value and false.
+func (m *Map) PopMin() (*Map, key.Sort, interface{}, bool) {
+	var n = m.root.min()
+	if n == nil {
+		return m, nil, nil, false
+	}
+	var nm, _, _ = m.Remove(n.key)
+	return nm, n.key, n.val, true
+}
+
+// PopMax returns a new Map without the entry with the greatest key, along with
+// that key and its value. If the Map is empty it returns the receiver Map, a
+// nil key, a nil value and false.
+func (m *Map) PopMax() (*Map, key.Sort, interface{}, bool) {
+	var n = m.root.max()
+	if n == nil {
+		return m, nil, nil, false
+	}
+	var nm, _, _ = m.Remove(n.key)
+	return nm, n.key, n.val, true
+}
+
+// nodeKeyVal() returns the key and value of n and true, or nil, nil and false
+// if n is nil.
+func nodeKeyVal(n *node) (key.Sort, interface{}, bool) {
+	if n == nil {
+		return nil, nil, false
+	}
+	return n.key, n.val, true
+}
+
 // LoadOrStore finds the value for a given key. If the key is found then
 // it simply return the current map, the value found, and a true value
 // indicating is was found. If the key is NOT found then it stores the
@@ -196,6 +323,7 @@ func (m *Map) insert(k key.Sort, v interface{}, path *nodeStack) {
 func (m *Map) persist(on, nn *node, path *nodeStack) {
 	if path.len() == 0 {
 		m.root = nn
+		m.root.fixSize()
 		return
 	}
 
@@ -215,6 +343,8 @@ func (m *Map) persist(on, nn *node, path *nodeStack) {
 			parent.rn = nn
 		}
 	}
+
+	m.root.fixSize()
 }
 
 // rotateLeft takes the target node(n) and its parent(p). We are rotating on
@@ -902,3 +1032,273 @@ func (m *Map) String() string {
 	var s = "{" + strings.Join(strs, ", ") + "}"
 	return s
 }
+
+// Union returns a Map that contains all the key/value mappings of the receiver
+// Map and the argument Map. If a key exists in both Maps, the key/value mapping
+// from the receiver Map is kept.
+//
+// Union splits the argument Map by each key of the receiver Map and joins the
+// pieces back together. Given Maps of n and m entries, where m <= n, this is
+// O(m*log(n/m+1)). The returned Map maintains the structure sharing
+// relationship with both the receiver Map and the argument Map.
+func (m *Map) Union(other *Map) *Map {
+	var root, _, common = union(
+		m.root, m.root.blackHeight(),
+		other.root, other.root.blackHeight(), nil)
+
+	var nm = new(Map)
+	nm.root = blackRoot(root)
+	nm.root.fixSize()
+	nm.numEnts = m.numEnts + other.numEnts - common
+	return nm
+}
+
+// Intersect returns a Map that contains only the key/value mappings of the
+// receiver Map whose keys also exist in the argument Map.
+//
+// Intersect is implemented like Union, hence it is O(m*log(n/m+1)) and the
+// returned Map maintains the structure sharing relationship with the receiver
+// Map.
+func (m *Map) Intersect(other *Map) *Map {
+	var root, _, num = intersect(
+		m.root, m.root.blackHeight(),
+		other.root, other.root.blackHeight())
+
+	var nm = new(Map)
+	nm.root = blackRoot(root)
+	nm.root.fixSize()
+	nm.numEnts = num
+	return nm
+}
+
+// Difference returns a Map that contains only the key/value mappings of the
+// receiver Map whose keys do not exist in the argument Map.
+//
+// Difference splits the receiver Map by each key of the argument Map and joins
+// the pieces back together without the shared keys. It is O(m*log(n/m+1)) and
+// the returned Map maintains the structure sharing relationship with the
+// receiver Map.
+//
+// NOTE: a.Difference(b) != b.Difference(a)
+func (m *Map) Difference(other *Map) *Map {
+	var root, _, removed = difference(
+		m.root, m.root.blackHeight(),
+		other.root, other.root.blackHeight())
+
+	if removed == 0 {
+		return m
+	}
+
+	var nm = new(Map)
+	nm.root = blackRoot(root)
+	nm.root.fixSize()
+	nm.numEnts = m.numEnts - removed
+	return nm
+}
+
+// SymmetricDifference returns a Map that contains the key/value mappings of the
+// receiver Map and the argument Map, except for those whose keys exist in both
+// Maps.
+//
+// SymmetricDifference is implemented like Union, hence it is O(m*log(n/m+1))
+// and the returned Map maintains the structure sharing relationship with both
+// the receiver Map and the argument Map.
+func (m *Map) SymmetricDifference(other *Map) *Map {
+	var root, _, common = symmetricDifference(
+		m.root, m.root.blackHeight(),
+		other.root, other.root.blackHeight())
+
+	var nm = new(Map)
+	nm.root = blackRoot(root)
+	nm.root.fixSize()
+	nm.numEnts = m.numEnts + other.numEnts - 2*common
+	return nm
+}
+
+// buildTree() builds a balanced tree from kvs in O(n). The kvs MUST be sorted
+// by key with no duplicate keys. It returns the root of the tree and its black
+// height.
+//
+// The middle key,value pair of each sub-slice becomes the root of each
+// sub-tree, so every level of the tree is full except possibly the deepest.
+// The nodes of that partially filled deepest level are red, and every other
+// node is black.
+func buildTree(kvs []KeyVal) (*node, int) {
+	var full int // number of full levels
+	for 1<<uint(full+1)-1 <= len(kvs) {
+		full++
+	}
+	return buildSubtree(kvs, 0, full), full
+}
+
+func buildSubtree(kvs []KeyVal, depth, full int) *node {
+	if len(kvs) == 0 {
+		return nil
+	}
+
+	var mid = len(kvs) / 2
+
+	var n = newNode(kvs[mid].Key, kvs[mid].Val)
+	n.size = len(kvs)
+	if depth < full {
+		n.setBlack()
+	}
+	n.ln = buildSubtree(kvs[:mid], depth+1, full)
+	n.rn = buildSubtree(kvs[mid+1:], depth+1, full)
+
+	return n
+}
+
+// sortKeyVals() returns a sorted copy of kvs with no duplicate keys. The values
+// of duplicate keys are combined, in the order they appear in kvs, with the
+// given resolve function; TakeNewVal if it is nil.
+func sortKeyVals(kvs []KeyVal, resolve ResolveConflictFunc) []KeyVal {
+	if resolve == nil {
+		resolve = TakeNewVal
+	}
+
+	var sorted = make([]KeyVal, len(kvs))
+	copy(sorted, kvs)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return key.Less(sorted[i].Key, sorted[j].Key)
+	})
+
+	var n int
+	for i := range sorted {
+		if n > 0 && !key.Less(sorted[n-1].Key, sorted[i].Key) {
+			sorted[n-1].Val = resolve(sorted[n-1].Key,
+				sorted[n-1].Val, sorted[i].Val)
+			continue
+		}
+		sorted[n] = sorted[i]
+		n++
+	}
+
+	return sorted[:n]
+}
+
+// isSorted() returns true if the keys of kvs are strictly increasing.
+func isSorted(kvs []KeyVal) bool {
+	for i := 1; i < len(kvs); i++ {
+		if !key.Less(kvs[i-1].Key, kvs[i].Key) {
+			return false
+		}
+	}
+	return true
+}
+
+// NewFromList constructs a new *Map structure containing all the key,value
+// pairs of the given KeyVal slice. If a key occurs more than once, the last
+// key,value pair is kept, just like repeated calls to Put.
+//
+// NewFromList sorts a copy of the KeyVal slice, then builds a balanced tree
+// from it without any rebalancing. That is more efficient than repeated calls
+// to Put.
+func NewFromList(kvs []KeyVal) *Map {
+	return NewFromSortedList(sortKeyVals(kvs, TakeNewVal))
+}
+
+// NewFromSortedList constructs a new *Map structure containing all the
+// key,value pairs of the given KeyVal slice, which must be sorted by key with
+// no duplicate keys. The balanced tree is built in O(n).
+//
+// If the KeyVal slice is not sorted, NewFromSortedList falls back on
+// NewFromList.
+func NewFromSortedList(kvs []KeyVal) *Map {
+	if !isSorted(kvs) {
+		return NewFromList(kvs)
+	}
+
+	var m = New()
+	m.root, _ = buildTree(kvs)
+	m.numEnts = len(kvs)
+	return m
+}
+
+// BulkInsert stores all the given key,value pairs into the Map while
+// resolving any conflict with the given resolve function. Conflicts between
+// key,value pairs of the KeyVal slice are resolved first, in the order they
+// occur in the slice; then conflicts with the receiver Map are resolved. A nil
+// resolve function is TakeNewVal.
+//
+// The returned Map maintains the structure sharing relationship with the
+// original Map.
+//
+// BulkInsert builds a balanced tree from the key,value pairs, then joins it
+// with the receiver Map like Merge. That is more efficient than repeated
+// calls to Store.
+func (m *Map) BulkInsert(kvs []KeyVal, resolve ResolveConflictFunc) *Map {
+	if resolve == nil {
+		resolve = TakeNewVal
+	}
+	var sorted = sortKeyVals(kvs, resolve)
+	var root, h = buildTree(sorted)
+
+	var nroot, _, common = union(
+		m.root, m.root.blackHeight(), root, h, resolve)
+
+	var nm = new(Map)
+	nm.root = blackRoot(nroot)
+	nm.root.fixSize()
+	nm.numEnts = m.numEnts + len(sorted) - common
+	return nm
+}
+
+// Merge inserts all the key,value pairs from the Map provided as an argument.
+//
+// If the argument Map has a key that is equal to a key in the receiver Map,
+// then the receiver key, its corrosponding value, and the value for the equal
+// key in the argument Map, will be passed into the ResolveConflictFunc; the
+// result of which will be stored as the new key,value pair in the resulting
+// Map.
+//
+// Merge is implemented like Union, hence it is O(m*log(n/m+1)) and the
+// returned Map maintains the structure sharing relationship with both the
+// receiver Map and the argument Map.
+func (m *Map) Merge(om *Map, resolve ResolveConflictFunc) *Map {
+	var root, _, common = union(
+		m.root, m.root.blackHeight(),
+		om.root, om.root.blackHeight(), resolve)
+
+	var nm = new(Map)
+	nm.root = blackRoot(root)
+	nm.root.fixSize()
+	nm.numEnts = m.numEnts + om.numEnts - common
+	return nm
+}
+
+// BulkDelete removes all the keys in the given key.Sort slice. It then returns
+// a new persistent Map and a slice of the keys not found in the in the
+// original Map.
+//
+// BulkDelete builds a balanced tree from the keys, then removes them all from
+// the receiver Map like Difference. That is more efficient than repeated calls
+// to Remove.
+func (m *Map) BulkDelete(keys []key.Sort) (*Map, []key.Sort) {
+	var notFound []key.Sort
+	var kvs = make([]KeyVal, 0, len(keys))
+	for _, k := range keys {
+		if m.root.findNode(k) == nil {
+			notFound = append(notFound, k)
+			continue
+		}
+		kvs = append(kvs, KeyVal{Key: k})
+	}
+
+	var sorted = sortKeyVals(kvs, KeepOrigVal)
+	var root, h = buildTree(sorted)
+
+	var nroot, _, removed = difference(
+		m.root, m.root.blackHeight(), root, h)
+
+	if removed == 0 {
+		return m, notFound
+	}
+
+	var nm = new(Map)
+	nm.root = blackRoot(nroot)
+	nm.root.fixSize()
+	nm.numEnts = m.numEnts - removed
+	return nm, notFound
+}
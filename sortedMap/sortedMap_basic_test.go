@@ -1,7 +1,11 @@
 package sortedMap
 
 import (
+	"context"
+	"encoding/json"
 	"log"
+	"math"
+	"sync/atomic"
 	"testing"
 
 	"github.com/lleo/go-functional-collections/key"
@@ -1675,3 +1679,932 @@ func TestBasicMapString(t *testing.T) {
 //		}
 //	}
 //}
+
+func TestBasicUnion(t *testing.T) {
+	var kvs = genIntKeyVals(100)
+	var m0 = buildMap(randomizeKeyVals(kvs[:60]))
+	var dupM0 = m0.dup()
+	var m1 = New()
+	for _, kv := range kvs[40:] {
+		m1 = m1.Put(kv.Key, -kv.Val.(int))
+	}
+	var dupM1 = m1.dup()
+
+	var m = m0.Union(m1)
+
+	if err := m.valid(); err != nil {
+		t.Fatalf("m is not valid; err=%s", err)
+	}
+
+	if m.NumEntries() != 100 {
+		t.Fatalf("m.NumEntries(),%d != 100", m.NumEntries())
+	}
+
+	for i, kv := range kvs {
+		var expectedVal = kv.Val.(int)
+		if i >= 60 {
+			expectedVal = -expectedVal
+		}
+		var val, found = m.Load(kv.Key)
+		if !found {
+			t.Fatalf("failed to find key=%s", kv.Key)
+		}
+		if val != expectedVal {
+			t.Fatalf("val,%v != expectedVal,%d for key=%s",
+				val, expectedVal, kv.Key)
+		}
+	}
+
+	if !m0.equiv(dupM0) {
+		t.Fatal("orig Map m0 and duplicate of m0 are not identical.")
+	}
+	if !m1.equiv(dupM1) {
+		t.Fatal("orig Map m1 and duplicate of m1 are not identical.")
+	}
+}
+
+func TestBasicIntersect(t *testing.T) {
+	var kvs = genIntKeyVals(100)
+	var m0 = buildMap(randomizeKeyVals(kvs[:60]))
+	var m1 = buildMap(randomizeKeyVals(kvs[40:]))
+
+	var m = m0.Intersect(m1)
+
+	if err := m.valid(); err != nil {
+		t.Fatalf("m is not valid; err=%s", err)
+	}
+
+	if m.NumEntries() != 20 {
+		t.Fatalf("m.NumEntries(),%d != 20", m.NumEntries())
+	}
+
+	var i = 40
+	m.Range(func(k key.Sort, v interface{}) bool {
+		if key.Cmp(k, kvs[i].Key) != 0 {
+			t.Fatalf("k,%s != kvs[%d].Key,%s", k, i, kvs[i].Key)
+		}
+		i++
+		return true
+	})
+}
+
+func TestBasicDifference(t *testing.T) {
+	var kvs = genIntKeyVals(100)
+	var m0 = buildMap(randomizeKeyVals(kvs[:60]))
+	var dupM0 = m0.dup()
+	var m1 = buildMap(randomizeKeyVals(kvs[40:]))
+
+	var m = m0.Difference(m1)
+
+	if err := m.valid(); err != nil {
+		t.Fatalf("m is not valid; err=%s", err)
+	}
+
+	if m.NumEntries() != 40 {
+		t.Fatalf("m.NumEntries(),%d != 40", m.NumEntries())
+	}
+
+	var i int
+	m.Range(func(k key.Sort, v interface{}) bool {
+		if key.Cmp(k, kvs[i].Key) != 0 {
+			t.Fatalf("k,%s != kvs[%d].Key,%s", k, i, kvs[i].Key)
+		}
+		i++
+		return true
+	})
+
+	if !m0.equiv(dupM0) {
+		t.Fatal("orig Map m0 and duplicate of m0 are not identical.")
+	}
+
+	if m0.Difference(New()) != m0 {
+		t.Fatal("m0.Difference(New()) did not return m0")
+	}
+}
+
+func TestBasicSymmetricDifference(t *testing.T) {
+	var kvs = genIntKeyVals(100)
+	var m0 = buildMap(randomizeKeyVals(kvs[:60]))
+	var m1 = buildMap(randomizeKeyVals(kvs[40:]))
+
+	var m = m0.SymmetricDifference(m1)
+
+	if err := m.valid(); err != nil {
+		t.Fatalf("m is not valid; err=%s", err)
+	}
+
+	if m.NumEntries() != 80 {
+		t.Fatalf("m.NumEntries(),%d != 80", m.NumEntries())
+	}
+
+	for i, kv := range kvs {
+		var _, found = m.Load(kv.Key)
+		if found != (i < 40 || i >= 60) {
+			t.Fatalf("m.Load(%s) found=%t", kv.Key, found)
+		}
+	}
+}
+
+func TestBasicNewFromList(t *testing.T) {
+	var kvs = genIntKeyVals(100)
+	var dupKvs = append(randomizeKeyVals(kvs), KeyVal{kvs[10].Key, -1})
+
+	var m = NewFromList(dupKvs)
+
+	if err := m.valid(); err != nil {
+		t.Fatalf("m is not valid; err=%s", err)
+	}
+
+	if m.NumEntries() != 100 {
+		t.Fatalf("m.NumEntries(),%d != 100", m.NumEntries())
+	}
+
+	for _, kv := range kvs {
+		var expectedVal = kv.Val
+		if key.Cmp(kv.Key, kvs[10].Key) == 0 {
+			expectedVal = -1
+		}
+		if val, _ := m.Load(kv.Key); val != expectedVal {
+			t.Fatalf("m.Load(%s),%v != %v", kv.Key, val, expectedVal)
+		}
+	}
+}
+
+func TestBasicNewFromSortedList(t *testing.T) {
+	for size := 0; size < 130; size++ {
+		var kvs = genIntKeyVals(size)
+
+		var m = NewFromSortedList(kvs)
+
+		if err := m.valid(); err != nil {
+			t.Fatalf("size=%d; m is not valid; err=%s", size, err)
+		}
+
+		if m.root.isRed() {
+			t.Fatalf("size=%d; m.root is red", size)
+		}
+
+		var i int
+		m.Range(func(k key.Sort, v interface{}) bool {
+			if key.Cmp(k, kvs[i].Key) != 0 {
+				t.Fatalf("size=%d; k,%s != kvs[%d].Key,%s",
+					size, k, i, kvs[i].Key)
+			}
+			i++
+			return true
+		})
+		if i != size {
+			t.Fatalf("size=%d; ranged over %d entries", size, i)
+		}
+	}
+
+	var m = NewFromSortedList(randomizeKeyVals(genIntKeyVals(100)))
+
+	if err := m.valid(); err != nil {
+		t.Fatalf("m from unsorted list is not valid; err=%s", err)
+	}
+	if m.NumEntries() != 100 {
+		t.Fatalf("m.NumEntries(),%d != 100", m.NumEntries())
+	}
+}
+
+func TestBasicBulkInsert(t *testing.T) {
+	var kvs = genIntKeyVals(100)
+	var m0 = NewFromList(kvs[:70])
+	var dupM0 = m0.dup()
+
+	var m = m0.BulkInsert(randomizeKeyVals(kvs[50:]), KeepOrigVal)
+
+	if err := m.valid(); err != nil {
+		t.Fatalf("m is not valid; err=%s", err)
+	}
+
+	if m.NumEntries() != 100 {
+		t.Fatalf("m.NumEntries(),%d != 100", m.NumEntries())
+	}
+
+	for _, kv := range kvs {
+		if val, _ := m.Load(kv.Key); val != kv.Val {
+			t.Fatalf("m.Load(%s),%v != %v", kv.Key, val, kv.Val)
+		}
+	}
+
+	if !m0.equiv(dupM0) {
+		t.Fatal("orig Map and duplicate of orig Map are not identical.")
+	}
+}
+
+func TestBasicBulkInsertNilResolve(t *testing.T) {
+	var kvs = genIntKeyVals(10)
+	var m0 = NewFromList(kvs[:5])
+
+	// duplicate keys in the slice, and keys already in m0
+	var newKvs = []KeyVal{
+		{kvs[8].Key, -1}, {kvs[8].Key, -2}, {kvs[2].Key, -3},
+	}
+	var m = m0.BulkInsert(newKvs, nil)
+
+	if err := m.valid(); err != nil {
+		t.Fatalf("m is not valid; err=%s", err)
+	}
+	if m.NumEntries() != 6 {
+		t.Fatalf("m.NumEntries(),%d != 6", m.NumEntries())
+	}
+	if m.Get(kvs[8].Key) != -2 || m.Get(kvs[2].Key) != -3 {
+		t.Fatalf("BulkInsert(kvs, nil) did not take the new values; %v, %v",
+			m.Get(kvs[8].Key), m.Get(kvs[2].Key))
+	}
+}
+
+func TestBasicBulkInsertConflict(t *testing.T) {
+	var kvs = genIntKeyVals(100)
+	var m0 = NewFromList(kvs[:70])
+
+	var newKvs = make([]KeyVal, 0, 60)
+	for _, kv := range kvs[50:] {
+		newKvs = append(newKvs, KeyVal{kv.Key, kv.Val.(int) + 1})
+	}
+	newKvs = append(newKvs, KeyVal{kvs[60].Key, 1})
+
+	var sum = func(k key.Sort, origVal, newVal interface{}) interface{} {
+		return origVal.(int) + newVal.(int)
+	}
+
+	var m = m0.BulkInsert(newKvs, sum)
+
+	if err := m.valid(); err != nil {
+		t.Fatalf("m is not valid; err=%s", err)
+	}
+
+	if m.NumEntries() != 100 {
+		t.Fatalf("m.NumEntries(),%d != 100", m.NumEntries())
+	}
+
+	for i, kv := range kvs {
+		var expectedVal = kv.Val.(int)
+		switch {
+		case i == 60:
+			expectedVal += expectedVal + 1 + 1
+		case i >= 50 && i < 70:
+			expectedVal += expectedVal + 1
+		case i >= 70:
+			expectedVal++
+		}
+		if val, _ := m.Load(kv.Key); val != expectedVal {
+			t.Fatalf("m.Load(%s),%v != %d", kv.Key, val, expectedVal)
+		}
+	}
+}
+
+func TestBasicMerge(t *testing.T) {
+	var kvs = genIntKeyVals(100)
+	var m0 = NewFromList(kvs[:50])
+	var m1 = NewFromList(kvs[50:])
+
+	var m = m0.Merge(m1, TakeNewVal)
+
+	if err := m.valid(); err != nil {
+		t.Fatalf("m is not valid; err=%s", err)
+	}
+
+	if m.NumEntries() != 100 {
+		t.Fatalf("m.NumEntries(),%d != 100", m.NumEntries())
+	}
+
+	for _, kv := range kvs {
+		if val, _ := m.Load(kv.Key); val != kv.Val {
+			t.Fatalf("m.Load(%s),%v != %v", kv.Key, val, kv.Val)
+		}
+	}
+}
+
+func TestBasicMergeConflict(t *testing.T) {
+	var kvs = genIntKeyVals(100)
+	var m0 = NewFromList(kvs[:60])
+	var dupM0 = m0.dup()
+	var m1 = New()
+	for _, kv := range kvs[50:] {
+		m1 = m1.Put(kv.Key, -kv.Val.(int))
+	}
+	var dupM1 = m1.dup()
+
+	var m = m0.Merge(m1, TakeNewVal)
+
+	if err := m.valid(); err != nil {
+		t.Fatalf("m is not valid; err=%s", err)
+	}
+
+	if m.NumEntries() != 100 {
+		t.Fatalf("m.NumEntries(),%d != 100", m.NumEntries())
+	}
+
+	for i, kv := range kvs {
+		var expectedVal = kv.Val.(int)
+		if i >= 50 {
+			expectedVal = -expectedVal
+		}
+		if val, _ := m.Load(kv.Key); val != expectedVal {
+			t.Fatalf("m.Load(%s),%v != %d", kv.Key, val, expectedVal)
+		}
+	}
+
+	if !m0.equiv(dupM0) {
+		t.Fatal("orig Map m0 and duplicate of m0 are not identical.")
+	}
+	if !m1.equiv(dupM1) {
+		t.Fatal("orig Map m1 and duplicate of m1 are not identical.")
+	}
+}
+
+func TestBasicBulkDelete(t *testing.T) {
+	var kvs = genIntKeyVals(100)
+	var m0 = NewFromList(kvs[:70])
+	var dupM0 = m0.dup()
+
+	var keys = make([]key.Sort, 0, 50)
+	for _, kv := range randomizeKeyVals(kvs[50:]) {
+		keys = append(keys, kv.Key)
+	}
+
+	var m, notFound = m0.BulkDelete(keys)
+
+	if err := m.valid(); err != nil {
+		t.Fatalf("m is not valid; err=%s", err)
+	}
+
+	if len(notFound) != 30 {
+		t.Fatalf("len(notFound),%d != 30", len(notFound))
+	}
+	for _, k := range notFound {
+		if key.Less(k, kvs[70].Key) {
+			t.Fatalf("k=%s is in notFound", k)
+		}
+	}
+
+	if m.NumEntries() != 50 {
+		t.Fatalf("m.NumEntries(),%d != 50", m.NumEntries())
+	}
+
+	for i, kv := range kvs {
+		var _, found = m.Load(kv.Key)
+		if found != (i < 50) {
+			t.Fatalf("m.Load(%s) found=%t", kv.Key, found)
+		}
+	}
+
+	if !m0.equiv(dupM0) {
+		t.Fatal("orig Map and duplicate of orig Map are not identical.")
+	}
+}
+
+func TestBasicRank(t *testing.T) {
+	var kvs = genIntKeyVals(200)
+	var m = buildMap(randomizeKeyVals(kvs))
+	for _, kv := range randomizeKeyVals(kvs[100:150]) {
+		m = m.Del(kv.Key)
+	}
+	kvs = append(append([]KeyVal{}, kvs[:100]...), kvs[150:]...)
+
+	if err := m.valid(); err != nil {
+		t.Fatalf("m is not valid; err=%s", err)
+	}
+
+	for i, kv := range kvs {
+		if r := m.Rank(kv.Key); r != i {
+			t.Fatalf("m.Rank(%s),%d != %d", kv.Key, r, i)
+		}
+		// k-5 is not in the Map, but has the same rank as k
+		var k = key.Int(int(kv.Key.(key.Int)) - 5)
+		if r := m.Rank(k); r != i {
+			t.Fatalf("m.Rank(%s),%d != %d", k, r, i)
+		}
+	}
+
+	if r := m.Rank(key.Inf(1)); r != len(kvs) {
+		t.Fatalf("m.Rank(+Inf),%d != %d", r, len(kvs))
+	}
+	if r := New().Rank(key.Int(10)); r != 0 {
+		t.Fatalf("New().Rank(10),%d != 0", r)
+	}
+}
+
+func TestBasicSelect(t *testing.T) {
+	var kvs = genIntKeyVals(200)
+	var m = buildMap(randomizeKeyVals(kvs))
+
+	for i, kv := range kvs {
+		var k, v = m.Select(i)
+		if key.Cmp(k, kv.Key) != 0 || v != kv.Val {
+			t.Fatalf("m.Select(%d),{%s, %v} != %s", i, k, v, kv)
+		}
+	}
+
+	if k, v := m.Select(-1); k != nil || v != nil {
+		t.Fatalf("m.Select(-1),{%s, %v} != {nil, nil}", k, v)
+	}
+	if k, v := m.Select(len(kvs)); k != nil || v != nil {
+		t.Fatalf("m.Select(%d),{%s, %v} != {nil, nil}", len(kvs), k, v)
+	}
+}
+
+func TestBasicCountRange(t *testing.T) {
+	var kvs = genIntKeyVals(100) // 10, 20, ..., 1000
+	var m = NewFromList(randomizeKeyVals(kvs))
+
+	var tests = []struct {
+		lo, hi key.Sort
+		num    int
+	}{
+		{key.Int(10), key.Int(1000), 100},
+		{key.Int(1000), key.Int(10), 100},
+		{key.Int(15), key.Int(995), 98},
+		{key.Int(20), key.Int(20), 1},
+		{key.Int(21), key.Int(29), 0},
+		{key.Int(100), key.Int(200), 11},
+		{key.Inf(-1), key.Inf(1), 100},
+		{key.Inf(-1), key.Int(50), 5},
+	}
+
+	for _, test := range tests {
+		if num := m.CountRange(test.lo, test.hi); num != test.num {
+			t.Fatalf("m.CountRange(%s, %s),%d != %d",
+				test.lo, test.hi, num, test.num)
+		}
+	}
+}
+
+func TestBasicSlice(t *testing.T) {
+	var kvs = genIntKeyVals(100)
+	var m = NewFromList(randomizeKeyVals(kvs))
+	var dupM = m.dup()
+
+	for i := -1; i <= len(kvs)+1; i += 3 {
+		for j := i; j <= len(kvs)+1; j += 7 {
+			var sm = m.Slice(i, j)
+
+			if err := sm.valid(); err != nil {
+				t.Fatalf("m.Slice(%d, %d) is not valid; err=%s", i, j, err)
+			}
+
+			var lo, hi = i, j
+			if lo < 0 {
+				lo = 0
+			}
+			if hi > len(kvs) {
+				hi = len(kvs)
+			}
+			if lo > len(kvs) {
+				lo = len(kvs)
+			}
+			if hi < lo {
+				hi = lo
+			}
+
+			if sm.NumEntries() != hi-lo {
+				t.Fatalf("m.Slice(%d, %d).NumEntries(),%d != %d",
+					i, j, sm.NumEntries(), hi-lo)
+			}
+			var n = lo
+			sm.Range(func(k key.Sort, v interface{}) bool {
+				if key.Cmp(k, kvs[n].Key) != 0 || v != kvs[n].Val {
+					t.Fatalf("m.Slice(%d, %d): {%s, %v} != kvs[%d],%s",
+						i, j, k, v, n, kvs[n])
+				}
+				n++
+				return true
+			})
+		}
+	}
+
+	if !m.equiv(dupM) {
+		t.Fatal("orig Map and duplicate of orig Map are not identical.")
+	}
+}
+
+func TestBasicFloorCeiling(t *testing.T) {
+	var kvs = genIntKeyVals(100) // 10, 20, ..., 1000
+	var m = NewFromList(randomizeKeyVals(kvs))
+
+	var tests = []struct {
+		name string
+		fn   func(key.Sort) (key.Sort, interface{}, bool)
+		k    key.Sort
+		res  key.Sort // nil means not found
+	}{
+		{"Floor", m.Floor, key.Int(50), key.Int(50)},
+		{"Floor", m.Floor, key.Int(55), key.Int(50)},
+		{"Floor", m.Floor, key.Int(5), nil},
+		{"Floor", m.Floor, key.Inf(1), key.Int(1000)},
+		{"Ceiling", m.Ceiling, key.Int(50), key.Int(50)},
+		{"Ceiling", m.Ceiling, key.Int(55), key.Int(60)},
+		{"Ceiling", m.Ceiling, key.Int(1005), nil},
+		{"Ceiling", m.Ceiling, key.Inf(-1), key.Int(10)},
+		{"Lower", m.Lower, key.Int(50), key.Int(40)},
+		{"Lower", m.Lower, key.Int(55), key.Int(50)},
+		{"Lower", m.Lower, key.Int(10), nil},
+		{"Higher", m.Higher, key.Int(50), key.Int(60)},
+		{"Higher", m.Higher, key.Int(55), key.Int(60)},
+		{"Higher", m.Higher, key.Int(1000), nil},
+	}
+
+	for _, test := range tests {
+		var k, v, found = test.fn(test.k)
+		if found != (test.res != nil) {
+			t.Fatalf("m.%s(%s) found=%t", test.name, test.k, found)
+		}
+		if !found {
+			if k != nil || v != nil {
+				t.Fatalf("m.%s(%s),{%s, %v} != {nil, nil}",
+					test.name, test.k, k, v)
+			}
+			continue
+		}
+		if key.Cmp(k, test.res) != 0 || v != int(test.res.(key.Int)) {
+			t.Fatalf("m.%s(%s),{%s, %v} != %s",
+				test.name, test.k, k, v, test.res)
+		}
+	}
+
+	if k, v, found := New().Floor(key.Int(10)); found || k != nil || v != nil {
+		t.Fatalf("New().Floor(10),{%s, %v} found=%t", k, v, found)
+	}
+}
+
+func TestBasicMinMax(t *testing.T) {
+	var kvs = genIntKeyVals(100)
+	var m = NewFromList(randomizeKeyVals(kvs))
+
+	if k, v, found := m.Min(); !found || key.Cmp(k, kvs[0].Key) != 0 ||
+		v != kvs[0].Val {
+		t.Fatalf("m.Min(),{%s, %v},%t != %s,true", k, v, found, kvs[0])
+	}
+	if k, v, found := m.Max(); !found || key.Cmp(k, kvs[99].Key) != 0 ||
+		v != kvs[99].Val {
+		t.Fatalf("m.Max(),{%s, %v},%t != %s,true", k, v, found, kvs[99])
+	}
+
+	if k, v, found := New().Min(); found || k != nil || v != nil {
+		t.Fatalf("New().Min(),{%s, %v},%t != nil,false", k, v, found)
+	}
+	if k, v, found := New().Max(); found || k != nil || v != nil {
+		t.Fatalf("New().Max(),{%s, %v},%t != nil,false", k, v, found)
+	}
+}
+
+func TestBasicPopMinMax(t *testing.T) {
+	var kvs = genIntKeyVals(100)
+	var m = NewFromList(randomizeKeyVals(kvs))
+	var dupM = m.dup()
+
+	var nm = m
+	for i := 0; i < len(kvs)/2; i++ {
+		var k key.Sort
+		var v interface{}
+		var found bool
+
+		nm, k, v, found = nm.PopMin()
+		if !found || key.Cmp(k, kvs[i].Key) != 0 || v != kvs[i].Val {
+			t.Fatalf("PopMin(),{%s, %v},%t != %s,true", k, v, found, kvs[i])
+		}
+
+		var last = kvs[len(kvs)-1-i]
+		nm, k, v, found = nm.PopMax()
+		if !found || key.Cmp(k, last.Key) != 0 || v != last.Val {
+			t.Fatalf("PopMax(),{%s, %v},%t != %s,true", k, v, found, last)
+		}
+
+		if err := nm.valid(); err != nil {
+			t.Fatalf("nm is not valid; err=%s", err)
+		}
+		if nm.NumEntries() != len(kvs)-2*(i+1) {
+			t.Fatalf("nm.NumEntries(),%d != %d",
+				nm.NumEntries(), len(kvs)-2*(i+1))
+		}
+	}
+
+	var empty, k, v, found = nm.PopMin()
+	if found || k != nil || v != nil || empty != nm {
+		t.Fatalf("PopMin() on an empty Map returned {%s, %v},%t", k, v, found)
+	}
+	empty, k, v, found = nm.PopMax()
+	if found || k != nil || v != nil || empty != nm {
+		t.Fatalf("PopMax() on an empty Map returned {%s, %v},%t", k, v, found)
+	}
+
+	if !m.equiv(dupM) {
+		t.Fatal("orig Map and duplicate of orig Map are not identical.")
+	}
+}
+
+func TestBasicDiff(t *testing.T) {
+	var kvs = genIntKeyVals(1000) //keys 10, 20, ..., 10000
+	var m0 = NewFromList(kvs)
+
+	var m1 = m0
+	var exp []DiffEntry
+	for i, kv := range kvs {
+		switch {
+		case i%97 == 0:
+			m1 = m1.Del(kv.Key)
+			exp = append(exp, DiffEntry{DiffRemoved, kv.Key, kv.Val, nil})
+		case i%89 == 0:
+			m1 = m1.Put(kv.Key, -1)
+			exp = append(exp, DiffEntry{DiffChanged, kv.Key, kv.Val, -1})
+		case i%83 == 0:
+			var k = key.Int(int(kv.Key.(key.Int)) + 5)
+			m1 = m1.Put(k, 5)
+			exp = append(exp, DiffEntry{DiffAdded, k, nil, 5})
+		case i%79 == 0:
+			m1 = m1.Put(kv.Key, kv.Val) //same value
+		}
+	}
+	if err := m1.valid(); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []DiffEntry
+	m0.Diff(m1, func(de DiffEntry) bool {
+		got = append(got, de)
+		return true
+	})
+	if len(got) != len(exp) {
+		t.Fatalf("len(got),%d != len(exp),%d", len(got), len(exp))
+	}
+	for i := range exp {
+		if got[i] != exp[i] {
+			t.Fatalf("got[%d],%s != exp[%d],%s", i, got[i], i, exp[i])
+		}
+	}
+
+	// unrelated but equivalent Maps have no differences
+	buildMap(randomizeKeyVals(kvs)).Diff(m0, func(de DiffEntry) bool {
+		t.Fatalf("equivalent Maps have a difference %s", de)
+		return false
+	})
+
+	var n int
+	New().Diff(m0, func(de DiffEntry) bool {
+		if de.Type != DiffAdded || de.Key != kvs[n].Key {
+			t.Fatalf("New().Diff(m0) entry %d,%s is wrong", n, de)
+		}
+		n++
+		return n < 10
+	})
+	if n != 10 {
+		t.Fatalf("Diff did not stop after 10 entries; n=%d", n)
+	}
+}
+
+func TestBasicJSON(t *testing.T) {
+	var m0 = New().
+		Put(key.Str("c"), 3.0).
+		Put(key.Str("a"), "one").
+		Put(key.Str("b"), nil)
+
+	var data, err = json.Marshal(m0)
+	if err != nil {
+		t.Fatalf("json.Marshal(m0) failed: %s", err)
+	}
+	if string(data) != `{"a":"one","b":null,"c":3}` {
+		t.Fatalf("json.Marshal(m0),%s is not an ordered object", data)
+	}
+
+	var m1 = New()
+	if err = json.Unmarshal(data, m1); err != nil {
+		t.Fatalf("json.Unmarshal(%s) failed: %s", data, err)
+	}
+	if m1.String() != m0.String() {
+		t.Fatalf("m1,%s != m0,%s", m1, m0)
+	}
+
+	var m2 = New().
+		Put(key.Int(30), "c").
+		Put(key.Int(10), "a").
+		Put(key.Int(20), "b")
+	data, err = json.Marshal(m2)
+	if err != nil {
+		t.Fatalf("json.Marshal(m2) failed: %s", err)
+	}
+	if string(data) != `[[10,"a"],[20,"b"],[30,"c"]]` {
+		t.Fatalf("json.Marshal(m2),%s is not an ordered array of pairs", data)
+	}
+
+	var m3 = New()
+	if err = json.Unmarshal(data, m3); err != nil {
+		t.Fatalf("json.Unmarshal(%s) failed: %s", data, err)
+	}
+	if m3.String() != m2.String() {
+		t.Fatalf("m3,%s != m2,%s", m3, m2)
+	}
+}
+
+func TestBasicRootDigest(t *testing.T) {
+	var kvs = make([]KeyVal, 1000)
+	for i := range kvs {
+		kvs[i] = KeyVal{key.Int(i), i}
+	}
+
+	// m0 is built balanced from a sorted list, m1 by Puts in reverse order,
+	// so their trees have different shapes.
+	var m0 = NewFromSortedList(kvs)
+	var m1 = New()
+	for i := len(kvs) - 1; i >= 0; i-- {
+		m1 = m1.Put(kvs[i].Key, kvs[i].Val)
+	}
+
+	var d0 = m0.RootDigest()
+	if m1.RootDigest() != d0 {
+		t.Fatal("equal Maps built differently have different RootDigests")
+	}
+
+	var m2 = m1.Put(key.Int(500), -1)
+	if m2.RootDigest() == d0 {
+		t.Fatal("m2.RootDigest() == m0.RootDigest() after a change")
+	}
+
+	var diffs []DiffEntry
+	m0.Diff(m2, func(de DiffEntry) bool {
+		diffs = append(diffs, de)
+		return true
+	})
+	if len(diffs) != 1 || diffs[0].Key != key.Int(500) {
+		t.Fatalf("m0.Diff(m2),%v != [{Changed 500 500 -1}]", diffs)
+	}
+
+	m0.Diff(m1, func(de DiffEntry) bool {
+		t.Fatalf("m0.Diff(m1) found %s", de)
+		return false
+	})
+
+	if m2.Put(key.Int(500), 500).RootDigest() != d0 {
+		t.Fatal("RootDigest() after undoing the change != m0.RootDigest()")
+	}
+}
+
+// pointVal is a value type without a codec, so its digest is taken from its
+// "%#v" form, which distinct pointers to equal pointVals share.
+type pointVal struct{ x, y int }
+
+func TestBasicInexactDigests(t *testing.T) {
+	var diffs = func(m0, m1 *Map) int {
+		var n int
+		m0.Diff(m1, func(de DiffEntry) bool {
+			n++
+			return true
+		})
+		return n
+	}
+
+	var m0 = New().Put(key.Str("a"), &pointVal{1, 2})
+	var m1 = New().Put(key.Str("a"), &pointVal{1, 2})
+	if m0.RootDigest() != m1.RootDigest() {
+		t.Fatal("the RootDigests of equal *pointVals differ")
+	}
+	if n := diffs(m0, m1); n != 1 {
+		t.Fatalf("m0.Diff(m1) of distinct pointers found %d differences", n)
+	}
+
+	var f0 = New().Put(key.Str("a"), 0.0)
+	var f1 = New().Put(key.Str("a"), math.Copysign(0, -1))
+	if f0.RootDigest() == f1.RootDigest() {
+		t.Fatal("the RootDigests of 0.0 and -0.0 are equal")
+	}
+	if n := diffs(f0, f1); n != 0 {
+		t.Fatalf("f0.Diff(f1) of 0.0 and -0.0 found %d differences", n)
+	}
+}
+
+func TestBasicStats(t *testing.T) {
+	var m = mkmap(
+		mknod(20, black,
+			mknod(10, red, nil, nil),
+			mknod(30, red, nil, nil)))
+
+	var stats = m.Stats()
+	if stats.Nodes != 3 || stats.RedNodes != 2 ||
+		stats.Height != 2 || stats.BlackHeight != 1 {
+		t.Fatalf("m.Stats(),%+v", stats)
+	}
+	if stats.AvgProbeDepth != 5.0/3.0 {
+		t.Fatalf("stats.AvgProbeDepth,%f != %f", stats.AvgProbeDepth, 5.0/3.0)
+	}
+	if stats.NodeBytes == 0 || stats.NodeBytes%3 != 0 {
+		t.Fatalf("stats.NodeBytes,%d", stats.NodeBytes)
+	}
+
+	var big = buildMap(randomizeKeyVals(genIntKeyVals(10000))).Stats()
+	if big.Nodes != 10000 {
+		t.Fatalf("big.Nodes,%d != 10000", big.Nodes)
+	}
+	if big.Height > 2*big.BlackHeight || big.AvgProbeDepth > float64(big.Height) {
+		t.Fatalf("big.Height,%d big.BlackHeight,%d big.AvgProbeDepth,%f",
+			big.Height, big.BlackHeight, big.AvgProbeDepth)
+	}
+
+	if empty := New().Stats(); *empty != (Stats{}) {
+		t.Fatalf("New().Stats(),%+v", empty)
+	}
+}
+
+func TestBasicAtomic(t *testing.T) {
+	var a Atomic // the zero value is ready to use
+
+	if _, found := a.Load(key.Int(1)); found {
+		t.Fatal("a zero Atomic is not empty")
+	}
+	a.Store(key.Int(1), 1)
+	if v, loaded := a.LoadOrStore(key.Int(1), 2); !loaded || v != 1 {
+		t.Fatalf("a.LoadOrStore(1, 2) = %v, %v", v, loaded)
+	}
+	if v, loaded := a.LoadOrStore(key.Int(2), 2); loaded || v != 2 {
+		t.Fatalf("a.LoadOrStore(2, 2) = %v, %v", v, loaded)
+	}
+
+	var snap = a.Snapshot()
+	a.Delete(key.Int(1))
+	if _, found := a.Load(key.Int(1)); found || snap.Get(key.Int(1)) != 1 {
+		t.Fatal("a.Delete(1) did not delete only from the Atomic")
+	}
+
+	if a.CompareAndSwap(snap, New()) {
+		t.Fatal("a.CompareAndSwap() of a stale Snapshot succeeded")
+	}
+	if !a.CompareAndSwap(a.Snapshot(), snap) || a.Snapshot() != snap {
+		t.Fatal("a.CompareAndSwap() of the current Snapshot failed")
+	}
+
+	// concurrent increments are not lost
+	var counter = NewAtomic(nil)
+	var done = make(chan struct{})
+	for g := 0; g < 4; g++ {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			for i := 0; i < 200; i++ {
+				counter.Update(func(m *Map) *Map {
+					var n, _ = m.Load(key.Int(0))
+					if n == nil {
+						n = 0
+					}
+					return m.Put(key.Int(0), n.(int)+1)
+				})
+			}
+		}()
+	}
+	for g := 0; g < 4; g++ {
+		<-done
+	}
+	if n, _ := counter.Load(key.Int(0)); n != 800 {
+		t.Fatalf("counter is %v; expected 800", n)
+	}
+}
+
+func TestBasicFold(t *testing.T) {
+	var kvs = genIntKeyVals(10000)
+	var m = buildMap(randomizeKeyVals(kvs))
+	var ctx = context.Background()
+
+	// appending is associative but not commutative, so the parts must be
+	// combined in order
+	var acc, err = m.Fold(ctx, 0, []key.Sort(nil),
+		func(acc interface{}, k key.Sort, v interface{}) interface{} {
+			return append(acc.([]key.Sort), k)
+		},
+		func(acc0, acc1 interface{}) interface{} {
+			return append(acc0.([]key.Sort), acc1.([]key.Sort)...)
+		})
+	if err != nil {
+		t.Fatalf("m.Fold() returned %v", err)
+	}
+	var keys = acc.([]key.Sort)
+	if len(keys) != len(kvs) {
+		t.Fatalf("m.Fold() folded %d keys", len(keys))
+	}
+	for i, kv := range kvs {
+		if key.Cmp(keys[i], kv.Key) != 0 {
+			t.Fatalf("m.Fold() keys[%d],%s != %s", i, keys[i], kv.Key)
+		}
+	}
+
+	var count int64
+	err = m.ParallelRange(ctx, 4, func(k key.Sort, v interface{}) bool {
+		if m.Get(k) != v {
+			t.Errorf("m.ParallelRange() visited %s", k)
+		}
+		atomic.AddInt64(&count, 1)
+		return true
+	})
+	if err != nil || count != int64(len(kvs)) {
+		t.Fatalf("m.ParallelRange() visited %d pairs; err=%v", count, err)
+	}
+
+	var cancelled, cancel = context.WithCancel(ctx)
+	cancel()
+	if acc, err = m.Fold(cancelled, 0, 0, nil, nil); err != context.Canceled ||
+		acc != nil {
+		t.Fatalf("m.Fold() with a cancelled ctx = %v, %v", acc, err)
+	}
+
+	if acc, err = New().Fold(ctx, 0, 0, nil, nil); err != nil || acc != 0 {
+		t.Fatalf("Fold() of an empty Map = %v, %v", acc, err)
+	}
+}
@@ -101,3 +101,93 @@ func TestValidNegNumEntries(t *testing.T) {
 		t.Fatal("invalid map did not show incorrect NumEntries() value")
 	}
 }
+
+func TestJoin(t *testing.T) {
+	for _, sizes := range [][2]int{{0, 0}, {0, 10}, {10, 0}, {1, 100}, {100, 1},
+		{50, 50}, {7, 300}, {300, 7}} {
+		var lkvs = genIntKeyVals(sizes[0])
+		var rkvs = make([]KeyVal, sizes[1])
+		for i := range rkvs {
+			var x = (sizes[0] + 2 + i) * 10
+			rkvs[i] = KeyVal{key.Int(x), x}
+		}
+		var l = buildMap(randomizeKeyVals(lkvs))
+		var r = buildMap(randomizeKeyVals(rkvs))
+		var dupL, dupR = l.dup(), r.dup()
+
+		var x = (sizes[0] + 1) * 10
+		var m = newNode(key.Int(x), x)
+		var root, h = join(
+			l.root, l.root.blackHeight(), m, r.root, r.root.blackHeight())
+
+		var nm = mkmap(root)
+		if err := nm.valid(); err != nil {
+			t.Fatalf("join(%d, %d) is not valid; err=%s", sizes[0], sizes[1], err)
+		}
+		if h != root.blackHeight() {
+			t.Fatalf("join(%d, %d) returned h,%d != root.blackHeight(),%d",
+				sizes[0], sizes[1], h, root.blackHeight())
+		}
+		if val, _ := nm.Load(key.Int(x)); val != x {
+			t.Fatalf("join(%d, %d) lost the value of the middle node",
+				sizes[0], sizes[1])
+		}
+		if !l.equiv(dupL) || !r.equiv(dupR) {
+			t.Fatalf("join(%d, %d) modified its arguments", sizes[0], sizes[1])
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	var kvs = genIntKeyVals(200)
+	var m = buildMap(randomizeKeyVals(kvs))
+	var dupM = m.dup()
+
+	for i := 5; i < 2010; i += 10 {
+		var k = key.Int(i)
+		if i%20 == 5 {
+			k = key.Int(i + 5) // an existing key
+		}
+
+		var l, hl, n, r, hr = split(m.root, m.root.blackHeight(), k)
+
+		if _, found := m.Load(k); (n != nil) != found {
+			t.Fatalf("split(%s) found node n=%s", k, n)
+		}
+
+		var lm, rm = mkmap(blackRoot(l)), mkmap(blackRoot(r))
+		if err := lm.valid(); err != nil {
+			t.Fatalf("split(%s) left is not valid; err=%s", k, err)
+		}
+		if err := rm.valid(); err != nil {
+			t.Fatalf("split(%s) right is not valid; err=%s", k, err)
+		}
+		if hl != l.blackHeight() || hr != r.blackHeight() {
+			t.Fatalf("split(%s) returned wrong black heights", k)
+		}
+
+		var num = lm.NumEntries() + rm.NumEntries()
+		if n != nil {
+			num++
+		}
+		if num != m.NumEntries() {
+			t.Fatalf("split(%s) lost nodes; %d != %d", k, num, m.NumEntries())
+		}
+		lm.Range(func(k0 key.Sort, v interface{}) bool {
+			if !key.Less(k0, k) {
+				t.Fatalf("split(%s) left has key %s", k, k0)
+			}
+			return true
+		})
+		rm.Range(func(k0 key.Sort, v interface{}) bool {
+			if !key.Less(k, k0) {
+				t.Fatalf("split(%s) right has key %s", k, k0)
+			}
+			return true
+		})
+	}
+
+	if !m.equiv(dupM) {
+		t.Fatal("split modified its argument")
+	}
+}
@@ -24,17 +24,14 @@ func init() {
 }
 
 func mkmap(r *node) *Map {
+	r.fixSize()
 	var num = r.count()
 	return &Map{num, r}
 }
 
 func mknod(i int, c colorType, ln, rn *node) *node {
-	return &node{key.Int(i), i, c, ln, rn}
-}
-
-type KeyVal struct {
-	Key key.Sort
-	Val interface{}
+	return &node{key: key.Int(i), val: i, color: c, ln: ln, rn: rn,
+		size: size(ln) + size(rn) + 1}
 }
 
 func genIntKeyVals(n int) []KeyVal {
@@ -0,0 +1,62 @@
+package sortedMap
+
+import "unsafe"
+
+// Stats describes the shape of the Red-Black Tree of a Map, as returned by
+// Map.Stats. None of it is needed to use a Map.
+type Stats struct {
+	// Nodes is the total count of nodes in the Map; one per key/value pair.
+	Nodes int
+
+	// RedNodes is the count of red nodes in the Map.
+	RedNodes int
+
+	// Height is the number of nodes on the longest path from the root down to
+	// a nil leaf. It is never more than twice the BlackHeight.
+	Height int
+
+	// BlackHeight is the number of black nodes on every path from the root
+	// down to a nil leaf.
+	BlackHeight int
+
+	// AvgProbeDepth is the average number of nodes a lookup of a key of the
+	// Map visits, or 0 if the Map is empty.
+	AvgProbeDepth float64
+
+	// NodeBytes estimates the memory used by the nodes of the Map, not
+	// counting the keys and values. Nodes shared with other Maps are counted
+	// in full.
+	NodeBytes uint
+}
+
+// Stats walks the Red-Black Tree and populates a Stats data structure which it
+// returns.
+func (m *Map) Stats() *Stats {
+	var stats = new(Stats)
+	var probes int
+
+	var walk func(n *node, depth int)
+	walk = func(n *node, depth int) {
+		if n == nil {
+			if depth > stats.Height {
+				stats.Height = depth
+			}
+			return
+		}
+		stats.Nodes++
+		if n.isRed() {
+			stats.RedNodes++
+		}
+		probes += depth + 1
+		walk(n.ln, depth+1)
+		walk(n.rn, depth+1)
+	}
+	walk(m.root, 0)
+
+	stats.BlackHeight = m.root.blackHeight()
+	stats.NodeBytes = uint(stats.Nodes) * uint(unsafe.Sizeof(node{}))
+	if stats.Nodes > 0 {
+		stats.AvgProbeDepth = float64(probes) / float64(stats.Nodes)
+	}
+	return stats
+}
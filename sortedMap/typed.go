@@ -0,0 +1,159 @@
+//go:build go1.21
+// +build go1.21
+
+package sortedMap
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+
+	"github.com/lleo/go-functional-collections/key"
+)
+
+// TypedMap is a type safe wrapper of the Map structure. Keys are of the
+// cmp.Ordered type K, and values are of type V, so no type assertions are
+// needed on the keys and values returned.
+//
+// Internally every key is stored as a key.SortOf[K] and every value as an
+// interface{} in the same Red-Black Tree used by Map.
+type TypedMap[K cmp.Ordered, V any] struct {
+	m *Map
+}
+
+// NewTypedMap returns a properly initialized pointer to an empty TypedMap.
+func NewTypedMap[K cmp.Ordered, V any]() *TypedMap[K, V] {
+	return &TypedMap[K, V]{New()}
+}
+
+func typedVal[V any](v interface{}) V {
+	var tv, _ = v.(V) //v is nil only for zero values of interface types
+	return tv
+}
+
+// typedKeyVal() converts the key/value pair returned by a Map method to K and
+// V. The key is nil only if found is false.
+func typedKeyVal[K cmp.Ordered, V any](
+	k key.Sort, v interface{}, found bool,
+) (K, V, bool) {
+	if !found {
+		var zk K
+		var zv V
+		return zk, zv, false
+	}
+	return k.(key.SortOf[K]).Key, typedVal[V](v), true
+}
+
+// NumEntries returns the number of key/value entries in the *TypedMap.
+func (m *TypedMap[K, V]) NumEntries() int {
+	return m.m.NumEntries()
+}
+
+// Get loads the value stored for the given key. If the key doesn't exist in the
+// TypedMap the zero value of V is returned.
+func (m *TypedMap[K, V]) Get(k K) V {
+	return typedVal[V](m.m.Get(key.SortOf[K]{Key: k}))
+}
+
+// Load retrieves the value related to the key in the TypedMap. It also returns
+// a bool to indicate the value was found.
+func (m *TypedMap[K, V]) Load(k K) (V, bool) {
+	var v, found = m.m.Load(key.SortOf[K]{Key: k})
+	return typedVal[V](v), found
+}
+
+// LoadOrStore returns the existing value for the key if present. Otherwise, it
+// stores the given value and returns it, with a new persistent *TypedMap. The
+// bool is true if the value was loaded.
+func (m *TypedMap[K, V]) LoadOrStore(k K, v V) (*TypedMap[K, V], V, bool) {
+	var nm, val, found = m.m.LoadOrStore(key.SortOf[K]{Key: k}, v)
+	if !found {
+		return &TypedMap[K, V]{nm}, v, false
+	}
+	return m, typedVal[V](val), true
+}
+
+// Put stores a new key/value mapping. It returns a new persistent *TypedMap.
+func (m *TypedMap[K, V]) Put(k K, v V) *TypedMap[K, V] {
+	return &TypedMap[K, V]{m.m.Put(key.SortOf[K]{Key: k}, v)}
+}
+
+// Store inserts a new key/value pair and returns a new *TypedMap and a bool
+// indicating if the key/value was added (true) or merely replaced (false).
+func (m *TypedMap[K, V]) Store(k K, v V) (*TypedMap[K, V], bool) {
+	var nm, added = m.m.Store(key.SortOf[K]{Key: k}, v)
+	return &TypedMap[K, V]{nm}, added
+}
+
+// Del deletes any entry with the given key. If the key did not exist the
+// original *TypedMap is returned.
+func (m *TypedMap[K, V]) Del(k K) *TypedMap[K, V] {
+	var nm, _, _ = m.Remove(k)
+	return nm
+}
+
+// Remove deletes any key/value mapping for the given key. It returns the new
+// *TypedMap, the value that was stored for that key, and a bool indicating if
+// the key was found and deleted. If the key didn't exist the original
+// *TypedMap is returned.
+func (m *TypedMap[K, V]) Remove(k K) (*TypedMap[K, V], V, bool) {
+	var nm, val, removed = m.m.Remove(key.SortOf[K]{Key: k})
+	if !removed {
+		return m, typedVal[V](val), false
+	}
+	return &TypedMap[K, V]{nm}, typedVal[V](val), true
+}
+
+// Floor returns the key/value pair with the greatest key in the TypedMap less
+// than or equal to k. The bool is false if there is no such key.
+func (m *TypedMap[K, V]) Floor(k K) (K, V, bool) {
+	return typedKeyVal[K, V](m.m.Floor(key.SortOf[K]{Key: k}))
+}
+
+// Ceiling returns the key/value pair with the least key in the TypedMap
+// greater than or equal to k. The bool is false if there is no such key.
+func (m *TypedMap[K, V]) Ceiling(k K) (K, V, bool) {
+	return typedKeyVal[K, V](m.m.Ceiling(key.SortOf[K]{Key: k}))
+}
+
+// Min returns the key/value pair with the least key in the TypedMap. The bool
+// is false if the TypedMap is empty.
+func (m *TypedMap[K, V]) Min() (K, V, bool) {
+	return typedKeyVal[K, V](m.m.Min())
+}
+
+// Max returns the key/value pair with the greatest key in the TypedMap. The
+// bool is false if the TypedMap is empty.
+func (m *TypedMap[K, V]) Max() (K, V, bool) {
+	return typedKeyVal[K, V](m.m.Max())
+}
+
+// RangeLimit executes the given function on every key/value pair, in order,
+// from the start key up to and including the end key. If the start key is
+// greater than the end key, then the traversal will be in reverse order. If
+// the function returns false, RangeLimit stops.
+func (m *TypedMap[K, V]) RangeLimit(start, end K, fn func(K, V) bool) {
+	m.m.RangeLimit(key.SortOf[K]{Key: start}, key.SortOf[K]{Key: end},
+		func(k key.Sort, v interface{}) bool {
+			return fn(k.(key.SortOf[K]).Key, typedVal[V](v))
+		})
+}
+
+// Range executes the given function on every key/value pair in order. If the
+// function returns false, Range stops.
+func (m *TypedMap[K, V]) Range(fn func(K, V) bool) {
+	m.m.Range(func(k key.Sort, v interface{}) bool {
+		return fn(k.(key.SortOf[K]).Key, typedVal[V](v))
+	})
+}
+
+// String returns a string representation of the TypedMap, with the key/value
+// pairs in order.
+func (m *TypedMap[K, V]) String() string {
+	var strs = make([]string, 0, m.NumEntries())
+	m.Range(func(k K, v V) bool {
+		strs = append(strs, fmt.Sprintf("%#v: %#v", k, v))
+		return true
+	})
+	return "{" + strings.Join(strs, ", ") + "}"
+}
@@ -0,0 +1,57 @@
+//go:build go1.21
+// +build go1.21
+
+package sortedMap
+
+import (
+	"testing"
+)
+
+func TestTypedMap(t *testing.T) {
+	var m = NewTypedMap[int, string]()
+	for i := 0; i < 100; i += 10 {
+		m = m.Put(i, string(rune('a'+i/10)))
+	}
+
+	if m.NumEntries() != 10 {
+		t.Fatalf("m.NumEntries(),%d != 10", m.NumEntries())
+	}
+	if v, found := m.Load(30); !found || v != "d" {
+		t.Fatalf("m.Load(30),%q,%t != \"d\",true", v, found)
+	}
+	if v, found := m.Load(35); found || v != "" {
+		t.Fatalf("m.Load(35),%q,%t != \"\",false", v, found)
+	}
+
+	if k, v, found := m.Floor(35); !found || k != 30 || v != "d" {
+		t.Fatalf("m.Floor(35),%d,%q,%t != 30,\"d\",true", k, v, found)
+	}
+	if k, _, found := m.Ceiling(95); found || k != 0 {
+		t.Fatalf("m.Ceiling(95),%d,%t != 0,false", k, found)
+	}
+	if k, _, _ := m.Min(); k != 0 {
+		t.Fatalf("m.Min(),%d != 0", k)
+	}
+	if k, _, _ := m.Max(); k != 90 {
+		t.Fatalf("m.Max(),%d != 90", k)
+	}
+
+	var keys []int
+	m.RangeLimit(65, 20, func(k int, _ string) bool {
+		keys = append(keys, k)
+		return true
+	})
+	if len(keys) != 5 || keys[0] != 60 || keys[4] != 20 {
+		t.Fatalf("m.RangeLimit(65, 20) keys,%v != [60 50 40 30 20]", keys)
+	}
+
+	var nm, v, removed = m.Remove(50)
+	if !removed || v != "f" || nm.NumEntries() != 9 || m.NumEntries() != 10 {
+		t.Fatalf("m.Remove(50),%q,%t failed", v, removed)
+	}
+
+	var s = NewTypedMap[string, int]().Put("b", 2).Put("a", 1).String()
+	if s != `{"a": 1, "b": 2}` {
+		t.Fatalf("String(),%s != {\"a\": 1, \"b\": 2}", s)
+	}
+}
@@ -0,0 +1,52 @@
+package sortedSet
+
+import (
+	"fmt"
+
+	"github.com/lleo/go-functional-collections/key"
+)
+
+// DiffType is the kind of difference between two Sets reported by Diff.
+type DiffType int
+
+const (
+	// DiffAdded means the key is only in the argument Set.
+	DiffAdded DiffType = iota
+	// DiffRemoved means the key is only in the receiver Set.
+	DiffRemoved
+)
+
+func (dt DiffType) String() string {
+	switch dt {
+	case DiffAdded:
+		return "DiffAdded"
+	case DiffRemoved:
+		return "DiffRemoved"
+	}
+	return fmt.Sprintf("DiffType(%d)", int(dt))
+}
+
+// DiffEntry describes a key that is in only one of two Sets.
+type DiffEntry struct {
+	Type DiffType
+	Key  key.Sort
+}
+
+func (de DiffEntry) String() string {
+	return fmt.Sprintf("{%s, %s}", de.Type, de.Key)
+}
+
+// Diff calls the given function, in key order, for every key that is in only
+// one of the receiver Set and the argument Set; that is the keys added or
+// removed going from the receiver Set to the argument Set. If the function
+// returns false, Diff stops.
+//
+// Diff splits the argument Set by each key of the receiver Set, like Union,
+// and skips any sub-tree the two Sets share. So for Sets derived from one
+// another (like successive versions of the same Set), the cost of Diff tracks
+// the size of the changes, not the size of the Sets. Sub-trees which are not
+// shared are always looked inside, even when their digests are equal; see the
+// merkle package.
+func (s *Set) Diff(other *Set, fn func(DiffEntry) bool) {
+	diff(s.root, other.root, other.root.blackHeight(), fn)
+}
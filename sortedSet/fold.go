@@ -0,0 +1,119 @@
+package sortedSet
+
+import (
+	"context"
+
+	"github.com/lleo/go-functional-collections/internal/parallel"
+	"github.com/lleo/go-functional-collections/key"
+)
+
+// The Red-Black Tree of a Set is balanced, so cutting it a few levels down
+// gives sub-trees of about the same size, and a Set never changes, so they can
+// be walked each in its own goroutine with no locking. ParallelRange and Fold
+// do that, with a pool of workers taking the parts one at a time.
+
+// treePart is a part of the tree: the node first, if not nil, followed by
+// every node of the sub-tree sub, in order.
+type treePart struct {
+	first *node
+	sub   *node
+}
+
+// walk() calls fn on the nodes of the part in ascending order, until fn
+// returns false, and then returns false.
+func (p treePart) walk(fn func(*node) bool) bool {
+	if p.first != nil && !fn(p.first) {
+		return false
+	}
+	return p.sub.walk(key.Inf(-1), key.Inf(1), false, fn)
+}
+
+// ParallelRange calls fn for every key of the Set, from up to workers
+// goroutines at once, or runtime.GOMAXPROCS(0) if workers <= 0. So fn MUST be
+// safe for concurrent use; each goroutine visits the keys of its part of the
+// Set in order, but the parts are in no particular order. If fn returns false,
+// the remaining keys are skipped, though calls already under way in
+// other goroutines still finish.
+//
+// ParallelRange also stops once ctx is done, and then returns ctx.Err().
+func (s *Set) ParallelRange(
+	ctx context.Context,
+	workers int,
+	fn func(key.Sort) bool,
+) error {
+	workers = parallel.Workers(workers)
+	var parts = s.split(workers)
+	return parallel.Parts(ctx, workers, len(parts),
+		func(part int, halted func() bool) bool {
+			return parts[part].walk(func(n *node) bool {
+				return !halted() && fn(n.key)
+			})
+		})
+}
+
+// Fold reduces the keys of the Set to one value, MapReduce style, using up to
+// workers goroutines at once, or runtime.GOMAXPROCS(0) if workers <= 0. Each
+// goroutine folds the keys of a part of the Set, in order, starting from zero,
+// and the results of the parts are then combined in order. So zero MUST be an
+// identity of combine, and combine MUST be associative; it need not be
+// commutative.
+//
+// If ctx is done before the fold is, Fold returns nil and ctx.Err().
+func (s *Set) Fold(
+	ctx context.Context,
+	workers int,
+	zero interface{},
+	fold func(acc interface{}, k key.Sort) interface{},
+	combine func(acc0, acc1 interface{}) interface{},
+) (interface{}, error) {
+	workers = parallel.Workers(workers)
+	var parts = s.split(workers)
+	var accs = make([]interface{}, len(parts))
+	var err = parallel.Parts(ctx, workers, len(parts),
+		func(part int, halted func() bool) bool {
+			var acc = zero
+			if parts[part].walk(func(n *node) bool {
+				if halted() {
+					return false
+				}
+				acc = fold(acc, n.key)
+				return true
+			}) {
+				accs[part] = acc
+			}
+			return true
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	var acc = zero
+	for _, partAcc := range accs {
+		acc = combine(acc, partAcc)
+	}
+	return acc, nil
+}
+
+// split() cuts the tree of the Set into about four parts per worker, so a
+// worker held up by a slow part does not hold up the others; the parts are in
+// ascending order.
+func (s *Set) split(workers int) []treePart {
+	var depth int
+	for 1<<uint(depth) < 4*workers {
+		depth++
+	}
+	return splitTree(nil, s.root, depth, nil)
+}
+
+// splitTree() appends the parts of the tree n, preceded by the node first, cut
+// the given depth down.
+func splitTree(parts []treePart, n *node, depth int, first *node) []treePart {
+	if n == nil || depth == 0 {
+		if n == nil && first == nil {
+			return parts
+		}
+		return append(parts, treePart{first, n})
+	}
+	parts = splitTree(parts, n.ln, depth-1, first)
+	return splitTree(parts, n.rn, depth-1, n)
+}
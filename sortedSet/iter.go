@@ -0,0 +1,72 @@
+//go:build go1.23
+// +build go1.23
+
+package sortedSet
+
+import (
+	"cmp"
+	"iter"
+
+	"github.com/lleo/go-functional-collections/key"
+)
+
+// seq() returns an iterator over the keys of the Set between lo and hi
+// inclusive.
+func (s *Set) seq(lo, hi key.Sort, reverse bool) iter.Seq[key.Sort] {
+	return func(yield func(key.Sort) bool) {
+		s.root.walk(lo, hi, reverse, func(n *node) bool {
+			return yield(n.key)
+		})
+	}
+}
+
+// All returns an iterator over the keys of the *Set in ascending order, for
+// use with a for-range loop.
+func (s *Set) All() iter.Seq[key.Sort] {
+	return s.seq(key.Inf(-1), key.Inf(1), false)
+}
+
+// Backward returns an iterator over the keys of the *Set in descending order.
+func (s *Set) Backward() iter.Seq[key.Sort] {
+	return s.seq(key.Inf(-1), key.Inf(1), true)
+}
+
+// Between returns an iterator over the keys of the *Set, in ascending order,
+// that are between lo and hi inclusive. Like CountRange, lo and hi may be
+// given in either order.
+func (s *Set) Between(lo, hi key.Sort) iter.Seq[key.Sort] {
+	if key.Less(hi, lo) {
+		lo, hi = hi, lo
+	}
+	return s.seq(lo, hi, false)
+}
+
+// typedSeq() converts an iterator returned by a Set method to K.
+func typedSeq[K cmp.Ordered](seq iter.Seq[key.Sort]) iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for k := range seq {
+			if !yield(k.(key.SortOf[K]).Key) {
+				return
+			}
+		}
+	}
+}
+
+// All returns an iterator over the keys of the *TypedSet in ascending order,
+// for use with a for-range loop.
+func (s *TypedSet[K]) All() iter.Seq[K] {
+	return typedSeq[K](s.s.All())
+}
+
+// Backward returns an iterator over the keys of the *TypedSet in descending
+// order.
+func (s *TypedSet[K]) Backward() iter.Seq[K] {
+	return typedSeq[K](s.s.Backward())
+}
+
+// Between returns an iterator over the keys of the *TypedSet, in ascending
+// order, that are between lo and hi inclusive.
+func (s *TypedSet[K]) Between(lo, hi K) iter.Seq[K] {
+	var l, h = key.SortOf[K]{Key: lo}, key.SortOf[K]{Key: hi}
+	return typedSeq[K](s.s.Between(l, h))
+}
@@ -0,0 +1,71 @@
+//go:build go1.23
+// +build go1.23
+
+package sortedSet
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/lleo/go-functional-collections/key"
+)
+
+func TestIterAll(t *testing.T) {
+	var keys = buildKeys(1000)
+	var s = NewFromList(randomizeKeys(keys))
+
+	if got := slices.Collect(s.All()); !slices.Equal(got, keys) {
+		t.Fatal("slices.Collect(s.All()) != keys")
+	}
+
+	var i = len(keys)
+	for k := range s.Backward() {
+		i--
+		if k != keys[i] {
+			t.Fatalf("s.Backward() yielded %s; expected %s", k, keys[i])
+		}
+	}
+	if i != 0 {
+		t.Fatalf("s.Backward() stopped at %d", i)
+	}
+
+	for range New().All() {
+		t.Fatal("empty Set yielded a key")
+	}
+}
+
+func TestIterBetween(t *testing.T) {
+	var s = NewFromList(buildKeys(100)) //keys 10, 20, ..., 1000
+
+	var got = slices.Collect(s.Between(key.Int(15), key.Int(45)))
+	var exp = []key.Sort{key.Int(20), key.Int(30), key.Int(40)}
+	if !slices.Equal(got, exp) {
+		t.Fatalf("s.Between(15, 45),%v != %v", got, exp)
+	}
+	got = slices.Collect(s.Between(key.Int(1000), key.Int(990)))
+	exp = []key.Sort{key.Int(990), key.Int(1000)}
+	if !slices.Equal(got, exp) {
+		t.Fatalf("s.Between(1000, 990),%v != %v", got, exp)
+	}
+
+	var n int
+	for range s.Backward() {
+		n++
+		if n == 5 {
+			break
+		}
+	}
+	if n != 5 {
+		t.Fatalf("break out of s.Backward() loop after %d iterations", n)
+	}
+
+	var ts = NewTypedSetFromList([]string{"a", "b", "c", "d"})
+	if got := slices.Collect(ts.Backward()); !slices.Equal(got,
+		[]string{"d", "c", "b", "a"}) {
+		t.Fatalf("slices.Collect(ts.Backward()),%v != [d c b a]", got)
+	}
+	if got := slices.Collect(ts.Between("bb", "z")); !slices.Equal(got,
+		[]string{"c", "d"}) {
+		t.Fatalf("slices.Collect(ts.Between(\"bb\", \"z\")),%v != [c d]", got)
+	}
+}
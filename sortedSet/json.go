@@ -0,0 +1,53 @@
+package sortedSet
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/lleo/go-functional-collections/codec"
+	"github.com/lleo/go-functional-collections/key"
+)
+
+var (
+	_ json.Marshaler   = (*Set)(nil)
+	_ json.Unmarshaler = (*Set)(nil)
+)
+
+// MarshalJSON implements the json.Marshaler interface. A Set is encoded as a
+// JSON array of its keys in order.
+func (s *Set) MarshalJSON() ([]byte, error) {
+	var keys = make([]interface{}, 0, s.NumEntries())
+	s.Range(func(k key.Sort) bool {
+		keys = append(keys, k)
+		return true
+	})
+	return codec.EncodeJSONKeys(keys)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. It replaces the
+// contents of the receiver, so it SHOULD only be called on a Set that no one
+// else holds, like one just returned by New. Keys are decoded by
+// codec.DecodeJSONKey and MUST implement key.Sort.
+func (s *Set) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil
+	}
+
+	var keys []key.Sort
+	var err = codec.DecodeJSONKeys(data, func(k interface{}) error {
+		var sk, isSort = k.(key.Sort)
+		if !isSort {
+			return fmt.Errorf(
+				"sortedSet: decoded key of type %T is not a key.Sort", k)
+		}
+		keys = append(keys, sk)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	*s = *NewFromList(keys)
+	return nil
+}
@@ -0,0 +1,16 @@
+package sortedSet
+
+import "github.com/lleo/go-functional-collections/merkle"
+
+// RootDigest returns the content digest of the Set; the sum of the
+// merkle.Entry digests of its keys. Sets holding equal keys have equal
+// RootDigests, whatever the shape of their trees, but equal RootDigests do not
+// prove the keys equal. See the merkle package.
+//
+// The digest of every sub-tree is cached in its root node. So the first
+// RootDigest of a Set visits every node, but afterwards RootDigest is O(1),
+// and the RootDigest of a new version of the Set only visits the nodes created
+// since.
+func (s *Set) RootDigest() merkle.Digest {
+	return s.root.treeDigest()
+}
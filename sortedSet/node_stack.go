@@ -31,6 +31,27 @@ func (ns *nodeStack) dup() *nodeStack {
 	return nns
 }
 
+// own() is dup() for bulk operations. Only the nodes of the path that are not
+// already owned by o get copied and stitched together.
+func (ns *nodeStack) own(o nodeOwner) *nodeStack {
+	var nns = newNodeStack(ns.len())
+	if ns.len() == 0 {
+		return nns
+	}
+	(*nns)[0] = o.copy((*ns)[0])
+	for i, n := range (*ns)[1:] {
+		// i is relative to (*ns)[1:] not (*ns)[] so it is -1 what was expected.
+		var nn = o.copy(n)
+		if n.isLeftChildOf((*nns)[i]) {
+			(*nns)[i].ln = nn
+		} else {
+			(*nns)[i].rn = nn
+		}
+		(*nns)[i+1] = nn
+	}
+	return nns
+}
+
 func (ns *nodeStack) push(n *node) *nodeStack {
 	(*ns) = append(*ns, n)
 	return ns
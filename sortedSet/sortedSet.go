@@ -19,6 +19,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
 
 	"github.com/lleo/go-functional-collections/key"
@@ -35,14 +36,14 @@ func New() *Set {
 }
 
 func (s *Set) valid() error {
-	var _, err = s.root.valid()
-	if err != nil {
-		return err
-	}
 	var count = s.root.count()
 	if count != s.numEnts {
 		return errors.New("enumerated count of subnodes != s.NumEntries()")
 	}
+	var _, err = s.root.valid()
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -77,6 +78,128 @@ func (s *Set) IsSet(k key.Sort) bool {
 	return n != nil
 }
 
+// Rank returns the number of keys in the Set that are less than k. If k is in
+// the Set, that is the index of k in sorted order. Rank is O(log(n)).
+func (s *Set) Rank(k key.Sort) int {
+	return s.root.rank(k, false)
+}
+
+// Select returns the key at index i in sorted order, or nil if i is out of
+// range. Select is O(log(n)).
+func (s *Set) Select(i int) key.Sort {
+	var n = s.root.selectNode(i)
+	if n == nil {
+		return nil
+	}
+	return n.key
+}
+
+// CountRange returns the number of keys in the Set between lo and hi,
+// including lo and hi. Like IterLimit, lo and hi may be given in either order.
+// CountRange is O(log(n)).
+func (s *Set) CountRange(lo, hi key.Sort) int {
+	if key.Less(hi, lo) {
+		lo, hi = hi, lo
+	}
+	return s.root.rank(hi, true) - s.root.rank(lo, false)
+}
+
+// Slice returns a new Set containing the keys at indexes i through j-1 in
+// sorted order. The indexes are clamped to the range of the Set, like a Go
+// slice expression that never panics.
+//
+// Slice is O(log(n)) and the returned Set maintains the structure sharing
+// relationship with the receiver Set.
+func (s *Set) Slice(i, j int) *Set {
+	if i < 0 {
+		i = 0
+	}
+	if j > s.numEnts {
+		j = s.numEnts
+	}
+	if i >= j {
+		return New()
+	}
+	if i == 0 && j == s.numEnts {
+		return s
+	}
+
+	var l, hl, _, _ = splitAt(s.root, s.root.blackHeight(), j)
+	l.fixSize()
+	var _, _, r, _ = splitAt(l, hl, i)
+
+	var ns = new(Set)
+	ns.root = blackRoot(r)
+	ns.root.fixSize()
+	ns.numEnts = j - i
+	return ns
+}
+
+// Floor returns the greatest key in the Set less than or equal to k. The bool
+// is false, and the key nil, if there is no such key.
+func (s *Set) Floor(k key.Sort) (key.Sort, bool) {
+	return nodeKey(s.root.floor(k, true))
+}
+
+// Ceiling returns the least key in the Set greater than or equal to k. The bool
+// is false, and the key nil, if there is no such key.
+func (s *Set) Ceiling(k key.Sort) (key.Sort, bool) {
+	return nodeKey(s.root.ceiling(k, true))
+}
+
+// Lower returns the greatest key in the Set strictly less than k. The bool is
+// false, and the key nil, if there is no such key.
+func (s *Set) Lower(k key.Sort) (key.Sort, bool) {
+	return nodeKey(s.root.floor(k, false))
+}
+
+// Higher returns the least key in the Set strictly greater than k. The bool is
+// false, and the key nil, if there is no such key.
+func (s *Set) Higher(k key.Sort) (key.Sort, bool) {
+	return nodeKey(s.root.ceiling(k, false))
+}
+
+// Min returns the least key in the Set. The bool is false if the Set is empty.
+func (s *Set) Min() (key.Sort, bool) {
+	return nodeKey(s.root.min())
+}
+
+// Max returns the greatest key in the Set. The bool is false if the Set is
+// empty.
+func (s *Set) Max() (key.Sort, bool) {
+	return nodeKey(s.root.max())
+}
+
+// PopMin returns a new Set without its least key, along with that key. If the
+// Set is empty it returns the receiver Set, a nil key and false.
+func (s *Set) PopMin() (*Set, key.Sort, bool) {
+	var n = s.root.min()
+	if n == nil {
+		return s, nil, false
+	}
+	var ns, _ = s.Remove(n.key)
+	return ns, n.key, true
+}
+
+// PopMax returns a new Set without its greatest key, along with that key. If
+// the Set is empty it returns the receiver Set, a nil key and false.
+func (s *Set) PopMax() (*Set, key.Sort, bool) {
+	var n = s.root.max()
+	if n == nil {
+		return s, nil, false
+	}
+	var ns, _ = s.Remove(n.key)
+	return ns, n.key, true
+}
+
+// nodeKey() returns the key of n and true, or nil and false if n is nil.
+func nodeKey(n *node) (key.Sort, bool) {
+	if n == nil {
+		return nil, false
+	}
+	return n.key, true
+}
+
 func (s *Set) Set(k key.Sort) *Set {
 	var nm, _ = s.Add(k)
 	return nm
@@ -112,6 +235,11 @@ func (s *Set) addInplace(k key.Sort) bool {
 		return false
 	}
 
+	// The path is modified in place, so its sizes must be recalculated.
+	for _, n := range *path {
+		n.size = 0
+	}
+
 	var nn *node
 	on, nn, path = s.insert(k, path)
 	s.establishRoot(on, nn, path)
@@ -140,6 +268,7 @@ func (s *Set) insert(k key.Sort, path *nodeStack) (*node, *node, *nodeStack) {
 func (s *Set) persist(on, nn *node, path *nodeStack) {
 	if path.len() == 0 {
 		s.root = nn
+		s.root.fixSize()
 		return
 	}
 
@@ -159,11 +288,14 @@ func (s *Set) persist(on, nn *node, path *nodeStack) {
 			parent.rn = nn
 		}
 	}
+
+	s.root.fixSize()
 }
 
 func (s *Set) establishRoot(on, nn *node, path *nodeStack) {
 	if path.len() == 0 {
 		s.root = nn
+		s.root.fixSize()
 		return
 	}
 
@@ -183,6 +315,8 @@ func (s *Set) establishRoot(on, nn *node, path *nodeStack) {
 			parent.rn = nn
 		}
 	}
+
+	s.root.fixSize()
 }
 
 // rotateLeft() takes the target node(n) and its parent(p). We are rotating on
@@ -455,18 +589,29 @@ func (s *Set) Remove(k key.Sort) (*Set, bool) {
 	//found node associated with k
 
 	var nm = s.copy()
+	nm.remove(on, path, nil)
+
+	return nm, true
+}
+
+// remove() eliminates the node on from the tip of the given path, which MUST
+// already be copied or owned, then rebalances the *Set. Any further nodes that
+// need copying are copied via the nodeOwner o.
+//
+// remove() MUST be called on a new *Set.
+func (s *Set) remove(on *node, path *nodeStack, o nodeOwner) {
+	s.numEnts--
 
 	if on.ln == nil || on.rn == nil {
-		nm.removeNodeWithZeroOrOneChild(on, path)
-		nm.numEnts--
-		return nm, true
+		s.removeNodeWithZeroOrOneChild(on, path)
+		return
 	}
 	//else has two children
 
 	// if node has two children swap values with previous in-order node, then
 	// delete that child, which will have at most one child of its' own.
-	var otcn = on          //otcn == origninal-two-child-node
-	var ntcn = otcn.copy() //ntcn == new-two-child-node
+	var otcn = on           //otcn == origninal-two-child-node
+	var ntcn = o.copy(otcn) //ntcn == new-two-child-node
 
 	var parent = path.peek()
 	if parent != nil {
@@ -482,7 +627,7 @@ func (s *Set) Remove(k key.Sort) (*Set, bool) {
 	parent = ntcn
 	on = on.ln
 	for on.rn != nil {
-		var nn = on.copy()
+		var nn = o.copy(on)
 		if on.isLeftChildOf(parent) {
 			parent.ln = nn
 		} else {
@@ -498,9 +643,7 @@ func (s *Set) Remove(k key.Sort) (*Set, bool) {
 	//ntcn's content (key) is the previous node's (aka 'on').
 	ntcn.key = on.key
 
-	nm.removeNodeWithZeroOrOneChild(on, path)
-	nm.numEnts--
-	return nm, true
+	s.removeNodeWithZeroOrOneChild(on, path)
 }
 
 //removeNodeWithZeroOrOneChild() deletes a node that has only on child.
@@ -904,41 +1047,95 @@ func NewFromList(keys []key.Sort) *Set {
 	return s
 }
 
-// BulkInsert stores all the given keys from the argument hash.Key slice  into
+// addOwned() is the bulk operation version of Add(). Nodes owned by o are
+// modified in place, all other nodes are copied.
+//
+// addOwned() MUST be called on a new *Set.
+func (s *Set) addOwned(k key.Sort, o nodeOwner) bool {
+	var on, path = s.root.findNodeWithPath(k)
+
+	if on != nil {
+		return false
+	}
+
+	path = path.own(o)
+
+	var nn *node
+	on, nn, path = s.insert(k, path)
+	s.establishRoot(on, nn, path)
+
+	return true
+}
+
+// removeOwned() is the bulk operation version of Remove(). Nodes owned by o
+// are modified in place, all other nodes are copied.
+//
+// removeOwned() MUST be called on a new *Set.
+func (s *Set) removeOwned(k key.Sort, o nodeOwner) bool {
+	var on, path = s.root.findNodeWithPath(k)
+
+	if on == nil {
+		return false
+	}
+
+	path = path.own(o)
+
+	s.remove(on, path, o)
+
+	return true
+}
+
+// BulkInsert stores all the given keys from the argument key.Sort slice into
 // the receiver Set.
 //
 // The returned Set maintains the structure sharing relationship with the
 // receiver Set.
 //
 // BulkInsert is implemented more efficiently than repeated calls to Add.
-func BulkInsert(keys []key.Sort) *Set {
-	log.Println("not implemented")
-	return nil
+func (s *Set) BulkInsert(keys []key.Sort) *Set {
+	var o = make(nodeOwner)
+	var ns = s.copy()
+	for _, k := range keys {
+		ns.addOwned(k, o)
+	}
+	return ns
 }
 
 // Merge returns a Set that contains all the entries from the receiver Set and
 // the argument Set.
-func Merge(other *Set) *Set {
-	log.Println("not implemented")
-	return nil
+//
+// Merge is the same as Union.
+func (s *Set) Merge(other *Set) *Set {
+	return s.Union(other)
 }
 
-// BulkDelete removes all the keys in the given hash.Key slice. It returns a new
+// BulkDelete removes all the keys in the given key.Sort slice. It returns a new
 // persistent Set and a slice of the keys not found in the in the original Set.
 //
 // BulkDelete is implemented more efficiently than repeated calls to Remove.
-func BulkDelete(keys []key.Sort) (*Set, []key.Sort) {
-	log.Println("not implemented")
-	return nil, nil
+func (s *Set) BulkDelete(keys []key.Sort) (*Set, []key.Sort) {
+	var notFound []key.Sort
+	var o = make(nodeOwner)
+	var ns = s.copy()
+	for _, k := range keys {
+		if !ns.removeOwned(k, o) {
+			notFound = append(notFound, k)
+		}
+	}
+	return ns, notFound
 }
 
-// BulkDelete2 removes all the keys in the given hash.Key slice. It returns a
+// BulkDelete2 removes all the keys in the given key.Sort slice. It returns a
 // new persistent Set.
 //
 // BulkDelete2 is implemented more efficiently than repeated calls to Remove.
-func BulkDelete2(key []key.Sort) *Set {
-	log.Println("not implemented")
-	return nil
+func (s *Set) BulkDelete2(keys []key.Sort) *Set {
+	var o = make(nodeOwner)
+	var ns = s.copy()
+	for _, k := range keys {
+		ns.removeOwned(k, o)
+	}
+	return ns
 }
 
 // Union returns a Set that contains all entries of all given Sets.
@@ -953,15 +1150,44 @@ func BulkDelete2(key []key.Sort) *Set {
 //    return resultSet
 //
 func Union(sets ...*Set) *Set {
-	log.Println("not implemented")
-	return nil
+	if len(sets) == 0 {
+		return nil
+	}
+	if len(sets) == 1 {
+		return sets[0]
+	}
+
+	// sort a copy, so the caller's slice keeps its order
+	sets = append([]*Set(nil), sets...)
+	sort.Slice(sets, func(i, j int) bool {
+		return sets[i].NumEntries() > sets[j].NumEntries()
+	})
+	// sets is now sorted from largest to smallest
+
+	var resultSet = sets[0] // largest
+	for _, s := range sets[1:] {
+		resultSet = resultSet.Union(s)
+	}
+	return resultSet
 }
 
 // Union returns a Set that contains all entries for the receiver Set and the
 // argument Set.
+//
+// Union splits the argument Set by each key of the receiver Set and joins the
+// pieces back together. Given Sets of n and m entries, where m <= n, this is
+// O(m*log(n/m+1)). The returned Set maintains the structure sharing
+// relationship with both the receiver Set and the argument Set.
 func (s *Set) Union(other *Set) *Set {
-	log.Println("not implemented")
-	return nil
+	var root, _, common = union(
+		s.root, s.root.blackHeight(),
+		other.root, other.root.blackHeight())
+
+	var ns = new(Set)
+	ns.root = blackRoot(root)
+	ns.root.fixSize()
+	ns.numEnts = s.numEnts + other.numEnts - common
+	return ns
 }
 
 // Intersection returns a Set that is the Intersection  of all the given sets.
@@ -976,32 +1202,85 @@ func (s *Set) Union(other *Set) *Set {
 //    return resultSet
 //
 func Intersection(sets ...*Set) *Set {
-	log.Println("not implemented")
-	return nil
+	if len(sets) == 0 {
+		return nil
+	}
+	if len(sets) == 1 {
+		return sets[0]
+	}
+
+	// sort a copy, so the caller's slice keeps its order
+	sets = append([]*Set(nil), sets...)
+	sort.Slice(sets, func(i, j int) bool {
+		return sets[i].NumEntries() > sets[j].NumEntries()
+	})
+	// sets is now sorted from largest to smallest
+
+	var res = sets[0] // largest
+	for _, s := range sets[1:] {
+		res = res.Intersect(s)
+	}
+	return res
 }
 
 // Intersect returns a Set that contains only the entries that the receiver Set
 // and the argument Set have in common.
 //
-// There is no structural sharing with either the receiver Set or argument Set.
+// Intersect is implemented like Union, hence it is O(m*log(n/m+1)) and the
+// returned Set maintains the structure sharing relationship with the receiver
+// Set.
 func (s *Set) Intersect(other *Set) *Set {
-	log.Println("not implemented")
-	return nil
+	var root, _, num = intersect(
+		s.root, s.root.blackHeight(),
+		other.root, other.root.blackHeight())
+
+	var ns = new(Set)
+	ns.root = blackRoot(root)
+	ns.root.fixSize()
+	ns.numEnts = num
+	return ns
 }
 
 // Difference returns a new Set based on the receiver Set that contains none of
 // the entries from the argument Set.
 //
-// Difference is implemented by repeated calls to Unset.
-// OR
-// Difference2 is implemented by repeated calls to removePersist, the basic
-// function of BulkDelete.
-// OR
-// Difference1 is calculated by creating a list of shared keys, then doing a
-// BulkDelete2 of those shared keys from the receiver Set.
+// Difference splits the receiver Set by each key of the argument Set and joins
+// the pieces back together without the shared keys. It is O(m*log(n/m+1)) and
+// the returned Set maintains the structure sharing relationship with the
+// receiver Set.
 //
 // NOTE: a.Difference(b) != b.Difference(a)
 func (s *Set) Difference(other *Set) *Set {
-	log.Println("not implemented")
-	return nil
+	var root, _, removed = difference(
+		s.root, s.root.blackHeight(),
+		other.root, other.root.blackHeight())
+
+	if removed == 0 {
+		return s
+	}
+
+	var ns = new(Set)
+	ns.root = blackRoot(root)
+	ns.root.fixSize()
+	ns.numEnts = s.numEnts - removed
+	return ns
+}
+
+// SymmetricDifference returns a new Set that contains the entries of the
+// receiver Set and the argument Set, except for the entries they have in
+// common.
+//
+// SymmetricDifference is implemented like Union, hence it is O(m*log(n/m+1))
+// and the returned Set maintains the structure sharing relationship with both
+// the receiver Set and the argument Set.
+func (s *Set) SymmetricDifference(other *Set) *Set {
+	var root, _, common = symmetricDifference(
+		s.root, s.root.blackHeight(),
+		other.root, other.root.blackHeight())
+
+	var ns = new(Set)
+	ns.root = blackRoot(root)
+	ns.root.fixSize()
+	ns.numEnts = s.numEnts + other.numEnts - 2*common
+	return ns
 }
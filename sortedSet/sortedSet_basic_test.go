@@ -1,7 +1,10 @@
 package sortedSet
 
 import (
+	"context"
+	"encoding/json"
 	"log"
+	"sync/atomic"
 	"testing"
 
 	"github.com/lleo/go-functional-collections/key"
@@ -1490,16 +1493,747 @@ func TestBasicNewFromList(t *testing.T) {
 	}
 }
 
-func TestBasicBulkInsert(t *testing.T) {}
+func TestBasicBulkInsert(t *testing.T) {
+	var keys = buildKeys(100)
+	var s0 = NewFromList(keys[:70])
+	var dupS0 = s0.DeepCopy()
 
-func TestBasicMerge(t *testing.T) {}
+	var s1 = s0.BulkInsert(randomizeKeys(keys[50:]))
 
-func TestBasicBulkDelete(t *testing.T) {}
+	if err := s1.valid(); err != nil {
+		t.Fatalf("s1 is not valid; err=%s", err)
+	}
+
+	if s1.NumEntries() != 100 {
+		t.Fatalf("s1.NumEntries(),%d != 100", s1.NumEntries())
+	}
+
+	for _, k := range keys {
+		if !s1.IsSet(k) {
+			t.Fatalf("k=%s is not set", k)
+		}
+	}
+
+	if !s0.Equiv(dupS0) {
+		t.Fatal("orig Set and duplicate of orig Set are not identical.")
+	}
+}
+
+func TestBasicMerge(t *testing.T) {
+	var keys = buildKeys(100)
+	var s0 = NewFromList(keys[:60])
+	var dupS0 = s0.DeepCopy()
+	var s1 = NewFromList(keys[50:])
+	var dupS1 = s1.DeepCopy()
+
+	var s = s0.Merge(s1)
+
+	if err := s.valid(); err != nil {
+		t.Fatalf("s is not valid; err=%s", err)
+	}
+
+	if s.NumEntries() != 100 {
+		t.Fatalf("s.NumEntries(),%d != 100", s.NumEntries())
+	}
+
+	for _, k := range keys {
+		if !s.IsSet(k) {
+			t.Fatalf("k=%s is not set", k)
+		}
+	}
+
+	if !s0.Equiv(dupS0) {
+		t.Fatal("orig Set s0 and duplicate of s0 are not identical.")
+	}
+	if !s1.Equiv(dupS1) {
+		t.Fatal("orig Set s1 and duplicate of s1 are not identical.")
+	}
+}
+
+func TestBasicBulkDelete(t *testing.T) {
+	var keys = buildKeys(100)
+	var s0 = NewFromList(keys[:70])
+	var dupS0 = s0.DeepCopy()
+
+	var s1, notFound = s0.BulkDelete(randomizeKeys(keys[50:]))
+
+	if err := s1.valid(); err != nil {
+		t.Fatalf("s1 is not valid; err=%s", err)
+	}
+
+	if len(notFound) != 30 {
+		t.Fatalf("len(notFound),%d != 30", len(notFound))
+	}
+
+	for _, k := range notFound {
+		if key.Less(k, keys[70]) {
+			t.Fatalf("k=%s is in notFound", k)
+		}
+	}
+
+	if s1.NumEntries() != 50 {
+		t.Fatalf("s1.NumEntries(),%d != 50", s1.NumEntries())
+	}
+
+	for _, k := range keys[:50] {
+		if !s1.IsSet(k) {
+			t.Fatalf("k=%s is not set", k)
+		}
+	}
+
+	for _, k := range keys[50:] {
+		if s1.IsSet(k) {
+			t.Fatalf("k=%s is set", k)
+		}
+	}
+
+	if !s0.Equiv(dupS0) {
+		t.Fatal("orig Set and duplicate of orig Set are not identical.")
+	}
+}
+
+func TestBasicBulkDelete2(t *testing.T) {
+	var keys = buildKeys(100)
+	var s0 = NewFromList(keys)
+	var dupS0 = s0.DeepCopy()
+
+	var s1 = s0.BulkDelete2(randomizeKeys(keys))
+
+	if err := s1.valid(); err != nil {
+		t.Fatalf("s1 is not valid; err=%s", err)
+	}
+
+	if s1.NumEntries() != 0 {
+		t.Fatalf("s1.NumEntries(),%d != 0", s1.NumEntries())
+	}
+
+	if s1.Count() != 0 {
+		t.Fatalf("s1.Count(),%d != 0", s1.Count())
+	}
+
+	if !s0.Equiv(dupS0) {
+		t.Fatal("orig Set and duplicate of orig Set are not identical.")
+	}
+}
+
+func TestBasicUnion(t *testing.T) {
+	var keys = buildKeys(100)
+	var s0 = NewFromList(keys[:40])
+	var s1 = NewFromList(keys[30:70])
+	var s2 = NewFromList(keys[60:])
+
+	var s = Union(s0, s1, s2)
+
+	if err := s.valid(); err != nil {
+		t.Fatalf("s is not valid; err=%s", err)
+	}
+
+	if s.NumEntries() != 100 {
+		t.Fatalf("s.NumEntries(),%d != 100", s.NumEntries())
+	}
+
+	for _, k := range keys {
+		if !s.IsSet(k) {
+			t.Fatalf("k=%s is not set", k)
+		}
+	}
+
+	var s01 = s0.Union(s1)
+
+	if s01.NumEntries() != 70 {
+		t.Fatalf("s01.NumEntries(),%d != 70", s01.NumEntries())
+	}
+
+	// the variadic functions do not reorder the caller's slice
+	var sets = []*Set{s2, s0, s01}
+	Union(sets...)
+	Intersection(sets...)
+	if sets[0] != s2 || sets[1] != s0 || sets[2] != s01 {
+		t.Fatal("Union() or Intersection() reordered the given slice")
+	}
+}
+
+func TestBasicIntersect(t *testing.T) {
+	var keys = buildKeys(100)
+	var s0 = NewFromList(keys[:60])
+	var dupS0 = s0.DeepCopy()
+	var s1 = NewFromList(keys[40:])
+	var dupS1 = s1.DeepCopy()
+
+	var s = s0.Intersect(s1)
+
+	if err := s.valid(); err != nil {
+		t.Fatalf("s is not valid; err=%s", err)
+	}
+
+	var foundKeys = s.Keys()
+	if len(foundKeys) != 20 {
+		t.Fatalf("len(foundKeys),%d != 20", len(foundKeys))
+	}
+	for i, k := range keys[40:60] {
+		if key.Cmp(k, foundKeys[i]) != 0 {
+			t.Fatalf("key,%s != foundKeys[%d],%s", k, i, foundKeys[i])
+		}
+	}
+
+	if !s0.Equiv(dupS0) {
+		t.Fatal("orig Set s0 and duplicate of s0 are not identical.")
+	}
+	if !s1.Equiv(dupS1) {
+		t.Fatal("orig Set s1 and duplicate of s1 are not identical.")
+	}
+
+	var s2 = Intersection(s0, s1, NewFromList(keys[50:]))
+
+	if s2.NumEntries() != 10 {
+		t.Fatalf("s2.NumEntries(),%d != 10", s2.NumEntries())
+	}
+}
+
+func TestBasicDifference(t *testing.T) {
+	var keys = buildKeys(100)
+	var s0 = NewFromList(keys[:60])
+	var dupS0 = s0.DeepCopy()
+	var s1 = NewFromList(keys[40:])
+	var dupS1 = s1.DeepCopy()
+
+	var s = s0.Difference(s1)
+
+	if err := s.valid(); err != nil {
+		t.Fatalf("s is not valid; err=%s", err)
+	}
+
+	var foundKeys = s.Keys()
+	if len(foundKeys) != 40 {
+		t.Fatalf("len(foundKeys),%d != 40", len(foundKeys))
+	}
+	for i, k := range keys[:40] {
+		if key.Cmp(k, foundKeys[i]) != 0 {
+			t.Fatalf("key,%s != foundKeys[%d],%s", k, i, foundKeys[i])
+		}
+	}
+
+	if !s0.Equiv(dupS0) {
+		t.Fatal("orig Set s0 and duplicate of s0 are not identical.")
+	}
+	if !s1.Equiv(dupS1) {
+		t.Fatal("orig Set s1 and duplicate of s1 are not identical.")
+	}
+}
+
+func TestBasicSymmetricDifference(t *testing.T) {
+	var keys = buildKeys(100)
+	var s0 = NewFromList(keys[:60])
+	var dupS0 = s0.DeepCopy()
+	var s1 = NewFromList(keys[40:])
+	var dupS1 = s1.DeepCopy()
+
+	var s = s0.SymmetricDifference(s1)
+
+	if err := s.valid(); err != nil {
+		t.Fatalf("s is not valid; err=%s", err)
+	}
+
+	var shouldHaveKeys = append(append([]key.Sort{}, keys[:40]...), keys[60:]...)
+
+	var foundKeys = s.Keys()
+	if len(foundKeys) != len(shouldHaveKeys) {
+		t.Fatalf("len(foundKeys),%d != len(shouldHaveKeys),%d",
+			len(foundKeys), len(shouldHaveKeys))
+	}
+	for i, k := range shouldHaveKeys {
+		if key.Cmp(k, foundKeys[i]) != 0 {
+			t.Fatalf("key,%s != foundKeys[%d],%s", k, i, foundKeys[i])
+		}
+	}
+
+	if !s0.Equiv(dupS0) {
+		t.Fatal("orig Set s0 and duplicate of s0 are not identical.")
+	}
+	if !s1.Equiv(dupS1) {
+		t.Fatal("orig Set s1 and duplicate of s1 are not identical.")
+	}
+}
+
+func TestBasicUnionShared(t *testing.T) {
+	var keys = buildKeys(100)
+	var s0 = NewFromList(keys)
+	var s1 = s0.Unset(keys[50])
+
+	var s = s0.Union(s1)
+
+	if err := s.valid(); err != nil {
+		t.Fatalf("s is not valid; err=%s", err)
+	}
+
+	if s.NumEntries() != 100 {
+		t.Fatalf("s.NumEntries(),%d != 100", s.NumEntries())
+	}
+
+	var d = s0.Difference(s0)
+
+	if d.NumEntries() != 0 || d.root != nil {
+		t.Fatalf("s0.Difference(s0) is not empty; d=%s", d)
+	}
+}
 
-func TestBasicBulkDelete2(t *testing.T) {}
+func TestBasicRank(t *testing.T) {
+	var keys = buildKeys(200)
+	var s = NewFromList(randomizeKeys(keys))
+	s = s.BulkDelete2(keys[100:150])
+	keys = append(append([]key.Sort{}, keys[:100]...), keys[150:]...)
 
-func TestBasicUnion(t *testing.T) {}
+	if err := s.valid(); err != nil {
+		t.Fatalf("s is not valid; err=%s", err)
+	}
+
+	for i, k := range keys {
+		if r := s.Rank(k); r != i {
+			t.Fatalf("s.Rank(%s),%d != %d", k, r, i)
+		}
+		// k-5 is not in the Set, but has the same rank as k
+		if r := s.Rank(key.Int(int(k.(key.Int)) - 5)); r != i {
+			t.Fatalf("s.Rank(%d),%d != %d", int(k.(key.Int))-5, r, i)
+		}
+	}
+
+	if r := s.Rank(key.Inf(1)); r != len(keys) {
+		t.Fatalf("s.Rank(+Inf),%d != %d", r, len(keys))
+	}
+	if r := New().Rank(key.Int(10)); r != 0 {
+		t.Fatalf("New().Rank(10),%d != 0", r)
+	}
+}
+
+func TestBasicSelect(t *testing.T) {
+	var keys = buildKeys(200)
+	var s = NewFromList(randomizeKeys(keys))
+
+	for i, k := range keys {
+		if k0 := s.Select(i); key.Cmp(k0, k) != 0 {
+			t.Fatalf("s.Select(%d),%s != %s", i, k0, k)
+		}
+	}
+
+	if k := s.Select(-1); k != nil {
+		t.Fatalf("s.Select(-1),%s != nil", k)
+	}
+	if k := s.Select(len(keys)); k != nil {
+		t.Fatalf("s.Select(%d),%s != nil", len(keys), k)
+	}
+}
+
+func TestBasicCountRange(t *testing.T) {
+	var keys = buildKeys(100) // 10, 20, ..., 1000
+	var s = NewFromList(randomizeKeys(keys))
+
+	var tests = []struct {
+		lo, hi key.Sort
+		num    int
+	}{
+		{key.Int(10), key.Int(1000), 100},
+		{key.Int(1000), key.Int(10), 100},
+		{key.Int(15), key.Int(995), 98},
+		{key.Int(20), key.Int(20), 1},
+		{key.Int(21), key.Int(29), 0},
+		{key.Int(100), key.Int(200), 11},
+		{key.Inf(-1), key.Inf(1), 100},
+		{key.Inf(-1), key.Int(50), 5},
+	}
+
+	for _, test := range tests {
+		if num := s.CountRange(test.lo, test.hi); num != test.num {
+			t.Fatalf("s.CountRange(%s, %s),%d != %d",
+				test.lo, test.hi, num, test.num)
+		}
+	}
+}
+
+func TestBasicSlice(t *testing.T) {
+	var keys = buildKeys(100)
+	var s = NewFromList(randomizeKeys(keys))
+	var dupS = s.DeepCopy()
+
+	for i := -1; i <= len(keys)+1; i += 3 {
+		for j := i; j <= len(keys)+1; j += 7 {
+			var ss = s.Slice(i, j)
+
+			if err := ss.valid(); err != nil {
+				t.Fatalf("s.Slice(%d, %d) is not valid; err=%s", i, j, err)
+			}
+
+			var lo, hi = i, j
+			if lo < 0 {
+				lo = 0
+			}
+			if hi > len(keys) {
+				hi = len(keys)
+			}
+			if lo > len(keys) {
+				lo = len(keys)
+			}
+			if hi < lo {
+				hi = lo
+			}
+
+			var foundKeys = ss.Keys()
+			if len(foundKeys) != hi-lo {
+				t.Fatalf("len(s.Slice(%d, %d).Keys()),%d != %d",
+					i, j, len(foundKeys), hi-lo)
+			}
+			for n, k := range keys[lo:hi] {
+				if key.Cmp(k, foundKeys[n]) != 0 {
+					t.Fatalf("s.Slice(%d, %d): key,%s != foundKeys[%d],%s",
+						i, j, k, n, foundKeys[n])
+				}
+			}
+		}
+	}
+
+	if !s.Equiv(dupS) {
+		t.Fatal("orig Set s and duplicate of s are not identical.")
+	}
+}
+
+func TestBasicFloorCeiling(t *testing.T) {
+	var keys = buildKeys(100) // 10, 20, ..., 1000
+	var s = NewFromList(randomizeKeys(keys))
+
+	var tests = []struct {
+		name string
+		fn   func(key.Sort) (key.Sort, bool)
+		k    key.Sort
+		res  key.Sort // nil means not found
+	}{
+		{"Floor", s.Floor, key.Int(50), key.Int(50)},
+		{"Floor", s.Floor, key.Int(55), key.Int(50)},
+		{"Floor", s.Floor, key.Int(5), nil},
+		{"Floor", s.Floor, key.Inf(1), key.Int(1000)},
+		{"Ceiling", s.Ceiling, key.Int(50), key.Int(50)},
+		{"Ceiling", s.Ceiling, key.Int(55), key.Int(60)},
+		{"Ceiling", s.Ceiling, key.Int(1005), nil},
+		{"Ceiling", s.Ceiling, key.Inf(-1), key.Int(10)},
+		{"Lower", s.Lower, key.Int(50), key.Int(40)},
+		{"Lower", s.Lower, key.Int(55), key.Int(50)},
+		{"Lower", s.Lower, key.Int(10), nil},
+		{"Higher", s.Higher, key.Int(50), key.Int(60)},
+		{"Higher", s.Higher, key.Int(55), key.Int(60)},
+		{"Higher", s.Higher, key.Int(1000), nil},
+	}
+
+	for _, test := range tests {
+		var k, found = test.fn(test.k)
+		if found != (test.res != nil) {
+			t.Fatalf("s.%s(%s) found=%t", test.name, test.k, found)
+		}
+		if found && key.Cmp(k, test.res) != 0 {
+			t.Fatalf("s.%s(%s),%s != %s", test.name, test.k, k, test.res)
+		}
+		if !found && k != nil {
+			t.Fatalf("s.%s(%s),%s != nil", test.name, test.k, k)
+		}
+	}
+
+	if k, found := New().Floor(key.Int(10)); found || k != nil {
+		t.Fatalf("New().Floor(10),%s found=%t", k, found)
+	}
+}
+
+func TestBasicMinMax(t *testing.T) {
+	var keys = buildKeys(100)
+	var s = NewFromList(randomizeKeys(keys))
+
+	if k, found := s.Min(); !found || key.Cmp(k, keys[0]) != 0 {
+		t.Fatalf("s.Min(),%s,%t != %s,true", k, found, keys[0])
+	}
+	if k, found := s.Max(); !found || key.Cmp(k, keys[99]) != 0 {
+		t.Fatalf("s.Max(),%s,%t != %s,true", k, found, keys[99])
+	}
+
+	if k, found := New().Min(); found || k != nil {
+		t.Fatalf("New().Min(),%s,%t != nil,false", k, found)
+	}
+	if k, found := New().Max(); found || k != nil {
+		t.Fatalf("New().Max(),%s,%t != nil,false", k, found)
+	}
+}
+
+func TestBasicPopMinMax(t *testing.T) {
+	var keys = buildKeys(100)
+	var s = NewFromList(randomizeKeys(keys))
+	var dupS = s.DeepCopy()
+
+	var ns = s
+	for i := 0; i < len(keys)/2; i++ {
+		var k key.Sort
+		var found bool
+
+		ns, k, found = ns.PopMin()
+		if !found || key.Cmp(k, keys[i]) != 0 {
+			t.Fatalf("PopMin(),%s,%t != %s,true", k, found, keys[i])
+		}
+
+		ns, k, found = ns.PopMax()
+		if !found || key.Cmp(k, keys[len(keys)-1-i]) != 0 {
+			t.Fatalf("PopMax(),%s,%t != %s,true", k, found, keys[len(keys)-1-i])
+		}
+
+		if err := ns.valid(); err != nil {
+			t.Fatalf("ns is not valid; err=%s", err)
+		}
+		if ns.NumEntries() != len(keys)-2*(i+1) {
+			t.Fatalf("ns.NumEntries(),%d != %d",
+				ns.NumEntries(), len(keys)-2*(i+1))
+		}
+	}
+
+	var empty, k, found = ns.PopMin()
+	if found || k != nil || empty != ns {
+		t.Fatalf("PopMin() on an empty Set returned %s,%t", k, found)
+	}
+	empty, k, found = ns.PopMax()
+	if found || k != nil || empty != ns {
+		t.Fatalf("PopMax() on an empty Set returned %s,%t", k, found)
+	}
+
+	if !s.Equiv(dupS) {
+		t.Fatal("orig Set s and duplicate of s are not identical.")
+	}
+}
+
+func TestBasicDiff(t *testing.T) {
+	var keys = buildKeys(1000) //keys 10, 20, ..., 10000
+	var s0 = NewFromList(keys)
+
+	var s1 = s0
+	var exp []DiffEntry
+	for i, k := range keys {
+		switch {
+		case i%97 == 0:
+			s1 = s1.Unset(k)
+			exp = append(exp, DiffEntry{DiffRemoved, k})
+		case i%83 == 0:
+			var nk = key.Int(int(k.(key.Int)) + 5)
+			s1 = s1.Set(nk)
+			exp = append(exp, DiffEntry{DiffAdded, nk})
+		}
+	}
+
+	var got []DiffEntry
+	s0.Diff(s1, func(de DiffEntry) bool {
+		got = append(got, de)
+		return true
+	})
+	if len(got) != len(exp) {
+		t.Fatalf("len(got),%d != len(exp),%d", len(got), len(exp))
+	}
+	for i := range exp {
+		if got[i] != exp[i] {
+			t.Fatalf("got[%d],%s != exp[%d],%s", i, got[i], i, exp[i])
+		}
+	}
+
+	// unrelated but equivalent Sets have no differences
+	buildSet(randomizeKeys(keys)).Diff(s0, func(de DiffEntry) bool {
+		t.Fatalf("equivalent Sets have a difference %s", de)
+		return false
+	})
+
+	var n int
+	s0.Diff(New(), func(de DiffEntry) bool {
+		if de.Type != DiffRemoved || de.Key != keys[n] {
+			t.Fatalf("s0.Diff(New()) entry %d,%s is wrong", n, de)
+		}
+		n++
+		return n < 10
+	})
+	if n != 10 {
+		t.Fatalf("Diff did not stop after 10 entries; n=%d", n)
+	}
+}
+
+func TestBasicJSON(t *testing.T) {
+	var s0 = New().
+		Set(key.Int(3)).
+		Set(key.Int(1)).
+		Set(key.Int(2))
+
+	var data, err = json.Marshal(s0)
+	if err != nil {
+		t.Fatalf("json.Marshal(s0) failed: %s", err)
+	}
+	if string(data) != `[1,2,3]` {
+		t.Fatalf("json.Marshal(s0),%s != [1,2,3]", data)
+	}
+
+	var s1 = New()
+	if err = json.Unmarshal(data, s1); err != nil {
+		t.Fatalf("json.Unmarshal(%s) failed: %s", data, err)
+	}
+	if s1.String() != s0.String() {
+		t.Fatalf("s1,%s != s0,%s", s1, s0)
+	}
+
+	data, err = json.Marshal(New())
+	if err != nil || string(data) != "[]" {
+		t.Fatalf("json.Marshal(New()),%s,%v != [],nil", data, err)
+	}
+}
+
+func TestBasicRootDigest(t *testing.T) {
+	var keys = buildKeys(1000)
 
-func TestBasicIntersect(t *testing.T) {}
+	var s0 = NewFromList(keys)
+	var s1 = New()
+	for _, k := range randomizeKeys(keys) {
+		s1 = s1.Set(k)
+	}
+
+	var d0 = s0.RootDigest()
+	if s1.RootDigest() != d0 {
+		t.Fatal("equal Sets built differently have different RootDigests")
+	}
+
+	var s2 = s1.Unset(keys[500])
+	if s2.RootDigest() == d0 {
+		t.Fatal("s2.RootDigest() == s0.RootDigest() after a change")
+	}
 
-func TestBasicDifference(t *testing.T) {}
+	var diffs []DiffEntry
+	s0.Diff(s2, func(de DiffEntry) bool {
+		diffs = append(diffs, de)
+		return true
+	})
+	if len(diffs) != 1 || diffs[0].Key != keys[500] {
+		t.Fatalf("s0.Diff(s2),%v != [{Removed %s}]", diffs, keys[500])
+	}
+
+	if s2.Set(keys[500]).RootDigest() != d0 {
+		t.Fatal("RootDigest() after undoing the change != s0.RootDigest()")
+	}
+}
+
+func TestBasicStats(t *testing.T) {
+	var s = mkset(
+		mknod(20, black,
+			mknod(10, red, nil, nil),
+			mknod(30, red, nil, nil)))
+
+	var stats = s.Stats()
+	if stats.Nodes != 3 || stats.RedNodes != 2 ||
+		stats.Height != 2 || stats.BlackHeight != 1 {
+		t.Fatalf("s.Stats(),%+v", stats)
+	}
+	if stats.AvgProbeDepth != 5.0/3.0 {
+		t.Fatalf("stats.AvgProbeDepth,%f != %f", stats.AvgProbeDepth, 5.0/3.0)
+	}
+	if stats.NodeBytes == 0 || stats.NodeBytes%3 != 0 {
+		t.Fatalf("stats.NodeBytes,%d", stats.NodeBytes)
+	}
+
+	var big = buildSet(randomizeKeys(buildKeys(10000))).Stats()
+	if big.Nodes != 10000 {
+		t.Fatalf("big.Nodes,%d != 10000", big.Nodes)
+	}
+	if big.Height > 2*big.BlackHeight || big.AvgProbeDepth > float64(big.Height) {
+		t.Fatalf("big.Height,%d big.BlackHeight,%d big.AvgProbeDepth,%f",
+			big.Height, big.BlackHeight, big.AvgProbeDepth)
+	}
+
+	if empty := New().Stats(); *empty != (Stats{}) {
+		t.Fatalf("New().Stats(),%+v", empty)
+	}
+}
+
+func TestBasicAtomic(t *testing.T) {
+	var a Atomic // the zero value is ready to use
+
+	if a.Load(key.Int(1)) {
+		t.Fatal("a zero Atomic is not empty")
+	}
+	a.Store(key.Int(1))
+	if !a.LoadOrStore(key.Int(1)) || a.LoadOrStore(key.Int(2)) {
+		t.Fatal("a.LoadOrStore() did not report the keys already set")
+	}
+
+	var snap = a.Snapshot()
+	a.Delete(key.Int(1))
+	if a.Load(key.Int(1)) || !snap.IsSet(key.Int(1)) {
+		t.Fatal("a.Delete(1) did not delete only from the Atomic")
+	}
+
+	if a.CompareAndSwap(snap, New()) {
+		t.Fatal("a.CompareAndSwap() of a stale Snapshot succeeded")
+	}
+	if !a.CompareAndSwap(a.Snapshot(), snap) || a.Snapshot() != snap {
+		t.Fatal("a.CompareAndSwap() of the current Snapshot failed")
+	}
+
+	// concurrent additions are not lost
+	var keys = buildKeys(1000)
+	var as = NewAtomic(nil)
+	var done = make(chan struct{})
+	for g := 0; g < 4; g++ {
+		go func(g int) {
+			defer func() { done <- struct{}{} }()
+			for i := g; i < len(keys); i += 4 {
+				as.Store(keys[i])
+			}
+		}(g)
+	}
+	for g := 0; g < 4; g++ {
+		<-done
+	}
+	if as.Snapshot().NumEntries() != len(keys) {
+		t.Fatalf("as.Snapshot().NumEntries(),%d != %d",
+			as.Snapshot().NumEntries(), len(keys))
+	}
+}
+
+func TestBasicFold(t *testing.T) {
+	var keys = buildKeys(10000)
+	var s = buildSet(randomizeKeys(keys))
+	var ctx = context.Background()
+
+	// appending is associative but not commutative, so the parts must be
+	// combined in order
+	var acc, err = s.Fold(ctx, 0, []key.Sort(nil),
+		func(acc interface{}, k key.Sort) interface{} {
+			return append(acc.([]key.Sort), k)
+		},
+		func(acc0, acc1 interface{}) interface{} {
+			return append(acc0.([]key.Sort), acc1.([]key.Sort)...)
+		})
+	if err != nil {
+		t.Fatalf("s.Fold() returned %v", err)
+	}
+	var folded = acc.([]key.Sort)
+	if len(folded) != len(keys) {
+		t.Fatalf("s.Fold() folded %d keys", len(folded))
+	}
+	for i, k := range keys {
+		if key.Cmp(folded[i], k) != 0 {
+			t.Fatalf("s.Fold() folded[%d],%s != %s", i, folded[i], k)
+		}
+	}
+
+	var count int64
+	err = s.ParallelRange(ctx, 4, func(k key.Sort) bool {
+		if !s.IsSet(k) {
+			t.Errorf("s.ParallelRange() visited %s", k)
+		}
+		atomic.AddInt64(&count, 1)
+		return true
+	})
+	if err != nil || count != int64(len(keys)) {
+		t.Fatalf("s.ParallelRange() visited %d keys; err=%v", count, err)
+	}
+
+	var cancelled, cancel = context.WithCancel(ctx)
+	cancel()
+	if err = s.ParallelRange(cancelled, 0, func(key.Sort) bool {
+		return true
+	}); err != context.Canceled {
+		t.Fatalf("s.ParallelRange() with a cancelled ctx returned %v", err)
+	}
+}
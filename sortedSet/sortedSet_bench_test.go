@@ -193,3 +193,22 @@ func BenchmarkSetOne10MM(b *testing.B) {
 		_ = s.Set(xtraKeys[i])
 	}
 }
+
+func benchmarkUnion(b *testing.B, numKeys, numKeysXtra int) {
+	var keys, xtraKeys = buildKeys(numKeys, numKeysXtra)
+	var s = sortedSet.NewFromList(keys)
+	var xtra = sortedSet.NewFromList(xtraKeys)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_ = s.Union(xtra)
+	}
+}
+
+func BenchmarkUnion100M(b *testing.B) {
+	benchmarkUnion(b, NumKeys100M, NumKeysExtra100M)
+}
+
+func BenchmarkUnion1MM(b *testing.B) {
+	benchmarkUnion(b, NumKeys1MM, NumKeysExtra1MM)
+}
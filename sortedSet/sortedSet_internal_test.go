@@ -101,3 +101,91 @@ func TestValidNegNumEntries(t *testing.T) {
 		t.Fatal("invalid set did not show incorrect NumEntries() value")
 	}
 }
+
+func TestJoin(t *testing.T) {
+	for _, sizes := range [][2]int{{0, 0}, {0, 10}, {10, 0}, {1, 100}, {100, 1},
+		{50, 50}, {7, 300}, {300, 7}} {
+		var lkeys = buildKeys(sizes[0])
+		var rkeys = make([]key.Sort, sizes[1])
+		for i := range rkeys {
+			rkeys[i] = key.Int((sizes[0] + 2 + i) * 10)
+		}
+		var l = buildSet(randomizeKeys(lkeys))
+		var r = buildSet(randomizeKeys(rkeys))
+		var dupL, dupR = l.DeepCopy(), r.DeepCopy()
+
+		var m = newNode(key.Int((sizes[0] + 1) * 10))
+		var root, h = join(
+			l.root, l.root.blackHeight(), m, r.root, r.root.blackHeight())
+
+		var s = mkset(root)
+		if err := s.valid(); err != nil {
+			t.Fatalf("join(%d, %d) is not valid; err=%s", sizes[0], sizes[1], err)
+		}
+		if h != root.blackHeight() {
+			t.Fatalf("join(%d, %d) returned h,%d != root.blackHeight(),%d",
+				sizes[0], sizes[1], h, root.blackHeight())
+		}
+		if s.NumEntries() != sizes[0]+sizes[1]+1 {
+			t.Fatalf("s.NumEntries(),%d != %d",
+				s.NumEntries(), sizes[0]+sizes[1]+1)
+		}
+		if !l.Equiv(dupL) || !r.Equiv(dupR) {
+			t.Fatalf("join(%d, %d) modified its arguments", sizes[0], sizes[1])
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	var keys = buildKeys(200)
+	var s = buildSet(randomizeKeys(keys))
+	var dupS = s.DeepCopy()
+
+	for i := 5; i < 2010; i += 10 {
+		var k = key.Int(i)
+		if i%20 == 5 {
+			k = key.Int(i + 5) // an existing key
+		}
+
+		var l, hl, m, r, hr = split(s.root, s.root.blackHeight(), k)
+
+		if (m != nil) != s.IsSet(k) {
+			t.Fatalf("split(%s) found node m=%s", k, m)
+		}
+
+		var ls, rs = mkset(blackRoot(l)), mkset(blackRoot(r))
+		if err := ls.valid(); err != nil {
+			t.Fatalf("split(%s) left is not valid; err=%s", k, err)
+		}
+		if err := rs.valid(); err != nil {
+			t.Fatalf("split(%s) right is not valid; err=%s", k, err)
+		}
+		if hl != l.blackHeight() || hr != r.blackHeight() {
+			t.Fatalf("split(%s) returned wrong black heights", k)
+		}
+
+		var num = ls.NumEntries() + rs.NumEntries()
+		if m != nil {
+			num++
+		}
+		if num != s.NumEntries() {
+			t.Fatalf("split(%s) lost nodes; %d != %d", k, num, s.NumEntries())
+		}
+		ls.Range(func(k0 key.Sort) bool {
+			if !key.Less(k0, k) {
+				t.Fatalf("split(%s) left has key %s", k, k0)
+			}
+			return true
+		})
+		rs.Range(func(k0 key.Sort) bool {
+			if !key.Less(k, k0) {
+				t.Fatalf("split(%s) right has key %s", k, k0)
+			}
+			return true
+		})
+	}
+
+	if !s.Equiv(dupS) {
+		t.Fatal("split modified its argument")
+	}
+}
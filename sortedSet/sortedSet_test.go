@@ -24,12 +24,14 @@ func init() {
 }
 
 func mkset(r *node) *Set {
+	r.fixSize()
 	var num = r.count()
 	return &Set{num, r}
 }
 
 func mknod(i int, c colorType, ln, rn *node) *node {
-	return &node{key.Int(i), c, ln, rn}
+	return &node{key: key.Int(i), color: c, ln: ln, rn: rn,
+		size: size(ln) + size(rn) + 1}
 }
 
 func buildKeys(n int) []key.Sort {
@@ -0,0 +1,61 @@
+package sortedSet
+
+import "unsafe"
+
+// Stats describes the shape of the Red-Black Tree of a Set, as returned by
+// Set.Stats. None of it is needed to use a Set.
+type Stats struct {
+	// Nodes is the total count of nodes in the Set; one per key.
+	Nodes int
+
+	// RedNodes is the count of red nodes in the Set.
+	RedNodes int
+
+	// Height is the number of nodes on the longest path from the root down to
+	// a nil leaf. It is never more than twice the BlackHeight.
+	Height int
+
+	// BlackHeight is the number of black nodes on every path from the root
+	// down to a nil leaf.
+	BlackHeight int
+
+	// AvgProbeDepth is the average number of nodes a lookup of a key of the
+	// Set visits, or 0 if the Set is empty.
+	AvgProbeDepth float64
+
+	// NodeBytes estimates the memory used by the nodes of the Set, not
+	// counting the keys. Nodes shared with other Sets are counted in full.
+	NodeBytes uint
+}
+
+// Stats walks the Red-Black Tree and populates a Stats data structure which it
+// returns.
+func (s *Set) Stats() *Stats {
+	var stats = new(Stats)
+	var probes int
+
+	var walk func(n *node, depth int)
+	walk = func(n *node, depth int) {
+		if n == nil {
+			if depth > stats.Height {
+				stats.Height = depth
+			}
+			return
+		}
+		stats.Nodes++
+		if n.isRed() {
+			stats.RedNodes++
+		}
+		probes += depth + 1
+		walk(n.ln, depth+1)
+		walk(n.rn, depth+1)
+	}
+	walk(s.root, 0)
+
+	stats.BlackHeight = s.root.blackHeight()
+	stats.NodeBytes = uint(stats.Nodes) * uint(unsafe.Sizeof(node{}))
+	if stats.Nodes > 0 {
+		stats.AvgProbeDepth = float64(probes) / float64(stats.Nodes)
+	}
+	return stats
+}
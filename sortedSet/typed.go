@@ -0,0 +1,169 @@
+//go:build go1.21
+// +build go1.21
+
+package sortedSet
+
+import (
+	"cmp"
+	"fmt"
+	"strings"
+
+	"github.com/lleo/go-functional-collections/key"
+)
+
+// TypedSet is a type safe wrapper of the Set structure. Keys are of the
+// cmp.Ordered type K, so no type assertions are needed on the keys returned.
+//
+// Internally every key is stored as a key.SortOf[K] in the same Red-Black Tree
+// used by Set.
+type TypedSet[K cmp.Ordered] struct {
+	s *Set
+}
+
+// NewTypedSet returns a properly initialized pointer to an empty TypedSet.
+func NewTypedSet[K cmp.Ordered]() *TypedSet[K] {
+	return &TypedSet[K]{New()}
+}
+
+// NewTypedSetFromList constructs a new *TypedSet containing all the given
+// keys.
+func NewTypedSetFromList[K cmp.Ordered](keys []K) *TypedSet[K] {
+	var sortKeys = make([]key.Sort, len(keys))
+	for i, k := range keys {
+		sortKeys[i] = key.SortOf[K]{Key: k}
+	}
+	return &TypedSet[K]{NewFromList(sortKeys)}
+}
+
+// typedKey() converts the key returned by a Set method to K. The key is nil
+// only if found is false.
+func typedKey[K cmp.Ordered](k key.Sort, found bool) (K, bool) {
+	if !found {
+		var zk K
+		return zk, false
+	}
+	return k.(key.SortOf[K]).Key, true
+}
+
+// NumEntries returns the number of keys in the *TypedSet.
+func (s *TypedSet[K]) NumEntries() int {
+	return s.s.NumEntries()
+}
+
+// IsSet returns true if the key is in the TypedSet, otherwise it returns false.
+func (s *TypedSet[K]) IsSet(k K) bool {
+	return s.s.IsSet(key.SortOf[K]{Key: k})
+}
+
+// Set inserts the given key into the TypedSet and returns a new *TypedSet.
+func (s *TypedSet[K]) Set(k K) *TypedSet[K] {
+	var ns, _ = s.Add(k)
+	return ns
+}
+
+// Add inserts the given key into the TypedSet. It returns the new *TypedSet
+// and a bool indicating if the key was added.
+func (s *TypedSet[K]) Add(k K) (*TypedSet[K], bool) {
+	var ns, added = s.s.Add(key.SortOf[K]{Key: k})
+	return &TypedSet[K]{ns}, added
+}
+
+// Unset removes the given key and returns the new *TypedSet. If the key is not
+// in the TypedSet the original *TypedSet is returned.
+func (s *TypedSet[K]) Unset(k K) *TypedSet[K] {
+	var ns, _ = s.Remove(k)
+	return ns
+}
+
+// Remove deletes the given key. It returns the new *TypedSet and a bool
+// indicating if the key was found. If the key was not found the original
+// *TypedSet is returned with a false value.
+func (s *TypedSet[K]) Remove(k K) (*TypedSet[K], bool) {
+	var ns, removed = s.s.Remove(key.SortOf[K]{Key: k})
+	if !removed {
+		return s, false
+	}
+	return &TypedSet[K]{ns}, true
+}
+
+// Floor returns the greatest key in the TypedSet less than or equal to k. The
+// bool is false if there is no such key.
+func (s *TypedSet[K]) Floor(k K) (K, bool) {
+	return typedKey[K](s.s.Floor(key.SortOf[K]{Key: k}))
+}
+
+// Ceiling returns the least key in the TypedSet greater than or equal to k.
+// The bool is false if there is no such key.
+func (s *TypedSet[K]) Ceiling(k K) (K, bool) {
+	return typedKey[K](s.s.Ceiling(key.SortOf[K]{Key: k}))
+}
+
+// Min returns the least key in the TypedSet. The bool is false if the TypedSet
+// is empty.
+func (s *TypedSet[K]) Min() (K, bool) {
+	return typedKey[K](s.s.Min())
+}
+
+// Max returns the greatest key in the TypedSet. The bool is false if the
+// TypedSet is empty.
+func (s *TypedSet[K]) Max() (K, bool) {
+	return typedKey[K](s.s.Max())
+}
+
+// RangeLimit applies the given function on every key, in order, from the
+// start key up to and including the end key. If the start key is greater than
+// the end key, then the traversal will be in reverse order. If the function
+// returns false, RangeLimit stops.
+func (s *TypedSet[K]) RangeLimit(start, end K, fn func(K) bool) {
+	s.s.RangeLimit(key.SortOf[K]{Key: start}, key.SortOf[K]{Key: end},
+		func(k key.Sort) bool {
+			return fn(k.(key.SortOf[K]).Key)
+		})
+}
+
+// Range applies the given function on every key in the *TypedSet in sorted
+// order. If the function returns false, Range stops.
+func (s *TypedSet[K]) Range(fn func(K) bool) {
+	s.s.Range(func(k key.Sort) bool {
+		return fn(k.(key.SortOf[K]).Key)
+	})
+}
+
+// Keys returns a new slice holding all the keys of the *TypedSet in order.
+func (s *TypedSet[K]) Keys() []K {
+	var keys = make([]K, 0, s.NumEntries())
+	s.Range(func(k K) bool {
+		keys = append(keys, k)
+		return true
+	})
+	return keys
+}
+
+// Union returns a *TypedSet that contains all the keys of the receiver and the
+// argument *TypedSet.
+func (s *TypedSet[K]) Union(other *TypedSet[K]) *TypedSet[K] {
+	return &TypedSet[K]{s.s.Union(other.s)}
+}
+
+// Intersect returns a *TypedSet that contains only the keys that the receiver
+// and the argument *TypedSet have in common.
+func (s *TypedSet[K]) Intersect(other *TypedSet[K]) *TypedSet[K] {
+	return &TypedSet[K]{s.s.Intersect(other.s)}
+}
+
+// Difference returns a *TypedSet that contains the keys of the receiver that
+// are not in the argument *TypedSet.
+func (s *TypedSet[K]) Difference(other *TypedSet[K]) *TypedSet[K] {
+	return &TypedSet[K]{s.s.Difference(other.s)}
+}
+
+// String returns a string representation of the TypedSet, with the keys in
+// order.
+func (s *TypedSet[K]) String() string {
+	var strs = make([]string, 0, s.NumEntries())
+	s.Range(func(k K) bool {
+		strs = append(strs, fmt.Sprintf("%#v", k))
+		return true
+	})
+	return "{" + strings.Join(strs, ", ") + "}"
+}
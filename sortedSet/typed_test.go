@@ -0,0 +1,45 @@
+//go:build go1.21
+// +build go1.21
+
+package sortedSet
+
+import (
+	"testing"
+)
+
+func TestTypedSet(t *testing.T) {
+	var s = NewTypedSetFromList([]string{"d", "b", "a", "c", "b"})
+
+	if s.NumEntries() != 4 || !s.IsSet("c") || s.IsSet("e") {
+		t.Fatal("NewTypedSetFromList() is wrong")
+	}
+	if str := s.String(); str != `{"a", "b", "c", "d"}` {
+		t.Fatalf("s.String(),%s != {\"a\", \"b\", \"c\", \"d\"}", str)
+	}
+
+	if k, found := s.Floor("bb"); !found || k != "b" {
+		t.Fatalf("s.Floor(\"bb\"),%q,%t != \"b\",true", k, found)
+	}
+	if k, found := s.Ceiling("bb"); !found || k != "c" {
+		t.Fatalf("s.Ceiling(\"bb\"),%q,%t != \"c\",true", k, found)
+	}
+	if k, found := NewTypedSet[string]().Min(); found || k != "" {
+		t.Fatalf("empty Min(),%q,%t != \"\",false", k, found)
+	}
+
+	var o = NewTypedSetFromList([]string{"c", "d", "e"})
+	if keys := s.Union(o).Keys(); len(keys) != 5 || keys[4] != "e" {
+		t.Fatalf("s.Union(o).Keys(),%v is wrong", keys)
+	}
+	if keys := s.Intersect(o).Keys(); len(keys) != 2 || keys[0] != "c" {
+		t.Fatalf("s.Intersect(o).Keys(),%v is wrong", keys)
+	}
+	if keys := s.Difference(o).Keys(); len(keys) != 2 || keys[1] != "b" {
+		t.Fatalf("s.Difference(o).Keys(),%v is wrong", keys)
+	}
+
+	var ns, removed = s.Remove("a")
+	if !removed || ns.IsSet("a") || !s.IsSet("a") {
+		t.Fatal("s.Remove(\"a\") failed")
+	}
+}
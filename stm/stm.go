@@ -0,0 +1,198 @@
+// Package stm implements software transactional memory over the persistent
+// collections, so several of them can be updated together with all-or-nothing
+// visibility; for instance a fmap.Map index and a sortedMap.Map of the same
+// entries.
+//
+// A Ref is a mutable cell holding an immutable value, usually a persistent
+// collection. Atomically runs a function in a Tx, which reads the Refs as of
+// one instant and buffers its writes, then commits every write at once. Since
+// the collections are persistent, reading them costs nothing more than loading
+// a pointer, and the buffered writes are new versions of the collections,
+// built without copying them.
+//
+// Conflicts are detected optimistically, like TL2: every Ref carries the
+// version of the global clock at which it was last written. A Tx sees only
+// versions no newer than its start, and its commit checks that none of the
+// Refs it read were written since. A Tx that loses a conflict is run again from
+// the start, so the function given to Atomically SHOULD have no side effects
+// other than through its Tx.
+package stm
+
+import (
+	"runtime"
+	"sync/atomic"
+)
+
+// clock is the global version clock; every commit writing a Ref increments it.
+var clock uint64
+
+// Ref is a mutable cell holding an immutable value, read and written in a Tx.
+// The value MUST NOT be modified once stored; store a new version of it
+// instead, like every persistent collection returns.
+type Ref struct {
+	locked int32 // 1 while a commit is writing the Ref
+	p      atomic.Pointer[version]
+}
+
+// version is the value of a Ref written by the commit at the given stamp.
+type version struct {
+	val   interface{}
+	stamp uint64
+}
+
+// NewRef returns a new *Ref holding the value.
+func NewRef(val interface{}) *Ref {
+	var r = new(Ref)
+	r.p.Store(&version{val: val})
+	return r
+}
+
+func (r *Ref) load() *version {
+	return r.p.Load()
+}
+
+func (r *Ref) isLocked() bool {
+	return atomic.LoadInt32(&r.locked) != 0
+}
+
+// Load returns the value of the Ref outside of a Tx. It is the latest
+// committed value, but values Loaded from several Refs may come from different
+// commits; read them in a Tx to see them as of one instant.
+func (r *Ref) Load() interface{} {
+	return r.load().val
+}
+
+// Tx is a transaction, as passed to the function run by Atomically. A Tx MUST
+// NOT be used outside of that function, nor by several goroutines.
+type Tx struct {
+	start  uint64
+	reads  map[*Ref]*version
+	writes map[*Ref]interface{}
+}
+
+// conflict is panicked by Tx.Get to abort a Tx that can no longer see a
+// consistent snapshot; Atomically recovers it and runs the Tx again.
+type conflict struct{}
+
+// Get returns the value of the Ref as of the start of the Tx, or the value Set
+// by the Tx itself.
+func (tx *Tx) Get(r *Ref) interface{} {
+	if val, written := tx.writes[r]; written {
+		return val
+	}
+	if v, read := tx.reads[r]; read {
+		return v.val
+	}
+
+	// A commit locks the Refs it writes before incrementing the clock, and
+	// unlocks them after writing them all. So a version no newer than the
+	// start of the Tx, of an unlocked Ref, is consistent with every other
+	// version read.
+	if r.isLocked() {
+		panic(conflict{})
+	}
+	var v = r.load()
+	if r.isLocked() || v.stamp > tx.start {
+		panic(conflict{})
+	}
+	tx.reads[r] = v
+	return v.val
+}
+
+// Set buffers a new value of the Ref, written when the Tx commits. Until
+// then, only this Tx sees it.
+func (tx *Tx) Set(r *Ref, val interface{}) {
+	tx.writes[r] = val
+}
+
+// Update sets the Ref to fn of its value, and returns the new value.
+func (tx *Tx) Update(r *Ref, fn func(interface{}) interface{}) interface{} {
+	var val = fn(tx.Get(r))
+	tx.Set(r, val)
+	return val
+}
+
+// Atomically runs fn in a new Tx and commits its writes, all at once. If
+// another Tx commits a write to a Ref this Tx read, fn is run again in a new
+// Tx, until it commits. If fn returns an error, its writes are discarded and
+// Atomically returns the error.
+//
+// Atomically MUST NOT be called from within fn; the inner Tx would not be
+// part of the outer one.
+func Atomically(fn func(tx *Tx) error) error {
+	for {
+		var tx = &Tx{
+			start:  atomic.LoadUint64(&clock),
+			reads:  make(map[*Ref]*version),
+			writes: make(map[*Ref]interface{}),
+		}
+		var ok, err = tx.run(fn)
+		if ok {
+			if err != nil {
+				return err
+			}
+			if tx.commit() {
+				return nil
+			}
+		}
+		runtime.Gosched()
+	}
+}
+
+// run() calls fn, and returns false if the Tx was aborted by a conflict.
+func (tx *Tx) run(fn func(tx *Tx) error) (ok bool, err error) {
+	defer func() {
+		if ok {
+			return
+		}
+		var r = recover()
+		if r == nil {
+			// fn called runtime.Goexit, like t.FailNow does; let it go on
+			return
+		}
+		if _, isConflict := r.(conflict); !isConflict {
+			panic(r)
+		}
+	}()
+	err = fn(tx)
+	return true, err
+}
+
+// commit() writes the values Set by the Tx, unless one of the Refs it read
+// was written since it started, and returns false if one was.
+func (tx *Tx) commit() bool {
+	if len(tx.writes) == 0 {
+		// every version read was consistent with the others when read
+		return true
+	}
+
+	var locked = make([]*Ref, 0, len(tx.writes))
+	defer func() {
+		for _, r := range locked {
+			atomic.StoreInt32(&r.locked, 0)
+		}
+	}()
+	for r := range tx.writes {
+		// a Ref locked by another commit is a conflict; waiting for it
+		// could deadlock with a commit waiting for a Ref locked here.
+		if !atomic.CompareAndSwapInt32(&r.locked, 0, 1) {
+			return false
+		}
+		locked = append(locked, r)
+	}
+
+	var stamp = atomic.AddUint64(&clock, 1)
+	for r, v := range tx.reads {
+		if _, written := tx.writes[r]; !written && r.isLocked() {
+			return false
+		}
+		if r.load() != v {
+			return false
+		}
+	}
+
+	for r, val := range tx.writes {
+		r.p.Store(&version{val, stamp})
+	}
+	return true
+}
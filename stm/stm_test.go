@@ -0,0 +1,159 @@
+package stm_test
+
+import (
+	"errors"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/lleo/go-functional-collections/fmap"
+	"github.com/lleo/go-functional-collections/key"
+	"github.com/lleo/go-functional-collections/sortedMap"
+	"github.com/lleo/go-functional-collections/stm"
+)
+
+func TestAtomically(t *testing.T) {
+	var byName = stm.NewRef(fmap.New())
+	var byID = stm.NewRef(sortedMap.New())
+
+	var err = stm.Atomically(func(tx *stm.Tx) error {
+		tx.Set(byName, tx.Get(byName).(*fmap.Map).Put(key.Str("a"), 1))
+		tx.Update(byID, func(v interface{}) interface{} {
+			return v.(*sortedMap.Map).Put(key.Int(1), "a")
+		})
+		if tx.Get(byName).(*fmap.Map).Get(key.Str("a")) != 1 {
+			t.Fatal("a Tx does not see its own writes")
+		}
+		if byName.Load().(*fmap.Map).NumEntries() != 0 {
+			t.Fatal("a write is visible before the Tx commits")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("stm.Atomically() failed: %s", err)
+	}
+	if byName.Load().(*fmap.Map).Get(key.Str("a")) != 1 ||
+		byID.Load().(*sortedMap.Map).Get(key.Int(1)) != "a" {
+		t.Fatal("the writes of a committed Tx are not visible")
+	}
+
+	var errAbort = errors.New("abort")
+	err = stm.Atomically(func(tx *stm.Tx) error {
+		tx.Set(byName, fmap.New())
+		return errAbort
+	})
+	if err != errAbort {
+		t.Fatalf("stm.Atomically() returned %v; expected %v", err, errAbort)
+	}
+	if byName.Load().(*fmap.Map).NumEntries() != 1 {
+		t.Fatal("the writes of a Tx returning an error were committed")
+	}
+
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Fatalf("recover() = %v; expected the panic of fn", r)
+			}
+		}()
+		stm.Atomically(func(tx *stm.Tx) error {
+			panic("boom")
+		})
+	}()
+}
+
+// TestAtomicallyConcurrent updates two indexes of the same entries from
+// several goroutines, while others check that they always agree.
+func TestAtomicallyConcurrent(t *testing.T) {
+	const numWriters, numPuts = 4, 250
+	var byName = stm.NewRef(fmap.New())
+	var byID = stm.NewRef(sortedMap.New())
+
+	var wg sync.WaitGroup
+	for w := 0; w < numWriters; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < numPuts; i++ {
+				var id = w*numPuts + i
+				stm.Atomically(func(tx *stm.Tx) error {
+					var names = tx.Get(byName).(*fmap.Map)
+					var ids = tx.Get(byID).(*sortedMap.Map)
+					var name = key.Str(strconv.Itoa(id))
+					tx.Set(byName, names.Put(name, id))
+					tx.Set(byID, ids.Put(key.Int(id), name))
+					return nil
+				})
+			}
+		}(w)
+	}
+
+	var done = make(chan struct{})
+	var readers sync.WaitGroup
+	for r := 0; r < 2; r++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				var numNames, numIDs int
+				stm.Atomically(func(tx *stm.Tx) error {
+					numNames = tx.Get(byName).(*fmap.Map).NumEntries()
+					numIDs = tx.Get(byID).(*sortedMap.Map).NumEntries()
+					return nil
+				})
+				if numNames != numIDs {
+					t.Errorf("a Tx saw %d names but %d ids", numNames, numIDs)
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(done)
+	readers.Wait()
+
+	var names = byName.Load().(*fmap.Map)
+	var ids = byID.Load().(*sortedMap.Map)
+	if names.NumEntries() != numWriters*numPuts ||
+		ids.NumEntries() != numWriters*numPuts {
+		t.Fatalf("names.NumEntries(),%d ids.NumEntries(),%d != %d",
+			names.NumEntries(), ids.NumEntries(), numWriters*numPuts)
+	}
+	ids.Range(func(k key.Sort, v interface{}) bool {
+		if names.Get(v.(key.Str)) != int(k.(key.Int)) {
+			t.Fatalf("ids[%s],%s is not names[%s]", k, v, v)
+		}
+		return true
+	})
+}
+
+// TestAtomicallyGoexit checks that a Tx calling runtime.Goexit, like
+// t.FailNow does, ends its goroutine rather than panicking.
+func TestAtomicallyGoexit(t *testing.T) {
+	var ref = stm.NewRef(0)
+	var returned = make(chan bool, 1)
+	go func() {
+		var finished bool
+		defer func() {
+			returned <- finished
+		}()
+		stm.Atomically(func(tx *stm.Tx) error {
+			tx.Set(ref, 1)
+			runtime.Goexit()
+			return nil
+		})
+		finished = true
+	}()
+	if <-returned {
+		t.Fatal("Atomically() returned after runtime.Goexit()")
+	}
+	if ref.Load() != 0 {
+		t.Fatalf("ref.Load(),%v != 0 after runtime.Goexit()", ref.Load())
+	}
+}
@@ -0,0 +1,48 @@
+//go:build go1.21
+// +build go1.21
+
+package stm
+
+// TypedRef is a type safe wrapper of the Ref structure, holding values of type
+// T, so no type assertions are needed on the values returned.
+type TypedRef[T any] struct {
+	r *Ref
+}
+
+// NewTypedRef returns a new *TypedRef holding the value.
+func NewTypedRef[T any](val T) *TypedRef[T] {
+	return &TypedRef[T]{NewRef(val)}
+}
+
+func typedVal[T any](v interface{}) T {
+	var tv, _ = v.(T) //v is nil only for zero values of interface types
+	return tv
+}
+
+// Ref returns the Ref the TypedRef wraps.
+func (r *TypedRef[T]) Ref() *Ref {
+	return r.r
+}
+
+// Load returns the value of the TypedRef outside of a Tx, like Ref.Load.
+func (r *TypedRef[T]) Load() T {
+	return typedVal[T](r.r.Load())
+}
+
+// Get returns the value of the TypedRef in the Tx, like Tx.Get.
+func (r *TypedRef[T]) Get(tx *Tx) T {
+	return typedVal[T](tx.Get(r.r))
+}
+
+// Set buffers a new value of the TypedRef in the Tx, like Tx.Set.
+func (r *TypedRef[T]) Set(tx *Tx, val T) {
+	tx.Set(r.r, val)
+}
+
+// Update sets the TypedRef to fn of its value in the Tx, and returns the new
+// value, like Tx.Update.
+func (r *TypedRef[T]) Update(tx *Tx, fn func(T) T) T {
+	var val = fn(r.Get(tx))
+	r.Set(tx, val)
+	return val
+}
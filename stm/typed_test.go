@@ -0,0 +1,31 @@
+//go:build go1.21
+// +build go1.21
+
+package stm_test
+
+import (
+	"testing"
+
+	"github.com/lleo/go-functional-collections/fmap"
+	"github.com/lleo/go-functional-collections/key"
+	"github.com/lleo/go-functional-collections/stm"
+)
+
+func TestTypedRef(t *testing.T) {
+	var counter = stm.NewTypedRef(0)
+	var m = stm.NewTypedRef(fmap.NewTypedMap[key.Str, int]())
+
+	stm.Atomically(func(tx *stm.Tx) error {
+		var n = counter.Update(tx, func(n int) int { return n + 1 })
+		m.Set(tx, m.Get(tx).Put(key.Str("n"), n))
+		return nil
+	})
+
+	if counter.Load() != 1 || m.Load().Get(key.Str("n")) != 1 {
+		t.Fatalf("counter.Load(),%d m.Load().Get(\"n\"),%d",
+			counter.Load(), m.Load().Get(key.Str("n")))
+	}
+	if counter.Ref().Load() != 1 {
+		t.Fatal("counter.Ref().Load() != 1")
+	}
+}
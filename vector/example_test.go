@@ -0,0 +1,38 @@
+package vector_test
+
+import (
+	"fmt"
+
+	"github.com/lleo/go-functional-collections/vector"
+)
+
+func ExampleVector_Append() {
+	var v = vector.New().
+		Append("a").
+		Append("b").
+		Append("c")
+
+	v.Range(func(i int, val interface{}) bool {
+		fmt.Println(i, val)
+		return true
+	})
+
+	// Output:
+	// 0 a
+	// 1 b
+	// 2 c
+}
+
+func ExampleVector_Concat() {
+	var v0 = vector.NewFromList([]interface{}{1, 2, 3})
+	var v1 = vector.NewFromList([]interface{}{4, 5, 6})
+
+	var v = v0.Concat(v1).Slice(2, 5)
+
+	fmt.Println(v)
+	fmt.Println(v0, v1)
+
+	// Output:
+	// [3, 4, 5]
+	// [1, 2, 3] [4, 5, 6]
+}
@@ -0,0 +1,40 @@
+//go:build go1.23
+// +build go1.23
+
+package vector
+
+import (
+	"iter"
+)
+
+// All returns an iterator over the index/value pairs of the *Vector in order,
+// for use with a for-range loop.
+func (v *Vector) All() iter.Seq2[int, interface{}] {
+	return func(yield func(int, interface{}) bool) {
+		for i := 0; i < v.numEnts; {
+			var vals, li = v.leafFor(i)
+			for _, val := range vals[li:] {
+				if !yield(i, val) {
+					return
+				}
+				i++
+			}
+		}
+	}
+}
+
+// Backward returns an iterator over the index/value pairs of the *Vector in
+// reverse order.
+func (v *Vector) Backward() iter.Seq2[int, interface{}] {
+	return func(yield func(int, interface{}) bool) {
+		for i := v.numEnts - 1; i >= 0; {
+			var vals, li = v.leafFor(i)
+			for ; li >= 0; li-- {
+				if !yield(i, vals[li]) {
+					return
+				}
+				i--
+			}
+		}
+	}
+}
@@ -0,0 +1,53 @@
+//go:build go1.23
+// +build go1.23
+
+package vector
+
+import (
+	"testing"
+)
+
+func TestIterAll(t *testing.T) {
+	// a relaxed tree, so leaves are not all full
+	var vals = buildVals(0, 5000)
+	var v = NewFromList(vals[:1234]).Concat(NewFromList(vals[1234:3001])).
+		Concat(buildVec(vals[3001:]))
+
+	var n int
+	for i, val := range v.All() {
+		if i != n || val != vals[i] {
+			t.Fatalf("v.All() yielded %d,%v; expected %d,%v", i, val, n, vals[n])
+		}
+		n++
+	}
+	if n != len(vals) {
+		t.Fatalf("v.All() yielded %d values; expected %d", n, len(vals))
+	}
+
+	n = len(vals)
+	for i, val := range v.Backward() {
+		n--
+		if i != n || val != vals[i] {
+			t.Fatalf("v.Backward() yielded %d,%v; expected %d,%v",
+				i, val, n, vals[n])
+		}
+	}
+	if n != 0 {
+		t.Fatalf("v.Backward() stopped at %d", n)
+	}
+
+	n = 0
+	for range v.All() {
+		n++
+		if n == 40 {
+			break
+		}
+	}
+	if n != 40 {
+		t.Fatalf("break out of v.All() loop after %d iterations", n)
+	}
+
+	for range New().Backward() {
+		t.Fatal("empty Vector yielded a value")
+	}
+}
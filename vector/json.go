@@ -0,0 +1,35 @@
+package vector
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+var (
+	_ json.Marshaler   = (*Vector)(nil)
+	_ json.Unmarshaler = (*Vector)(nil)
+)
+
+// MarshalJSON implements the json.Marshaler interface. A Vector is encoded as
+// a JSON array of its values.
+func (v *Vector) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.Values())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface. It replaces the
+// contents of the receiver, so it SHOULD only be called on a Vector that no
+// one else holds, like one just returned by New. Values are decoded as the
+// generic encoding/json types.
+func (v *Vector) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil
+	}
+
+	var vals []interface{}
+	if err := json.Unmarshal(data, &vals); err != nil {
+		return err
+	}
+
+	*v = *NewFromList(vals)
+	return nil
+}
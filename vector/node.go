@@ -0,0 +1,294 @@
+package vector
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	nodeBits  uint = 5
+	nodeWidth int  = 1 << nodeBits
+	nodeMask  int  = nodeWidth - 1
+)
+
+// node is either a leaf, holding up to nodeWidth values, or a branch, holding
+// up to nodeWidth child nodes. All the leaves of a tree are at the same depth,
+// so whether a node is a leaf or a branch is known from its shift; leaves are
+// at shift zero, and every level up adds nodeBits to the shift.
+//
+// Each child of a branch at a given shift holds at most 1<<shift values. A
+// branch is balanced when every child but the last holds exactly 1<<shift
+// values and the last child is balanced. The child holding a given index of a
+// balanced branch is found by radix indexing. Every other branch is relaxed,
+// and records the cumulative number of values held by its children in sizes.
+type node struct {
+	vals  []interface{} //leaf only
+	kids  []*node       //branch only
+	sizes []int         //relaxed branch only
+}
+
+func newLeaf(vals []interface{}) *node {
+	var n = new(node)
+	n.vals = make([]interface{}, len(vals))
+	copy(n.vals, vals)
+	return n
+}
+
+// newBranch() returns a branch, at the given shift, of the given children. The
+// branch is balanced if it can be, otherwise it is relaxed.
+func newBranch(kids []*node, shift uint) *node {
+	var n = new(node)
+	n.kids = kids
+
+	var last = len(kids) - 1
+	var balanced = kids[last].isBalanced(shift - nodeBits)
+	var sizes = make([]int, len(kids))
+	var sum int
+	for i, kid := range kids {
+		var sz = kid.size(shift - nodeBits)
+		if i < last && sz != 1<<shift {
+			balanced = false
+		}
+		sum += sz
+		sizes[i] = sum
+	}
+
+	if !balanced {
+		n.sizes = sizes
+	}
+	return n
+}
+
+// newPath() returns the chain of single child branches, up to the given
+// shift, that ends with the leaf.
+func newPath(leaf *node, shift uint) *node {
+	var n = leaf
+	for s := uint(0); s < shift; s += nodeBits {
+		n = &node{kids: []*node{n}}
+	}
+	return n
+}
+
+// copy() returns a copy of n that can be modified without changing n.
+func (n *node) copy() *node {
+	var nn = new(node)
+	if n.vals != nil {
+		nn.vals = make([]interface{}, len(n.vals))
+		copy(nn.vals, n.vals)
+	}
+	if n.kids != nil {
+		nn.kids = make([]*node, len(n.kids))
+		copy(nn.kids, n.kids)
+	}
+	if n.sizes != nil {
+		nn.sizes = make([]int, len(n.sizes))
+		copy(nn.sizes, n.sizes)
+	}
+	return nn
+}
+
+func (n *node) isBalanced(shift uint) bool {
+	return shift == 0 || n.sizes == nil
+}
+
+// size() returns the number of values held by the tree n at the given shift.
+// It is O(1) for leaves and relaxed branches, but has to walk down the last
+// children of a balanced branch.
+func (n *node) size(shift uint) int {
+	if shift == 0 {
+		return len(n.vals)
+	}
+	if n.sizes != nil {
+		return n.sizes[len(n.sizes)-1]
+	}
+	var last = len(n.kids) - 1
+	return last<<shift + n.kids[last].size(shift-nodeBits)
+}
+
+// childIndex() returns the index of the child of the branch n, at the given
+// shift, which holds the value at index i, and the index of that value within
+// the child.
+//
+// No child holds more than 1<<shift values, so i>>shift is never past the
+// child holding i. A relaxed branch only has to search forward from there.
+func (n *node) childIndex(i int, shift uint) (int, int) {
+	var idx = i >> shift
+	if n.sizes == nil {
+		return idx, i - idx<<shift
+	}
+	for n.sizes[idx] <= i {
+		idx++
+	}
+	if idx > 0 {
+		i -= n.sizes[idx-1]
+	}
+	return idx, i
+}
+
+// leafFor() returns the leaf of the tree n, at the given shift, which holds
+// the value at index i, and the index of that value within the leaf.
+func (n *node) leafFor(i int, shift uint) (*node, int) {
+	for ; shift > 0; shift -= nodeBits {
+		var idx int
+		idx, i = n.childIndex(i, shift)
+		n = n.kids[idx]
+	}
+	return n, i
+}
+
+// set() returns a copy of the tree n, at the given shift, with the value at
+// index i replaced by v.
+func (n *node) set(i int, v interface{}, shift uint) *node {
+	var nn = n.copy()
+	if shift == 0 {
+		nn.vals[i] = v
+		return nn
+	}
+	var idx, ci = n.childIndex(i, shift)
+	nn.kids[idx] = n.kids[idx].set(ci, v, shift-nodeBits)
+	return nn
+}
+
+// pushDense() adds the full leaf to the end of the dense tree n, at the given
+// shift, which holds cnt values. A dense tree is balanced and all its leaves
+// are full, so it stays dense. The tree MUST have room for the leaf.
+func (n *node) pushDense(leaf *node, cnt int, shift uint) *node {
+	var nn = n.copy()
+	var idx = (cnt >> shift) & nodeMask
+	if idx == len(n.kids) {
+		nn.kids = append(nn.kids, newPath(leaf, shift-nodeBits))
+	} else {
+		nn.kids[idx] = n.kids[idx].pushDense(leaf, cnt, shift-nodeBits)
+	}
+	return nn
+}
+
+// popLeaf() removes the last leaf of the tree n, at the given shift. It
+// returns the remaining tree, or nil if nothing remains, and the removed leaf.
+//
+// Removing the last leaf of a balanced tree leaves it balanced.
+func (n *node) popLeaf(shift uint) (*node, *node) {
+	if shift == 0 {
+		return nil, n
+	}
+
+	var last = len(n.kids) - 1
+	var kid, leaf = n.kids[last].popLeaf(shift - nodeBits)
+	if kid == nil && last == 0 {
+		return nil, leaf
+	}
+
+	var nn = n.copy()
+	if kid == nil {
+		nn.kids = nn.kids[:last]
+		if nn.sizes != nil {
+			nn.sizes = nn.sizes[:last]
+		}
+	} else {
+		nn.kids[last] = kid
+		if nn.sizes != nil {
+			nn.sizes[last] -= len(leaf.vals)
+		}
+	}
+	return nn, leaf
+}
+
+// sliceRight() returns the tree holding the first end values of the tree n, at
+// the given shift. end MUST be greater than zero.
+//
+// Only the right edge of the tree changes, so a balanced tree stays balanced.
+func (n *node) sliceRight(end int, shift uint) *node {
+	if shift == 0 {
+		if end == len(n.vals) {
+			return n
+		}
+		return newLeaf(n.vals[:end])
+	}
+
+	var idx, ci = n.childIndex(end-1, shift)
+	var kid = n.kids[idx].sliceRight(ci+1, shift-nodeBits)
+	if idx == len(n.kids)-1 && kid == n.kids[idx] {
+		return n
+	}
+
+	var nn = new(node)
+	nn.kids = make([]*node, idx+1)
+	copy(nn.kids, n.kids[:idx])
+	nn.kids[idx] = kid
+	if n.sizes != nil {
+		nn.sizes = make([]int, idx+1)
+		copy(nn.sizes, n.sizes[:idx])
+		nn.sizes[idx] = end
+	}
+	return nn
+}
+
+// sliceLeft() returns the tree holding the values of the tree n, at the given
+// shift, from index start onwards. start MUST be less than the size of n.
+func (n *node) sliceLeft(start int, shift uint) *node {
+	if start == 0 {
+		return n
+	}
+	if shift == 0 {
+		return newLeaf(n.vals[start:])
+	}
+
+	var idx, ci = n.childIndex(start, shift)
+	var kids = make([]*node, len(n.kids)-idx)
+	kids[0] = n.kids[idx].sliceLeft(ci, shift-nodeBits)
+	copy(kids[1:], n.kids[idx+1:])
+	return newBranch(kids, shift)
+}
+
+// valid() is for testing only. It checks the tree n, at the given shift, and
+// returns the number of values it holds.
+func (n *node) valid(shift uint) (int, error) {
+	if shift == 0 {
+		if n.kids != nil || n.sizes != nil {
+			return -1, errors.New("leaf with children")
+		}
+		if len(n.vals) == 0 || len(n.vals) > nodeWidth {
+			return -1, fmt.Errorf("leaf with %d values", len(n.vals))
+		}
+		return len(n.vals), nil
+	}
+
+	if n.vals != nil {
+		return -1, errors.New("branch with values")
+	}
+	if len(n.kids) == 0 || len(n.kids) > nodeWidth {
+		return -1, fmt.Errorf("branch with %d children", len(n.kids))
+	}
+	if n.sizes != nil && len(n.sizes) != len(n.kids) {
+		return -1, fmt.Errorf("len(sizes),%d != len(kids),%d",
+			len(n.sizes), len(n.kids))
+	}
+
+	var last = len(n.kids) - 1
+	var sum int
+	for i, kid := range n.kids {
+		var sz, err = kid.valid(shift - nodeBits)
+		if err != nil {
+			return -1, err
+		}
+		if sz > 1<<shift {
+			return -1, fmt.Errorf("child holds %d > %d values", sz, 1<<shift)
+		}
+		sum += sz
+		if n.sizes != nil {
+			if n.sizes[i] != sum {
+				return -1, fmt.Errorf("sizes[%d],%d != %d", i, n.sizes[i], sum)
+			}
+			continue
+		}
+		if i < last && sz != 1<<shift {
+			return -1, fmt.Errorf("balanced branch with child %d of %d values",
+				i, sz)
+		}
+		if i == last && !kid.isBalanced(shift-nodeBits) {
+			return -1, errors.New("balanced branch with relaxed last child")
+		}
+	}
+
+	return sum, nil
+}
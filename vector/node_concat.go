@@ -0,0 +1,187 @@
+package vector
+
+// The functions in this file concatenate two trees in O(log(n)). Only the
+// right edge of the left tree and the left edge of the right tree are
+// rebuilt; every other node is shared with the inputs.
+//
+// The nodes along the seams are rebalanced as in an RRB tree: at each level,
+// the grandchildren of the children meeting at the seam are redistributed over
+// fewer children, until finding the child holding a given index takes at most
+// concatExtraSteps more steps than radix indexing would. So the nodes stay
+// nearly full, the tree stays O(log(n)) tall however the vectors were
+// concatenated, and childIndex() stays fast.
+
+// concatExtraSteps is the number of children by which a rebalanced branch may
+// exceed the fewest that could hold its grandchildren.
+const concatExtraSteps = 2
+
+// concatTrees() returns the tree, and its shift, holding the values of the
+// tree l, at shift ls, followed by the values of the tree r, at shift rs.
+// Either tree may be nil.
+func concatTrees(l *node, ls uint, r *node, rs uint) (*node, uint) {
+	if l == nil {
+		return r, rs
+	}
+	if r == nil {
+		return l, ls
+	}
+
+	var shift = ls
+	if rs > shift {
+		shift = rs
+	}
+
+	var ns = concat(l, ls, r, rs)
+	if len(ns) == 1 {
+		return trimRoot(ns[0], shift)
+	}
+	return newBranch(ns, shift+nodeBits), shift + nodeBits
+}
+
+// trimRoot() removes the single child branches from the top of the tree n,
+// at the given shift.
+func trimRoot(n *node, shift uint) (*node, uint) {
+	for shift > 0 && len(n.kids) == 1 {
+		n = n.kids[0]
+		shift -= nodeBits
+	}
+	return n, shift
+}
+
+// concat() returns the one or two nodes, at the greater of ls and rs, holding
+// the values of the tree l, at shift ls, followed by the values of the tree r,
+// at shift rs.
+func concat(l *node, ls uint, r *node, rs uint) []*node {
+	switch {
+	case ls > rs:
+		var last = len(l.kids) - 1
+		var mid = concat(l.kids[last], ls-nodeBits, r, rs)
+		return rebalance(l.kids[:last], mid, nil, ls)
+	case ls < rs:
+		var mid = concat(l, ls, r.kids[0], rs-nodeBits)
+		return rebalance(nil, mid, r.kids[1:], rs)
+	case ls == 0: //both are leaves
+		if len(l.vals)+len(r.vals) > nodeWidth {
+			return []*node{l, r}
+		}
+		var nn = new(node)
+		nn.vals = make([]interface{}, 0, len(l.vals)+len(r.vals))
+		nn.vals = append(append(nn.vals, l.vals...), r.vals...)
+		return []*node{nn}
+	default:
+		var last = len(l.kids) - 1
+		var mid = concat(l.kids[last], ls-nodeBits, r.kids[0], rs-nodeBits)
+		return rebalance(l.kids[:last], mid, r.kids[1:], ls)
+	}
+}
+
+// rebalance() returns the one or two branches, at the given shift, holding
+// the children left, mid and right in that order, after redistributing the
+// grandchildren over the children as concatPlan() says. A single branch is
+// used when they all fit in it.
+func rebalance(left, mid, right []*node, shift uint) []*node {
+	var kids = make([]*node, 0, len(left)+len(mid)+len(right))
+	kids = append(append(append(kids, left...), mid...), right...)
+	kids = redistribute(kids, concatPlan(kids, shift-nodeBits), shift-nodeBits)
+
+	if len(kids) <= nodeWidth {
+		return []*node{newBranch(kids, shift)}
+	}
+	return []*node{
+		newBranch(kids[:nodeWidth], shift),
+		newBranch(kids[nodeWidth:], shift),
+	}
+}
+
+// slots() returns the number of values of the leaf n, or of children of the
+// branch n, at the given shift.
+func (n *node) slots(shift uint) int {
+	if shift == 0 {
+		return len(n.vals)
+	}
+	return len(n.kids)
+}
+
+// concatPlan() returns the number of slots each of the nodes, at the given
+// shift, should have after rebalancing. It keeps the slots in order and
+// takes the first node that is not full and spreads its slots over the nodes
+// after it, until there are at most concatExtraSteps more nodes than the
+// fewest that could hold all the slots.
+func concatPlan(nodes []*node, shift uint) []int {
+	var sizes = make([]int, len(nodes))
+	var total int
+	for i, n := range nodes {
+		sizes[i] = n.slots(shift)
+		total += sizes[i]
+	}
+
+	var optimal = (total + nodeMask) / nodeWidth
+	var n = len(sizes)
+	for n > optimal+concatExtraSteps {
+		var i = 0
+		for sizes[i] > nodeWidth-concatExtraSteps/2 {
+			i++
+		}
+		// node i has room, so moving its slots along empties one of the
+		// nodes after it before reaching the last node
+		for r := sizes[i]; r > 0; i++ {
+			var sz = r + sizes[i+1]
+			if sz > nodeWidth {
+				sz = nodeWidth
+			}
+			sizes[i] = sz
+			r += sizes[i+1] - sz
+		}
+		copy(sizes[i:n-1], sizes[i+1:n])
+		n--
+	}
+	return sizes[:n]
+}
+
+// redistribute() returns the nodes, at the given shift, holding the slots of
+// the given nodes in order, with as many slots as sizes says. The nodes whose
+// slots do not move are reused.
+func redistribute(nodes []*node, sizes []int, shift uint) []*node {
+	if len(sizes) == len(nodes) {
+		return nodes
+	}
+
+	var nns = make([]*node, 0, len(sizes))
+	var src, off int // the next slot is slot off of nodes[src]
+	for _, sz := range sizes {
+		if off == 0 && nodes[src].slots(shift) == sz {
+			nns = append(nns, nodes[src])
+			src++
+			continue
+		}
+
+		var nn = new(node)
+		if shift == 0 {
+			nn.vals = make([]interface{}, 0, sz)
+		} else {
+			nn.kids = make([]*node, 0, sz)
+		}
+		for have := 0; have < sz; {
+			var n = nodes[src].slots(shift) - off
+			if n > sz-have {
+				n = sz - have
+			}
+			if shift == 0 {
+				nn.vals = append(nn.vals, nodes[src].vals[off:off+n]...)
+			} else {
+				nn.kids = append(nn.kids, nodes[src].kids[off:off+n]...)
+			}
+			have += n
+			off += n
+			if off == nodes[src].slots(shift) {
+				src++
+				off = 0
+			}
+		}
+		if shift > 0 {
+			nn = newBranch(nn.kids, shift)
+		}
+		nns = append(nns, nn)
+	}
+	return nns
+}
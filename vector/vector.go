@@ -0,0 +1,354 @@
+// Package vector implements a functional Vector data structure. A Vector is an
+// ordered sequence of values indexed from zero, like a Go slice. The internal
+// data structure of the Vector is a Relaxed Radix Balanced Tree (RRB-Tree),
+// which is the Clojure Vector's 32-way trie extended so that Concat and Slice
+// are O(log(n)) as well.
+//
+// Functional means that each data structure is immutable and persistent.
+// The Vector is immutable because you never modify a Vector in place, but
+// rather every modification (like an Append or Set) creates a new Vector with
+// that modification. This is not as inefficient as it sounds like it would be.
+// Each modification only copies the nodes of the tree leading to the change.
+// Otherwise, the new data structure shares the majority of the previous data
+// structure. That is the Persistent property.
+//
+// Like the Clojure Vector, the last values are kept in a separate tail leaf,
+// so that Append and Pop rarely have to touch the tree at all.
+//
+// Each method call that potentially modifies the Vector, returns a new Vector
+// data structure in addition to the other pertinent return values.
+package vector
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// The Vector struct maintains the immutable sequence of values.
+//
+// The values are held by the tree root, at the given shift, followed by the
+// tail. The tail is never empty unless the Vector is.
+type Vector struct {
+	numEnts int
+	shift   uint
+	root    *node
+	tail    []interface{}
+}
+
+// New returns a properly initialized pointer to an empty vector.Vector struct.
+func New() *Vector {
+	var v = new(Vector)
+	return v
+}
+
+// NewFromList constructs a new *Vector holding the given values in order.
+//
+// NewFromList is O(n) and is implemented more efficiently than repeated calls
+// to Append.
+func NewFromList(vals []interface{}) *Vector {
+	var v = New()
+	if len(vals) == 0 {
+		return v
+	}
+
+	// The tail gets the last 1 to nodeWidth values, the tree gets the rest as
+	// full leaves; hence the tree is dense.
+	var tailOff = (len(vals) - 1) &^ nodeMask
+
+	var level = make([]*node, 0, tailOff/nodeWidth)
+	for i := 0; i < tailOff; i += nodeWidth {
+		level = append(level, newLeaf(vals[i:i+nodeWidth]))
+	}
+	for len(level) > 1 {
+		var next = make([]*node, 0, (len(level)+nodeMask)/nodeWidth)
+		for i := 0; i < len(level); i += nodeWidth {
+			var j = i + nodeWidth
+			if j > len(level) {
+				j = len(level)
+			}
+			next = append(next, &node{kids: level[i:j]})
+		}
+		level = next
+		v.shift += nodeBits
+	}
+	if len(level) == 1 {
+		v.root = level[0]
+	}
+
+	v.tail = newLeaf(vals[tailOff:]).vals
+	v.numEnts = len(vals)
+	return v
+}
+
+func (v *Vector) valid() error {
+	if v.root == nil && v.shift != 0 {
+		return errors.New("v.root == nil but v.shift != 0")
+	}
+	if v.numEnts > 0 && (len(v.tail) == 0 || len(v.tail) > nodeWidth) {
+		return fmt.Errorf("v.numEnts,%d > 0 but len(v.tail),%d",
+			v.numEnts, len(v.tail))
+	}
+	if v.root != nil && v.shift > 0 && len(v.root.kids) == 1 {
+		return errors.New("v.root is a single child branch")
+	}
+
+	var count = len(v.tail)
+	if v.root != nil {
+		var sz, err = v.root.valid(v.shift)
+		if err != nil {
+			return err
+		}
+		count += sz
+	}
+	if count != v.numEnts {
+		return errors.New("enumerated count of values != v.NumEntries()")
+	}
+	return nil
+}
+
+// NumEntries returns the number of values in the *Vector. This operation is
+// O(1), because the count is maintained at the top level of the *Vector.
+func (v *Vector) NumEntries() int {
+	return v.numEnts
+}
+
+func (v *Vector) copy() *Vector {
+	var nv = new(Vector)
+	*nv = *v
+	return nv
+}
+
+// tailOffset() returns the index of the first value in the tail.
+func (v *Vector) tailOffset() int {
+	return v.numEnts - len(v.tail)
+}
+
+// leafFor() returns the values of the leaf holding the value at index i, and
+// the index of that value within them.
+func (v *Vector) leafFor(i int) ([]interface{}, int) {
+	var tailOff = v.tailOffset()
+	if i >= tailOff {
+		return v.tail, i - tailOff
+	}
+	var leaf, li = v.root.leafFor(i, v.shift)
+	return leaf.vals, li
+}
+
+// Load retrieves the value at index i of the Vector. It also returns a bool to
+// indicate whether i is in range. This allows you to store nil values in the
+// Vector and distinguish them from an index out of range. Load is O(log(n)).
+func (v *Vector) Load(i int) (interface{}, bool) {
+	if i < 0 || i >= v.numEnts {
+		return nil, false
+	}
+	var vals, li = v.leafFor(i)
+	return vals[li], true
+}
+
+// Get retrieves the value at index i of the Vector. If i is out of range a nil
+// is returned. If you need to store nil values and want to distinguish between
+// a nil value and an index out of range, you must use the Load method.
+func (v *Vector) Get(i int) interface{} {
+	var val, _ = v.Load(i)
+	return val
+}
+
+// Set returns a new Vector with the value at index i replaced by val. Like
+// assigning to a slice element, Set panics if i is out of range.
+func (v *Vector) Set(i int, val interface{}) *Vector {
+	if i < 0 || i >= v.numEnts {
+		panic(fmt.Sprintf("vector: Set index %d out of range [0:%d]",
+			i, v.numEnts))
+	}
+
+	var nv = v.copy()
+	var tailOff = v.tailOffset()
+	if i >= tailOff {
+		nv.tail = newLeaf(v.tail).vals
+		nv.tail[i-tailOff] = val
+	} else {
+		nv.root = v.root.set(i, val, v.shift)
+	}
+	return nv
+}
+
+// Append returns a new Vector with val added after the last value.
+func (v *Vector) Append(val interface{}) *Vector {
+	var nv = v.copy()
+	if len(v.tail) < nodeWidth {
+		nv.tail = make([]interface{}, len(v.tail)+1)
+		copy(nv.tail, v.tail)
+		nv.tail[len(v.tail)] = val
+	} else {
+		nv.pushLeaf(&node{vals: v.tail})
+		nv.tail = []interface{}{val}
+	}
+	nv.numEnts++
+	return nv
+}
+
+// pushLeaf() adds the leaf to the end of the tree of v. It MUST be called
+// before v.numEnts and v.tail are updated.
+func (v *Vector) pushLeaf(leaf *node) {
+	var cnt = v.tailOffset() //number of values in the tree
+
+	switch {
+	case v.root == nil:
+		v.root, v.shift = leaf, 0
+	case cnt&nodeMask == 0 && v.root.isBalanced(v.shift):
+		// The tree is dense, so the leaf is added the same way as the Clojure
+		// Vector does it.
+		if cnt == 1<<(v.shift+nodeBits) {
+			v.root = &node{kids: []*node{v.root, newPath(leaf, v.shift)}}
+			v.shift += nodeBits
+		} else {
+			v.root = v.root.pushDense(leaf, cnt, v.shift)
+		}
+	default:
+		v.root, v.shift = concatTrees(v.root, v.shift, leaf, 0)
+	}
+}
+
+// fillTail() moves the last leaf of the tree of v into its empty tail.
+func (v *Vector) fillTail() {
+	if v.root == nil {
+		return
+	}
+
+	var leaf *node
+	v.root, leaf = v.root.popLeaf(v.shift)
+	v.tail = leaf.vals
+
+	if v.root == nil {
+		v.shift = 0
+		return
+	}
+	v.root, v.shift = trimRoot(v.root, v.shift)
+}
+
+// Pop returns a new Vector without the last value, along with that value. If
+// the Vector is empty it returns the receiver Vector, a nil value and false.
+func (v *Vector) Pop() (*Vector, interface{}, bool) {
+	if v.numEnts == 0 {
+		return v, nil, false
+	}
+
+	var val = v.tail[len(v.tail)-1]
+
+	var nv = v.copy()
+	nv.numEnts--
+	nv.tail = v.tail[:len(v.tail)-1]
+	if len(nv.tail) == 0 {
+		nv.tail = nil
+		nv.fillTail()
+	}
+
+	return nv, val, true
+}
+
+// Slice returns a new Vector containing the values at indexes i through j-1.
+// The indexes are clamped to the range of the Vector, like a Go slice
+// expression that never panics.
+//
+// Slice is O(log(n)) and the returned Vector maintains the structure sharing
+// relationship with the receiver Vector.
+func (v *Vector) Slice(i, j int) *Vector {
+	if i < 0 {
+		i = 0
+	}
+	if j > v.numEnts {
+		j = v.numEnts
+	}
+	if i >= j {
+		return New()
+	}
+	if i == 0 && j == v.numEnts {
+		return v
+	}
+
+	var nv = New()
+	nv.numEnts = j - i
+
+	var tailOff = v.tailOffset()
+	if i >= tailOff {
+		nv.tail = v.tail[i-tailOff : j-tailOff]
+		return nv
+	}
+
+	var end = j
+	if j > tailOff {
+		nv.tail = v.tail[:j-tailOff]
+		end = tailOff
+	}
+
+	nv.root = v.root.sliceRight(end, v.shift).sliceLeft(i, v.shift)
+	nv.root, nv.shift = trimRoot(nv.root, v.shift)
+
+	if len(nv.tail) == 0 {
+		nv.fillTail()
+	}
+
+	return nv
+}
+
+// Concat returns a new Vector holding the values of the receiver Vector
+// followed by the values of the argument Vector.
+//
+// Concat is O(log(n)) and the returned Vector maintains the structure sharing
+// relationship with both the receiver Vector and the argument Vector.
+func (v *Vector) Concat(other *Vector) *Vector {
+	if other.numEnts == 0 {
+		return v
+	}
+	if v.numEnts == 0 {
+		return other
+	}
+
+	var nv = v.copy()
+	nv.pushLeaf(&node{vals: v.tail})
+	nv.root, nv.shift = concatTrees(nv.root, nv.shift, other.root, other.shift)
+	nv.tail = other.tail
+	nv.numEnts = v.numEnts + other.numEnts
+	return nv
+}
+
+// Iter returns an *Iter structure. You can call the Next() method on the *Iter
+// structure sucessively until it returns a false bool, to walk the values of
+// the Vector in order.
+func (v *Vector) Iter() *Iter {
+	return newIter(v)
+}
+
+// Range executes the given function on every index, value pair in order. If
+// the function returns false, Range stops.
+func (v *Vector) Range(fn func(int, interface{}) bool) {
+	var it = v.Iter()
+	for i := 0; ; i++ {
+		var val, ok = it.Next()
+		if !ok || !fn(i, val) {
+			return
+		}
+	}
+}
+
+// Values returns a new slice holding the values of the Vector in order.
+func (v *Vector) Values() []interface{} {
+	var vals = make([]interface{}, 0, v.numEnts)
+	v.Range(func(_ int, val interface{}) bool {
+		vals = append(vals, val)
+		return true
+	})
+	return vals
+}
+
+// String returns a string representation of the Vector, with the values in
+// order.
+func (v *Vector) String() string {
+	var strs = make([]string, 0, v.numEnts)
+	v.Range(func(_ int, val interface{}) bool {
+		strs = append(strs, fmt.Sprintf("%#v", val))
+		return true
+	})
+	return "[" + strings.Join(strs, ", ") + "]"
+}
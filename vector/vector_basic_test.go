@@ -0,0 +1,322 @@
+package vector
+
+import (
+	"encoding/json"
+	"math/rand"
+	"testing"
+)
+
+func TestBasicNew(t *testing.T) {
+	var v = New()
+	checkVec(t, "New()", v, nil)
+
+	if val, found := v.Load(0); found || val != nil {
+		t.Fatalf("New().Load(0),%v,%t != nil,false", val, found)
+	}
+	if s := v.String(); s != "[]" {
+		t.Fatalf("New().String(),%q != \"[]\"", s)
+	}
+}
+
+func TestBasicAppend(t *testing.T) {
+	var vals = buildVals(0, 40000)
+	var v = New()
+	var saved = make(map[int]*Vector)
+	for i, val := range vals {
+		v = v.Append(val)
+		if i == 31 || i == 32 || i == 1055 || i == 1056 || i == 33000 {
+			saved[i+1] = v
+		}
+	}
+	checkVec(t, "v", v, vals)
+
+	// the earlier versions are unchanged
+	for n, v0 := range saved {
+		checkVec(t, "v0", v0, vals[:n])
+	}
+}
+
+func TestBasicNewFromList(t *testing.T) {
+	for _, n := range []int{0, 1, 31, 32, 33, 64, 65, 1024, 1056, 1057, 33824,
+		40000} {
+		var vals = buildVals(0, n)
+		var v = NewFromList(vals)
+		checkVec(t, "NewFromList(vals)", v, vals)
+
+		var v1 = v.Append(n)
+		checkVec(t, "v.Append(n)", v1, append(vals, n))
+	}
+}
+
+func TestBasicGet(t *testing.T) {
+	var vals = buildVals(0, 100)
+	var v = NewFromList(vals)
+
+	for i, val := range vals {
+		if v.Get(i) != val {
+			t.Fatalf("v.Get(%d),%v != %v", i, v.Get(i), val)
+		}
+	}
+	if v.Get(-1) != nil || v.Get(100) != nil {
+		t.Fatal("v.Get() out of range did not return nil")
+	}
+	if _, found := v.Load(100); found {
+		t.Fatal("v.Load(100) found a value")
+	}
+}
+
+func TestBasicSet(t *testing.T) {
+	var vals = buildVals(0, 2000)
+	var v = NewFromList(vals)
+
+	var nvals = make([]interface{}, len(vals))
+	copy(nvals, vals)
+
+	var nv = v
+	for _, i := range rand.Perm(len(vals)) {
+		nvals[i] = -i
+		nv = nv.Set(i, -i)
+	}
+	checkVec(t, "nv", nv, nvals)
+	checkVec(t, "v", v, vals)
+}
+
+func TestBasicSetPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("v.Set(10, nil) did not panic")
+		}
+	}()
+	NewFromList(buildVals(0, 10)).Set(10, nil)
+}
+
+func TestBasicPop(t *testing.T) {
+	var vals = buildVals(0, 2000)
+	var v = NewFromList(vals)
+
+	var nv = v
+	for i := len(vals) - 1; i >= 0; i-- {
+		var val interface{}
+		var found bool
+		nv, val, found = nv.Pop()
+		if !found || val != vals[i] {
+			t.Fatalf("Pop(),%v,%t != %v,true", val, found, vals[i])
+		}
+		if i%97 == 0 {
+			checkVec(t, "nv", nv, vals[:i])
+		}
+	}
+	checkVec(t, "nv", nv, nil)
+
+	var empty, val, found = nv.Pop()
+	if found || val != nil || empty != nv {
+		t.Fatalf("Pop() on an empty Vector returned %v,%t", val, found)
+	}
+
+	checkVec(t, "v", v, vals)
+}
+
+func TestBasicSlice(t *testing.T) {
+	var vals = buildVals(0, 3000)
+	var v = NewFromList(vals)
+
+	for _, ij := range [][2]int{{0, 3000}, {0, 0}, {-5, 10}, {2990, 3010},
+		{10, 5}, {0, 1}, {31, 33}, {32, 64}, {1, 2999}, {100, 1100},
+		{1024, 2048}, {2980, 2990}, {2000, 2999}, {1, 3000}} {
+		var i, j = ij[0], ij[1]
+		var lo, hi = i, j
+		if lo < 0 {
+			lo = 0
+		}
+		if hi > len(vals) {
+			hi = len(vals)
+		}
+		if hi < lo {
+			hi = lo
+		}
+
+		var sv = v.Slice(i, j)
+		checkVec(t, "sv", sv, vals[lo:hi])
+
+		// a sliced Vector is still usable as any other Vector
+		var avals = append(append([]interface{}{}, vals[lo:hi]...),
+			buildVals(5000, 100)...)
+		checkVec(t, "sv.Append()", buildAppend(sv, buildVals(5000, 100)), avals)
+	}
+
+	checkVec(t, "v", v, vals)
+}
+
+func buildAppend(v *Vector, vals []interface{}) *Vector {
+	for _, val := range vals {
+		v = v.Append(val)
+	}
+	return v
+}
+
+func TestBasicConcat(t *testing.T) {
+	var sizes = []int{0, 1, 5, 31, 32, 33, 100, 1024, 1057, 5000}
+	for _, n0 := range sizes {
+		for _, n1 := range sizes {
+			var vals0, vals1 = buildVals(0, n0), buildVals(n0, n1)
+			var v0, v1 = NewFromList(vals0), buildVec(vals1)
+
+			var v = v0.Concat(v1)
+			checkVec(t, "v0.Concat(v1)", v, append(vals0, vals1...))
+
+			checkVec(t, "v0", v0, vals0)
+			checkVec(t, "v1", v1, vals1)
+		}
+	}
+}
+
+func TestBasicConcatMany(t *testing.T) {
+	var v = New()
+	var vals []interface{}
+	for i := 0; i < 500; i++ {
+		var n = rand.Intn(70)
+		var more = buildVals(len(vals), n)
+		vals = append(vals, more...)
+		v = v.Concat(NewFromList(more))
+	}
+	checkVec(t, "v", v, vals)
+
+	for i := 0; i < 200; i++ {
+		var lo = rand.Intn(len(vals))
+		var hi = lo + rand.Intn(len(vals)-lo+1)
+		checkVec(t, "v.Slice()", v.Slice(lo, hi), vals[lo:hi])
+	}
+}
+
+// TestBasicConcatPrepend prepends many small Vectors, which adds them all at
+// the left edge of the tree, and checks that the tree stays valid and no more
+// than one level taller than a dense tree of as many values.
+func TestBasicConcatPrepend(t *testing.T) {
+	var v = New()
+	var vals []interface{}
+	for i := 0; i < 3000; i++ {
+		var n = rand.Intn(40)
+		var more = buildVals(-len(vals)-n, n)
+		vals = append(more, vals...)
+		v = NewFromList(more).Concat(v)
+
+		if err := v.valid(); err != nil {
+			t.Fatalf("prepend %d: v is not valid; err=%s", i, err)
+		}
+		var shift uint
+		for 1<<(shift+nodeBits) < v.NumEntries() {
+			shift += nodeBits
+		}
+		if v.shift > shift+nodeBits {
+			t.Fatalf("prepend %d: v.shift,%d > %d for %d values",
+				i, v.shift, shift+nodeBits, v.NumEntries())
+		}
+	}
+	checkVec(t, "v", v, vals)
+}
+
+// TestBasicRandomOps applies random operations to a Vector and a slice
+// holding the same values.
+func TestBasicRandomOps(t *testing.T) {
+	var v = New()
+	var vals []interface{}
+	var next = 0
+
+	for op := 0; op < 3000; op++ {
+		switch rand.Intn(6) {
+		case 0, 1:
+			var n = rand.Intn(100)
+			for i := 0; i < n; i++ {
+				v = v.Append(next)
+				vals = append(vals, next)
+				next++
+			}
+		case 2:
+			var n = rand.Intn(50)
+			for i := 0; i < n && len(vals) > 0; i++ {
+				v, _, _ = v.Pop()
+				vals = vals[:len(vals)-1]
+			}
+		case 3:
+			if len(vals) > 0 {
+				var i = rand.Intn(len(vals))
+				v = v.Set(i, next)
+				vals = append([]interface{}{}, vals...)
+				vals[i] = next
+				next++
+			}
+		case 4:
+			var lo = rand.Intn(len(vals) + 1)
+			var hi = lo + rand.Intn(len(vals)-lo+1)
+			v = v.Slice(lo, hi)
+			vals = vals[lo:hi:hi]
+		case 5:
+			var v0 = v.Slice(0, rand.Intn(len(vals)+1))
+			var vals0 = vals[:v0.NumEntries():v0.NumEntries()]
+			v = v0.Concat(v)
+			vals = append(vals0, vals...)
+		}
+
+		if err := v.valid(); err != nil {
+			t.Fatalf("op %d: v is not valid; err=%s", op, err)
+		}
+		if op%100 == 0 {
+			checkVec(t, "v", v, vals)
+		}
+	}
+	checkVec(t, "v", v, vals)
+}
+
+func TestBasicIter(t *testing.T) {
+	var vals = buildVals(0, 1000)
+	var v = NewFromList(vals).Slice(10, 990)
+
+	var it = v.Iter()
+	for i := 10; i < 990; i++ {
+		var val, ok = it.Next()
+		if !ok || val != vals[i] {
+			t.Fatalf("it.Next(),%v,%t != %v,true", val, ok, vals[i])
+		}
+	}
+	if val, ok := it.Next(); ok || val != nil {
+		t.Fatalf("it.Next(),%v,%t != nil,false", val, ok)
+	}
+}
+
+func TestBasicRangeStop(t *testing.T) {
+	var v = NewFromList(buildVals(0, 100))
+
+	var n int
+	v.Range(func(i int, val interface{}) bool {
+		n++
+		return i < 49
+	})
+	if n != 50 {
+		t.Fatalf("Range() called fn %d times; expected 50", n)
+	}
+}
+
+func TestBasicString(t *testing.T) {
+	var v = NewFromList([]interface{}{1, "a", nil})
+	if s := v.String(); s != `[1, "a", <nil>]` {
+		t.Fatalf("v.String(),%s != [1, \"a\", <nil>]", s)
+	}
+}
+
+func TestBasicJSON(t *testing.T) {
+	var v0 = New().Append("a").Append(2.0).Append(nil)
+
+	var data, err = json.Marshal(v0)
+	if err != nil {
+		t.Fatalf("json.Marshal(v0) failed: %s", err)
+	}
+	if string(data) != `["a",2,null]` {
+		t.Fatalf("json.Marshal(v0),%s != [\"a\",2,null]", data)
+	}
+
+	var v1 = New()
+	if err = json.Unmarshal(data, v1); err != nil {
+		t.Fatalf("json.Unmarshal(%s) failed: %s", data, err)
+	}
+	checkVec(t, "v1", v1, []interface{}{"a", 2.0, nil})
+}
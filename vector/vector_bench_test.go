@@ -0,0 +1,57 @@
+package vector_test
+
+import (
+	"testing"
+
+	"github.com/lleo/go-functional-collections/vector"
+)
+
+func buildVals(n int) []interface{} {
+	var vals = make([]interface{}, n)
+	for i := range vals {
+		vals[i] = i
+	}
+	return vals
+}
+
+func BenchmarkAppend(b *testing.B) {
+	var v = vector.New()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v = v.Append(i)
+	}
+}
+
+func BenchmarkGet1M(b *testing.B) {
+	var v = vector.NewFromList(buildVals(1000000))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = v.Get(i % 1000000)
+	}
+}
+
+func BenchmarkSet1M(b *testing.B) {
+	var v = vector.NewFromList(buildVals(1000000))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = v.Set(i%1000000, i)
+	}
+}
+
+func BenchmarkConcat(b *testing.B) {
+	var small = vector.NewFromList(buildVals(100))
+	var v = vector.New()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v = v.Concat(small)
+	}
+}
+
+func BenchmarkSlice1M(b *testing.B) {
+	var v = vector.NewFromList(buildVals(1000000))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var lo = (i * 7919) % 500000
+		_ = v.Slice(lo, lo+400000)
+	}
+}
@@ -0,0 +1,32 @@
+package vector
+
+// Iter struct maintains the current state for walking the *Vector data
+// structure.
+type Iter struct {
+	vec  *Vector
+	idx  int           //index of the next value
+	leaf []interface{} //values of the leaf holding idx
+	off  int           //index of the next value in leaf
+}
+
+func newIter(v *Vector) *Iter {
+	var it = new(Iter)
+	it.vec = v
+	return it
+}
+
+// Next returns each sucessive value of the *Vector and true. When all values
+// have been returned it will return a nil value and false.
+func (it *Iter) Next() (interface{}, bool) {
+	if it.idx >= it.vec.numEnts {
+		return nil, false
+	}
+	if it.off >= len(it.leaf) {
+		it.leaf, it.off = it.vec.leafFor(it.idx)
+	}
+
+	var val = it.leaf[it.off]
+	it.off++
+	it.idx++
+	return val, true
+}
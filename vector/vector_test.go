@@ -0,0 +1,51 @@
+package vector
+
+import (
+	"testing"
+)
+
+func buildVals(start, n int) []interface{} {
+	var vals = make([]interface{}, n)
+	for i := range vals {
+		vals[i] = start + i
+	}
+	return vals
+}
+
+func buildVec(vals []interface{}) *Vector {
+	var v = New()
+	for _, val := range vals {
+		v = v.Append(val)
+	}
+	return v
+}
+
+// checkVec() fails the test unless v is valid and holds exactly vals.
+func checkVec(t *testing.T, name string, v *Vector, vals []interface{}) {
+	t.Helper()
+
+	if err := v.valid(); err != nil {
+		t.Fatalf("%s is not valid; err=%s", name, err)
+	}
+	if v.NumEntries() != len(vals) {
+		t.Fatalf("%s.NumEntries(),%d != %d", name, v.NumEntries(), len(vals))
+	}
+	for i, val := range vals {
+		if v0, found := v.Load(i); !found || v0 != val {
+			t.Fatalf("%s.Load(%d),%v,%t != %v,true", name, i, v0, found, val)
+		}
+	}
+
+	var n int
+	v.Range(func(i int, val interface{}) bool {
+		if i != n || val != vals[i] {
+			t.Fatalf("%s.Range() gave %d,%v; expected %d,%v",
+				name, i, val, n, vals[n])
+		}
+		n++
+		return true
+	})
+	if n != len(vals) {
+		t.Fatalf("%s.Range() called fn %d times; expected %d", name, n, len(vals))
+	}
+}